@@ -198,15 +198,28 @@ func runServer(args []string) {
 		},
 	}
 
+	// LLM calls get their own client: self-hosted models are slower than the
+	// hosted APIs and often live on private networks.
+	aiHTTPClient := &http.Client{
+		Timeout: 120 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         handlers.SSRFSafeDialer(cfg.App.AllowInternalWebhookURLs || cfg.App.AllowInternalAIURLs),
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+
 	app := &handlers.App{
-		Config:     cfg,
-		DB:         db,
-		Redis:      rdb,
-		Log:        lo,
-		WhatsApp:   waClient,
-		WSHub:      wsHub,
-		Queue:      jobQueue,
-		HTTPClient: httpClient,
+		Config:       cfg,
+		DB:           db,
+		Redis:        rdb,
+		Log:          lo,
+		WhatsApp:     waClient,
+		WSHub:        wsHub,
+		Queue:        jobQueue,
+		HTTPClient:   httpClient,
+		AIHTTPClient: aiHTTPClient,
 	}
 
 	// Initialize S3 client for call recordings (optional)
@@ -241,6 +254,9 @@ func runServer(args []string) {
 		lo.Info("TTS initialized", "piper", cfg.TTS.PiperBinary, "model", cfg.TTS.PiperModel)
 	}
 
+	// Knowledge documents whose ingest was cut short by a restart
+	app.FailStaleKnowledgeIngests()
+
 	// Start campaign stats subscriber for real-time WebSocket updates from worker
 	if err := app.StartCampaignStatsSubscriber(); err != nil {
 		lo.Error("Failed to start campaign stats subscriber", "error", err)
@@ -288,6 +304,24 @@ func runServer(args []string) {
 	go webhookDeliveryProcessor.Start(webhookDeliveryCtx)
 	lo.Info("Webhook delivery processor started")
 
+	// Start chatbot wake-up processor (runs every minute)
+	chatbotWakeupProcessor := handlers.NewChatbotWakeupProcessor(app, time.Minute)
+	chatbotWakeupCtx, chatbotWakeupCancel := context.WithCancel(context.Background())
+	go chatbotWakeupProcessor.Start(chatbotWakeupCtx)
+	lo.Info("Chatbot wake-up processor started")
+
+	// Start campaign A/B test processor (runs every minute)
+	campaignABTestProcessor := handlers.NewCampaignABTestProcessor(app, time.Minute)
+	campaignABTestCtx, campaignABTestCancel := context.WithCancel(context.Background())
+	go campaignABTestProcessor.Start(campaignABTestCtx)
+	lo.Info("Campaign A/B test processor started")
+
+	// Start campaign schedule processor for sequences and recurring campaigns (runs every minute)
+	campaignScheduleProcessor := handlers.NewCampaignScheduleProcessor(app, time.Minute)
+	campaignScheduleCtx, campaignScheduleCancel := context.WithCancel(context.Background())
+	go campaignScheduleProcessor.Start(campaignScheduleCtx)
+	lo.Info("Campaign schedule processor started")
+
 	// Start media cleanup processor (runs every 6 hours)
 	mediaCleanupProcessor := handlers.NewMediaCleanupProcessor(app, 6*time.Hour)
 	mediaCleanupCtx, mediaCleanupCancel := context.WithCancel(context.Background())
@@ -348,6 +382,24 @@ func runServer(args []string) {
 	webhookDeliveryProcessor.Stop()
 	lo.Info("Webhook delivery processor stopped")
 
+	// Stop chatbot wake-up processor
+	lo.Info("Stopping chatbot wake-up processor...")
+	chatbotWakeupCancel()
+	chatbotWakeupProcessor.Stop()
+	lo.Info("Chatbot wake-up processor stopped")
+
+	// Stop campaign A/B test processor
+	lo.Info("Stopping campaign A/B test processor...")
+	campaignABTestCancel()
+	campaignABTestProcessor.Stop()
+	lo.Info("Campaign A/B test processor stopped")
+
+	// Stop campaign schedule processor
+	lo.Info("Stopping campaign schedule processor...")
+	campaignScheduleCancel()
+	campaignScheduleProcessor.Stop()
+	lo.Info("Campaign schedule processor stopped")
+
 	// Stop workers first
 	if workerCancel != nil {
 		lo.Info("Stopping workers...", "count", len(workers))
@@ -659,6 +711,12 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/contacts/{id}/tags", app.UpdateContactTags)
 	g.GET("/api/contacts/{id}/session-data", app.GetContactSessionData)
 
+	// Custom contact fields
+	g.GET("/api/contact-fields", app.ListContactFields)
+	g.POST("/api/contact-fields", app.CreateContactField)
+	g.PUT("/api/contact-fields/{id}", app.UpdateContactField)
+	g.DELETE("/api/contact-fields/{id}", app.DeleteContactField)
+
 	// Generic Import/Export
 	g.POST("/api/export", app.ExportData)
 	g.POST("/api/import", app.ImportData)
@@ -676,6 +734,8 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/contacts/{id}/messages", app.SendMessage)
 	g.POST("/api/contacts/{id}/mark-read", app.MarkContactRead)
 	g.POST("/api/contacts/{id}/messages/{message_id}/reaction", app.SendReaction)
+	g.POST("/api/contacts/{id}/suggestions", app.RequestReplySuggestions)
+	g.POST("/api/contacts/{id}/suggestions/{suggestion_id}/use", app.UseReplySuggestion)
 	g.GET("/api/messages/{id}", app.GetMessageByID)
 	g.POST("/api/messages", app.SendMessage) // Legacy route
 	g.POST("/api/messages/template", app.SendTemplateMessage)
@@ -724,15 +784,37 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/campaigns/{id}/cancel", app.CancelCampaign)
 	g.POST("/api/campaigns/{id}/retry-failed", app.RetryFailed)
 	g.GET("/api/campaigns/{id}/progress", app.GetCampaign)
+	g.GET("/api/campaigns/{id}/ab-test", app.GetCampaignABTestResults)
 	g.POST("/api/campaigns/{id}/recipients/import", app.ImportRecipients)
 	g.GET("/api/campaigns/{id}/recipients", app.GetCampaignRecipients)
 	g.DELETE("/api/campaigns/{id}/recipients/{recipientId}", app.DeleteCampaignRecipient)
 	g.POST("/api/campaigns/{id}/media", app.UploadCampaignMedia)
 	g.GET("/api/campaigns/{id}/media", app.ServeCampaignMedia)
 
+	// Contact segments (campaign audiences)
+	g.GET("/api/segments", app.ListSegments)
+	g.POST("/api/segments", app.CreateSegment)
+	g.POST("/api/segments/preview", app.PreviewSegmentRules)
+	g.GET("/api/segments/{id}", app.GetSegment)
+	g.PUT("/api/segments/{id}", app.UpdateSegment)
+	g.DELETE("/api/segments/{id}", app.DeleteSegment)
+	g.GET("/api/segments/{id}/preview", app.PreviewSegment)
+
+	// Campaign sequences (drip campaigns)
+	g.GET("/api/sequences", app.ListSequences)
+	g.POST("/api/sequences", app.CreateSequence)
+	g.GET("/api/sequences/{id}", app.GetSequence)
+	g.PUT("/api/sequences/{id}", app.UpdateSequence)
+	g.DELETE("/api/sequences/{id}", app.DeleteSequence)
+	g.GET("/api/sequences/{id}/enrollments", app.ListSequenceEnrollments)
+	g.POST("/api/sequences/{id}/enrollments", app.EnrollSequenceContact)
+	g.DELETE("/api/sequences/{id}/enrollments/{enrollmentId}", app.ExitSequenceEnrollment)
+
 	// Chatbot Settings
 	g.GET("/api/chatbot/settings", app.GetChatbotSettings)
 	g.PUT("/api/chatbot/settings", app.UpdateChatbotSettings)
+	g.GET("/api/chatbot/ai-budget", app.GetAIBudget)
+	g.PUT("/api/chatbot/ai-budget", app.UpdateAIBudget)
 
 	// Keyword Rules
 	g.GET("/api/chatbot/keywords", app.ListKeywordRules)
@@ -744,7 +826,10 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	// Chatbot Flows
 	g.GET("/api/chatbot/flows", app.ListChatbotFlows)
 	g.POST("/api/chatbot/flows", app.CreateChatbotFlow)
+	g.POST("/api/chatbot/flows/media", app.UploadChatbotMedia)
 	g.GET("/api/chatbot/flows/{id}", app.GetChatbotFlow)
+	g.GET("/api/chatbot/flows/{id}/analytics", app.GetChatbotFlowAnalytics)
+	g.POST("/api/chatbot/flows/{id}/start", app.StartChatbotFlow)
 	g.PUT("/api/chatbot/flows/{id}", app.UpdateChatbotFlow)
 	g.DELETE("/api/chatbot/flows/{id}", app.DeleteChatbotFlow)
 
@@ -755,12 +840,41 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/chatbot/ai-contexts/{id}", app.UpdateAIContext)
 	g.DELETE("/api/chatbot/ai-contexts/{id}", app.DeleteAIContext)
 
+	// AI tools (functions the AI can call mid-conversation)
+	g.GET("/api/chatbot/ai-tools", app.ListAITools)
+	g.POST("/api/chatbot/ai-tools", app.CreateAITool)
+	g.GET("/api/chatbot/ai-tools/{id}", app.GetAITool)
+	g.PUT("/api/chatbot/ai-tools/{id}", app.UpdateAITool)
+	g.DELETE("/api/chatbot/ai-tools/{id}", app.DeleteAITool)
+
+	// Intents (AI classification of incoming messages, with review)
+	g.GET("/api/chatbot/intents", app.ListChatbotIntents)
+	g.POST("/api/chatbot/intents", app.CreateChatbotIntent)
+	g.POST("/api/chatbot/intents/classify", app.ClassifyChatbotIntent)
+	g.GET("/api/chatbot/intents/classifications", app.ListIntentClassifications)
+	g.PUT("/api/chatbot/intents/classifications/{id}", app.ReviewIntentClassification)
+	g.GET("/api/chatbot/intents/{id}", app.GetChatbotIntent)
+	g.PUT("/api/chatbot/intents/{id}", app.UpdateChatbotIntent)
+	g.DELETE("/api/chatbot/intents/{id}", app.DeleteChatbotIntent)
+
+	// AI redaction audit log (PII masked before text reaches the AI provider)
+	g.GET("/api/chatbot/redactions", app.ListAIRedactions)
+
+	// Knowledge base (RAG documents for AI responses)
+	g.GET("/api/chatbot/knowledge", app.ListKnowledgeDocuments)
+	g.POST("/api/chatbot/knowledge", app.UploadKnowledgeDocument)
+	g.POST("/api/chatbot/knowledge/search", app.SearchKnowledgeBase)
+	g.GET("/api/chatbot/knowledge/{id}", app.GetKnowledgeDocument)
+	g.POST("/api/chatbot/knowledge/{id}/reindex", app.ReindexKnowledgeDocument)
+	g.DELETE("/api/chatbot/knowledge/{id}", app.DeleteKnowledgeDocument)
+
 	// Agent Transfers
 	g.GET("/api/chatbot/transfers", app.ListAgentTransfers)
 	g.POST("/api/chatbot/transfers", app.CreateAgentTransfer)
 	g.POST("/api/chatbot/transfers/pick", app.PickNextTransfer)
 	g.PUT("/api/chatbot/transfers/{id}/resume", app.ResumeFromTransfer)
 	g.PUT("/api/chatbot/transfers/{id}/assign", app.AssignAgentTransfer)
+	g.POST("/api/chatbot/transfers/{id}/summary", app.RegenerateTransferSummary)
 
 	// Teams (admin/manager - access control in handler)
 	g.GET("/api/teams", app.ListTeams)
@@ -787,6 +901,12 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	// Sessions (admin/debug)
 	g.GET("/api/chatbot/sessions", app.ListChatbotSessions)
 	g.GET("/api/chatbot/sessions/{id}", app.GetChatbotSession)
+	g.GET("/api/chatbot/sessions/{id}/state", app.GetChatbotSessionState)
+	g.PUT("/api/chatbot/sessions/{id}/variables", app.UpdateChatbotSessionVariables)
+	g.POST("/api/chatbot/sessions/{id}/jump", app.JumpChatbotSession)
+	g.POST("/api/chatbot/sessions/{id}/restart", app.RestartChatbotSession)
+	g.POST("/api/chatbot/sessions/{id}/cancel", app.CancelChatbotSession)
+	g.POST("/api/chatbot/sessions/{id}/takeover", app.TakeOverChatbotSession)
 
 	// Analytics
 	g.GET("/api/analytics/dashboard", app.GetDashboardStats)
@@ -795,6 +915,8 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/analytics/agents", app.GetAgentAnalytics)
 	g.GET("/api/analytics/agents/{id}", app.GetAgentDetails)
 	g.GET("/api/analytics/agents/comparison", app.GetAgentComparison)
+	g.GET("/api/analytics/ai-suggestions", app.GetReplySuggestionStats)
+	g.GET("/api/analytics/ai-usage", app.GetAIUsageAnalytics)
 
 	// Meta WhatsApp Analytics
 	g.GET("/api/analytics/meta", app.GetMetaAnalytics)
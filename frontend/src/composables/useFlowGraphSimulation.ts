@@ -90,6 +90,7 @@ export function useFlowGraphSimulation(
       case 'whatsapp_flow':
         return 'whatsapp_flow'
       case 'prompt':
+      case 'wait_for_reply':
         return 'text'
       default:
         return null
@@ -259,6 +260,15 @@ export function useFlowGraphSimulation(
         return 'default'
       case 'message':
         return execMessage(node)
+      case 'media': {
+        const caption = interpolate(stringField(node, 'caption'), state.variables)
+        const kind = stringField(node, 'media_type') || 'media'
+        addMessage('bot', caption ? `[${kind}] ${caption}` : `[${kind}]`, { stepName: node.id })
+        return 'default'
+      }
+      case 'template':
+        addMessage('bot', `[Template: ${stringField(node, 'template_name') || '?'}]`, { stepName: node.id })
+        return 'default'
       case 'buttons':
         return execButtons(node)
       case 'end':
@@ -282,6 +292,34 @@ export function useFlowGraphSimulation(
         // In a single-flow preview we can't actually jump; show a system note.
         addMessage('system', `[goto_flow] would jump to flow ${node.config?.flow_id || '?'}`)
         return '__end__'
+      case 'call_flow':
+        // Sub-flows aren't loaded in the preview; continue as if it returned.
+        addMessage('system', `[call_flow] would run flow ${node.config?.flow_id || '?'} and return`)
+        return 'default'
+      case 'wait':
+        // The preview doesn't sleep; note the wait and carry on.
+        addMessage('system', `[wait] ${describeWait(node)} — skipped in preview`)
+        return 'default'
+      case 'wait_for_reply':
+        addMessage('system', '[wait_for_reply] waiting for a reply; the timeout is not simulated')
+        state.status = 'waiting_input'
+        return '__yield__'
+      case 'split':
+        return execSplit(node)
+      case 'foreach':
+        return execForeach(node)
+      case 'script':
+        // Scripts run server-side in a sandbox; the preview doesn't execute them.
+        addMessage('system', '[script] simulated — code runs only in live conversations')
+        return 'default'
+      case 'tag_contact':
+      case 'set_contact_field':
+      case 'set_opt_out':
+      case 'assign_contact':
+      case 'add_note':
+        // Contact changes aren't applied in preview.
+        addMessage('system', `[${node.type}] simulated — contact not changed in preview`)
+        return 'default'
       case 'ai_response':
         addMessage('system', '[ai_response] simulated — backend AI is not invoked in preview')
         return 'default'
@@ -323,6 +361,69 @@ export function useFlowGraphSimulation(
     return '__yield__'
   }
 
+  // The backend assigns variants by hashing the contact; the preview has
+  // no contact, so it picks one at random by weight.
+  function execSplit(node: ChatNode): string {
+    const variants = ((node.config?.variants as any[] | undefined) || []).filter((v) => v?.id && Number(v.weight) > 0)
+    const total = variants.reduce((sum, v) => sum + Number(v.weight), 0)
+    if (!total) return 'default'
+    let bucket = Math.random() * total
+    let picked = variants[variants.length - 1]
+    for (const v of variants) {
+      bucket -= Number(v.weight)
+      if (bucket < 0) {
+        picked = v
+        break
+      }
+    }
+    const storeAs = stringField(node, 'store_as')
+    if (storeAs) setVariable(storeAs, picked.id)
+    addMessage('system', `[split] variant ${picked.label || picked.id}`)
+    log('branch', node.id, { variant: picked.id })
+    return `variant:${picked.id}`
+  }
+
+  // Mirrors the backend: the cursor lives in variables.__loops__ and the
+  // body routes back to the foreach node to advance.
+  function execForeach(node: ChatNode): string {
+    const path = stringField(node, 'list').replace(/^\{\{|\}\}$/g, '').trim()
+    let items: any = path.split('.').reduce((v: any, k) => v?.[k], state.variables)
+    if (typeof items === 'string') {
+      try {
+        items = JSON.parse(items)
+      } catch {
+        items = null
+      }
+    }
+    const max = Number(node.config?.max_iterations)
+    items = (Array.isArray(items) ? items : []).slice(0, Math.min(max > 0 ? max : 20, 50))
+
+    const loops = { ...(state.variables.__loops__ || {}) }
+    if (loops[node.id] === undefined && !items.length) {
+      const hasEmpty = graph.value?.edges.some((e) => e.from === node.id && e.condition === 'empty')
+      return hasEmpty ? 'empty' : 'done'
+    }
+    const index = (loops[node.id] ?? -1) + 1
+    if (index >= items.length) {
+      delete loops[node.id]
+      state.variables.__loops__ = loops
+      return 'done'
+    }
+    loops[node.id] = index
+    state.variables.__loops__ = loops
+    setVariable(stringField(node, 'item_as') || 'item', items[index])
+    setVariable(stringField(node, 'index_as') || 'index', index)
+    return 'item'
+  }
+
+  function describeWait(node: ChatNode): string {
+    if (node.config?.mode === 'until') {
+      const days = (node.config?.days as string[] | undefined) || []
+      return `until ${node.config?.time || '?'}${days.length ? ` on ${days.join(', ')}` : ''}`
+    }
+    return `for ${node.config?.amount || 0} ${node.config?.unit || 'minutes'}`
+  }
+
   function execTransfer(node: ChatNode): string {
     const body = interpolate(stringField(node, 'body', 'message', 'text'), state.variables)
     if (body) addMessage('bot', body, { stepName: node.id })
@@ -450,6 +551,12 @@ export function useFlowGraphSimulation(
         await advance(node, 'default')
         return
       }
+      if (node.type === 'wait_for_reply') {
+        const storeAs = stringField(node, 'store_as')
+        if (storeAs) setVariable(storeAs, input)
+        await advance(node, 'reply')
+        return
+      }
       // Treat free-text into a buttons node as no-op (re-yield).
       state.status = 'waiting_input'
     } else {
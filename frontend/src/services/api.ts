@@ -324,7 +324,12 @@ export const messagesService = {
     return api.post('/messages/template', { contact_id: contactId, ...data })
   },
   sendReaction: (contactId: string, messageId: string, emoji: string) =>
-    api.post(`/contacts/${contactId}/messages/${messageId}/reaction`, { emoji })
+    api.post(`/contacts/${contactId}/messages/${messageId}/reaction`, { emoji }),
+  // AI reply suggestions arrive over the websocket (ai_reply_suggestions)
+  requestSuggestions: (contactId: string) =>
+    api.post<{ id: string; status: string }>(`/contacts/${contactId}/suggestions`),
+  useSuggestion: (contactId: string, suggestionId: string, data: { index: number; edited: boolean }) =>
+    api.post(`/contacts/${contactId}/suggestions/${suggestionId}/use`, data)
 }
 
 export const templatesService = {
@@ -354,7 +359,7 @@ export const flowsService = {
 }
 
 export const campaignsService = {
-  list: (params?: { status?: string; from?: string; to?: string; search?: string; page?: number; limit?: number }) =>
+  list: (params?: { status?: string; from?: string; to?: string; search?: string; parent_campaign_id?: string; page?: number; limit?: number }) =>
     api.get('/campaigns', { params }),
   get: (id: string) => api.get(`/campaigns/${id}`),
   create: (data: any) => api.post('/campaigns', data),
@@ -364,6 +369,7 @@ export const campaignsService = {
   pause: (id: string) => api.post(`/campaigns/${id}/pause`),
   cancel: (id: string) => api.post(`/campaigns/${id}/cancel`),
   retryFailed: (id: string) => api.post(`/campaigns/${id}/retry-failed`),
+  getABTestResults: (id: string) => api.get(`/campaigns/${id}/ab-test`),
   // Recipients
   getRecipients: (id: string) => api.get(`/campaigns/${id}/recipients`),
   addRecipients: (id: string, recipients: Array<{ phone_number: string; recipient_name?: string; template_params?: Record<string, any> }>) =>
@@ -382,10 +388,77 @@ export const campaignsService = {
     api.get(`/campaigns/${campaignId}/media`, { responseType: 'arraybuffer' })
 }
 
+export interface CampaignVariantResult {
+  variant_id: string
+  name: string
+  template_name?: string
+  recipients: number
+  sent: number
+  delivered: number
+  read: number
+  replied: number
+  clicked: number
+  rate: number
+  is_winner: boolean
+}
+
+export interface ContactField {
+  id: string
+  key: string
+  label: string
+  type: 'text' | 'number' | 'date' | 'enum' | 'boolean' | 'phone' | 'email'
+  options: string[]
+  default_value: string
+  is_required: boolean
+  is_unique: boolean
+  show_in_panel: boolean
+  position: number
+}
+
+export const contactFieldsService = {
+  list: () => api.get<{ fields: ContactField[] }>('/contact-fields'),
+  create: (data: Partial<ContactField>) => api.post('/contact-fields', data),
+  update: (id: string, data: Partial<ContactField>) => api.put(`/contact-fields/${id}`, data),
+  delete: (id: string) => api.delete(`/contact-fields/${id}`)
+}
+
+export const segmentsService = {
+  list: (params?: { search?: string; page?: number; limit?: number }) =>
+    api.get('/segments', { params }),
+  get: (id: string) => api.get(`/segments/${id}`),
+  create: (data: any) => api.post('/segments', data),
+  update: (id: string, data: any) => api.put(`/segments/${id}`, data),
+  delete: (id: string) => api.delete(`/segments/${id}`),
+  preview: (id: string) =>
+    api.get<{ count: number; sample: Array<{ id: string; phone_number: string; profile_name: string }> }>(`/segments/${id}/preview`),
+  previewRules: (rules: any) =>
+    api.post<{ count: number; sample: Array<{ id: string; phone_number: string; profile_name: string }> }>('/segments/preview', { rules })
+}
+
+export const sequencesService = {
+  list: (params?: { search?: string; page?: number; limit?: number }) =>
+    api.get('/sequences', { params }),
+  get: (id: string) => api.get(`/sequences/${id}`),
+  create: (data: any) => api.post('/sequences', data),
+  update: (id: string, data: any) => api.put(`/sequences/${id}`, data),
+  delete: (id: string) => api.delete(`/sequences/${id}`),
+  // Enrollments
+  listEnrollments: (id: string, params?: { status?: string; page?: number; limit?: number }) =>
+    api.get(`/sequences/${id}/enrollments`, { params }),
+  enroll: (id: string, data: { contact_id?: string; phone_number?: string }) =>
+    api.post(`/sequences/${id}/enrollments`, data),
+  exitEnrollment: (id: string, enrollmentId: string) =>
+    api.delete(`/sequences/${id}/enrollments/${enrollmentId}`)
+}
+
 export const chatbotService = {
   // Settings
   getSettings: () => api.get('/chatbot/settings'),
   updateSettings: (data: any) => api.put('/chatbot/settings', data),
+  getAIBudget: () => api.get('/chatbot/ai-budget'),
+  updateAIBudget: (data: any) => api.put('/chatbot/ai-budget', data),
+  listRedactions: (params?: { contact_id?: string; feature?: string; page?: number; limit?: number }) =>
+    api.get('/chatbot/redactions', { params }),
 
   // Keywords
   listKeywords: (params?: { search?: string; page?: number; limit?: number }) =>
@@ -399,9 +472,27 @@ export const chatbotService = {
   listFlows: (params?: { search?: string; page?: number; limit?: number }) =>
     api.get<{ flows: any[]; total?: number }>('/chatbot/flows', { params }),
   getFlow: (id: string) => api.get(`/chatbot/flows/${id}`),
+  getFlowAnalytics: (id: string, params?: { from?: string; to?: string }) =>
+    api.get<ChatbotFlowAnalytics>(`/chatbot/flows/${id}/analytics`, { params }),
   createFlow: (data: any) => api.post('/chatbot/flows', data),
   updateFlow: (id: string, data: any) => api.put(`/chatbot/flows/${id}`, data),
   deleteFlow: (id: string) => api.delete(`/chatbot/flows/${id}`),
+  uploadFlowMedia: (file: File) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return api.post<{ media_path: string; filename: string; mime_type: string; size: number }>('/chatbot/flows/media', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' }
+    })
+  },
+
+  // Sessions
+  getSessionState: (id: string) => api.get(`/chatbot/sessions/${id}/state`),
+  updateSessionVariables: (id: string, data: { set?: Record<string, any>; unset?: string[] }) =>
+    api.put(`/chatbot/sessions/${id}/variables`, data),
+  jumpSession: (id: string, nodeId: string) => api.post(`/chatbot/sessions/${id}/jump`, { node_id: nodeId }),
+  restartSession: (id: string) => api.post(`/chatbot/sessions/${id}/restart`),
+  cancelSession: (id: string) => api.post(`/chatbot/sessions/${id}/cancel`),
+  takeOverSession: (id: string, notes?: string) => api.post(`/chatbot/sessions/${id}/takeover`, { notes }),
 
   // AI Contexts
   listAIContexts: (params?: { search?: string; page?: number; limit?: number }) =>
@@ -411,6 +502,28 @@ export const chatbotService = {
   updateAIContext: (id: string, data: any) => api.put(`/chatbot/ai-contexts/${id}`, data),
   deleteAIContext: (id: string) => api.delete(`/chatbot/ai-contexts/${id}`),
 
+  // AI Tools
+  listAITools: (params?: { search?: string; page?: number; limit?: number }) =>
+    api.get<{ tools: any[]; total?: number }>('/chatbot/ai-tools', { params }),
+  getAITool: (id: string) => api.get(`/chatbot/ai-tools/${id}`),
+  createAITool: (data: any) => api.post('/chatbot/ai-tools', data),
+  updateAITool: (id: string, data: any) => api.put(`/chatbot/ai-tools/${id}`, data),
+  deleteAITool: (id: string) => api.delete(`/chatbot/ai-tools/${id}`),
+
+  // Intents
+  listIntents: (params?: { search?: string; page?: number; limit?: number }) =>
+    api.get<{ intents: any[]; total?: number }>('/chatbot/intents', { params }),
+  getIntent: (id: string) => api.get(`/chatbot/intents/${id}`),
+  createIntent: (data: any) => api.post('/chatbot/intents', data),
+  updateIntent: (id: string, data: any) => api.put(`/chatbot/intents/${id}`, data),
+  deleteIntent: (id: string) => api.delete(`/chatbot/intents/${id}`),
+  classifyIntent: (data: { text: string; whatsapp_account?: string }) =>
+    api.post('/chatbot/intents/classify', data),
+  listIntentClassifications: (params?: { intent_id?: string; routed?: boolean; reviewed?: boolean; page?: number; limit?: number }) =>
+    api.get<{ classifications: any[]; total?: number }>('/chatbot/intents/classifications', { params }),
+  reviewIntentClassification: (id: string, data: { correct_intent_id: string | null; add_as_example?: boolean }) =>
+    api.put(`/chatbot/intents/classifications/${id}`, data),
+
   // Agent Transfers
   listTransfers: (params?: {
     status?: string
@@ -429,6 +542,7 @@ export const chatbotService = {
   }) => api.post('/chatbot/transfers', data),
   pickNextTransfer: () => api.post('/chatbot/transfers/pick'),
   resumeTransfer: (id: string) => api.put(`/chatbot/transfers/${id}/resume`),
+  regenerateTransferSummary: (id: string) => api.post(`/chatbot/transfers/${id}/summary`),
   assignTransfer: (id: string, agentId: string | null, teamId?: string | null) =>
     api.put(`/chatbot/transfers/${id}/assign`, { agent_id: agentId, team_id: teamId })
 }
@@ -480,7 +594,11 @@ export const cannedResponsesService = {
 
 export const agentAnalyticsService = {
   getSummary: (params?: { from?: string; to?: string; agent_id?: string }) =>
-    api.get('/analytics/agents', { params })
+    api.get('/analytics/agents', { params }),
+  getSuggestionStats: (params?: { from?: string; to?: string }) =>
+    api.get('/analytics/ai-suggestions', { params }),
+  getAIUsage: (params?: { from?: string; to?: string }) =>
+    api.get('/analytics/ai-usage', { params })
 }
 
 // Meta WhatsApp Analytics Types
@@ -1018,6 +1136,9 @@ export interface ConversationNote {
   created_by_id: string
   created_by_name: string
   content: string
+  source: 'agent' | 'ai_summary' | 'chatbot'
+  is_pinned: boolean
+  transfer_id?: string
   created_at: string
   updated_at: string
 }
@@ -1104,6 +1225,8 @@ export interface IVRFlowData {
 export type ChatNodeType =
   | 'start'
   | 'message'
+  | 'media'
+  | 'template'
   | 'buttons'
   | 'end'
   | 'prompt'
@@ -1115,7 +1238,42 @@ export type ChatNodeType =
   | 'transfer'
   | 'webhook'
   | 'goto_flow'
+  | 'call_flow'
   | 'whatsapp_flow'
+  | 'wait'
+  | 'wait_for_reply'
+  | 'split'
+  | 'foreach'
+  | 'script'
+  | 'tag_contact'
+  | 'set_contact_field'
+  | 'set_opt_out'
+  | 'assign_contact'
+  | 'add_note'
+
+export interface ChatbotVariantAnalytics {
+  variant: string
+  label: string
+  weight: number
+  sessions: number
+  completed: number
+  converted: number
+  completion_rate: number
+  conversion_rate: number
+}
+
+export interface ChatbotFlowAnalytics {
+  from: string
+  to: string
+  sessions: number
+  completed: number
+  splits: {
+    node_id: string
+    label: string
+    goal_node?: string
+    variants: ChatbotVariantAnalytics[]
+  }[]
+}
 
 export interface ChatNode {
   id: string
@@ -45,6 +45,7 @@ function showNotification(title: string, body: string, contactId: string) {
 const WS_TYPE_AUTH = 'auth'
 const WS_TYPE_NEW_MESSAGE = 'new_message'
 const WS_TYPE_STATUS_UPDATE = 'status_update'
+const WS_TYPE_CONTACT_UPDATE = 'contact_update'
 const WS_TYPE_SET_CONTACT = 'set_contact'
 const WS_TYPE_PING = 'ping'
 const WS_TYPE_PONG = 'pong'
@@ -88,6 +89,12 @@ const WS_TYPE_CONVERSATION_NOTE_CREATED = 'conversation_note_created'
 const WS_TYPE_CONVERSATION_NOTE_UPDATED = 'conversation_note_updated'
 const WS_TYPE_CONVERSATION_NOTE_DELETED = 'conversation_note_deleted'
 
+// AI reply suggestion types
+const WS_TYPE_AI_REPLY_SUGGESTIONS = 'ai_reply_suggestions'
+
+// Chatbot session types
+const WS_TYPE_CHATBOT_SESSION_UPDATE = 'chatbot_session_update'
+
 interface WSMessage {
   type: string
   payload: any
@@ -106,6 +113,8 @@ class WebSocketService {
   private isConnected = false
   private hasConnectedBefore = false
   private campaignStatsCallbacks: ((payload: any) => void)[] = []
+  private replySuggestionsCallbacks: ((payload: any) => void)[] = []
+  private chatbotSessionCallbacks: ((payload: any) => void)[] = []
   private getTokenFn: (() => Promise<string | null>) | null = null
 
   async connect(getToken?: () => Promise<string | null>) {
@@ -226,6 +235,9 @@ class WebSocketService {
         case WS_TYPE_STATUS_UPDATE:
           this.handleStatusUpdate(store, message.payload)
           break
+        case WS_TYPE_CONTACT_UPDATE:
+          store.updateContact(message.payload)
+          break
         case WS_TYPE_AGENT_TRANSFER:
           this.handleAgentTransfer(message.payload)
           break
@@ -282,6 +294,12 @@ class WebSocketService {
         case WS_TYPE_CONVERSATION_NOTE_DELETED:
           useNotesStore().onNoteDeleted(message.payload.id)
           break
+        case WS_TYPE_AI_REPLY_SUGGESTIONS:
+          this.replySuggestionsCallbacks.forEach(callback => callback(message.payload))
+          break
+        case WS_TYPE_CHATBOT_SESSION_UPDATE:
+          this.chatbotSessionCallbacks.forEach(callback => callback(message.payload))
+          break
         default:
           // Unknown message type, ignore
           break
@@ -575,6 +593,26 @@ class WebSocketService {
     }
   }
 
+  onReplySuggestions(callback: (payload: any) => void) {
+    this.replySuggestionsCallbacks.push(callback)
+    return () => {
+      const index = this.replySuggestionsCallbacks.indexOf(callback)
+      if (index > -1) {
+        this.replySuggestionsCallbacks.splice(index, 1)
+      }
+    }
+  }
+
+  onChatbotSessionUpdate(callback: (payload: any) => void) {
+    this.chatbotSessionCallbacks.push(callback)
+    return () => {
+      const index = this.chatbotSessionCallbacks.indexOf(callback)
+      if (index > -1) {
+        this.chatbotSessionCallbacks.splice(index, 1)
+      }
+    }
+  }
+
   // Reconnection must not outlive the session: logout is a client-side nav, so
   // this singleton and its backoff timer survive it. Without this gate, once the
   // socket closes post-logout the token fetch returns null (401) and — with the
@@ -30,6 +30,8 @@ export interface Contact {
   assigned_user_id?: string
   whatsapp_account?: string
   marketing_opt_out?: boolean
+  language?: string
+  language_source?: 'detected' | 'flow' | 'manual'
   created_at: string
   updated_at: string
 }
@@ -364,6 +366,16 @@ export const useContactsStore = defineStore('contacts', () => {
     }
   }
 
+  function updateContact(updated: Contact) {
+    const index = contacts.value.findIndex(c => c.id === updated.id)
+    if (index !== -1) {
+      contacts.value[index] = { ...contacts.value[index], ...updated }
+    }
+    if (currentContact.value?.id === updated.id) {
+      currentContact.value = { ...currentContact.value, ...updated }
+    }
+  }
+
   // Debounce server-side search so each keystroke doesn't fire a request.
   let searchDebounceHandle: ReturnType<typeof setTimeout> | null = null
   watch(searchQuery, (query) => {
@@ -407,6 +419,7 @@ export const useContactsStore = defineStore('contacts', () => {
     setReplyingTo,
     clearReplyingTo,
     updateMessageReactions,
-    updateContactTags
+    updateContactTags,
+    updateContact
   }
 })
@@ -0,0 +1,268 @@
+// Package ai wraps the LLM chat-completion and embedding APIs used by the
+// chatbot behind small provider interfaces. Each provider speaks its vendor's
+// wire format and accepts a configurable base URL, so the same code path works
+// against the hosted APIs and self-hosted OpenAI-compatible servers (Ollama,
+// vLLM, LocalAI). The package has no knowledge of the chatbot domain; callers
+// build a Request from their own settings and history.
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Supported provider identifiers. These match the values stored in
+// models.AIProvider so callers can pass the setting straight through.
+const (
+	ProviderOpenAI           = "openai"
+	ProviderAnthropic        = "anthropic"
+	ProviderGoogle           = "google"
+	ProviderOpenAICompatible = "openai_compatible"
+)
+
+// maxResponseBytes caps how much of a provider response is read into memory.
+const maxResponseBytes = 4 * 1024 * 1024
+
+// Role identifies the author of a conversation turn.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	// RoleTool carries the result of a ToolCall back to the model.
+	RoleTool Role = "tool"
+)
+
+// Message is a single conversation turn sent to the model.
+type Message struct {
+	Role    Role
+	Content string
+	// ToolCalls are the calls an assistant turn requested; set when
+	// replaying a tool-calling turn back to the model.
+	ToolCalls []ToolCall
+	// ToolCallID and ToolName identify the call a RoleTool message answers.
+	ToolCallID string
+	ToolName   string
+}
+
+// Tool is a function the model may call. Parameters is a JSON Schema
+// object describing the arguments.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall is a model's request to run a Tool. Arguments is the raw JSON
+// object the model produced; it is not validated against the schema.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// Request is a provider-neutral chat-completion request.
+type Request struct {
+	Model        string
+	SystemPrompt string
+	Messages     []Message
+	Tools        []Tool
+	MaxTokens    int
+	Temperature  float64 // 0 = provider default
+}
+
+// Usage reports token consumption for a single completion, when the
+// provider returns it.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Response is a provider-neutral chat-completion result. When ToolCalls is
+// non-empty the model is waiting for their results and Content may be empty.
+type Response struct {
+	Content   string
+	ToolCalls []ToolCall
+	Model     string
+	Usage     Usage
+}
+
+// Provider generates chat completions against a single LLM backend.
+type Provider interface {
+	// Name returns the provider identifier (one of the Provider* constants).
+	Name() string
+	// Complete sends the request and returns the model's reply.
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// Embedder turns text into vectors for semantic search. Implemented by the
+// providers whose API offers an embeddings endpoint.
+type Embedder interface {
+	// Embed returns one vector per input, in input order.
+	Embed(ctx context.Context, model string, inputs []string) (*EmbeddingResponse, error)
+}
+
+// EmbeddingResponse holds the vectors for an Embed call.
+type EmbeddingResponse struct {
+	Vectors [][]float32
+	Usage   Usage
+}
+
+// ErrEmbeddingsUnsupported is returned by NewEmbedder for providers without
+// an embeddings API (Anthropic).
+var ErrEmbeddingsUnsupported = errors.New("provider does not support embeddings")
+
+// Config selects and configures a provider.
+type Config struct {
+	Provider string
+	APIKey   string
+	// BaseURL overrides the vendor's default endpoint root. Required for
+	// openai_compatible, optional for the hosted providers (useful for
+	// gateways and tests).
+	BaseURL string
+	// Headers are sent on every request in addition to the provider's
+	// own auth headers, e.g. a gateway tenant id.
+	Headers map[string]string
+}
+
+// New returns the Provider described by cfg. client is used for all HTTP
+// calls; pass a client with an SSRF-safe dialer in production.
+func New(cfg Config, client *http.Client) (Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("openai: API key is required")
+		}
+		return newOpenAI(ProviderOpenAI, cfg, defaultOpenAIBaseURL, client), nil
+	case ProviderOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("openai_compatible: base URL is required")
+		}
+		return newOpenAI(ProviderOpenAICompatible, cfg, cfg.BaseURL, client), nil
+	case ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("anthropic: API key is required")
+		}
+		return newAnthropic(cfg, client), nil
+	case ProviderGoogle:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("google: API key is required")
+		}
+		return newGoogle(cfg, client), nil
+	default:
+		return nil, fmt.Errorf("unsupported AI provider: %s", cfg.Provider)
+	}
+}
+
+// NewEmbedder returns the Embedder for cfg, or ErrEmbeddingsUnsupported when
+// the provider has no embeddings API.
+func NewEmbedder(cfg Config, client *http.Client) (Embedder, error) {
+	p, err := New(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", cfg.Provider, ErrEmbeddingsUnsupported)
+	}
+	return e, nil
+}
+
+// RequiresAPIKey reports whether the provider refuses to run without an API
+// key. Self-hosted OpenAI-compatible servers usually don't need one.
+func RequiresAPIKey(provider string) bool {
+	return provider != ProviderOpenAICompatible
+}
+
+// baseURLOr returns cfg.BaseURL without a trailing slash, or def when unset.
+func baseURLOr(cfg Config, def string) string {
+	if cfg.BaseURL == "" {
+		return def
+	}
+	return strings.TrimRight(cfg.BaseURL, "/")
+}
+
+// postJSON marshals payload, POSTs it to url with the given headers, and
+// returns the raw response body and status code.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) ([]byte, int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	return respBody, resp.StatusCode, nil
+}
+
+// apiErrorMessage extracts {"error": {"message": "..."}} — the shape shared by
+// OpenAI, Anthropic and Google — falling back to the HTTP status.
+func apiErrorMessage(body []byte, status int) string {
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		return errResp.Error.Message
+	}
+	return fmt.Sprintf("HTTP %d", status)
+}
+
+// toolArguments decodes a ToolCall's arguments into an object, treating
+// empty or malformed JSON as no arguments.
+func toolArguments(raw string) map[string]any {
+	args := map[string]any{}
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &args)
+	}
+	return args
+}
+
+// toolParameters returns t.Parameters, or an empty object schema so
+// providers that require one accept argument-less tools.
+func toolParameters(t Tool) map[string]any {
+	if len(t.Parameters) > 0 {
+		return t.Parameters
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+// mergeHeaders returns the provider's auth headers overlaid on cfg.Headers,
+// so custom headers can't silently replace credentials.
+func mergeHeaders(custom map[string]string, auth map[string]string) map[string]string {
+	out := make(map[string]string, len(custom)+len(auth))
+	for k, v := range custom {
+		out[k] = v
+	}
+	for k, v := range auth {
+		out[k] = v
+	}
+	return out
+}
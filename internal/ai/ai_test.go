@@ -0,0 +1,365 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Validation(t *testing.T) {
+	_, err := ai.New(ai.Config{Provider: ai.ProviderOpenAI}, nil)
+	assert.Error(t, err, "openai requires an API key")
+
+	_, err = ai.New(ai.Config{Provider: ai.ProviderOpenAICompatible}, nil)
+	assert.Error(t, err, "openai_compatible requires a base URL")
+
+	_, err = ai.New(ai.Config{Provider: "unknown", APIKey: "k"}, nil)
+	assert.Error(t, err)
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderOpenAICompatible, BaseURL: "http://localhost:11434/v1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ai.ProviderOpenAICompatible, p.Name())
+}
+
+func TestRequiresAPIKey(t *testing.T) {
+	assert.True(t, ai.RequiresAPIKey(ai.ProviderOpenAI))
+	assert.True(t, ai.RequiresAPIKey(ai.ProviderAnthropic))
+	assert.True(t, ai.RequiresAPIKey(ai.ProviderGoogle))
+	assert.False(t, ai.RequiresAPIKey(ai.ProviderOpenAICompatible))
+}
+
+func TestOpenAICompatible_Complete(t *testing.T) {
+	var gotPath, gotAuth, gotTenant string
+	var gotBody map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotTenant = r.Header.Get("X-Tenant")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{
+			"model": "llama3",
+			"choices": [{"message": {"role": "assistant", "content": "  hello there  "}}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 3}
+		}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{
+		Provider: ai.ProviderOpenAICompatible,
+		BaseURL:  srv.URL + "/v1/",
+		Headers:  map[string]string{"X-Tenant": "acme"},
+	}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Complete(context.Background(), ai.Request{
+		Model:        "llama3",
+		SystemPrompt: "be brief",
+		Messages:     []ai.Message{{Role: ai.RoleUser, Content: "hi"}},
+		MaxTokens:    50,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/chat/completions", gotPath)
+	assert.Empty(t, gotAuth, "no Authorization header without an API key")
+	assert.Equal(t, "acme", gotTenant)
+	assert.Equal(t, "llama3", gotBody["model"])
+	msgs := gotBody["messages"].([]any)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "system", msgs[0].(map[string]any)["role"])
+
+	assert.Equal(t, "hello there", resp.Content)
+	assert.Equal(t, 12, resp.Usage.PromptTokens)
+	assert.Equal(t, 3, resp.Usage.CompletionTokens)
+}
+
+func TestOpenAI_AuthHeaderNotOverridable(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{
+		Provider: ai.ProviderOpenAI,
+		APIKey:   "sk-test",
+		BaseURL:  srv.URL,
+		Headers:  map[string]string{"Authorization": "Bearer other"},
+	}, srv.Client())
+	require.NoError(t, err)
+
+	_, err = p.Complete(context.Background(), ai.Request{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer sk-test", gotAuth)
+}
+
+func TestOpenAI_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"message": "invalid api key"}}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderOpenAI, APIKey: "bad", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	_, err = p.Complete(context.Background(), ai.Request{Model: "gpt-4o"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid api key")
+}
+
+func TestAnthropic_Complete(t *testing.T) {
+	var gotPath, gotKey, gotVersion string
+	var gotBody map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("x-api-key")
+		gotVersion = r.Header.Get("anthropic-version")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{
+			"model": "claude-test",
+			"content": [{"type": "text", "text": "hi!"}],
+			"usage": {"input_tokens": 7, "output_tokens": 2}
+		}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderAnthropic, APIKey: "ak", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Complete(context.Background(), ai.Request{
+		Model:        "claude-test",
+		SystemPrompt: "sys",
+		Messages:     []ai.Message{{Role: ai.RoleUser, Content: "hello"}},
+		MaxTokens:    100,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/messages", gotPath)
+	assert.Equal(t, "ak", gotKey)
+	assert.Equal(t, "2023-06-01", gotVersion)
+	assert.Equal(t, "sys", gotBody["system"])
+	assert.Equal(t, "hi!", resp.Content)
+	assert.Equal(t, 7, resp.Usage.PromptTokens)
+	assert.Equal(t, 2, resp.Usage.CompletionTokens)
+}
+
+func TestGoogle_Complete(t *testing.T) {
+	var gotPath, gotKey string
+	var gotBody map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.URL.Query().Get("key")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{
+			"candidates": [{"content": {"parts": [{"text": "bonjour"}]}}],
+			"usageMetadata": {"promptTokenCount": 5, "candidatesTokenCount": 1}
+		}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderGoogle, APIKey: "gk", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Complete(context.Background(), ai.Request{
+		Model: "gemini-pro",
+		Messages: []ai.Message{
+			{Role: ai.RoleUser, Content: "hi"},
+			{Role: ai.RoleAssistant, Content: "hello"},
+			{Role: ai.RoleUser, Content: "in french?"},
+		},
+		MaxTokens: 10,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/models/gemini-pro:generateContent", gotPath)
+	assert.Equal(t, "gk", gotKey)
+	contents := gotBody["contents"].([]any)
+	require.Len(t, contents, 3)
+	assert.Equal(t, "model", contents[1].(map[string]any)["role"])
+	assert.Equal(t, "bonjour", resp.Content)
+	assert.Equal(t, 5, resp.Usage.PromptTokens)
+}
+
+func TestOpenAICompatible_Embed(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		// Out-of-order indexes must be restored to input order.
+		_, _ = w.Write([]byte(`{
+			"data": [
+				{"index": 1, "embedding": [0.0, 1.0]},
+				{"index": 0, "embedding": [1.0, 0.0]}
+			],
+			"usage": {"prompt_tokens": 4}
+		}`))
+	}))
+	defer srv.Close()
+
+	e, err := ai.NewEmbedder(ai.Config{Provider: ai.ProviderOpenAICompatible, BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := e.Embed(context.Background(), "nomic-embed-text", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "/embeddings", gotPath)
+	assert.Equal(t, "nomic-embed-text", gotBody["model"])
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, resp.Vectors)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+}
+
+func TestGoogle_Embed(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"embeddings": [{"values": [0.5, 0.5]}]}`))
+	}))
+	defer srv.Close()
+
+	e, err := ai.NewEmbedder(ai.Config{Provider: ai.ProviderGoogle, APIKey: "gk", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := e.Embed(context.Background(), "text-embedding-004", []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, "/models/text-embedding-004:batchEmbedContents", gotPath)
+	assert.Equal(t, [][]float32{{0.5, 0.5}}, resp.Vectors)
+}
+
+func TestNewEmbedder_Unsupported(t *testing.T) {
+	_, err := ai.NewEmbedder(ai.Config{Provider: ai.ProviderAnthropic, APIKey: "k"}, nil)
+	assert.ErrorIs(t, err, ai.ErrEmbeddingsUnsupported)
+}
+
+var lookupOrderTool = ai.Tool{
+	Name:        "lookup_order",
+	Description: "Look up an order by id",
+	Parameters: map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"order_id": map[string]any{"type": "string"}},
+	},
+}
+
+func TestOpenAI_ToolCalls(t *testing.T) {
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": null, "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "lookup_order", "arguments": "{\"order_id\":\"42\"}"}}
+		]}}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderOpenAI, APIKey: "k", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Complete(context.Background(), ai.Request{
+		Model: "gpt-4o",
+		Messages: []ai.Message{
+			{Role: ai.RoleUser, Content: "where is 41?"},
+			{Role: ai.RoleAssistant, ToolCalls: []ai.ToolCall{{ID: "call_0", Name: "lookup_order", Arguments: `{"order_id":"41"}`}}},
+			{Role: ai.RoleTool, ToolCallID: "call_0", ToolName: "lookup_order", Content: `{"status":"shipped"}`},
+		},
+		Tools: []ai.Tool{lookupOrderTool},
+	})
+	require.NoError(t, err)
+
+	tools := gotBody["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "lookup_order", tools[0].(map[string]any)["function"].(map[string]any)["name"])
+	msgs := gotBody["messages"].([]any)
+	require.Len(t, msgs, 3)
+	assert.NotNil(t, msgs[1].(map[string]any)["tool_calls"])
+	assert.Equal(t, "call_0", msgs[2].(map[string]any)["tool_call_id"])
+
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, ai.ToolCall{ID: "call_1", Name: "lookup_order", Arguments: `{"order_id":"42"}`}, resp.ToolCalls[0])
+}
+
+func TestAnthropic_ToolCalls(t *testing.T) {
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{"content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "tu_1", "name": "lookup_order", "input": {"order_id": "42"}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderAnthropic, APIKey: "k", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Complete(context.Background(), ai.Request{
+		Model:     "claude-test",
+		MaxTokens: 100,
+		Messages: []ai.Message{
+			{Role: ai.RoleUser, Content: "where are 40 and 41?"},
+			{Role: ai.RoleAssistant, ToolCalls: []ai.ToolCall{
+				{ID: "tu_a", Name: "lookup_order", Arguments: `{"order_id":"40"}`},
+				{ID: "tu_b", Name: "lookup_order", Arguments: `{"order_id":"41"}`},
+			}},
+			{Role: ai.RoleTool, ToolCallID: "tu_a", Content: "shipped"},
+			{Role: ai.RoleTool, ToolCallID: "tu_b", Content: "pending"},
+		},
+		Tools: []ai.Tool{lookupOrderTool},
+	})
+	require.NoError(t, err)
+
+	msgs := gotBody["messages"].([]any)
+	require.Len(t, msgs, 3, "consecutive tool results share one user turn")
+	results := msgs[2].(map[string]any)["content"].([]any)
+	require.Len(t, results, 2)
+	assert.Equal(t, "tool_result", results[0].(map[string]any)["type"])
+	assert.NotNil(t, gotBody["tools"].([]any)[0].(map[string]any)["input_schema"])
+
+	assert.Equal(t, "Checking.", resp.Content)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "tu_1", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"order_id":"42"}`, resp.ToolCalls[0].Arguments)
+}
+
+func TestGoogle_ToolCalls(t *testing.T) {
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"parts": [
+			{"functionCall": {"name": "lookup_order", "args": {"order_id": "42"}}}
+		]}}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := ai.New(ai.Config{Provider: ai.ProviderGoogle, APIKey: "k", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Complete(context.Background(), ai.Request{
+		Model: "gemini-pro",
+		Messages: []ai.Message{
+			{Role: ai.RoleUser, Content: "where is 41?"},
+			{Role: ai.RoleAssistant, ToolCalls: []ai.ToolCall{{ID: "call_0", Name: "lookup_order", Arguments: `{"order_id":"41"}`}}},
+			{Role: ai.RoleTool, ToolCallID: "call_0", ToolName: "lookup_order", Content: "not json"},
+		},
+		Tools: []ai.Tool{lookupOrderTool},
+	})
+	require.NoError(t, err)
+
+	contents := gotBody["contents"].([]any)
+	require.Len(t, contents, 3)
+	fr := contents[2].(map[string]any)["parts"].([]any)[0].(map[string]any)["functionResponse"].(map[string]any)
+	assert.Equal(t, "lookup_order", fr["name"])
+	assert.Equal(t, map[string]any{"result": "not json"}, fr["response"])
+
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "lookup_order", resp.ToolCalls[0].Name)
+	assert.NotEmpty(t, resp.ToolCalls[0].ID)
+	assert.Empty(t, resp.Content)
+}
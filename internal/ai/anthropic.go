@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	anthropicVersion        = "2023-06-01"
+)
+
+// anthropicProvider speaks the Anthropic Messages API.
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newAnthropic(cfg Config, client *http.Client) *anthropicProvider {
+	return &anthropicProvider{
+		baseURL: baseURLOr(cfg, defaultAnthropicBaseURL),
+		apiKey:  cfg.APIKey,
+		headers: cfg.Headers,
+		client:  client,
+	}
+}
+
+func (p *anthropicProvider) Name() string { return ProviderAnthropic }
+
+func (p *anthropicProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]map[string]any, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		switch {
+		case m.Role == RoleTool:
+			// Tool results go back as user-turn blocks; consecutive
+			// results must share one user message.
+			block := map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": m.ToolCallID,
+				"content":     m.Content,
+			}
+			if n := len(messages); n > 0 && messages[n-1]["role"] == "user" {
+				if blocks, ok := messages[n-1]["content"].([]map[string]any); ok {
+					messages[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			messages = append(messages, map[string]any{
+				"role":    "user",
+				"content": []map[string]any{block},
+			})
+		case len(m.ToolCalls) > 0:
+			blocks := make([]map[string]any, 0, len(m.ToolCalls)+1)
+			if m.Content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    tc.ID,
+					"name":  tc.Name,
+					"input": toolArguments(tc.Arguments),
+				})
+			}
+			messages = append(messages, map[string]any{
+				"role":    string(m.Role),
+				"content": blocks,
+			})
+		default:
+			messages = append(messages, map[string]any{
+				"role":    string(m.Role),
+				"content": m.Content,
+			})
+		}
+	}
+
+	payload := map[string]any{
+		"model":      req.Model,
+		"messages":   messages,
+		"max_tokens": req.MaxTokens,
+	}
+	if req.SystemPrompt != "" {
+		payload["system"] = req.SystemPrompt
+	}
+	if req.Temperature > 0 {
+		payload["temperature"] = req.Temperature
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, len(req.Tools))
+		for i, t := range req.Tools {
+			tools[i] = map[string]any{
+				"name":         t.Name,
+				"description":  t.Description,
+				"input_schema": toolParameters(t),
+			}
+		}
+		payload["tools"] = tools
+	}
+
+	headers := mergeHeaders(p.headers, map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	})
+
+	body, status, err := postJSON(ctx, p.client, p.baseURL+"/messages", headers, payload)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("anthropic API error: %s", apiErrorMessage(body, status))
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	resp := &Response{
+		Model: result.Model,
+		Usage: Usage{
+			PromptTokens:     result.Usage.InputTokens,
+			CompletionTokens: result.Usage.OutputTokens,
+		},
+	}
+	var texts []string
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			texts = append(texts, content.Text)
+		case "tool_use":
+			resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+				ID:        content.ID,
+				Name:      content.Name,
+				Arguments: string(content.Input),
+			})
+		}
+	}
+	if len(texts) == 0 && len(resp.ToolCalls) == 0 {
+		return nil, fmt.Errorf("no text response from Anthropic")
+	}
+	resp.Content = strings.TrimSpace(strings.Join(texts, "\n"))
+	return resp, nil
+}
@@ -0,0 +1,221 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultGoogleBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// googleProvider speaks the Gemini generateContent API.
+type googleProvider struct {
+	baseURL string
+	apiKey  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newGoogle(cfg Config, client *http.Client) *googleProvider {
+	return &googleProvider{
+		baseURL: baseURLOr(cfg, defaultGoogleBaseURL),
+		apiKey:  cfg.APIKey,
+		headers: cfg.Headers,
+		client:  client,
+	}
+}
+
+func (p *googleProvider) Name() string { return ProviderGoogle }
+
+func (p *googleProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	contents := make([]map[string]any, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		switch {
+		case m.Role == RoleTool:
+			// Gemini has no call ids; results are matched by function name
+			// and consecutive results share one turn.
+			part := map[string]any{
+				"functionResponse": map[string]any{
+					"name":     m.ToolName,
+					"response": googleToolResponse(m.Content),
+				},
+			}
+			if n := len(contents); n > 0 && contents[n-1]["role"] == "user" {
+				if parts, ok := contents[n-1]["parts"].([]map[string]any); ok && len(parts) > 0 && parts[0]["functionResponse"] != nil {
+					contents[n-1]["parts"] = append(parts, part)
+					continue
+				}
+			}
+			contents = append(contents, map[string]any{
+				"role":  "user",
+				"parts": []map[string]any{part},
+			})
+		default:
+			role := "user"
+			if m.Role == RoleAssistant {
+				role = "model"
+			}
+			parts := make([]map[string]any, 0, len(m.ToolCalls)+1)
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				parts = append(parts, map[string]any{"text": m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				parts = append(parts, map[string]any{
+					"functionCall": map[string]any{
+						"name": tc.Name,
+						"args": toolArguments(tc.Arguments),
+					},
+				})
+			}
+			contents = append(contents, map[string]any{
+				"role":  role,
+				"parts": parts,
+			})
+		}
+	}
+
+	generationConfig := map[string]any{
+		"maxOutputTokens": req.MaxTokens,
+	}
+	if req.Temperature > 0 {
+		generationConfig["temperature"] = req.Temperature
+	}
+	payload := map[string]any{
+		"contents":         contents,
+		"generationConfig": generationConfig,
+	}
+	if req.SystemPrompt != "" {
+		payload["systemInstruction"] = map[string]any{
+			"parts": []map[string]string{
+				{"text": req.SystemPrompt},
+			},
+		}
+	}
+	if len(req.Tools) > 0 {
+		decls := make([]map[string]any, len(req.Tools))
+		for i, t := range req.Tools {
+			decls[i] = map[string]any{
+				"name":        t.Name,
+				"description": t.Description,
+				"parameters":  toolParameters(t),
+			}
+		}
+		payload["tools"] = []map[string]any{{"functionDeclarations": decls}}
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent?key=%s",
+		p.baseURL, url.PathEscape(req.Model), url.QueryEscape(p.apiKey))
+
+	body, status, err := postJSON(ctx, p.client, endpoint, p.headers, payload)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("google AI API error: %s", apiErrorMessage(body, status))
+	}
+
+	var result struct {
+		ModelVersion string `json:"modelVersion"`
+		Candidates   []struct {
+			Content struct {
+				Parts []struct {
+					Text         string `json:"text"`
+					FunctionCall *struct {
+						ID   string          `json:"id"`
+						Name string          `json:"name"`
+						Args json.RawMessage `json:"args"`
+					} `json:"functionCall"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no response from Google AI")
+	}
+
+	resp := &Response{
+		Model: result.ModelVersion,
+		Usage: Usage{
+			PromptTokens:     result.UsageMetadata.PromptTokenCount,
+			CompletionTokens: result.UsageMetadata.CandidatesTokenCount,
+		},
+	}
+	var texts []string
+	for i, part := range result.Candidates[0].Content.Parts {
+		if fc := part.FunctionCall; fc != nil {
+			id := fc.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d", i)
+			}
+			resp.ToolCalls = append(resp.ToolCalls, ToolCall{ID: id, Name: fc.Name, Arguments: string(fc.Args)})
+			continue
+		}
+		texts = append(texts, part.Text)
+	}
+	resp.Content = strings.TrimSpace(strings.Join(texts, ""))
+	return resp, nil
+}
+
+// googleToolResponse wraps a tool result in the object Gemini requires,
+// passing JSON objects through unchanged.
+func googleToolResponse(content string) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"result": content}
+}
+
+// Embed calls batchEmbedContents.
+func (p *googleProvider) Embed(ctx context.Context, model string, inputs []string) (*EmbeddingResponse, error) {
+	modelPath := "models/" + strings.TrimPrefix(model, "models/")
+	requests := make([]map[string]any, len(inputs))
+	for i, in := range inputs {
+		requests[i] = map[string]any{
+			"model": modelPath,
+			"content": map[string]any{
+				"parts": []map[string]string{{"text": in}},
+			},
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/%s:batchEmbedContents?key=%s",
+		p.baseURL, modelPath, url.QueryEscape(p.apiKey))
+
+	body, status, err := postJSON(ctx, p.client, endpoint, p.headers, map[string]any{"requests": requests})
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("google AI API error: %s", apiErrorMessage(body, status))
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("google AI returned %d embeddings for %d inputs", len(result.Embeddings), len(inputs))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for i, e := range result.Embeddings {
+		vectors[i] = e.Values
+	}
+	return &EmbeddingResponse{Vectors: vectors}, nil
+}
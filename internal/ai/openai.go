@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// openAIProvider speaks the OpenAI chat-completions wire format. It backs
+// both the hosted OpenAI API and any OpenAI-compatible server (Ollama,
+// vLLM, LocalAI), which differ only in base URL and whether a key is sent.
+type openAIProvider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newOpenAI(name string, cfg Config, defaultBaseURL string, client *http.Client) *openAIProvider {
+	return &openAIProvider{
+		name:    name,
+		baseURL: baseURLOr(cfg, defaultBaseURL),
+		apiKey:  cfg.APIKey,
+		headers: cfg.Headers,
+		client:  client,
+	}
+}
+
+func (p *openAIProvider) Name() string { return p.name }
+
+func (p *openAIProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]map[string]any, 0, len(req.Messages)+1)
+	if req.SystemPrompt != "" {
+		messages = append(messages, map[string]any{
+			"role":    "system",
+			"content": req.SystemPrompt,
+		})
+	}
+	for _, m := range req.Messages {
+		msg := map[string]any{
+			"role":    string(m.Role),
+			"content": m.Content,
+		}
+		if m.Role == RoleTool {
+			msg["tool_call_id"] = m.ToolCallID
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]map[string]any, len(m.ToolCalls))
+			for i, tc := range m.ToolCalls {
+				calls[i] = map[string]any{
+					"id":   tc.ID,
+					"type": "function",
+					"function": map[string]string{
+						"name":      tc.Name,
+						"arguments": tc.Arguments,
+					},
+				}
+			}
+			msg["tool_calls"] = calls
+		}
+		messages = append(messages, msg)
+	}
+
+	payload := map[string]any{
+		"model":    req.Model,
+		"messages": messages,
+	}
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature > 0 {
+		payload["temperature"] = req.Temperature
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, len(req.Tools))
+		for i, t := range req.Tools {
+			tools[i] = map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name":        t.Name,
+					"description": t.Description,
+					"parameters":  toolParameters(t),
+				},
+			}
+		}
+		payload["tools"] = tools
+	}
+
+	body, status, err := postJSON(ctx, p.client, p.baseURL+"/chat/completions", p.requestHeaders(), payload)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s API error: %s", p.name, apiErrorMessage(body, status))
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no response from %s", p.name)
+	}
+
+	msg := result.Choices[0].Message
+	resp := &Response{
+		Content: strings.TrimSpace(msg.Content),
+		Model:   result.Model,
+		Usage: Usage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+		},
+	}
+	for _, tc := range msg.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+	return resp, nil
+}
+
+// Embed calls the /embeddings endpoint.
+func (p *openAIProvider) Embed(ctx context.Context, model string, inputs []string) (*EmbeddingResponse, error) {
+	payload := map[string]any{
+		"model": model,
+		"input": inputs,
+	}
+
+	body, status, err := postJSON(ctx, p.client, p.baseURL+"/embeddings", p.requestHeaders(), payload)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s API error: %s", p.name, apiErrorMessage(body, status))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Data) != len(inputs) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", p.name, len(result.Data), len(inputs))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for i, d := range result.Data {
+		idx := d.Index
+		if idx < 0 || idx >= len(vectors) {
+			idx = i
+		}
+		vectors[idx] = d.Embedding
+	}
+	return &EmbeddingResponse{
+		Vectors: vectors,
+		Usage:   Usage{PromptTokens: result.Usage.PromptTokens},
+	}, nil
+}
+
+func (p *openAIProvider) requestHeaders() map[string]string {
+	auth := map[string]string{}
+	if p.apiKey != "" {
+		auth["Authorization"] = "Bearer " + p.apiKey
+	}
+	return mergeHeaders(p.headers, auth)
+}
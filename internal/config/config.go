@@ -104,6 +104,7 @@ type AppConfig struct {
 	Debug                    bool   `koanf:"debug"`
 	EncryptionKey            string `koanf:"encryption_key"`              // AES-256 key for encrypting secrets at rest
 	AllowInternalWebhookURLs bool   `koanf:"allow_internal_webhook_urls"` // Unsafe opt-in for private/internal webhook URLs
+	AllowInternalAIURLs      bool   `koanf:"allow_internal_ai_urls"`      // Opt-in for self-hosted LLM endpoints on private networks
 }
 
 type AuthConfig struct {
@@ -57,6 +57,12 @@ func GetOrCreateContact(db *gorm.DB, orgID uuid.UUID, phoneNumber, profileName s
 		PhoneNumber:    normalizedPhone,
 		ProfileName:    profileName,
 	}
+	if fields, err := LoadFields(db, orgID); err == nil && len(fields) > 0 {
+		// Defaults were validated when the fields were saved
+		if metadata, err := ApplyFields(fields, nil, MetadataOptions{ApplyDefaults: true}); err == nil && len(metadata) > 0 {
+			contact.Metadata = metadata
+		}
+	}
 	if err := db.Create(&contact).Error; err != nil {
 		// Race condition: another goroutine may have created the contact
 		if err2 := db.Unscoped().Where("organization_id = ? AND phone_number = ?", orgID, normalizedPhone).First(&contact).Error; err2 == nil {
@@ -0,0 +1,229 @@
+package contactutil
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrFieldValueTaken is returned when a unique contact field value is
+// already used by another contact in the organization.
+var ErrFieldValueTaken = errors.New("value is already used by another contact")
+
+// FieldError describes why a contact field value was rejected.
+type FieldError struct {
+	Key string
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+// MetadataOptions control how ApplyFields treats fields missing from the
+// metadata being written.
+type MetadataOptions struct {
+	ApplyDefaults   bool // Fill absent fields from their default value (new contacts)
+	EnforceRequired bool // Reject metadata missing a required field
+}
+
+// LoadFields returns the organization's contact field definitions in
+// display order.
+func LoadFields(db *gorm.DB, orgID uuid.UUID) ([]models.ContactField, error) {
+	var fields []models.ContactField
+	err := db.Where("organization_id = ?", orgID).Order("position ASC, created_at ASC").Find(&fields).Error
+	return fields, err
+}
+
+// CoerceFieldValue validates v against field's type and returns it in its
+// stored form: numbers as float64, dates as YYYY-MM-DD, booleans as bool and
+// everything else as a trimmed string. Empty values coerce to nil.
+func CoerceFieldValue(field *models.ContactField, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok {
+		v = strings.TrimSpace(s)
+		if v == "" {
+			return nil, nil
+		}
+	}
+
+	switch field.Type {
+	case models.ContactFieldNumber:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case string:
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+
+	case models.ContactFieldBoolean:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			switch strings.ToLower(b) {
+			case "true", "yes", "1":
+				return true, nil
+			case "false", "no", "0":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		if f, isNum := v.(float64); isNum && field.Type == models.ContactFieldText {
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return nil, fmt.Errorf("must be a string")
+	}
+
+	switch field.Type {
+	case models.ContactFieldText:
+		if len(s) > 1000 {
+			return nil, fmt.Errorf("must be at most 1000 characters")
+		}
+		return s, nil
+	case models.ContactFieldDate:
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+		return nil, fmt.Errorf("must be a date (YYYY-MM-DD)")
+	case models.ContactFieldEnum:
+		if !slices.Contains(field.Options, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(field.Options, ", "))
+		}
+		return s, nil
+	case models.ContactFieldPhone:
+		phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(s)
+		if !phonePattern.MatchString(phone) {
+			return nil, fmt.Errorf("must be a phone number")
+		}
+		return phone, nil
+	case models.ContactFieldEmail:
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return nil, fmt.Errorf("must be an email address")
+		}
+		return strings.ToLower(s), nil
+	}
+	return nil, fmt.Errorf("has unknown type %q", field.Type)
+}
+
+// FieldValueText renders a stored value the way Postgres' ->> operator
+// returns it, for comparisons against metadata in SQL.
+func FieldValueText(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return fmt.Sprint(v)
+}
+
+// ApplyFields returns a copy of metadata with every defined field coerced
+// to its type. Keys without a definition pass through untouched, so
+// metadata stays usable for ad-hoc data.
+func ApplyFields(fields []models.ContactField, metadata models.JSONB, opts MetadataOptions) (models.JSONB, error) {
+	out := models.JSONB{}
+	maps.Copy(out, metadata)
+	for i := range fields {
+		field := &fields[i]
+		raw, present := out[field.Key]
+		if !present && opts.ApplyDefaults && field.DefaultValue != "" {
+			raw, present = field.DefaultValue, true
+		}
+		if present {
+			value, err := CoerceFieldValue(field, raw)
+			if err != nil {
+				return nil, &FieldError{Key: field.Key, Err: err}
+			}
+			if value == nil {
+				delete(out, field.Key)
+			} else {
+				out[field.Key] = value
+			}
+		}
+		if _, ok := out[field.Key]; !ok && opts.EnforceRequired && field.IsRequired {
+			return nil, &FieldError{Key: field.Key, Err: errors.New("is required")}
+		}
+	}
+	return out, nil
+}
+
+// CheckUniqueFields returns ErrFieldValueTaken (wrapped in a FieldError)
+// when a unique field's value in metadata belongs to another contact.
+// contactID is the contact being written, or uuid.Nil for a new one.
+func CheckUniqueFields(db *gorm.DB, orgID, contactID uuid.UUID, fields []models.ContactField, metadata models.JSONB) error {
+	for _, field := range fields {
+		value, ok := metadata[field.Key]
+		if !field.IsUnique || !ok || value == nil {
+			continue
+		}
+		var count int64
+		if err := db.Model(&models.Contact{}).
+			Where("organization_id = ? AND id <> ? AND metadata->>?::text = ?", orgID, contactID, field.Key, FieldValueText(value)).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return &FieldError{Key: field.Key, Err: ErrFieldValueTaken}
+		}
+	}
+	return nil
+}
+
+// NormalizeMetadata loads the organization's field definitions, applies
+// them to metadata and checks uniqueness. With no fields defined it returns
+// metadata unchanged.
+func NormalizeMetadata(db *gorm.DB, orgID, contactID uuid.UUID, metadata models.JSONB, opts MetadataOptions) (models.JSONB, error) {
+	fields, err := LoadFields(db, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return metadata, nil
+	}
+	out, err := ApplyFields(fields, metadata, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckUniqueFields(db, orgID, contactID, fields, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
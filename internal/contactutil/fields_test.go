@@ -0,0 +1,95 @@
+package contactutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoerceFieldValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		field models.ContactField
+		in    any
+		want  any
+	}{
+		{"text trims", models.ContactField{Type: models.ContactFieldText}, "  hi ", "hi"},
+		{"text from number", models.ContactField{Type: models.ContactFieldText}, float64(42), "42"},
+		{"empty unsets", models.ContactField{Type: models.ContactFieldNumber}, " ", nil},
+		{"number from string", models.ContactField{Type: models.ContactFieldNumber}, "12.5", 12.5},
+		{"number from json", models.ContactField{Type: models.ContactFieldNumber}, float64(3), float64(3)},
+		{"date", models.ContactField{Type: models.ContactFieldDate}, "2026-02-01", "2026-02-01"},
+		{"date from timestamp", models.ContactField{Type: models.ContactFieldDate}, "2026-02-01T10:00:00Z", "2026-02-01"},
+		{"enum", models.ContactField{Type: models.ContactFieldEnum, Options: models.StringArray{"gold", "silver"}}, "gold", "gold"},
+		{"boolean yes", models.ContactField{Type: models.ContactFieldBoolean}, "Yes", true},
+		{"boolean json", models.ContactField{Type: models.ContactFieldBoolean}, false, false},
+		{"phone strips formatting", models.ContactField{Type: models.ContactFieldPhone}, "+1 (555) 010-9999", "+15550109999"},
+		{"email lowercased", models.ContactField{Type: models.ContactFieldEmail}, "Asha@Example.com", "asha@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceFieldValue(&tt.field, tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCoerceFieldValue_Rejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		field models.ContactField
+		in    any
+	}{
+		{"number", models.ContactField{Type: models.ContactFieldNumber}, "twelve"},
+		{"date", models.ContactField{Type: models.ContactFieldDate}, "01/02/2026"},
+		{"enum", models.ContactField{Type: models.ContactFieldEnum, Options: models.StringArray{"gold"}}, "bronze"},
+		{"boolean", models.ContactField{Type: models.ContactFieldBoolean}, "maybe"},
+		{"phone", models.ContactField{Type: models.ContactFieldPhone}, "call me"},
+		{"email", models.ContactField{Type: models.ContactFieldEmail}, "Asha <asha@example.com>"},
+		{"text from object", models.ContactField{Type: models.ContactFieldText}, map[string]any{"a": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CoerceFieldValue(&tt.field, tt.in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyFields(t *testing.T) {
+	fields := []models.ContactField{
+		{Key: "tier", Type: models.ContactFieldEnum, Options: models.StringArray{"gold", "silver"}, DefaultValue: "silver"},
+		{Key: "points", Type: models.ContactFieldNumber},
+		{Key: "email", Type: models.ContactFieldEmail, IsRequired: true},
+	}
+
+	metadata := models.JSONB{"points": "10", "email": "a@b.co", "note": "free-form"}
+	out, err := ApplyFields(fields, metadata, MetadataOptions{ApplyDefaults: true, EnforceRequired: true})
+	require.NoError(t, err)
+	assert.Equal(t, models.JSONB{"tier": "silver", "points": float64(10), "email": "a@b.co", "note": "free-form"}, out)
+	assert.Equal(t, "10", metadata["points"], "input is not modified")
+
+	out, err = ApplyFields(fields, models.JSONB{"points": ""}, MetadataOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, models.JSONB{}, out)
+
+	_, err = ApplyFields(fields, models.JSONB{"points": 1.0}, MetadataOptions{EnforceRequired: true})
+	var fieldErr *FieldError
+	require.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "email", fieldErr.Key)
+
+	_, err = ApplyFields(fields, models.JSONB{"tier": "bronze"}, MetadataOptions{})
+	assert.EqualError(t, err, "tier: must be one of gold, silver")
+}
+
+func TestFieldValueText(t *testing.T) {
+	assert.Equal(t, "12.5", FieldValueText(12.5))
+	assert.Equal(t, "3", FieldValueText(float64(3)))
+	assert.Equal(t, "true", FieldValueText(true))
+	assert.Equal(t, "gold", FieldValueText("gold"))
+	assert.Equal(t, "", FieldValueText(nil))
+}
@@ -0,0 +1,62 @@
+package cronutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// Sunday 18 October 2026, 10:17
+	from := time.Date(2026, 10, 18, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 18, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 6,7", time.Date(2026, 10, 24, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 jan *", time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches
+		{"0 8 1 * 1", time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+func TestSchedule_NextInLocation(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	s, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+	next := s.Next(time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC).In(loc))
+	assert.Equal(t, time.Date(2026, 10, 19, 3, 30, 0, 0, time.UTC), next.UTC())
+}
+
+func TestSchedule_NeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "* * * * funday",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -71,6 +71,7 @@ func GetMigrationModels() []MigrationModel {
 		{"WhatsAppAccount", &models.WhatsAppAccount{}},
 		{"Contact", &models.Contact{}},
 		{"Tag", &models.Tag{}},
+		{"ContactField", &models.ContactField{}},
 		{"Message", &models.Message{}},
 		{"Template", &models.Template{}},
 		{"WhatsAppFlow", &models.WhatsAppFlow{}},
@@ -78,6 +79,11 @@ func GetMigrationModels() []MigrationModel {
 		// Bulk & Notifications
 		{"BulkMessageCampaign", &models.BulkMessageCampaign{}},
 		{"BulkMessageRecipient", &models.BulkMessageRecipient{}},
+		{"CampaignVariant", &models.CampaignVariant{}},
+		{"ContactSegment", &models.ContactSegment{}},
+		{"CampaignSequence", &models.CampaignSequence{}},
+		{"CampaignSequenceStep", &models.CampaignSequenceStep{}},
+		{"SequenceEnrollment", &models.SequenceEnrollment{}},
 		{"NotificationRule", &models.NotificationRule{}},
 
 		// Chatbot models
@@ -91,8 +97,18 @@ func GetMigrationModels() []MigrationModel {
 		// dropped in a future maintenance migration.
 		{"ChatbotSession", &models.ChatbotSession{}},
 		{"ChatbotSessionMessage", &models.ChatbotSessionMessage{}},
+		{"ChatbotWakeup", &models.ChatbotWakeup{}},
 		{"AIContext", &models.AIContext{}},
+		{"AITool", &models.AITool{}},
+		{"ChatbotIntent", &models.ChatbotIntent{}},
+		{"IntentClassification", &models.IntentClassification{}},
+		{"AIReplySuggestion", &models.AIReplySuggestion{}},
+		{"AIUsageLog", &models.AIUsageLog{}},
+		{"AIBudget", &models.AIBudget{}},
+		{"AIRedactionLog", &models.AIRedactionLog{}},
 		{"AgentTransfer", &models.AgentTransfer{}},
+		{"KnowledgeDocument", &models.KnowledgeDocument{}},
+		{"KnowledgeChunk", &models.KnowledgeChunk{}},
 
 		// User tracking
 		{"UserAvailabilityLog", &models.UserAvailabilityLog{}},
@@ -238,12 +254,16 @@ func getIndexes() []string {
 		`ALTER TABLE chatbot_sessions ALTER COLUMN phone_number TYPE varchar(50)`,
 		`ALTER TABLE agent_transfers ALTER COLUMN phone_number TYPE varchar(50)`,
 		`ALTER TABLE bulk_message_recipients ALTER COLUMN phone_number TYPE varchar(50)`,
+		`ALTER TABLE conversation_notes ALTER COLUMN created_by_id DROP NOT NULL`,
 		// Indexes
 		`CREATE INDEX IF NOT EXISTS idx_messages_contact_created ON messages(contact_id, created_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_contacts_org_phone ON contacts(organization_id, phone_number)`,
 		`CREATE INDEX IF NOT EXISTS idx_contacts_assigned_read ON contacts(assigned_user_id, is_read)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_phone_status ON chatbot_sessions(organization_id, phone_number, status)`,
+		`CREATE INDEX IF NOT EXISTS idx_chatbot_wakeups_status_wake ON chatbot_wakeups(status, wake_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_sequence_enrollments_due ON sequence_enrollments(status, next_run_at)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_sequence_enrollments_active ON sequence_enrollments(sequence_id, contact_id) WHERE status = 'active' AND deleted_at IS NULL`,
 		`CREATE INDEX IF NOT EXISTS idx_keyword_rules_priority ON keyword_rules(organization_id, is_enabled, priority DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_active ON agent_transfers(organization_id, phone_number, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_org_contact ON agent_transfers(organization_id, contact_id, status)`,
@@ -253,7 +273,12 @@ func getIndexes() []string {
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_templates_account_name_lang ON templates(whats_app_account, name, language)`,
 		`CREATE INDEX IF NOT EXISTS idx_keyword_rules_account ON keyword_rules(whats_app_account, is_enabled, priority DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_chatbot_flows_account ON chatbot_flows(whats_app_account, is_enabled)`,
+		`CREATE INDEX IF NOT EXISTS idx_intent_classifications_review ON intent_classifications(organization_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_reply_suggestions_user ON ai_reply_suggestions(organization_id, user_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_usage_logs_org_created ON ai_usage_logs(organization_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_redaction_logs_org_created ON ai_redaction_logs(organization_id, created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_ai_contexts_account ON ai_contexts(whats_app_account, is_enabled, priority DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_retrieval ON knowledge_chunks(organization_id, embedding_model, whats_app_account)`,
 		`CREATE INDEX IF NOT EXISTS idx_bulk_campaigns_account ON bulk_message_campaigns(whats_app_account, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_notification_rules_account ON notification_rules(whats_app_account, is_enabled)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_account ON messages(whats_app_account, created_at DESC)`,
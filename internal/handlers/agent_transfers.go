@@ -536,23 +536,7 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 	a.broadcastTransferCreated(&transfer, contact)
 
 	// Dispatch webhook for transfer created
-	var agentIDStr *string
-	var agentName *string
-	if transfer.AgentID != nil {
-		idStr := transfer.AgentID.String()
-		agentIDStr = &idStr
-	}
-	a.DispatchWebhook(orgID, models.WebhookEventTransferCreated, TransferEventData{
-		TransferID:      transfer.ID.String(),
-		ContactID:       contact.ID.String(),
-		ContactPhone:    contact.PhoneNumber,
-		ContactName:     contact.ProfileName,
-		Source:          transfer.Source,
-		Reason:          transfer.Notes,
-		AgentID:         agentIDStr,
-		AgentName:       agentName,
-		WhatsAppAccount: transfer.WhatsAppAccount,
-	})
+	a.dispatchTransferCreated(&transfer, contact, settings)
 
 	// Load relations for response
 	a.DB.Preload("Agent").Preload("Team").Preload("TransferredByUser").First(&transfer, transfer.ID)
@@ -660,6 +644,9 @@ func (a *App) ResumeFromTransfer(r *fastglue.Request) error {
 	// Broadcast WebSocket notification
 	a.broadcastTransferResumed(transfer)
 
+	// Refresh the summary now that the agent's part of the conversation is known
+	a.startTransferSummary(transfer)
+
 	// Get contact for webhook data
 	var contact models.Contact
 	a.DB.Where("id = ?", transfer.ContactID).First(&contact)
@@ -1213,6 +1200,8 @@ func (a *App) saveAndFinalizeTransfer(transfer *models.AgentTransfer, account *m
 	// Broadcast to WebSocket
 	a.broadcastTransferCreated(transfer, contact)
 
+	a.dispatchTransferCreated(transfer, contact, settings)
+
 	return nil
 }
 
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// aiHeaderMask replaces custom header values in API responses. Submitting
+// it back on update keeps the stored value, mirroring how the API key is
+// write-only.
+const aiHeaderMask = "********"
+
+// aiConfigured reports whether the AI settings carry enough to call the
+// provider. Hosted providers need an API key; self-hosted OpenAI-compatible
+// servers need a base URL instead.
+func aiConfigured(cfg models.AIConfig) bool {
+	if !cfg.Enabled || cfg.Provider == "" {
+		return false
+	}
+	if cfg.Provider == models.AIProviderOpenAICompatible {
+		return cfg.BaseURL != ""
+	}
+	return cfg.APIKey != ""
+}
+
+// aiHTTPClient returns the client used for LLM calls, falling back to the
+// shared HTTPClient when no dedicated AI client is configured.
+func (a *App) aiHTTPClient() *http.Client {
+	if a.AIHTTPClient != nil {
+		return a.AIHTTPClient
+	}
+	return a.HTTPClient
+}
+
+// newAIProvider builds an ai.Provider from the org's AI settings.
+func (a *App) newAIProvider(cfg models.AIConfig) (ai.Provider, error) {
+	return ai.New(ai.Config{
+		Provider: string(cfg.Provider),
+		APIKey:   cfg.APIKey,
+		BaseURL:  cfg.BaseURL,
+		Headers:  aiHeadersFromJSONB(cfg.Headers),
+	}, a.aiHTTPClient())
+}
+
+// validateAIBaseURL checks a provider base URL with the same rules as
+// webhook URLs. Private addresses are allowed when either
+// app.allow_internal_ai_urls or app.allow_internal_webhook_urls is set.
+func (a *App) validateAIBaseURL(rawURL string) error {
+	allowInternal := a.Config != nil &&
+		(a.Config.App.AllowInternalAIURLs || a.Config.App.AllowInternalWebhookURLs)
+	if err := validateWebhookURL(rawURL, allowInternal); err != nil {
+		return fmt.Errorf("invalid AI base URL: %w", err)
+	}
+	return nil
+}
+
+// aiHeadersFromJSONB converts the stored header map to strings, dropping
+// non-string values.
+func aiHeadersFromJSONB(j models.JSONB) map[string]string {
+	if len(j) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(j))
+	for k, v := range j {
+		if s, ok := v.(string); ok && k != "" {
+			headers[k] = s
+		}
+	}
+	return headers
+}
+
+// maskAIHeaders returns the header names with their values masked, for
+// display in settings responses.
+func maskAIHeaders(j models.JSONB) map[string]string {
+	masked := make(map[string]string, len(j))
+	for k := range j {
+		masked[k] = aiHeaderMask
+	}
+	return masked
+}
+
+// mergeAIHeaders applies submitted headers on top of the stored ones.
+// Headers omitted from the request are removed; a masked value keeps the
+// existing secret.
+func mergeAIHeaders(existing models.JSONB, submitted map[string]string) models.JSONB {
+	merged := models.JSONB{}
+	for k, v := range submitted {
+		if k == "" {
+			continue
+		}
+		if v == aiHeaderMask {
+			if old, ok := existing[k]; ok {
+				merged[k] = old
+			}
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/redact"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// newAIRedactor returns a fresh redactor for one AI request, or nil when
+// redaction is off (a nil redactor passes text through). A stored custom
+// pattern that no longer compiles is dropped rather than disabling the
+// built-in detectors.
+func (a *App) newAIRedactor(cfg models.AIConfig) *redact.Redactor {
+	if !cfg.RedactionEnabled {
+		return nil
+	}
+	rc := redact.Config{
+		Detectors: []string(cfg.RedactionTypes),
+		Patterns:  redactionPatternsFromJSONB(cfg.RedactionPatterns),
+	}
+	r, err := redact.New(rc)
+	if err != nil {
+		a.Log.Warn("Invalid redaction settings, using built-in detectors only", "error", err)
+		rc.Patterns = nil
+		r, _ = redact.New(rc)
+	}
+	return r
+}
+
+// logRedactions records what the redactor masked during a request. Nothing
+// is written when no value was found.
+func (a *App) logRedactions(scope aiUsageScope, r *redact.Redactor) {
+	counts := r.Counts()
+	if len(counts) == 0 {
+		return
+	}
+	entry := models.AIRedactionLog{
+		OrganizationID:  scope.OrgID,
+		WhatsAppAccount: scope.Account,
+		Feature:         scope.Feature,
+		Counts:          models.JSONB{},
+		SessionID:       scope.SessionID,
+		ContactID:       scope.ContactID,
+		UserID:          scope.UserID,
+	}
+	for detector, n := range counts {
+		entry.Counts[detector] = n
+		entry.Total += n
+	}
+	if err := a.DB.Create(&entry).Error; err != nil {
+		a.Log.Error("Failed to record AI redactions", "error", err, "org_id", scope.OrgID)
+	}
+}
+
+// ListAIRedactions lists redaction audit entries, newest first.
+func (a *App) ListAIRedactions(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	args := r.RequestCtx.QueryArgs()
+
+	query := a.DB.Model(&models.AIRedactionLog{}).Where("organization_id = ?", orgID)
+	if contactID := string(args.Peek("contact_id")); contactID != "" {
+		id, err := uuid.Parse(contactID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid contact_id", nil, "")
+		}
+		query = query.Where("contact_id = ?", id)
+	}
+	if feature := string(args.Peek("feature")); feature != "" {
+		query = query.Where("feature = ?", feature)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var items []models.AIRedactionLog
+	if err := pg.Apply(query.Order("created_at DESC")).Find(&items).Error; err != nil {
+		a.Log.Error("Failed to list AI redactions", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list redactions", nil, "")
+	}
+
+	return r.SendEnvelope(listEnvelope("redactions", items, total, pg))
+}
+
+func redactionPatternsFromJSONB(j models.JSONBArray) []redact.Pattern {
+	patterns := make([]redact.Pattern, 0, len(j))
+	for _, item := range j {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := m["name"].(string)
+		pattern, _ := m["pattern"].(string)
+		if name != "" && pattern != "" {
+			patterns = append(patterns, redact.Pattern{Name: name, Pattern: pattern})
+		}
+	}
+	return patterns
+}
+
+func redactionPatternsToJSONB(patterns []redact.Pattern) models.JSONBArray {
+	j := make(models.JSONBArray, 0, len(patterns))
+	for _, p := range patterns {
+		j = append(j, map[string]any{"name": p.Name, "pattern": p.Pattern})
+	}
+	return j
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/redact"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactionPatternsJSONBRoundTrip(t *testing.T) {
+	patterns := []redact.Pattern{{Name: "order", Pattern: `ORD-\d+`}}
+	stored := redactionPatternsToJSONB(patterns)
+	assert.Equal(t, patterns, redactionPatternsFromJSONB(stored))
+
+	// Malformed entries are skipped.
+	stored = append(stored, "x", map[string]any{"name": "no pattern"})
+	assert.Equal(t, patterns, redactionPatternsFromJSONB(stored))
+}
+
+func TestNewAIRedactor(t *testing.T) {
+	app := &App{Log: testutil.NopLogger()}
+
+	assert.Nil(t, app.newAIRedactor(models.AIConfig{RedactionTypes: models.StringArray{"email"}}), "disabled")
+
+	// A broken stored pattern keeps the built-in detectors working.
+	r := app.newAIRedactor(models.AIConfig{
+		RedactionEnabled:  true,
+		RedactionTypes:    models.StringArray{"email"},
+		RedactionPatterns: models.JSONBArray{map[string]any{"name": "bad", "pattern": "("}},
+	})
+	require.NotNil(t, r)
+	assert.Equal(t, "mail [EMAIL_1]", r.Redact("mail a@x.io"))
+}
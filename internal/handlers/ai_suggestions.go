@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+const (
+	maxReplySuggestions       = 3
+	suggestionHistoryMessages = 20
+	// maxSuggestionCanned is how many matching canned responses are offered
+	// to the model as house-style examples.
+	maxSuggestionCanned  = 3
+	suggestionMaxTokens  = 500
+	aiSuggestionsTimeout = 30 * time.Second
+)
+
+const suggestionSystemPrompt = `You help a customer support agent reply on WhatsApp.
+Suggest up to 3 distinct replies the agent could send next, each ready to send as written.
+Ground every reply in the conversation and the reference material; never promise anything it doesn't support.
+Match the tone of the canned responses when they fit.
+Reply with a JSON array of strings only, e.g. ["First reply", "Second reply"].`
+
+// ReplySuggestionUseRequest records that the agent sent a suggestion.
+type ReplySuggestionUseRequest struct {
+	Index  int  `json:"index"`
+	Edited bool `json:"edited"`
+}
+
+// AgentSuggestionStats is per-agent adoption of AI reply suggestions.
+type AgentSuggestionStats struct {
+	UserID       uuid.UUID `json:"user_id"`
+	AgentName    string    `json:"agent_name"`
+	Requested    int64     `json:"requested"`
+	Used         int64     `json:"used"`
+	UsedEdited   int64     `json:"used_edited"`
+	AdoptionRate float64   `json:"adoption_rate"` // used / requested, percent
+}
+
+// RequestReplySuggestions starts generating reply suggestions for a
+// contact. The response only carries the request id; the suggestions are
+// pushed to the requesting agent over the websocket when ready.
+func (a *App) RequestReplySuggestions(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChat, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var contact models.Contact
+	query := a.scopeAssignedContact(a.DB.Where("id = ? AND organization_id = ?", contactID, orgID), userID, orgID)
+	if err := query.First(&contact).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Contact not found", nil, "")
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, contact.WhatsAppAccount)
+	if err != nil || !settings.AI.ReplySuggestionsEnabled || !aiConfigured(settings.AI) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "AI reply suggestions are not enabled", nil, "")
+	}
+
+	suggestion := models.AIReplySuggestion{
+		OrganizationID: orgID,
+		ContactID:      contact.ID,
+		UserID:         userID,
+		Status:         models.SuggestionStatusGenerating,
+	}
+	if err := a.DB.Create(&suggestion).Error; err != nil {
+		a.Log.Error("Failed to create reply suggestion", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to request suggestions", nil, "")
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.generateReplySuggestions(settings, &contact, &suggestion)
+	}()
+
+	return r.SendEnvelope(map[string]any{
+		"id":     suggestion.ID,
+		"status": suggestion.Status,
+	})
+}
+
+// UseReplySuggestion records which suggestion the agent sent.
+func (a *App) UseReplySuggestion(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChat, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+	suggestionID, err := parsePathUUID(r, "suggestion_id", "suggestion")
+	if err != nil {
+		return nil
+	}
+
+	var req ReplySuggestionUseRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	var suggestion models.AIReplySuggestion
+	if err := a.DB.Where("id = ? AND organization_id = ? AND contact_id = ? AND user_id = ?",
+		suggestionID, orgID, contactID, userID).First(&suggestion).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Suggestion not found", nil, "")
+	}
+	if req.Index < 0 || req.Index >= len(suggestion.Suggestions) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid suggestion index", nil, "")
+	}
+
+	now := time.Now()
+	suggestion.UsedIndex = &req.Index
+	suggestion.Edited = req.Edited
+	suggestion.UsedAt = &now
+	if err := a.DB.Save(&suggestion).Error; err != nil {
+		a.Log.Error("Failed to record suggestion use", "error", err, "suggestion_id", suggestion.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to record suggestion use", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Suggestion use recorded"})
+}
+
+// GetReplySuggestionStats returns per-agent suggestion adoption for a
+// date range (defaults to the current month).
+func (a *App) GetReplySuggestionStats(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceAnalyticsAgents, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := now
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+	if fromStr != "" && toStr != "" {
+		start, end, errMsg := parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+		periodStart, periodEnd = start, end
+	}
+
+	var stats []AgentSuggestionStats
+	if err := a.DB.Model(&models.AIReplySuggestion{}).
+		Select(`ai_reply_suggestions.user_id,
+			users.full_name AS agent_name,
+			COUNT(*) AS requested,
+			COUNT(ai_reply_suggestions.used_at) AS used,
+			COUNT(ai_reply_suggestions.used_at) FILTER (WHERE ai_reply_suggestions.edited) AS used_edited`).
+		Joins("LEFT JOIN users ON users.id = ai_reply_suggestions.user_id").
+		Where("ai_reply_suggestions.organization_id = ? AND ai_reply_suggestions.created_at >= ? AND ai_reply_suggestions.created_at <= ?",
+			orgID, periodStart, periodEnd).
+		Group("ai_reply_suggestions.user_id, users.full_name").
+		Order("requested DESC").
+		Scan(&stats).Error; err != nil {
+		a.Log.Error("Failed to load suggestion stats", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load suggestion stats", nil, "")
+	}
+
+	var totalRequested, totalUsed int64
+	for i := range stats {
+		if stats[i].Requested > 0 {
+			stats[i].AdoptionRate = float64(stats[i].Used) / float64(stats[i].Requested) * 100
+		}
+		totalRequested += stats[i].Requested
+		totalUsed += stats[i].Used
+	}
+	adoption := 0.0
+	if totalRequested > 0 {
+		adoption = float64(totalUsed) / float64(totalRequested) * 100
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"agents":         stats,
+		"total_requests": totalRequested,
+		"total_used":     totalUsed,
+		"adoption_rate":  adoption,
+	})
+}
+
+// generateReplySuggestions fills in a pending suggestion request and
+// pushes the result to the requesting agent.
+func (a *App) generateReplySuggestions(settings *models.ChatbotSettings, contact *models.Contact, suggestion *models.AIReplySuggestion) {
+	replies, err := a.buildReplySuggestions(settings, contact, aiUsageScope{
+		OrgID:     contact.OrganizationID,
+		Account:   contact.WhatsAppAccount,
+		Feature:   models.AIFeatureSuggestion,
+		ContactID: &contact.ID,
+		UserID:    &suggestion.UserID,
+	})
+	if err != nil {
+		a.Log.Error("Failed to generate reply suggestions", "error", err, "contact_id", contact.ID)
+		suggestion.Status = models.SuggestionStatusFailed
+		suggestion.Error = err.Error()
+	} else {
+		suggestion.Status = models.SuggestionStatusReady
+		suggestion.Suggestions = models.StringArray(replies)
+	}
+	a.DB.Model(suggestion).Updates(map[string]any{
+		"status":      suggestion.Status,
+		"suggestions": suggestion.Suggestions,
+		"error":       suggestion.Error,
+	})
+
+	if a.WSHub == nil {
+		return
+	}
+	suggestions := []string(suggestion.Suggestions)
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+	a.WSHub.BroadcastToUser(suggestion.OrganizationID, suggestion.UserID, websocket.WSMessage{
+		Type: websocket.TypeAIReplySuggestions,
+		Payload: map[string]any{
+			"id":          suggestion.ID,
+			"contact_id":  suggestion.ContactID,
+			"status":      suggestion.Status,
+			"suggestions": suggestions,
+		},
+	})
+}
+
+// buildReplySuggestions grounds the model in the recent conversation, the
+// org's AI contexts, knowledge base hits and matching canned responses.
+func (a *App) buildReplySuggestions(settings *models.ChatbotSettings, contact *models.Contact, scope aiUsageScope) ([]string, error) {
+	var messages []models.Message
+	a.DB.Where("organization_id = ? AND contact_id = ?", contact.OrganizationID, contact.ID).
+		Order("created_at DESC").
+		Limit(suggestionHistoryMessages).
+		Find(&messages)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no conversation to reply to")
+	}
+
+	lastCustomerMessage := ""
+	for _, msg := range messages {
+		if msg.Direction == models.DirectionIncoming && strings.TrimSpace(msg.Content) != "" {
+			lastCustomerMessage = msg.Content
+			break
+		}
+	}
+
+	redactor := a.newAIRedactor(settings.AI)
+	defer a.logRedactions(scope, redactor)
+
+	var sections []string
+	session := &models.ChatbotSession{
+		OrganizationID:  contact.OrganizationID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: contact.WhatsAppAccount,
+		PhoneNumber:     contact.PhoneNumber,
+		SessionData:     models.JSONB{},
+	}
+	if contextData := a.buildAIContext(contact.OrganizationID, session, lastCustomerMessage, redactor); contextData != "" {
+		sections = append(sections, contextData)
+	}
+	if lastCustomerMessage != "" {
+		hits, err := a.retrieveKnowledge(settings, scope, redactor.Redact(lastCustomerMessage))
+		if err != nil {
+			a.Log.Warn("Knowledge retrieval for suggestions failed", "error", err, "contact_id", contact.ID)
+		} else if len(hits) > 0 {
+			sections = append(sections, formatKnowledgeContext(hits))
+		}
+
+		var canned []models.CannedResponse
+		a.DB.Where("organization_id = ? AND is_active = ?", contact.OrganizationID, true).Find(&canned)
+		if matched := matchCannedResponses(canned, lastCustomerMessage, maxSuggestionCanned); len(matched) > 0 {
+			var b strings.Builder
+			b.WriteString("## Canned Responses\n")
+			for _, c := range matched {
+				fmt.Fprintf(&b, "\n### %s\n%s\n", c.Name, c.Content)
+			}
+			sections = append(sections, b.String())
+		}
+	}
+
+	systemPrompt := suggestionSystemPrompt
+	if len(sections) > 0 {
+		systemPrompt += "\n\n" + strings.Join(sections, "\n\n")
+	}
+
+	provider, err := a.newMeteredAIProvider(settings.AI, scope)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), aiSuggestionsTimeout)
+	defer cancel()
+
+	resp, err := provider.Complete(ctx, ai.Request{
+		Model:        settings.AI.Model,
+		SystemPrompt: systemPrompt,
+		Messages:     []ai.Message{{Role: ai.RoleUser, Content: "Conversation:\n" + redactor.Redact(formatTranscript(messages))}},
+		MaxTokens:    suggestionMaxTokens,
+		Temperature:  0.5,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseReplySuggestions(redactor.Restore(resp.Content))
+}
+
+// parseReplySuggestions extracts the JSON array of replies, tolerating
+// code fences or prose around it.
+func parseReplySuggestions(content string) ([]string, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON array in suggestions reply")
+	}
+	var raw []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid suggestions reply: %w", err)
+	}
+
+	replies := make([]string, 0, maxReplySuggestions)
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s != "" && !containsFold(replies, s) {
+			replies = append(replies, s)
+		}
+		if len(replies) == maxReplySuggestions {
+			break
+		}
+	}
+	if len(replies) == 0 {
+		return nil, fmt.Errorf("model returned no suggestions")
+	}
+	return replies, nil
+}
+
+// matchCannedResponses ranks canned responses by how many distinct words
+// of text appear in their name, shortcut or content, returning at most
+// limit responses with at least one match.
+func matchCannedResponses(responses []models.CannedResponse, text string, limit int) []models.CannedResponse {
+	words := suggestionKeywords(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		resp  models.CannedResponse
+		score int
+	}
+	var matches []scored
+	for _, resp := range responses {
+		haystack := suggestionKeywords(resp.Name + " " + resp.Shortcut + " " + resp.Content)
+		score := 0
+		for w := range words {
+			if _, ok := haystack[w]; ok {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{resp, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	out := make([]models.CannedResponse, 0, min(limit, len(matches)))
+	for i := 0; i < len(matches) && i < limit; i++ {
+		out = append(out, matches[i].resp)
+	}
+	return out
+}
+
+// suggestionKeywords returns the lowercase words of s with three or more
+// letters, which skips most stop words without a language-specific list.
+func suggestionKeywords(s string) map[string]struct{} {
+	words := map[string]struct{}{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) >= 3 {
+			words[w] = struct{}{}
+		}
+	}
+	return words
+}
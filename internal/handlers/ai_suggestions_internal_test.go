@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReplySuggestions(t *testing.T) {
+	replies, err := parseReplySuggestions("Here you go:\n```json\n[\"Sure, one moment\", \" \", \"sure, one moment\", \"Can you share your order ID?\", \"Thanks!\", \"Extra\"]\n```")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Sure, one moment", "Can you share your order ID?", "Thanks!"}, replies)
+
+	_, err = parseReplySuggestions("I can't help with that")
+	assert.Error(t, err)
+
+	_, err = parseReplySuggestions(`[]`)
+	assert.Error(t, err)
+}
+
+func TestMatchCannedResponses(t *testing.T) {
+	responses := []models.CannedResponse{
+		{Name: "Greeting", Content: "Hello! How can we help?"},
+		{Name: "Refund policy", Content: "Refunds are processed within 5 days of the return."},
+		{Name: "Order status", Shortcut: "order", Content: "You can track your order on our site."},
+	}
+
+	matched := matchCannedResponses(responses, "Where is my order? I want a refund", 2)
+	require.Len(t, matched, 2)
+	names := []string{matched[0].Name, matched[1].Name}
+	assert.ElementsMatch(t, []string{"Refund policy", "Order status"}, names)
+
+	assert.Empty(t, matchCannedResponses(responses, "ok", 3), "short words are ignored")
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+const (
+	// maxSummaryMessages is how much of the conversation the summary sees.
+	maxSummaryMessages = 50
+	summaryMaxTokens   = 400
+	aiSummaryTimeout   = 45 * time.Second
+)
+
+const summarySystemPrompt = `You summarize WhatsApp customer conversations for the support agent taking over.
+Reply in exactly this format:
+Intent: <what the customer wants, one line>
+Details: <key facts and collected values the agent needs, or "none">
+Sentiment: <positive, neutral or negative>
+Open questions: <what still needs an answer, or "none">
+Be concise and only use facts from the conversation.`
+
+var errNothingToSummarize = errors.New("no messages to summarize")
+
+// aiSummariesEnabled reports whether transfers should be summarized
+// automatically for these settings.
+func aiSummariesEnabled(settings *models.ChatbotSettings) bool {
+	return settings != nil && settings.AI.SummariesEnabled && aiConfigured(settings.AI)
+}
+
+// RegenerateTransferSummary re-runs the AI summary for a transfer and
+// returns the updated note.
+func (a *App) RegenerateTransferSummary(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChat, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	transferID, err := parsePathUUID(r, "id", "transfer")
+	if err != nil {
+		return nil
+	}
+	transfer, err := findByIDAndOrg[models.AgentTransfer](a.DB, r, transferID, orgID, "Transfer")
+	if err != nil {
+		return nil
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, transfer.WhatsAppAccount)
+	if err != nil || !aiConfigured(settings.AI) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "AI is not configured", nil, "")
+	}
+
+	note, err := a.summarizeTransfer(settings, transfer)
+	if errors.Is(err, errNothingToSummarize) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Conversation has no messages to summarize", nil, "")
+	}
+	if err != nil {
+		a.Log.Error("Failed to generate transfer summary", "error", err, "transfer_id", transfer.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Failed to generate summary", nil, "")
+	}
+	return r.SendEnvelope(noteToResponse(*note))
+}
+
+// dispatchTransferCreated sends the transfer.created webhook. With AI
+// summaries enabled the summary is generated first, in the background,
+// and included in the payload.
+func (a *App) dispatchTransferCreated(transfer *models.AgentTransfer, contact *models.Contact, settings *models.ChatbotSettings) {
+	data := TransferEventData{
+		TransferID:      transfer.ID.String(),
+		ContactID:       contact.ID.String(),
+		ContactPhone:    contact.PhoneNumber,
+		ContactName:     contact.ProfileName,
+		Source:          transfer.Source,
+		Reason:          transfer.Notes,
+		WhatsAppAccount: transfer.WhatsAppAccount,
+	}
+	if transfer.AgentID != nil {
+		idStr := transfer.AgentID.String()
+		data.AgentID = &idStr
+	}
+
+	if !aiSummariesEnabled(settings) {
+		a.DispatchWebhook(transfer.OrganizationID, models.WebhookEventTransferCreated, data)
+		return
+	}
+
+	t := *transfer
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		note, err := a.summarizeTransfer(settings, &t)
+		switch {
+		case err == nil:
+			data.Summary = note.Content
+		case !errors.Is(err, errNothingToSummarize):
+			a.Log.Error("Failed to generate transfer summary", "error", err, "transfer_id", t.ID)
+		}
+		a.DispatchWebhook(t.OrganizationID, models.WebhookEventTransferCreated, data)
+	}()
+}
+
+// startTransferSummary refreshes a transfer's summary in the background,
+// e.g. once the conversation is resolved and the agent's part is known.
+func (a *App) startTransferSummary(transfer *models.AgentTransfer) {
+	settings, err := a.getChatbotSettingsCached(transfer.OrganizationID, transfer.WhatsAppAccount)
+	if err != nil || !aiSummariesEnabled(settings) {
+		return
+	}
+	t := *transfer
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if _, err := a.summarizeTransfer(settings, &t); err != nil && !errors.Is(err, errNothingToSummarize) {
+			a.Log.Error("Failed to generate transfer summary", "error", err, "transfer_id", t.ID)
+		}
+	}()
+}
+
+// summarizeTransfer generates a summary of the contact's recent
+// conversation and stores it as the transfer's pinned note, replacing any
+// earlier summary of the same transfer.
+func (a *App) summarizeTransfer(settings *models.ChatbotSettings, transfer *models.AgentTransfer) (*models.ConversationNote, error) {
+	var messages []models.Message
+	a.DB.Where("organization_id = ? AND contact_id = ? AND whats_app_account = ?",
+		transfer.OrganizationID, transfer.ContactID, transfer.WhatsAppAccount).
+		Order("created_at DESC").
+		Limit(maxSummaryMessages).
+		Find(&messages)
+	if len(messages) == 0 {
+		return nil, errNothingToSummarize
+	}
+
+	var session models.ChatbotSession
+	var sessionData models.JSONB
+	if a.DB.Where("organization_id = ? AND contact_id = ?", transfer.OrganizationID, transfer.ContactID).
+		Order("updated_at DESC").First(&session).Error == nil {
+		sessionData = session.SessionData
+	}
+
+	scope := aiUsageScope{
+		OrgID:     transfer.OrganizationID,
+		Account:   transfer.WhatsAppAccount,
+		Feature:   models.AIFeatureSummary,
+		ContactID: &transfer.ContactID,
+	}
+	provider, err := a.newMeteredAIProvider(settings.AI, scope)
+	if err != nil {
+		return nil, err
+	}
+	redactor := a.newAIRedactor(settings.AI)
+	defer a.logRedactions(scope, redactor)
+	ctx, cancel := context.WithTimeout(context.Background(), aiSummaryTimeout)
+	defer cancel()
+
+	resp, err := provider.Complete(ctx, ai.Request{
+		Model:        settings.AI.Model,
+		SystemPrompt: summarySystemPrompt,
+		Messages:     []ai.Message{{Role: ai.RoleUser, Content: redactor.Redact(buildSummaryInput(messages, sessionData))}},
+		MaxTokens:    summaryMaxTokens,
+		Temperature:  0.2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(redactor.Restore(resp.Content))
+	if content == "" {
+		return nil, fmt.Errorf("empty summary")
+	}
+
+	return a.saveSummaryNote(transfer, content)
+}
+
+// saveSummaryNote upserts the transfer's summary note, pins it and unpins
+// the contact's older summaries.
+func (a *App) saveSummaryNote(transfer *models.AgentTransfer, content string) (*models.ConversationNote, error) {
+	var note models.ConversationNote
+	created := false
+	err := a.DB.Where("organization_id = ? AND transfer_id = ? AND source = ?",
+		transfer.OrganizationID, transfer.ID, models.NoteSourceAISummary).First(&note).Error
+	if err == nil {
+		note.Content = content
+		note.IsPinned = true
+		err = a.DB.Save(&note).Error
+	} else {
+		transferID := transfer.ID
+		note = models.ConversationNote{
+			OrganizationID: transfer.OrganizationID,
+			ContactID:      transfer.ContactID,
+			Content:        content,
+			Source:         models.NoteSourceAISummary,
+			IsPinned:       true,
+			TransferID:     &transferID,
+		}
+		err = a.DB.Create(&note).Error
+		created = true
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.DB.Model(&models.ConversationNote{}).
+		Where("organization_id = ? AND contact_id = ? AND source = ? AND id <> ? AND is_pinned = true",
+			transfer.OrganizationID, transfer.ContactID, models.NoteSourceAISummary, note.ID).
+		Update("is_pinned", false)
+
+	if a.WSHub != nil {
+		msgType := websocket.TypeConversationNoteUpdated
+		if created {
+			msgType = websocket.TypeConversationNoteCreated
+		}
+		a.WSHub.BroadcastToContact(transfer.OrganizationID, transfer.ContactID, websocket.WSMessage{
+			Type:    msgType,
+			Payload: noteToResponse(note),
+		})
+	}
+	return &note, nil
+}
+
+// buildSummaryInput renders collected session variables and the
+// conversation transcript.
+func buildSummaryInput(messages []models.Message, sessionData models.JSONB) string {
+	var b strings.Builder
+
+	keys := make([]string, 0, len(sessionData))
+	for k, v := range sessionData {
+		// Underscore keys are runner bookkeeping; nested values are
+		// API payloads rather than answers.
+		if strings.HasPrefix(k, "_") || v == nil {
+			continue
+		}
+		switch v.(type) {
+		case map[string]any, []any:
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		b.WriteString("Collected variables:\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "- %s: %v\n", k, sessionData[k])
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Conversation:\n")
+	b.WriteString(formatTranscript(messages))
+	return b.String()
+}
+
+// formatTranscript renders messages (newest first, as loaded) oldest
+// first, one "Speaker: text" line each. Outgoing messages sent by a user
+// are the agent's; other outgoing messages are the bot's.
+func formatTranscript(messages []models.Message) string {
+	var b strings.Builder
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		speaker := "Customer"
+		if msg.Direction == models.DirectionOutgoing {
+			speaker = "Bot"
+			if msg.SentByUserID != nil && *msg.SentByUserID != uuid.Nil {
+				speaker = "Agent"
+			}
+		}
+		content := strings.TrimSpace(msg.Content)
+		if content == "" {
+			content = "[" + string(msg.MessageType) + "]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", speaker, content)
+	}
+	return b.String()
+}
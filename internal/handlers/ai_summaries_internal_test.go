@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSummaryInput(t *testing.T) {
+	agentID := uuid.New()
+	// Newest first, as loaded from the DB.
+	messages := []models.Message{
+		{Direction: models.DirectionOutgoing, Content: "Let me check that order", SentByUserID: &agentID},
+		{Direction: models.DirectionIncoming, MessageType: models.MessageTypeImage},
+		{Direction: models.DirectionOutgoing, Content: "Connecting you to an agent"},
+		{Direction: models.DirectionIncoming, Content: "Where is my order?"},
+	}
+	sessionData := models.JSONB{
+		"order_id":  "A-123",
+		"name":      "Asha",
+		"_internal": "skip",
+		"api":       map[string]any{"status": 200},
+	}
+
+	input := buildSummaryInput(messages, sessionData)
+
+	assert.Equal(t, "Collected variables:\n- name: Asha\n- order_id: A-123\n\n"+
+		"Conversation:\n"+
+		"Customer: Where is my order?\n"+
+		"Bot: Connecting you to an agent\n"+
+		"Customer: [image]\n"+
+		"Agent: Let me check that order\n", input)
+}
+
+func TestSaveSummaryNote_PinsLatestPerContact(t *testing.T) {
+	app := newSLATestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	first := createSLATestTransfer(t, app, org.ID, contact.ID, agent.ID, account.Name, models.SLATracking{})
+	second := createSLATestTransfer(t, app, org.ID, contact.ID, agent.ID, account.Name, models.SLATracking{})
+
+	firstNote, err := app.saveSummaryNote(first, "first summary")
+	require.NoError(t, err)
+	assert.Nil(t, firstNote.CreatedByID)
+	assert.True(t, firstNote.IsPinned)
+
+	secondNote, err := app.saveSummaryNote(second, "second summary")
+	require.NoError(t, err)
+
+	// Regenerating updates the existing note in place.
+	regenerated, err := app.saveSummaryNote(first, "first summary v2")
+	require.NoError(t, err)
+	assert.Equal(t, firstNote.ID, regenerated.ID)
+
+	var notes []models.ConversationNote
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).Order("created_at").Find(&notes).Error)
+	require.Len(t, notes, 2)
+	assert.Equal(t, "first summary v2", notes[0].Content)
+	assert.True(t, notes[0].IsPinned)
+	assert.Equal(t, secondNote.ID, notes[1].ID)
+	assert.False(t, notes[1].IsPinned)
+}
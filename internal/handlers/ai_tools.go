@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+const (
+	defaultAIToolTimeout = 10 * time.Second
+	maxAIToolTimeout     = 60 * time.Second
+	// maxAIToolResultBytes caps how much of a tool's response is fed back to
+	// the model; larger payloads burn tokens without helping the answer.
+	maxAIToolResultBytes = 8 * 1024
+	// maxAIToolIterations is the upper bound for ai_max_tool_iterations.
+	maxAIToolIterations = 10
+)
+
+// aiToolNamePattern matches the function-name rules shared by OpenAI,
+// Anthropic and Gemini.
+var aiToolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// AIToolRequest is the request body for creating/updating an AI tool
+type AIToolRequest struct {
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	WhatsAppAccount string         `json:"whatsapp_account"`
+	Parameters      map[string]any `json:"parameters"`
+	ApiConfig       map[string]any `json:"api_config"`
+	TimeoutSeconds  int            `json:"timeout_seconds"`
+	IsEnabled       *bool          `json:"is_enabled"`
+}
+
+// AIToolResponse represents an AI tool for API responses
+type AIToolResponse struct {
+	ID              uuid.UUID      `json:"id"`
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	WhatsAppAccount string         `json:"whatsapp_account"`
+	Parameters      map[string]any `json:"parameters"`
+	ApiConfig       map[string]any `json:"api_config"`
+	TimeoutSeconds  int            `json:"timeout_seconds"`
+	IsEnabled       bool           `json:"is_enabled"`
+	CreatedAt       string         `json:"created_at"`
+	UpdatedAt       string         `json:"updated_at"`
+}
+
+func aiToolToResponse(tool models.AITool) AIToolResponse {
+	return AIToolResponse{
+		ID:              tool.ID,
+		Name:            tool.Name,
+		Description:     tool.Description,
+		WhatsAppAccount: tool.WhatsAppAccount,
+		Parameters:      tool.Parameters,
+		ApiConfig:       tool.ApiConfig,
+		TimeoutSeconds:  tool.TimeoutSeconds,
+		IsEnabled:       tool.IsEnabled,
+		CreatedAt:       tool.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       tool.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ListAITools returns the organization's AI tools
+func (a *App) ListAITools(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.AITool{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		searchPattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var tools []models.AITool
+	if err := pg.Apply(query.Order("name ASC")).Find(&tools).Error; err != nil {
+		a.Log.Error("Failed to list AI tools", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list AI tools", nil, "")
+	}
+
+	result := make([]AIToolResponse, len(tools))
+	for i, tool := range tools {
+		result[i] = aiToolToResponse(tool)
+	}
+
+	return r.SendEnvelope(listEnvelope("tools", result, total, pg))
+}
+
+// GetAITool returns a single AI tool
+func (a *App) GetAITool(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "tool")
+	if err != nil {
+		return nil
+	}
+
+	tool, err := findByIDAndOrg[models.AITool](a.DB, r, id, orgID, "AI tool")
+	if err != nil {
+		return nil
+	}
+	return r.SendEnvelope(aiToolToResponse(*tool))
+}
+
+// CreateAITool creates a new AI tool
+func (a *App) CreateAITool(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	var req AIToolRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if err := a.validateAIToolRequest(orgID, uuid.Nil, &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	tool := models.AITool{
+		OrganizationID:  orgID,
+		WhatsAppAccount: req.WhatsAppAccount,
+		Name:            req.Name,
+		Description:     req.Description,
+		Parameters:      models.JSONB(req.Parameters),
+		ApiConfig:       models.JSONB(req.ApiConfig),
+		TimeoutSeconds:  req.TimeoutSeconds,
+		IsEnabled:       req.IsEnabled == nil || *req.IsEnabled,
+		CreatedByID:     &userID,
+		UpdatedByID:     &userID,
+	}
+	if err := a.DB.Create(&tool).Error; err != nil {
+		a.Log.Error("Failed to create AI tool", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create AI tool", nil, "")
+	}
+
+	a.InvalidateAIToolsCache(orgID)
+	a.logAudit(orgID, userID, "ai_tool", tool.ID, models.AuditActionCreated, nil, &tool)
+
+	return r.SendEnvelope(aiToolToResponse(tool))
+}
+
+// UpdateAITool replaces an AI tool's definition
+func (a *App) UpdateAITool(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "tool")
+	if err != nil {
+		return nil
+	}
+
+	tool, err := findByIDAndOrg[models.AITool](a.DB, r, id, orgID, "AI tool")
+	if err != nil {
+		return nil
+	}
+	oldTool := *tool
+
+	var req AIToolRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if err := a.validateAIToolRequest(orgID, tool.ID, &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	tool.Name = req.Name
+	tool.Description = req.Description
+	tool.WhatsAppAccount = req.WhatsAppAccount
+	tool.Parameters = models.JSONB(req.Parameters)
+	tool.ApiConfig = models.JSONB(req.ApiConfig)
+	tool.TimeoutSeconds = req.TimeoutSeconds
+	if req.IsEnabled != nil {
+		tool.IsEnabled = *req.IsEnabled
+	}
+	tool.UpdatedByID = &userID
+
+	if err := a.DB.Save(tool).Error; err != nil {
+		a.Log.Error("Failed to update AI tool", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update AI tool", nil, "")
+	}
+
+	a.InvalidateAIToolsCache(orgID)
+	a.logAudit(orgID, userID, "ai_tool", tool.ID, models.AuditActionUpdated, &oldTool, tool)
+
+	return r.SendEnvelope(aiToolToResponse(*tool))
+}
+
+// DeleteAITool deletes an AI tool
+func (a *App) DeleteAITool(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionDelete)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "tool")
+	if err != nil {
+		return nil
+	}
+
+	tool, err := findByIDAndOrg[models.AITool](a.DB, r, id, orgID, "AI tool")
+	if err != nil {
+		return nil
+	}
+	if err := a.DB.Delete(tool).Error; err != nil {
+		a.Log.Error("Failed to delete AI tool", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete AI tool", nil, "")
+	}
+
+	a.InvalidateAIToolsCache(orgID)
+	a.logAudit(orgID, userID, "ai_tool", tool.ID, models.AuditActionDeleted, tool, nil)
+
+	return r.SendEnvelope(map[string]string{"message": "AI tool deleted"})
+}
+
+// validateAIToolRequest normalizes req and checks it against the provider
+// naming rules, the webhook URL policy and per-account name uniqueness.
+func (a *App) validateAIToolRequest(orgID, toolID uuid.UUID, req *AIToolRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.WhatsAppAccount = strings.TrimSpace(req.WhatsAppAccount)
+	if !aiToolNamePattern.MatchString(req.Name) {
+		return fmt.Errorf("name must be 1-64 letters, digits, underscores or hyphens")
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		return fmt.Errorf("description is required so the model knows when to call the tool")
+	}
+
+	if req.Parameters == nil {
+		req.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	if t, _ := req.Parameters["type"].(string); t != "object" {
+		return fmt.Errorf(`parameters must be a JSON Schema with "type": "object"`)
+	}
+
+	apiURL, _ := req.ApiConfig["url"].(string)
+	if apiURL == "" {
+		return fmt.Errorf("api_config.url is required")
+	}
+	// Templated hosts can only be checked at call time, where the
+	// SSRF-safe dialer still applies.
+	if !strings.Contains(apiURL, "{{") {
+		if err := validateWebhookURL(apiURL, a.Config.App.AllowInternalWebhookURLs); err != nil {
+			return fmt.Errorf("api_config.url: %w", err)
+		}
+	}
+	if method, ok := req.ApiConfig["method"].(string); ok && method != "" {
+		switch strings.ToUpper(method) {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return fmt.Errorf("api_config.method must be GET, POST, PUT, PATCH or DELETE")
+		}
+	}
+
+	if req.TimeoutSeconds < 0 || time.Duration(req.TimeoutSeconds)*time.Second > maxAIToolTimeout {
+		return fmt.Errorf("timeout_seconds must be between 1 and %d", int(maxAIToolTimeout.Seconds()))
+	}
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = int(defaultAIToolTimeout.Seconds())
+	}
+
+	if req.WhatsAppAccount != "" {
+		if _, err := a.resolveWhatsAppAccount(orgID, req.WhatsAppAccount); err != nil {
+			return fmt.Errorf("WhatsApp account not found")
+		}
+	}
+
+	var count int64
+	a.DB.Model(&models.AITool{}).
+		Where("organization_id = ? AND whats_app_account = ? AND name = ? AND id <> ?",
+			orgID, req.WhatsAppAccount, req.Name, toolID).
+		Count(&count)
+	if count > 0 {
+		return fmt.Errorf("a tool named %q already exists", req.Name)
+	}
+	return nil
+}
+
+// loadAITools returns the enabled tools for the session's account. When
+// allowed is non-nil only the named tools are returned, so a flow node can
+// expose a subset.
+func (a *App) loadAITools(orgID uuid.UUID, accountName string, allowed []string) []models.AITool {
+	if allowed != nil && len(allowed) == 0 {
+		return nil
+	}
+	tools, err := a.getAIToolsCached(orgID, accountName)
+	if err != nil {
+		a.Log.Error("Failed to load AI tools", "error", err, "org_id", orgID)
+		return nil
+	}
+
+	allow := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allow[name] = true
+	}
+	seen := make(map[string]bool, len(tools))
+	out := make([]models.AITool, 0, len(tools))
+	for _, t := range tools {
+		if seen[t.Name] || (allowed != nil && !allow[t.Name]) {
+			continue
+		}
+		seen[t.Name] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// aiToolDefinitions converts stored tools to the provider-neutral form.
+func aiToolDefinitions(tools []models.AITool) []ai.Tool {
+	defs := make([]ai.Tool, len(tools))
+	for i, t := range tools {
+		defs[i] = ai.Tool{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.Parameters,
+		}
+	}
+	return defs
+}
+
+// executeAITool runs the tool's HTTP call for a model-issued ToolCall and
+// returns the text fed back to the model. Failures are reported to the
+// model as text rather than aborting the reply, so it can apologise or try
+// something else. The model's arguments, plus SessionData and
+// {{phone_number}}, are available to the url/headers/body templates;
+// {{arguments}} expands to the raw JSON. A body-less POST/PUT/PATCH sends
+// the arguments as the JSON body.
+//
+// The arguments are chosen by the model, so a prompt-injected message can
+// steer them: SessionData and {{phone_number}} take precedence over
+// arguments of the same name, and values are escaped for the URL, JSON
+// body and headers they're substituted into.
+func (a *App) executeAITool(tool *models.AITool, call ai.ToolCall, session *models.ChatbotSession) string {
+	args := map[string]any{}
+	if call.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+			return "error: arguments are not a valid JSON object"
+		}
+	}
+	rawArgs := call.Arguments
+	if rawArgs == "" {
+		rawArgs = "{}"
+	}
+
+	data := map[string]any{}
+	for k, v := range args {
+		data[k] = v
+	}
+	if session != nil {
+		for k, v := range session.SessionData {
+			data[k] = v
+		}
+		data["phone_number"] = session.PhoneNumber
+	}
+
+	// Render the templates here, where we know which context each is, and
+	// hand the finished request to executeConfiguredAPIContext
+	urlData := escapeAIToolData(data, aiToolURLEscape)
+	urlData["arguments"] = aiToolURLEscape(rawArgs)
+	bodyData := escapeAIToolData(data, aiToolJSONEscape)
+	bodyData["arguments"] = rawArgs
+	headerData := escapeAIToolData(data, aiToolHeaderEscape)
+	headerData["arguments"] = aiToolHeaderEscape(rawArgs)
+
+	apiConfig := models.JSONB{}
+	for k, v := range tool.ApiConfig {
+		apiConfig[k] = v
+	}
+	method, _ := apiConfig["method"].(string)
+	if body, _ := apiConfig["body"].(string); body == "" && method != "" && !strings.EqualFold(method, http.MethodGet) {
+		apiConfig["body"] = "{{arguments}}"
+	}
+	if u, ok := apiConfig["url"].(string); ok {
+		apiConfig["url"] = processTemplate(u, urlData)
+	}
+	if body, ok := apiConfig["body"].(string); ok {
+		apiConfig["body"] = processTemplate(body, bodyData)
+	}
+	if headers, ok := apiConfig["headers"].(map[string]any); ok {
+		rendered := make(map[string]any, len(headers))
+		for k, v := range headers {
+			if str, ok := v.(string); ok {
+				v = processTemplate(str, headerData)
+			}
+			rendered[k] = v
+		}
+		apiConfig["headers"] = rendered
+	}
+
+	timeout := time.Duration(tool.TimeoutSeconds) * time.Second
+	if timeout <= 0 || timeout > maxAIToolTimeout {
+		timeout = defaultAIToolTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rendered := func(s string) string { return s }
+	body, status, err := a.executeConfiguredAPIContext(ctx, apiConfig, rendered)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	result := string(body)
+	if len(result) > maxAIToolResultBytes {
+		result = result[:maxAIToolResultBytes] + "…[truncated]"
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Sprintf("error: HTTP %d: %s", status, result)
+	}
+	if result == "" {
+		return fmt.Sprintf("HTTP %d (empty response)", status)
+	}
+	return result
+}
+
+// escapeAIToolData returns a copy of data with every string, including
+// those nested in maps and lists, passed through escape.
+func escapeAIToolData(data map[string]any, escape func(string) string) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		out[k] = escapeAIToolValue(v, escape)
+	}
+	return out
+}
+
+func escapeAIToolValue(v any, escape func(string) string) any {
+	switch val := v.(type) {
+	case string:
+		return escape(val)
+	case map[string]any:
+		return escapeAIToolData(val, escape)
+	case models.JSONB:
+		return escapeAIToolData(val, escape)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = escapeAIToolValue(item, escape)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// aiToolURLEscape escapes s for a URL path segment or query value.
+func aiToolURLEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// aiToolJSONEscape escapes s for use inside a JSON string literal.
+func aiToolJSONEscape(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	b := bytes.TrimSpace(buf.Bytes())
+	return string(b[1 : len(b)-1])
+}
+
+// aiToolHeaderEscape drops line breaks, which would end the header.
+func aiToolHeaderEscape(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// logAIToolCall records a tool call and its result in the session transcript.
+func (a *App) logAIToolCall(sessionID uuid.UUID, call ai.ToolCall, result string) {
+	args := models.JSONB{}
+	if call.Arguments != "" {
+		_ = json.Unmarshal([]byte(call.Arguments), &args)
+	}
+	msg := models.ChatbotSessionMessage{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		SessionID:     sessionID,
+		Direction:     models.DirectionOutgoing,
+		Message:       result,
+		StepName:      "ai_tool",
+		ToolName:      call.Name,
+		ToolCallID:    call.ID,
+		ToolArguments: args,
+	}
+	if err := a.DB.Create(&msg).Error; err != nil {
+		a.Log.Error("Failed to log AI tool call", "error", err)
+	}
+}
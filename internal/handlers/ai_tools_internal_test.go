@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteAITool_ArgumentsCannotOverrideSession(t *testing.T) {
+	var gotURI, gotBody, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.URL.RequestURI()
+		gotHeader = r.Header.Get("X-Order")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	app := &App{Log: testutil.NopLogger(), HTTPClient: server.Client()}
+	tool := &models.AITool{
+		Name: "lookup_orders",
+		ApiConfig: models.JSONB{
+			"url":     server.URL + "/customers/{{phone_number}}/orders?q={{query}}",
+			"method":  "POST",
+			"headers": map[string]any{"X-Order": "{{query}}"},
+			"body":    `{"query": "{{query}}", "customer": "{{customer_id}}"}`,
+		},
+	}
+	session := &models.ChatbotSession{
+		PhoneNumber: "15550001111",
+		SessionData: models.JSONB{"customer_id": "c-1"},
+	}
+	call := ai.ToolCall{
+		Name:      "lookup_orders",
+		Arguments: `{"phone_number": "15559999999", "customer_id": "c-2", "query": "a&b=\"c\"\r\nX-Evil: 1"}`,
+	}
+
+	result := app.executeAITool(tool, call, session)
+	require.Equal(t, `{"ok":true}`, result)
+	assert.Equal(t, "/customers/15550001111/orders?q=a%26b%3D%22c%22%0D%0AX-Evil%3A%201", gotURI)
+	assert.Equal(t, `{"query": "a&b=\"c\"\r\nX-Evil: 1", "customer": "c-1"}`, gotBody)
+	assert.Equal(t, `a&b="c"X-Evil: 1`, gotHeader)
+}
@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// errAIBudgetExceeded is returned instead of calling the provider once the
+// org has used its monthly tokens and the hard limit is on.
+var errAIBudgetExceeded = errors.New("monthly AI token budget exhausted")
+
+const (
+	aiBudgetLevelSoft = "soft"
+	aiBudgetLevelHard = "hard"
+)
+
+// aiUsageScope describes what an AI call is made for, so its usage can be
+// attributed. Only OrgID and Feature are required.
+type aiUsageScope struct {
+	OrgID     uuid.UUID
+	Account   string
+	Feature   models.AIFeature
+	SessionID *uuid.UUID
+	FlowID    *uuid.UUID
+	ContactID *uuid.UUID
+	UserID    *uuid.UUID
+}
+
+// sessionUsageScope attributes a call to a chatbot session and the flow it
+// is running, if any.
+func sessionUsageScope(orgID uuid.UUID, session *models.ChatbotSession, feature models.AIFeature) aiUsageScope {
+	scope := aiUsageScope{OrgID: orgID, Feature: feature}
+	if session != nil {
+		scope.Account = session.WhatsAppAccount
+		if session.ID != uuid.Nil {
+			id := session.ID
+			scope.SessionID = &id
+		}
+		if session.ContactID != uuid.Nil {
+			id := session.ContactID
+			scope.ContactID = &id
+		}
+		scope.FlowID = session.CurrentFlowID
+	}
+	return scope
+}
+
+// newMeteredAIProvider builds the org's provider wrapped so every call is
+// checked against the budget and logged.
+func (a *App) newMeteredAIProvider(cfg models.AIConfig, scope aiUsageScope) (ai.Provider, error) {
+	provider, err := a.newAIProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &meteredProvider{app: a, inner: provider, scope: scope}, nil
+}
+
+// newMeteredAIEmbedder is newMeteredAIProvider for embeddings.
+func (a *App) newMeteredAIEmbedder(cfg models.AIConfig, scope aiUsageScope) (ai.Embedder, error) {
+	embedder, err := a.newAIEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &meteredEmbedder{app: a, inner: embedder, provider: string(cfg.Provider), scope: scope}, nil
+}
+
+type meteredProvider struct {
+	app   *App
+	inner ai.Provider
+	scope aiUsageScope
+}
+
+func (p *meteredProvider) Name() string {
+	return p.inner.Name()
+}
+
+func (p *meteredProvider) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	entry := p.app.newAIUsageLog(p.scope, p.inner.Name(), req.Model, models.AIOperationCompletion)
+	if err := p.app.checkAIBudget(p.scope.OrgID); err != nil {
+		p.app.recordAIUsage(entry, 0, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := p.inner.Complete(ctx, req)
+	latency := time.Since(start).Milliseconds()
+	if resp != nil {
+		if resp.Model != "" {
+			entry.Model = resp.Model
+		}
+		entry.PromptTokens = resp.Usage.PromptTokens
+		entry.CompletionTokens = resp.Usage.CompletionTokens
+	}
+	p.app.recordAIUsage(entry, latency, err)
+	return resp, err
+}
+
+type meteredEmbedder struct {
+	app      *App
+	inner    ai.Embedder
+	provider string
+	scope    aiUsageScope
+}
+
+func (e *meteredEmbedder) Embed(ctx context.Context, model string, inputs []string) (*ai.EmbeddingResponse, error) {
+	entry := e.app.newAIUsageLog(e.scope, e.provider, model, models.AIOperationEmbedding)
+	if err := e.app.checkAIBudget(e.scope.OrgID); err != nil {
+		e.app.recordAIUsage(entry, 0, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := e.inner.Embed(ctx, model, inputs)
+	latency := time.Since(start).Milliseconds()
+	if resp != nil {
+		entry.PromptTokens = resp.Usage.PromptTokens
+	}
+	e.app.recordAIUsage(entry, latency, err)
+	return resp, err
+}
+
+func (a *App) newAIUsageLog(scope aiUsageScope, provider, model string, op models.AIOperation) *models.AIUsageLog {
+	return &models.AIUsageLog{
+		OrganizationID:  scope.OrgID,
+		WhatsAppAccount: scope.Account,
+		Provider:        provider,
+		Model:           model,
+		Feature:         scope.Feature,
+		Operation:       op,
+		SessionID:       scope.SessionID,
+		FlowID:          scope.FlowID,
+		ContactID:       scope.ContactID,
+		UserID:          scope.UserID,
+	}
+}
+
+// checkAIBudget returns errAIBudgetExceeded when the org's hard limit is on
+// and this month's usage has reached it. Budget lookup failures let the
+// call through; metering must not take the bot down.
+func (a *App) checkAIBudget(orgID uuid.UUID) error {
+	budget, err := a.getAIBudgetCached(orgID)
+	if err != nil {
+		a.Log.Warn("Failed to load AI budget", "error", err, "org_id", orgID)
+		return nil
+	}
+	if !budget.HardLimitEnabled || budget.MonthlyTokenLimit <= 0 {
+		return nil
+	}
+	if a.aiMonthTokens(orgID, time.Now()) >= budget.MonthlyTokenLimit {
+		return errAIBudgetExceeded
+	}
+	return nil
+}
+
+// recordAIUsage stores the usage log, adds its tokens to the monthly
+// counter and raises budget alerts when a threshold is crossed.
+func (a *App) recordAIUsage(entry *models.AIUsageLog, latencyMs int64, callErr error) {
+	entry.LatencyMs = latencyMs
+	entry.TotalTokens = entry.PromptTokens + entry.CompletionTokens
+	entry.Success = callErr == nil
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+	if err := a.DB.Create(entry).Error; err != nil {
+		a.Log.Error("Failed to record AI usage", "error", err, "org_id", entry.OrganizationID)
+		return
+	}
+	if entry.TotalTokens == 0 {
+		return
+	}
+
+	used := a.addAIMonthTokens(entry.OrganizationID, entry.CreatedAt, int64(entry.TotalTokens))
+	a.checkAIBudgetAlerts(entry.OrganizationID, used)
+}
+
+// aiMonthTokens returns the org's token usage for the month containing t.
+// The running total lives in Redis and is rebuilt from the usage logs
+// when missing.
+func (a *App) aiMonthTokens(orgID uuid.UUID, t time.Time) int64 {
+	ctx := context.Background()
+	key := aiUsageCounterKey(orgID, t)
+	if used, err := a.Redis.Get(ctx, key).Int64(); err == nil {
+		return used
+	}
+
+	start, end := aiUsagePeriodBounds(t)
+	var used int64
+	a.DB.Model(&models.AIUsageLog{}).
+		Where("organization_id = ? AND created_at >= ? AND created_at < ?", orgID, start, end).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&used)
+	a.Redis.SetNX(ctx, key, used, aiUsageCounterTTL)
+	return used
+}
+
+// addAIMonthTokens adds tokens (already stored in a usage log) to the
+// monthly counter and returns the new total.
+func (a *App) addAIMonthTokens(orgID uuid.UUID, t time.Time, tokens int64) int64 {
+	ctx := context.Background()
+	key := aiUsageCounterKey(orgID, t)
+	// A missing counter is rebuilt from the logs, which already include
+	// this call; incrementing would start it from zero instead.
+	if a.Redis.Exists(ctx, key).Val() == 0 {
+		return a.aiMonthTokens(orgID, t)
+	}
+	used, err := a.Redis.IncrBy(ctx, key, tokens).Result()
+	if err != nil {
+		return a.aiMonthTokens(orgID, t)
+	}
+	return used
+}
+
+// checkAIBudgetAlerts notifies the org the first time this month's usage
+// crosses the soft or hard limit. The alert period column is claimed with
+// a conditional update so concurrent calls alert only once.
+func (a *App) checkAIBudgetAlerts(orgID uuid.UUID, used int64) {
+	budget, err := a.getAIBudgetCached(orgID)
+	if err != nil || budget.ID == uuid.Nil {
+		return
+	}
+	level := aiBudgetLevel(budget, used)
+	if level == "" {
+		return
+	}
+
+	column := "soft_alert_period"
+	if level == aiBudgetLevelHard {
+		column = "hard_alert_period"
+	}
+	period := aiUsagePeriod(time.Now())
+	result := a.DB.Model(&models.AIBudget{}).
+		Where("id = ? AND "+column+" IS DISTINCT FROM ?", budget.ID, period).
+		Update(column, period)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	data := AIBudgetAlertData{
+		Level:       level,
+		Period:      period,
+		UsedTokens:  used,
+		LimitTokens: budget.MonthlyTokenLimit,
+		UsedPercent: float64(used) / float64(budget.MonthlyTokenLimit) * 100,
+	}
+	a.Log.Warn("AI budget threshold reached", "org_id", orgID, "level", level,
+		"used_tokens", used, "limit_tokens", budget.MonthlyTokenLimit)
+	if a.WSHub != nil {
+		a.WSHub.BroadcastToOrg(orgID, websocket.WSMessage{
+			Type:    websocket.TypeAIBudgetAlert,
+			Payload: data,
+		})
+	}
+	a.DispatchWebhook(orgID, models.WebhookEventAIBudgetAlert, data)
+}
+
+// sendAIBudgetFallback sends the budget's fallback message when an AI reply
+// was refused by the hard limit. Returns false when the budget has no
+// fallback message, so the caller can use its own.
+func (a *App) sendAIBudgetFallback(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession) bool {
+	budget, err := a.getAIBudgetCached(account.OrganizationID)
+	if err != nil || budget.FallbackMessage == "" {
+		return false
+	}
+	if err := a.sendAndSaveTextMessage(account, contact, budget.FallbackMessage); err != nil {
+		a.Log.Error("Failed to send AI budget fallback", "error", err, "contact", contact.PhoneNumber)
+	}
+	if session != nil {
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, budget.FallbackMessage, "ai_budget_fallback")
+	}
+	return true
+}
+
+// aiBudgetLevel returns the highest limit usage has reached: "hard",
+// "soft" or "" (none, or no limit set).
+func aiBudgetLevel(budget *models.AIBudget, used int64) string {
+	if budget.MonthlyTokenLimit <= 0 {
+		return ""
+	}
+	if used >= budget.MonthlyTokenLimit {
+		return aiBudgetLevelHard
+	}
+	if budget.SoftLimitPercent > 0 && used*100 >= budget.MonthlyTokenLimit*int64(budget.SoftLimitPercent) {
+		return aiBudgetLevelSoft
+	}
+	return ""
+}
+
+// estimateAICost prices token counts with the budget's per-million rates.
+func estimateAICost(budget *models.AIBudget, promptTokens, completionTokens int64) float64 {
+	return float64(promptTokens)/1e6*budget.InputCostPerMillion +
+		float64(completionTokens)/1e6*budget.OutputCostPerMillion
+}
+
+// aiUsagePeriod is the budget month containing t, as YYYY-MM (UTC).
+func aiUsagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// aiUsagePeriodBounds returns the start of t's budget month and of the
+// next one.
+func aiUsagePeriodBounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+func aiUsageCounterKey(orgID uuid.UUID, t time.Time) string {
+	return fmt.Sprintf("%s%s:%s", aiUsageCounterPrefix, orgID.String(), aiUsagePeriod(t))
+}
+
+// AIBudgetResponse is the budget plus the current month's usage.
+type AIBudgetResponse struct {
+	MonthlyTokenLimit    int64   `json:"monthly_token_limit"`
+	SoftLimitPercent     int     `json:"soft_limit_percent"`
+	HardLimitEnabled     bool    `json:"hard_limit_enabled"`
+	FallbackMessage      string  `json:"fallback_message"`
+	InputCostPerMillion  float64 `json:"input_cost_per_million"`
+	OutputCostPerMillion float64 `json:"output_cost_per_million"`
+	Period               string  `json:"period"`
+	UsedTokens           int64   `json:"used_tokens"`
+	UsedPercent          float64 `json:"used_percent"`
+	Level                string  `json:"level"`
+}
+
+func (a *App) aiBudgetResponse(orgID uuid.UUID, budget *models.AIBudget) AIBudgetResponse {
+	now := time.Now()
+	used := a.aiMonthTokens(orgID, now)
+	resp := AIBudgetResponse{
+		MonthlyTokenLimit:    budget.MonthlyTokenLimit,
+		SoftLimitPercent:     budget.SoftLimitPercent,
+		HardLimitEnabled:     budget.HardLimitEnabled,
+		FallbackMessage:      budget.FallbackMessage,
+		InputCostPerMillion:  budget.InputCostPerMillion,
+		OutputCostPerMillion: budget.OutputCostPerMillion,
+		Period:               aiUsagePeriod(now),
+		UsedTokens:           used,
+		Level:                aiBudgetLevel(budget, used),
+	}
+	if budget.ID == uuid.Nil {
+		resp.SoftLimitPercent = 80
+	}
+	if budget.MonthlyTokenLimit > 0 {
+		resp.UsedPercent = float64(used) / float64(budget.MonthlyTokenLimit) * 100
+	}
+	return resp
+}
+
+// GetAIBudget returns the org's AI budget and this month's usage.
+func (a *App) GetAIBudget(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceSettingsChatbot, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+	budget, err := a.getAIBudgetCached(orgID)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load AI budget", nil, "")
+	}
+	return r.SendEnvelope(a.aiBudgetResponse(orgID, budget))
+}
+
+// UpdateAIBudget creates or updates the org's AI budget.
+func (a *App) UpdateAIBudget(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceSettingsChatbot, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		MonthlyTokenLimit    *int64   `json:"monthly_token_limit"`
+		SoftLimitPercent     *int     `json:"soft_limit_percent"`
+		HardLimitEnabled     *bool    `json:"hard_limit_enabled"`
+		FallbackMessage      *string  `json:"fallback_message"`
+		InputCostPerMillion  *float64 `json:"input_cost_per_million"`
+		OutputCostPerMillion *float64 `json:"output_cost_per_million"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if req.MonthlyTokenLimit != nil && *req.MonthlyTokenLimit < 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "monthly_token_limit cannot be negative", nil, "")
+	}
+	if req.SoftLimitPercent != nil && (*req.SoftLimitPercent < 1 || *req.SoftLimitPercent > 100) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "soft_limit_percent must be between 1 and 100", nil, "")
+	}
+	if (req.InputCostPerMillion != nil && *req.InputCostPerMillion < 0) ||
+		(req.OutputCostPerMillion != nil && *req.OutputCostPerMillion < 0) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Costs cannot be negative", nil, "")
+	}
+
+	budget := models.AIBudget{OrganizationID: orgID, SoftLimitPercent: 80}
+	exists := a.DB.Where("organization_id = ?", orgID).First(&budget).Error == nil
+	old := budget
+
+	if req.MonthlyTokenLimit != nil {
+		budget.MonthlyTokenLimit = *req.MonthlyTokenLimit
+	}
+	if req.SoftLimitPercent != nil {
+		budget.SoftLimitPercent = *req.SoftLimitPercent
+	}
+	if req.HardLimitEnabled != nil {
+		budget.HardLimitEnabled = *req.HardLimitEnabled
+	}
+	if req.FallbackMessage != nil {
+		budget.FallbackMessage = *req.FallbackMessage
+	}
+	if req.InputCostPerMillion != nil {
+		budget.InputCostPerMillion = *req.InputCostPerMillion
+	}
+	if req.OutputCostPerMillion != nil {
+		budget.OutputCostPerMillion = *req.OutputCostPerMillion
+	}
+	budget.UpdatedByID = &userID
+
+	if err := a.DB.Save(&budget).Error; err != nil {
+		a.Log.Error("Failed to save AI budget", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save AI budget", nil, "")
+	}
+	a.InvalidateAIBudgetCache(orgID)
+
+	action := models.AuditActionUpdated
+	if !exists {
+		action = models.AuditActionCreated
+	}
+	a.logAudit(orgID, userID, "ai_budget", budget.ID, action, &old, &budget)
+
+	return r.SendEnvelope(a.aiBudgetResponse(orgID, &budget))
+}
+
+// AIUsageTotals aggregates usage logs. Rows grouped by feature or model
+// fill in the matching label fields.
+type AIUsageTotals struct {
+	Date             string  `json:"date,omitempty"`
+	Feature          string  `json:"feature,omitempty"`
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Calls            int64   `json:"calls"`
+	Errors           int64   `json:"errors"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	EstimatedCost    float64 `json:"estimated_cost" gorm:"-"`
+}
+
+const aiUsageTotalsSelect = `COUNT(*) AS calls,
+	COUNT(*) FILTER (WHERE NOT success) AS errors,
+	COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+	COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+	COALESCE(SUM(total_tokens), 0) AS total_tokens,
+	COALESCE(AVG(latency_ms) FILTER (WHERE success), 0) AS avg_latency_ms`
+
+// GetAIUsageAnalytics returns AI usage and estimated spend for a date
+// range (default: this month), broken down by day, feature and model.
+func (a *App) GetAIUsageAnalytics(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceAnalytics, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	periodStart, _ := aiUsagePeriodBounds(now)
+	periodEnd := now
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+	if fromStr != "" && toStr != "" {
+		start, end, errMsg := parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+		periodStart, periodEnd = start, end
+	}
+
+	budget, err := a.getAIBudgetCached(orgID)
+	if err != nil {
+		budget = &models.AIBudget{}
+	}
+
+	scope := func() *gorm.DB {
+		return a.DB.Model(&models.AIUsageLog{}).
+			Where("organization_id = ? AND created_at >= ? AND created_at <= ?", orgID, periodStart, periodEnd)
+	}
+
+	var totals AIUsageTotals
+	var byDay, byFeature, byModel []AIUsageTotals
+	queries := []struct {
+		dest  any
+		query *gorm.DB
+	}{
+		{&totals, scope().Select(aiUsageTotalsSelect)},
+		{&byDay, scope().Select("TO_CHAR(DATE(created_at), 'YYYY-MM-DD') AS date, " + aiUsageTotalsSelect).
+			Group("DATE(created_at)").Order("DATE(created_at)")},
+		{&byFeature, scope().Select("feature, " + aiUsageTotalsSelect).
+			Group("feature").Order("total_tokens DESC")},
+		{&byModel, scope().Select("provider, model, " + aiUsageTotalsSelect).
+			Group("provider, model").Order("total_tokens DESC")},
+	}
+	for _, q := range queries {
+		if err := q.query.Scan(q.dest).Error; err != nil {
+			a.Log.Error("Failed to load AI usage", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load AI usage", nil, "")
+		}
+	}
+
+	price := func(rows []AIUsageTotals) []AIUsageTotals {
+		for i := range rows {
+			rows[i].EstimatedCost = estimateAICost(budget, rows[i].PromptTokens, rows[i].CompletionTokens)
+		}
+		if rows == nil {
+			rows = []AIUsageTotals{}
+		}
+		return rows
+	}
+	totals.EstimatedCost = estimateAICost(budget, totals.PromptTokens, totals.CompletionTokens)
+
+	return r.SendEnvelope(map[string]any{
+		"totals":     totals,
+		"by_day":     price(byDay),
+		"by_feature": price(byFeature),
+		"by_model":   price(byModel),
+		"budget":     a.aiBudgetResponse(orgID, budget),
+	})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAIBudgetLevel(t *testing.T) {
+	budget := &models.AIBudget{MonthlyTokenLimit: 1000, SoftLimitPercent: 80}
+
+	assert.Equal(t, "", aiBudgetLevel(budget, 799))
+	assert.Equal(t, aiBudgetLevelSoft, aiBudgetLevel(budget, 800))
+	assert.Equal(t, aiBudgetLevelHard, aiBudgetLevel(budget, 1000))
+	assert.Equal(t, "", aiBudgetLevel(&models.AIBudget{}, 1_000_000), "no limit set")
+}
+
+func TestEstimateAICost(t *testing.T) {
+	budget := &models.AIBudget{InputCostPerMillion: 0.5, OutputCostPerMillion: 1.5}
+	assert.InDelta(t, 0.5+3.0, estimateAICost(budget, 1_000_000, 2_000_000), 1e-9)
+	assert.Zero(t, estimateAICost(&models.AIBudget{}, 1000, 1000))
+}
+
+func TestAIUsagePeriodBounds(t *testing.T) {
+	start, end := aiUsagePeriodBounds(time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), end)
+	assert.Equal(t, "2026-12", aiUsagePeriod(start))
+}
+
+type stubAIProvider struct {
+	calls int
+}
+
+func (s *stubAIProvider) Name() string { return "stub" }
+
+func (s *stubAIProvider) Complete(_ context.Context, req ai.Request) (*ai.Response, error) {
+	s.calls++
+	return &ai.Response{Content: "ok", Model: req.Model, Usage: ai.Usage{PromptTokens: 60, CompletionTokens: 40}}, nil
+}
+
+func TestMeteredProvider_RecordsUsageAndEnforcesHardLimit(t *testing.T) {
+	app := newSLATestApp(t)
+	if app.Redis == nil {
+		t.Skip("Redis not available")
+	}
+	org := testutil.CreateTestOrganization(t, app.DB)
+	require.NoError(t, app.DB.Create(&models.AIBudget{
+		OrganizationID:    org.ID,
+		MonthlyTokenLimit: 150,
+		SoftLimitPercent:  50,
+		HardLimitEnabled:  true,
+	}).Error)
+
+	stub := &stubAIProvider{}
+	provider := &meteredProvider{app: app, inner: stub, scope: aiUsageScope{OrgID: org.ID, Feature: models.AIFeatureReply}}
+
+	_, err := provider.Complete(context.Background(), ai.Request{Model: "m1"})
+	require.NoError(t, err)
+	_, err = provider.Complete(context.Background(), ai.Request{Model: "m1"})
+	require.NoError(t, err)
+
+	// 200 tokens used against a 150 limit: the next call is refused.
+	_, err = provider.Complete(context.Background(), ai.Request{Model: "m1"})
+	assert.ErrorIs(t, err, errAIBudgetExceeded)
+	assert.Equal(t, 2, stub.calls)
+
+	var logs []models.AIUsageLog
+	require.NoError(t, app.DB.Where("organization_id = ?", org.ID).Order("created_at").Find(&logs).Error)
+	require.Len(t, logs, 3)
+	assert.Equal(t, 100, logs[0].TotalTokens)
+	assert.Equal(t, "stub", logs[0].Provider)
+	assert.True(t, logs[0].Success)
+	assert.False(t, logs[2].Success)
+	assert.Zero(t, logs[2].TotalTokens)
+
+	var budget models.AIBudget
+	require.NoError(t, app.DB.Where("organization_id = ?", org.ID).First(&budget).Error)
+	period := aiUsagePeriod(time.Now())
+	assert.Equal(t, period, budget.SoftAlertPeriod)
+	assert.Equal(t, period, budget.HardAlertPeriod)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/shridarpatil/whatomate/internal/assignment"
 	"github.com/shridarpatil/whatomate/internal/calling"
 	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/knowledge"
 	"github.com/shridarpatil/whatomate/internal/queue"
 	"github.com/shridarpatil/whatomate/internal/storage"
 	"github.com/shridarpatil/whatomate/internal/tts"
@@ -34,6 +35,9 @@ type App struct {
 	CampaignSubCancel context.CancelFunc
 	// HTTPClient is a shared HTTP client with connection pooling for external API calls
 	HTTPClient *http.Client
+	// AIHTTPClient is used for LLM provider calls; it may reach private networks
+	// when app.allow_internal_ai_urls is set. Falls back to HTTPClient when nil.
+	AIHTTPClient *http.Client
 	// Assigner provides shared team-based agent assignment (used by both chat and call transfers)
 	Assigner *assignment.Assigner
 	// CallManager handles WebRTC call sessions (nil when calling is disabled)
@@ -44,6 +48,11 @@ type App struct {
 	S3Client *storage.S3Client
 	// wg tracks background goroutines for graceful shutdown
 	wg sync.WaitGroup
+
+	// knowledgeCache holds knowledge-base vectors for retrieval; created on
+	// first use by knowledgeVectorCache
+	knowledgeCache     *knowledge.VectorCache
+	knowledgeCacheOnce sync.Once
 }
 
 // WaitForBackgroundTasks blocks until all background goroutines complete.
@@ -21,9 +21,12 @@ const (
 	webhooksCacheTTL        = 6 * time.Hour
 	slaSettingsCacheTTL     = 6 * time.Hour
 	aiContextsCacheTTL      = 6 * time.Hour
+	aiToolsCacheTTL         = 6 * time.Hour
 	userPermissionsCacheTTL = 6 * time.Hour
 	rolePermissionsCacheTTL = 6 * time.Hour
 	tagsCacheTTL            = 6 * time.Hour
+	aiBudgetCacheTTL        = 6 * time.Hour
+	aiUsageCounterTTL       = 40 * 24 * time.Hour // Outlives the month it counts
 
 	// Cache key prefixes
 	settingsCachePrefix        = "chatbot:settings:"
@@ -33,15 +36,20 @@ const (
 	webhooksCachePrefix        = "webhooks:"
 	slaSettingsCacheKey        = "chatbot:sla_enabled_settings"
 	aiContextsCachePrefix      = "chatbot:ai_contexts:"
+	aiToolsCachePrefix         = "chatbot:ai_tools:"
 	userPermissionsCachePrefix = "permissions:user:"
 	rolePermissionsCachePrefix = "permissions:role:"
 	tagsCachePrefix            = "tags:"
+	aiBudgetCachePrefix        = "ai:budget:"
+	aiUsageCounterPrefix       = "ai:usage:"
 )
 
-// chatbotSettingsCache is used for caching since AI.APIKey has json:"-" tag
+// chatbotSettingsCache is used for caching since AI.APIKey and AI.Headers
+// have json:"-" tags. They are cached encrypted, as stored.
 type chatbotSettingsCache struct {
 	models.ChatbotSettings
-	AIAPIKey string `json:"ai_api_key_cache"`
+	AIAPIKey  string       `json:"ai_api_key_cache"`
+	AIHeaders models.JSONB `json:"ai_headers_cache,omitempty"`
 }
 
 // getChatbotSettingsCached retrieves chatbot settings from cache or database
@@ -54,8 +62,10 @@ func (a *App) getChatbotSettingsCached(orgID uuid.UUID, whatsAppAccount string)
 	if err == nil && cached != "" {
 		var cacheData chatbotSettingsCache
 		if err := json.Unmarshal([]byte(cached), &cacheData); err == nil {
-			// Restore the API key from the cache wrapper
+			// Restore the API key and headers from the cache wrapper
 			cacheData.AI.APIKey = cacheData.AIAPIKey
+			cacheData.AI.Headers = cacheData.AIHeaders
+			cacheData.AI.DecryptSecrets(a.Config.App.EncryptionKey)
 			return &cacheData.ChatbotSettings, nil
 		}
 	}
@@ -71,15 +81,17 @@ func (a *App) getChatbotSettingsCached(orgID uuid.UUID, whatsAppAccount string)
 		return nil, result.Error
 	}
 
-	// Cache the result (include AI APIKey explicitly since it has json:"-" tag)
+	// Cache the result (include AI APIKey and Headers explicitly since they have json:"-" tags)
 	cacheData := chatbotSettingsCache{
 		ChatbotSettings: settings,
 		AIAPIKey:        settings.AI.APIKey,
+		AIHeaders:       settings.AI.Headers,
 	}
 	if data, err := json.Marshal(cacheData); err == nil {
 		a.Redis.Set(ctx, cacheKey, data, settingsCacheTTL)
 	}
 
+	settings.AI.DecryptSecrets(a.Config.App.EncryptionKey)
 	return &settings, nil
 }
 
@@ -385,6 +397,76 @@ func (a *App) InvalidateAIContextsCache(orgID uuid.UUID) {
 	a.deleteKeysByPattern(ctx, pattern)
 }
 
+// getAIToolsCached retrieves the enabled AI tools for an account (plus the
+// org-level ones) from cache or database. Account-specific tools come first
+// so they win when a name is defined at both levels.
+func (a *App) getAIToolsCached(orgID uuid.UUID, whatsAppAccount string) ([]models.AITool, error) {
+	ctx := context.Background()
+	cacheKey := fmt.Sprintf("%s%s:%s", aiToolsCachePrefix, orgID.String(), whatsAppAccount)
+
+	cached, err := a.Redis.Get(ctx, cacheKey).Result()
+	if err == nil && cached != "" {
+		var tools []models.AITool
+		if err := json.Unmarshal([]byte(cached), &tools); err == nil {
+			return tools, nil
+		}
+	}
+
+	var tools []models.AITool
+	if err := a.DB.Where("organization_id = ? AND (whats_app_account = ? OR whats_app_account = '') AND is_enabled = true",
+		orgID, whatsAppAccount).
+		Order("whats_app_account DESC, name ASC").
+		Find(&tools).Error; err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(tools); err == nil {
+		a.Redis.Set(ctx, cacheKey, data, aiToolsCacheTTL)
+	}
+
+	return tools, nil
+}
+
+// InvalidateAIToolsCache invalidates the AI tools cache for an organization
+func (a *App) InvalidateAIToolsCache(orgID uuid.UUID) {
+	ctx := context.Background()
+	pattern := fmt.Sprintf("%s%s:*", aiToolsCachePrefix, orgID.String())
+	a.deleteKeysByPattern(ctx, pattern)
+}
+
+// getAIBudgetCached retrieves the org's AI budget from cache or database.
+// Orgs without a budget get an empty (unlimited) one, which is cached too
+// so unmetered orgs don't hit the database on every AI call.
+func (a *App) getAIBudgetCached(orgID uuid.UUID) (*models.AIBudget, error) {
+	ctx := context.Background()
+	cacheKey := aiBudgetCachePrefix + orgID.String()
+
+	cached, err := a.Redis.Get(ctx, cacheKey).Result()
+	if err == nil && cached != "" {
+		var budget models.AIBudget
+		if err := json.Unmarshal([]byte(cached), &budget); err == nil {
+			return &budget, nil
+		}
+	}
+
+	var budget models.AIBudget
+	err = a.DB.Where("organization_id = ?", orgID).First(&budget).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(budget); err == nil {
+		a.Redis.Set(ctx, cacheKey, data, aiBudgetCacheTTL)
+	}
+
+	return &budget, nil
+}
+
+// InvalidateAIBudgetCache invalidates the AI budget cache for an organization
+func (a *App) InvalidateAIBudgetCache(orgID uuid.UUID) {
+	a.Redis.Del(context.Background(), aiBudgetCachePrefix+orgID.String())
+}
+
 // UserPermissions represents cached user permissions
 type UserPermissions struct {
 	RoleID       uuid.UUID `json:"role_id"`
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A/B test limits and defaults.
+const (
+	minCampaignVariants        = 2
+	maxCampaignVariants        = 4
+	defaultABTestPercent       = 20
+	defaultABTestWindowMinutes = 4 * 60
+	maxABTestWindowMinutes     = 7 * 24 * 60
+)
+
+// CampaignVariantRequest is one template in an A/B tested campaign
+type CampaignVariantRequest struct {
+	Name       string `json:"name"`
+	TemplateID string `json:"template_id"`
+}
+
+// CampaignABTestRequest configures how variants are tested
+type CampaignABTestRequest struct {
+	TestPercent   int                 `json:"test_percent"`   // Share of the audience in the test slice (1-90)
+	Metric        models.ABTestMetric `json:"metric"`         // read_rate, reply_rate or click_rate
+	WindowMinutes int                 `json:"window_minutes"` // How long to measure before picking a winner
+}
+
+// CampaignVariantResponse represents a campaign variant in API responses
+type CampaignVariantResponse struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	TemplateID   uuid.UUID `json:"template_id"`
+	TemplateName string    `json:"template_name,omitempty"`
+}
+
+// CampaignABTestResponse reports a campaign's A/B test settings and state
+type CampaignABTestResponse struct {
+	TestPercent     int                 `json:"test_percent"`
+	Metric          models.ABTestMetric `json:"metric"`
+	WindowMinutes   int                 `json:"window_minutes"`
+	Status          models.ABTestStatus `json:"status"`
+	EndsAt          *time.Time          `json:"ends_at,omitempty"`
+	WinnerVariantID *uuid.UUID          `json:"winner_variant_id,omitempty"`
+}
+
+// CampaignVariantResult is how one variant performed in the test slice.
+// Reads, replies and clicks only count within the test window after each
+// message was sent.
+type CampaignVariantResult struct {
+	VariantID    uuid.UUID `json:"variant_id"`
+	Name         string    `json:"name"`
+	TemplateName string    `json:"template_name,omitempty"`
+	Recipients   int64     `json:"recipients"`
+	Sent         int64     `json:"sent"`
+	Delivered    int64     `json:"delivered"`
+	Read         int64     `json:"read"`
+	Replied      int64     `json:"replied"`
+	Clicked      int64     `json:"clicked"`
+	Rate         float64   `json:"rate"` // The test metric, 0-1
+	IsWinner     bool      `json:"is_winner"`
+}
+
+// CampaignABTestResultsResponse is the response for GetCampaignABTestResults
+type CampaignABTestResultsResponse struct {
+	CampaignABTestResponse
+	Variants []CampaignVariantResult `json:"variants"`
+}
+
+// GetCampaignABTestResults returns per-variant results of a campaign's A/B test
+func (a *App) GetCampaignABTestResults(r *fastglue.Request) error {
+	orgID, err := a.getOrgID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "campaign")
+	if err != nil {
+		return nil
+	}
+
+	var campaign models.BulkMessageCampaign
+	if err := a.DB.Where("id = ? AND organization_id = ?", id, orgID).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Variants.Template").
+		First(&campaign).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Campaign not found", nil, "")
+	}
+	if len(campaign.Variants) < minCampaignVariants {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign is not an A/B test", nil, "")
+	}
+
+	results, err := a.campaignVariantResults(&campaign)
+	if err != nil {
+		a.Log.Error("Failed to load A/B test results", "error", err, "campaign_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load A/B test results", nil, "")
+	}
+
+	return r.SendEnvelope(CampaignABTestResultsResponse{
+		CampaignABTestResponse: *campaignABTestResponse(&campaign),
+		Variants:               results,
+	})
+}
+
+// campaignVariants validates a request's variants, sending a 400 or 404
+// when they are invalid. Names default to A, B, C, D.
+func (a *App) campaignVariants(r *fastglue.Request, orgID uuid.UUID, reqs []CampaignVariantRequest) ([]models.CampaignVariant, error) {
+	if len(reqs) < minCampaignVariants || len(reqs) > maxCampaignVariants {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+			fmt.Sprintf("A/B tests need %d to %d variants", minCampaignVariants, maxCampaignVariants), nil, "")
+		return nil, errEnvelopeSent
+	}
+
+	variants := make([]models.CampaignVariant, len(reqs))
+	seen := make(map[uuid.UUID]bool, len(reqs))
+	for i, req := range reqs {
+		templateID, err := uuid.Parse(req.TemplateID)
+		if err != nil {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid variant template ID", nil, "")
+			return nil, errEnvelopeSent
+		}
+		if seen[templateID] {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Each variant must use a different template", nil, "")
+			return nil, errEnvelopeSent
+		}
+		seen[templateID] = true
+
+		template, err := findByIDAndOrg[models.Template](a.DB, r, templateID, orgID, "Template")
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			name = string(rune('A' + i))
+		}
+		if len(name) > 50 {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Variant name must be at most 50 characters", nil, "")
+			return nil, errEnvelopeSent
+		}
+		variants[i] = models.CampaignVariant{
+			Name:       name,
+			TemplateID: templateID,
+			Position:   i,
+			Template:   template,
+		}
+	}
+	return variants, nil
+}
+
+// applyABTestSettings validates req and copies it onto campaign, filling
+// in defaults. Returns an error message for a 400.
+func applyABTestSettings(campaign *models.BulkMessageCampaign, req *CampaignABTestRequest) string {
+	settings := CampaignABTestRequest{}
+	if req != nil {
+		settings = *req
+	}
+	if settings.TestPercent == 0 {
+		settings.TestPercent = defaultABTestPercent
+	}
+	if settings.Metric == "" {
+		settings.Metric = models.ABTestMetricReadRate
+	}
+	if settings.WindowMinutes == 0 {
+		settings.WindowMinutes = defaultABTestWindowMinutes
+	}
+
+	if settings.TestPercent < 1 || settings.TestPercent > 90 {
+		return "test_percent must be between 1 and 90"
+	}
+	switch settings.Metric {
+	case models.ABTestMetricReadRate, models.ABTestMetricReplyRate, models.ABTestMetricClickRate:
+	default:
+		return "invalid metric. Valid metrics: read_rate, reply_rate, click_rate"
+	}
+	if settings.WindowMinutes < 1 || settings.WindowMinutes > maxABTestWindowMinutes {
+		return fmt.Sprintf("window_minutes must be between 1 and %d", maxABTestWindowMinutes)
+	}
+
+	campaign.ABTestPercent = settings.TestPercent
+	campaign.ABTestMetric = settings.Metric
+	campaign.ABTestWindowMinutes = settings.WindowMinutes
+	return ""
+}
+
+// campaignABTestResponse returns campaign's A/B test settings, or nil for
+// a campaign without variants. Variants must be preloaded.
+func campaignABTestResponse(campaign *models.BulkMessageCampaign) *CampaignABTestResponse {
+	if len(campaign.Variants) == 0 {
+		return nil
+	}
+	return &CampaignABTestResponse{
+		TestPercent:     campaign.ABTestPercent,
+		Metric:          campaign.ABTestMetric,
+		WindowMinutes:   campaign.ABTestWindowMinutes,
+		Status:          campaign.ABTestStatus,
+		EndsAt:          campaign.ABTestEndsAt,
+		WinnerVariantID: campaign.WinnerVariantID,
+	}
+}
+
+// applyCampaignVariants adds campaign's variants and A/B test settings to
+// resp. Variants (and their templates) must be preloaded.
+func applyCampaignVariants(resp *CampaignResponse, campaign *models.BulkMessageCampaign) {
+	if len(campaign.Variants) == 0 {
+		return
+	}
+	resp.Variants = make([]CampaignVariantResponse, len(campaign.Variants))
+	for i, v := range campaign.Variants {
+		resp.Variants[i] = CampaignVariantResponse{
+			ID:         v.ID,
+			Name:       v.Name,
+			TemplateID: v.TemplateID,
+		}
+		if v.Template != nil {
+			resp.Variants[i].TemplateName = v.Template.Name
+		}
+	}
+	resp.ABTest = campaignABTestResponse(campaign)
+}
+
+// replaceCampaignVariants swaps a campaign's variants for variants,
+// filling in their IDs.
+func replaceCampaignVariants(tx *gorm.DB, campaignID uuid.UUID, variants []models.CampaignVariant) error {
+	if err := tx.Where("campaign_id = ?", campaignID).Delete(&models.CampaignVariant{}).Error; err != nil {
+		return err
+	}
+	for i := range variants {
+		variants[i].CampaignID = campaignID
+		template := variants[i].Template
+		variants[i].Template = nil
+		err := tx.Create(&variants[i]).Error
+		variants[i].Template = template
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadCampaignVariants preloads variants in order with their templates.
+func preloadCampaignVariants(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Variants.Template")
+}
+
+// abTestSliceSize is how many of total recipients go into the test slice:
+// percent of the audience, but at least one per variant.
+func abTestSliceSize(total, percent, variants int) int {
+	size := int(math.Ceil(float64(total) * float64(percent) / 100))
+	size = max(size, variants)
+	return min(size, total)
+}
+
+// assignABTestVariants picks a random test slice from recipients and
+// splits it evenly across variants, setting each picked recipient's
+// VariantID. Returns the test slice.
+func assignABTestVariants(recipients []models.BulkMessageRecipient, variants []models.CampaignVariant, percent int) []models.BulkMessageRecipient {
+	rand.Shuffle(len(recipients), func(i, j int) {
+		recipients[i], recipients[j] = recipients[j], recipients[i]
+	})
+	slice := recipients[:abTestSliceSize(len(recipients), percent, len(variants))]
+	for i := range slice {
+		variantID := variants[i%len(variants)].ID
+		slice[i].VariantID = &variantID
+	}
+	return slice
+}
+
+// startABTest assigns the test slice of a campaign's pending recipients to
+// its variants and returns the recipients to send now.
+func (a *App) startABTest(campaign *models.BulkMessageCampaign, variants []models.CampaignVariant, pending []models.BulkMessageRecipient) ([]models.BulkMessageRecipient, error) {
+	slice := assignABTestVariants(pending, variants, campaign.ABTestPercent)
+
+	byVariant := make(map[uuid.UUID][]uuid.UUID, len(variants))
+	for _, rcpt := range slice {
+		byVariant[*rcpt.VariantID] = append(byVariant[*rcpt.VariantID], rcpt.ID)
+	}
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		for variantID, ids := range byVariant {
+			if err := tx.Model(&models.BulkMessageRecipient{}).
+				Where("id IN ?", ids).
+				Update("variant_id", variantID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return slice, err
+}
+
+// resetABTest undoes startABTest when the campaign failed to start.
+func (a *App) resetABTest(campaignID uuid.UUID) {
+	a.DB.Model(&models.BulkMessageRecipient{}).
+		Where("campaign_id = ? AND status = ?", campaignID, models.MessageStatusPending).
+		Update("variant_id", nil)
+	a.DB.Model(&models.BulkMessageCampaign{}).Where("id = ?", campaignID).Updates(map[string]any{
+		"ab_test_status":  models.ABTestStatusNone,
+		"ab_test_ends_at": nil,
+	})
+}
+
+// abTestHeldBack matches pending test-slice recipients of the campaign in
+// bulk_message_campaigns that are still waiting for their send window.
+const abTestHeldBack = `EXISTS (SELECT 1 FROM bulk_message_recipients r
+	WHERE r.campaign_id = bulk_message_campaigns.id AND r.variant_id IS NOT NULL
+		AND r.status = ? AND r.send_after IS NOT NULL AND r.deleted_at IS NULL)`
+
+// startABTestClock (re)starts the test window of campaigns whose test
+// slice was held back by its send window, once the last of the slice is
+// released. The slice may be held back before the clock starts or, when
+// the worker defers a recipient, after it; either way the window is pushed
+// out so the late sends are measured over a full window too.
+func (a *App) startABTestClock(tx *gorm.DB, campaignIDs []uuid.UUID, now time.Time) error {
+	if len(campaignIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.BulkMessageCampaign{}).
+		Where("id IN ? AND ab_test_status = ?", campaignIDs, models.ABTestStatusTesting).
+		Where("NOT "+abTestHeldBack, models.MessageStatusPending).
+		Update("ab_test_ends_at", gorm.Expr("GREATEST(ab_test_ends_at, ?::timestamptz + make_interval(mins => ab_test_window_minutes))", now)).Error
+}
+
+// campaignVariantResults measures each variant over the test slice.
+// Replies and clicks are inbound messages from the recipient's contact
+// within the window after the campaign message was sent; clicks are
+// template button taps. Variants must be preloaded.
+func (a *App) campaignVariantResults(campaign *models.BulkMessageCampaign) ([]CampaignVariantResult, error) {
+	type row struct {
+		VariantID uuid.UUID
+		Total     int64
+		Sent      int64
+		Delivered int64
+		Read      int64
+		Replied   int64
+		Clicked   int64
+	}
+	window := campaign.ABTestWindowMinutes
+	var rows []row
+	err := a.DB.Raw(`
+		SELECT r.variant_id,
+			COUNT(*) AS total,
+			COUNT(r.sent_at) AS sent,
+			COUNT(*) FILTER (WHERE r.delivered_at IS NOT NULL OR r.read_at IS NOT NULL) AS delivered,
+			COUNT(*) FILTER (WHERE r.read_at <= r.sent_at + make_interval(mins => ?)) AS read,
+			COUNT(*) FILTER (WHERE EXISTS (
+				SELECT 1 FROM messages om JOIN messages im ON im.contact_id = om.contact_id
+				WHERE om.whats_app_message_id = r.whats_app_message_id AND om.organization_id = ?
+					AND im.direction = ? AND im.deleted_at IS NULL
+					AND im.created_at > r.sent_at AND im.created_at <= r.sent_at + make_interval(mins => ?)
+			)) AS replied,
+			COUNT(*) FILTER (WHERE EXISTS (
+				SELECT 1 FROM messages om JOIN messages im ON im.contact_id = om.contact_id
+				WHERE om.whats_app_message_id = r.whats_app_message_id AND om.organization_id = ?
+					AND im.direction = ? AND im.message_type = 'button_reply' AND im.deleted_at IS NULL
+					AND im.created_at > r.sent_at AND im.created_at <= r.sent_at + make_interval(mins => ?)
+			)) AS clicked
+		FROM bulk_message_recipients r
+		WHERE r.campaign_id = ? AND r.variant_id IS NOT NULL AND r.deleted_at IS NULL
+			AND (? OR r.variant_id <> ? OR r.sent_at <= ?)
+		GROUP BY r.variant_id`,
+		window,
+		campaign.OrganizationID, models.DirectionIncoming, window,
+		campaign.OrganizationID, models.DirectionIncoming, window,
+		campaign.ID,
+		campaign.WinnerVariantID == nil, winnerOrNil(campaign.WinnerVariantID), abTestCutoff(campaign),
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byVariant := make(map[uuid.UUID]row, len(rows))
+	for _, rw := range rows {
+		byVariant[rw.VariantID] = rw
+	}
+	results := make([]CampaignVariantResult, len(campaign.Variants))
+	for i, v := range campaign.Variants {
+		rw := byVariant[v.ID]
+		results[i] = CampaignVariantResult{
+			VariantID:  v.ID,
+			Name:       v.Name,
+			Recipients: rw.Total,
+			Sent:       rw.Sent,
+			Delivered:  rw.Delivered,
+			Read:       rw.Read,
+			Replied:    rw.Replied,
+			Clicked:    rw.Clicked,
+		}
+		if v.Template != nil {
+			results[i].TemplateName = v.Template.Name
+		}
+		results[i].Rate = abTestRate(&results[i], campaign.ABTestMetric)
+	}
+
+	if campaign.WinnerVariantID != nil {
+		for i := range results {
+			results[i].IsWinner = results[i].VariantID == *campaign.WinnerVariantID
+		}
+	}
+	return results, nil
+}
+
+// winnerOrNil returns the winner's ID for SQL, or uuid.Nil before a winner
+// is picked.
+func winnerOrNil(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
+
+// abTestCutoff is the end of the test window. Once the winner is rolled
+// out, its later sends aren't part of the test and are left out of its
+// results.
+func abTestCutoff(campaign *models.BulkMessageCampaign) time.Time {
+	if campaign.ABTestEndsAt != nil {
+		return *campaign.ABTestEndsAt
+	}
+	return time.Now()
+}
+
+// abTestRate is result's value for metric, as a fraction of sent messages.
+func abTestRate(result *CampaignVariantResult, metric models.ABTestMetric) float64 {
+	if result.Sent == 0 {
+		return 0
+	}
+	var hits int64
+	switch metric {
+	case models.ABTestMetricReplyRate:
+		hits = result.Replied
+	case models.ABTestMetricClickRate:
+		hits = result.Clicked
+	default:
+		hits = result.Read
+	}
+	return float64(hits) / float64(result.Sent)
+}
+
+// pickABTestWinner returns the index of the best result. Ties go to the
+// variant listed first.
+func pickABTestWinner(results []CampaignVariantResult) int {
+	best := 0
+	for i := 1; i < len(results); i++ {
+		if results[i].Rate > results[best].Rate {
+			best = i
+		}
+	}
+	return best
+}
+
+// decideABTest picks the winning variant of a campaign whose test window
+// has ended and queues it for every recipient outside the test slice.
+// Safe to call concurrently: only the caller that flips the test to
+// decided rolls it out.
+func (a *App) decideABTest(ctx context.Context, campaign *models.BulkMessageCampaign) error {
+	// Part of the test slice hasn't been sent yet; its results would be
+	// empty. startABTestClock pushes the window out once it is released.
+	var heldBack int64
+	if err := a.DB.Model(&models.BulkMessageCampaign{}).
+		Where("id = ?", campaign.ID).
+		Where(abTestHeldBack, models.MessageStatusPending).
+		Count(&heldBack).Error; err != nil {
+		return fmt.Errorf("check test slice: %w", err)
+	}
+	if heldBack > 0 {
+		return nil
+	}
+
+	results, err := a.campaignVariantResults(campaign)
+	if err != nil {
+		return fmt.Errorf("load results: %w", err)
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("campaign has no variants")
+	}
+	winner := results[pickABTestWinner(results)]
+
+	// The clock is checked again in case the window was pushed out since
+	// the campaign was loaded
+	res := a.DB.Model(&models.BulkMessageCampaign{}).
+		Where("id = ? AND ab_test_status = ? AND ab_test_ends_at <= ?", campaign.ID, models.ABTestStatusTesting, time.Now()).
+		Updates(map[string]any{
+			"ab_test_status":    models.ABTestStatusDecided,
+			"winner_variant_id": winner.VariantID,
+		})
+	if res.Error != nil {
+		return fmt.Errorf("record winner: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	a.Log.Info("A/B test winner picked", "campaign_id", campaign.ID, "variant", winner.Name,
+		"metric", campaign.ABTestMetric, "rate", winner.Rate)
+
+	// Only the recipients assigned here are rolled out. Test-slice
+	// recipients of the winner may still be pending in the queue and must
+	// not be sent twice.
+	var remaining []models.BulkMessageRecipient
+	if err := a.DB.Model(&remaining).Clauses(clause.Returning{}).
+		Where("campaign_id = ? AND status = ? AND variant_id IS NULL", campaign.ID, models.MessageStatusPending).
+		Update("variant_id", winner.VariantID).Error; err != nil {
+		return fmt.Errorf("assign winner: %w", err)
+	}
+
+	// A paused campaign sends the rollout when it is resumed
+	if campaign.Status != models.CampaignStatusProcessing {
+		return nil
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	if _, err := a.dispatchRecipients(ctx, campaign, remaining); err != nil {
+		return fmt.Errorf("enqueue rollout: %w", err)
+	}
+	a.Log.Info("A/B test winner rolled out", "campaign_id", campaign.ID, "count", len(remaining))
+	return nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestABTestSliceSize(t *testing.T) {
+	assert.Equal(t, 20, abTestSliceSize(100, 20, 2))
+	assert.Equal(t, 3, abTestSliceSize(11, 20, 2), "rounds up")
+	assert.Equal(t, 4, abTestSliceSize(10, 10, 4), "at least one per variant")
+	assert.Equal(t, 3, abTestSliceSize(3, 10, 4), "capped at the audience")
+}
+
+func TestAssignABTestVariants(t *testing.T) {
+	variants := []models.CampaignVariant{
+		{BaseModel: models.BaseModel{ID: uuid.New()}},
+		{BaseModel: models.BaseModel{ID: uuid.New()}},
+		{BaseModel: models.BaseModel{ID: uuid.New()}},
+	}
+	recipients := make([]models.BulkMessageRecipient, 100)
+	for i := range recipients {
+		recipients[i].ID = uuid.New()
+	}
+
+	slice := assignABTestVariants(recipients, variants, 30)
+	require.Len(t, slice, 30)
+
+	counts := map[uuid.UUID]int{}
+	for _, r := range slice {
+		require.NotNil(t, r.VariantID)
+		counts[*r.VariantID]++
+	}
+	for _, v := range variants {
+		assert.Equal(t, 10, counts[v.ID])
+	}
+	for _, r := range recipients[30:] {
+		assert.Nil(t, r.VariantID, "holdout recipients wait for the winner")
+	}
+}
+
+func TestPickABTestWinner(t *testing.T) {
+	results := []CampaignVariantResult{
+		{Sent: 50, Read: 20, Replied: 5, Clicked: 1},
+		{Sent: 50, Read: 25, Replied: 2, Clicked: 4},
+		{Sent: 0},
+	}
+	for i := range results {
+		results[i].Rate = abTestRate(&results[i], models.ABTestMetricReadRate)
+	}
+	assert.Equal(t, 0.4, results[0].Rate)
+	assert.Equal(t, 0.0, results[2].Rate, "nothing sent")
+	assert.Equal(t, 1, pickABTestWinner(results))
+
+	for i := range results {
+		results[i].Rate = abTestRate(&results[i], models.ABTestMetricReplyRate)
+	}
+	assert.Equal(t, 0, pickABTestWinner(results))
+
+	for i := range results {
+		results[i].Rate = abTestRate(&results[i], models.ABTestMetricClickRate)
+	}
+	assert.Equal(t, 1, pickABTestWinner(results))
+
+	tied := []CampaignVariantResult{{Rate: 0.5}, {Rate: 0.5}}
+	assert.Equal(t, 0, pickABTestWinner(tied), "ties go to the first variant")
+}
+
+func TestApplyABTestSettings(t *testing.T) {
+	var campaign models.BulkMessageCampaign
+	require.Empty(t, applyABTestSettings(&campaign, nil))
+	assert.Equal(t, defaultABTestPercent, campaign.ABTestPercent)
+	assert.Equal(t, models.ABTestMetricReadRate, campaign.ABTestMetric)
+	assert.Equal(t, defaultABTestWindowMinutes, campaign.ABTestWindowMinutes)
+
+	require.Empty(t, applyABTestSettings(&campaign, &CampaignABTestRequest{
+		TestPercent: 10, Metric: models.ABTestMetricClickRate, WindowMinutes: 60,
+	}))
+	assert.Equal(t, 10, campaign.ABTestPercent)
+	assert.Equal(t, models.ABTestMetricClickRate, campaign.ABTestMetric)
+	assert.Equal(t, 60, campaign.ABTestWindowMinutes)
+
+	assert.NotEmpty(t, applyABTestSettings(&campaign, &CampaignABTestRequest{TestPercent: 95}))
+	assert.NotEmpty(t, applyABTestSettings(&campaign, &CampaignABTestRequest{Metric: "open_rate"}))
+	assert.NotEmpty(t, applyABTestSettings(&campaign, &CampaignABTestRequest{WindowMinutes: maxABTestWindowMinutes + 1}))
+}
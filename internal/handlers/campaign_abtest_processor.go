@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// CampaignABTestProcessor picks A/B test winners once their test window
+// ends and sends the winner to the rest of the audience.
+type CampaignABTestProcessor struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewCampaignABTestProcessor creates a new campaign A/B test processor.
+func NewCampaignABTestProcessor(app *App, interval time.Duration) *CampaignABTestProcessor {
+	return &CampaignABTestProcessor{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the processor loop.
+func (p *CampaignABTestProcessor) Start(ctx context.Context) {
+	p.app.Log.Info("Campaign A/B test processor started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.app.Log.Info("Campaign A/B test processor stopped by context")
+			return
+		case <-p.stopCh:
+			p.app.Log.Info("Campaign A/B test processor stopped")
+			return
+		case <-ticker.C:
+			p.processDueTests(ctx)
+		}
+	}
+}
+
+// Stop stops the processor.
+func (p *CampaignABTestProcessor) Stop() {
+	close(p.stopCh)
+}
+
+func (p *CampaignABTestProcessor) processDueTests(ctx context.Context) {
+	var campaigns []models.BulkMessageCampaign
+	if err := preloadCampaignVariants(p.app.DB).
+		Where("ab_test_status = ? AND ab_test_ends_at <= ?", models.ABTestStatusTesting, time.Now()).
+		Where("status IN ?", []models.CampaignStatus{
+			models.CampaignStatusProcessing, models.CampaignStatusPaused, models.CampaignStatusCompleted,
+		}).
+		Find(&campaigns).Error; err != nil {
+		p.app.Log.Error("Failed to load due A/B tests", "error", err)
+		return
+	}
+
+	for i := range campaigns {
+		if err := p.app.decideABTest(ctx, &campaigns[i]); err != nil {
+			p.app.Log.Error("Failed to decide A/B test", "error", err, "campaign_id", campaigns[i].ID)
+		}
+	}
+}
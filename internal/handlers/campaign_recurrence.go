@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/cronutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// campaignSchedule parses a recurring campaign's cron expression and
+// timezone.
+func campaignSchedule(campaign *models.BulkMessageCampaign) (*cronutil.Schedule, *time.Location, error) {
+	schedule, err := cronutil.Parse(campaign.Recurrence)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+	loc := time.UTC
+	if campaign.RecurrenceTimezone != "" {
+		if loc, err = time.LoadLocation(campaign.RecurrenceTimezone); err != nil {
+			return nil, nil, fmt.Errorf("invalid recurrence_timezone %q", campaign.RecurrenceTimezone)
+		}
+	}
+	return schedule, loc, nil
+}
+
+// nextCampaignRun is when a recurring campaign next runs after t, or nil
+// if its schedule never matches again.
+func nextCampaignRun(campaign *models.BulkMessageCampaign, t time.Time) (*time.Time, error) {
+	schedule, loc, err := campaignSchedule(campaign)
+	if err != nil {
+		return nil, err
+	}
+	next := schedule.Next(t.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+// validateCampaignRecurrence checks a recurring campaign's settings.
+// Returns an error message for a 400, or "" when campaign isn't recurring
+// or is valid.
+func validateCampaignRecurrence(campaign *models.BulkMessageCampaign, variants int) string {
+	if campaign.Recurrence == "" {
+		return ""
+	}
+	if campaign.SegmentID == nil {
+		return "Recurring campaigns need a segment_id"
+	}
+	if variants > 0 {
+		return "Recurring campaigns can't be A/B tested"
+	}
+	next, err := nextCampaignRun(campaign, time.Now())
+	if err != nil {
+		return err.Error()
+	}
+	if next == nil {
+		return "recurrence never runs"
+	}
+	return ""
+}
+
+// scheduleRecurringCampaign turns on a recurring campaign's schedule.
+func (a *App) scheduleRecurringCampaign(r *fastglue.Request, campaign *models.BulkMessageCampaign) error {
+	next, err := nextCampaignRun(campaign, time.Now())
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if next == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "recurrence never runs", nil, "")
+	}
+	if campaign.SegmentID == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Recurring campaigns need a segment_id", nil, "")
+	}
+
+	if err := a.DB.Model(campaign).Updates(map[string]any{
+		"status":      models.CampaignStatusScheduled,
+		"next_run_at": next,
+	}).Error; err != nil {
+		a.Log.Error("Failed to schedule recurring campaign", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to start campaign", nil, "")
+	}
+
+	a.Log.Info("Recurring campaign scheduled", "campaign_id", campaign.ID, "next_run_at", next)
+
+	return r.SendEnvelope(map[string]any{
+		"message":     "Recurring campaign scheduled",
+		"status":      models.CampaignStatusScheduled,
+		"next_run_at": next,
+	})
+}
+
+// runRecurringCampaign starts one run of a due recurring campaign: a copy
+// sent to whoever matches its segment now. Safe to call concurrently:
+// only the caller that moves NextRunAt on starts the run.
+func (a *App) runRecurringCampaign(ctx context.Context, parent *models.BulkMessageCampaign) error {
+	if parent.NextRunAt == nil {
+		return nil
+	}
+	runAt := *parent.NextRunAt
+	next, err := nextCampaignRun(parent, time.Now())
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]any{"next_run_at": next}
+	if next == nil {
+		updates["status"] = models.CampaignStatusCompleted
+		updates["completed_at"] = time.Now()
+	}
+	res := a.DB.Model(&models.BulkMessageCampaign{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", parent.ID, models.CampaignStatusScheduled, runAt).
+		Updates(updates)
+	if res.Error != nil {
+		return fmt.Errorf("advance schedule: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+
+	var segment models.ContactSegment
+	if err := a.DB.Where("id = ? AND organization_id = ?", parent.SegmentID, parent.OrganizationID).First(&segment).Error; err != nil {
+		return fmt.Errorf("load segment: %w", err)
+	}
+
+	_, loc, _ := campaignSchedule(parent)
+	now := time.Now()
+	run := models.BulkMessageCampaign{
+		OrganizationID:       parent.OrganizationID,
+		WhatsAppAccount:      parent.WhatsAppAccount,
+		Name:                 fmt.Sprintf("%s (%s)", parent.Name, runAt.In(loc).Format("2006-01-02 15:04")),
+		TemplateID:           parent.TemplateID,
+		HeaderMediaID:        parent.HeaderMediaID,
+		HeaderMediaFilename:  parent.HeaderMediaFilename,
+		HeaderMediaMimeType:  parent.HeaderMediaMimeType,
+		HeaderMediaLocalPath: parent.HeaderMediaLocalPath,
+		Status:               models.CampaignStatusProcessing,
+		StartedAt:            &now,
+		SegmentID:            parent.SegmentID,
+		SegmentParams:        parent.SegmentParams,
+		CreatedBy:            parent.CreatedBy,
+		ParentCampaignID:     &parent.ID,
+		SendWindowStart:      parent.SendWindowStart,
+		SendWindowEnd:        parent.SendWindowEnd,
+		TimezoneSource:       parent.TimezoneSource,
+		TimezoneField:        parent.TimezoneField,
+		DefaultTimezone:      parent.DefaultTimezone,
+	}
+	if err := a.DB.Create(&run).Error; err != nil {
+		return fmt.Errorf("create run: %w", err)
+	}
+
+	added, err := a.addSegmentRecipients(&run, &segment)
+	if err != nil {
+		a.DB.Model(&run).Update("status", models.CampaignStatusFailed)
+		return fmt.Errorf("resolve segment: %w", err)
+	}
+	if added == 0 {
+		a.DB.Model(&run).Updates(map[string]any{
+			"status":       models.CampaignStatusCompleted,
+			"completed_at": now,
+		})
+		a.Log.Info("Recurring campaign run had no recipients", "campaign_id", parent.ID, "run_id", run.ID)
+		return nil
+	}
+
+	var recipients []models.BulkMessageRecipient
+	if err := a.DB.Where("campaign_id = ? AND status = ?", run.ID, models.MessageStatusPending).Find(&recipients).Error; err != nil {
+		return fmt.Errorf("load recipients: %w", err)
+	}
+	if _, err := a.dispatchRecipients(ctx, &run, recipients); err != nil {
+		a.DB.Model(&run).Update("status", models.CampaignStatusFailed)
+		return fmt.Errorf("enqueue run: %w", err)
+	}
+
+	a.Log.Info("Recurring campaign run started", "campaign_id", parent.ID, "run_id", run.ID, "recipients", len(recipients))
+	return nil
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCampaignRecurrence(t *testing.T) {
+	segmentID := uuid.New()
+	campaign := &models.BulkMessageCampaign{Recurrence: "0 9 * * mon"}
+	assert.NotEmpty(t, validateCampaignRecurrence(campaign, 0), "needs a segment")
+
+	campaign.SegmentID = &segmentID
+	assert.Empty(t, validateCampaignRecurrence(campaign, 0))
+	assert.NotEmpty(t, validateCampaignRecurrence(campaign, 2), "no A/B tests")
+
+	campaign.RecurrenceTimezone = "Mars/Olympus"
+	assert.NotEmpty(t, validateCampaignRecurrence(campaign, 0))
+
+	campaign.RecurrenceTimezone = "Asia/Kolkata"
+	next, err := nextCampaignRun(campaign, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2026, 10, 19, 3, 30, 0, 0, time.UTC), next.UTC())
+
+	campaign.Recurrence = "0 0 30 2 *"
+	assert.Equal(t, "recurrence never runs", validateCampaignRecurrence(campaign, 0))
+}
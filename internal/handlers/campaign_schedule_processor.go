@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CampaignScheduleProcessor sends campaign messages that are due at a
+// time rather than on request: the next step of each sequence enrollment,
+// the runs of recurring campaigns, and recipients whose send window has
+// opened.
+type CampaignScheduleProcessor struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewCampaignScheduleProcessor creates a new campaign schedule processor.
+func NewCampaignScheduleProcessor(app *App, interval time.Duration) *CampaignScheduleProcessor {
+	return &CampaignScheduleProcessor{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the schedule loop.
+func (p *CampaignScheduleProcessor) Start(ctx context.Context) {
+	p.app.Log.Info("Campaign schedule processor started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.app.Log.Info("Campaign schedule processor stopped by context")
+			return
+		case <-p.stopCh:
+			p.app.Log.Info("Campaign schedule processor stopped")
+			return
+		case <-ticker.C:
+			p.processDueEnrollments(ctx)
+			p.processDueRecurringCampaigns(ctx)
+			p.processDeferredRecipients(ctx)
+		}
+	}
+}
+
+// Stop stops the campaign schedule processor.
+func (p *CampaignScheduleProcessor) Stop() {
+	close(p.stopCh)
+}
+
+func (p *CampaignScheduleProcessor) processDueEnrollments(ctx context.Context) {
+	now := time.Now().UTC()
+	batchSize := 100
+	sequences := map[uuid.UUID]*models.CampaignSequence{}
+	fields := map[uuid.UUID][]models.ContactField{}
+
+	for {
+		var jobs []*queue.RecipientJob
+		var enrollments []models.SequenceEnrollment
+		err := p.app.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ? AND next_run_at <= ?", models.EnrollmentStatusActive, now).
+				Where("sequence_id IN (SELECT id FROM campaign_sequences WHERE is_active = ? AND deleted_at IS NULL)", true).
+				Order("next_run_at ASC").
+				Limit(batchSize).
+				Find(&enrollments).Error; err != nil {
+				return err
+			}
+
+			for i := range enrollments {
+				e := &enrollments[i]
+				sequence, ok := sequences[e.SequenceID]
+				if !ok {
+					sequence = &models.CampaignSequence{}
+					if err := preloadSequenceSteps(tx).Where("id = ?", e.SequenceID).First(sequence).Error; err != nil {
+						return err
+					}
+					sequences[e.SequenceID] = sequence
+				}
+				orgFields, ok := fields[e.OrganizationID]
+				if !ok {
+					var err error
+					if orgFields, err = contactutil.LoadFields(tx, e.OrganizationID); err != nil {
+						return err
+					}
+					fields[e.OrganizationID] = orgFields
+				}
+
+				job, err := p.app.runSequenceStep(tx, sequence, e, orgFields, now)
+				if err != nil {
+					return err
+				}
+				if job != nil {
+					jobs = append(jobs, job)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			p.app.Log.Error("Failed to process sequence enrollments", "error", err)
+			return
+		}
+
+		if len(jobs) > 0 {
+			if err := p.app.Queue.EnqueueRecipients(ctx, jobs); err != nil {
+				p.app.Log.Error("Failed to enqueue sequence messages", "error", err, "count", len(jobs))
+				// The steps are already recorded as sent; defer the recipients
+				// so processDeferredRecipients retries them on the next tick.
+				ids := make([]uuid.UUID, len(jobs))
+				for i, job := range jobs {
+					ids[i] = job.RecipientID
+				}
+				p.app.DB.Model(&models.BulkMessageRecipient{}).Where("id IN ?", ids).Update("send_after", now.Add(p.interval))
+			} else {
+				p.app.Log.Info("Sequence messages queued", "count", len(jobs))
+			}
+		}
+
+		if len(enrollments) < batchSize {
+			return
+		}
+	}
+}
+
+// runSequenceStep sends an enrollment its next step, or exits it when an
+// exit condition holds. Returns the job to enqueue once tx commits, if any.
+func (a *App) runSequenceStep(tx *gorm.DB, sequence *models.CampaignSequence, e *models.SequenceEnrollment, fields []models.ContactField, now time.Time) (*queue.RecipientJob, error) {
+	var contact models.Contact
+	if err := tx.Where("id = ?", e.ContactID).First(&contact).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exitEnrollment(tx, e, models.SequenceExitNoContact)
+		}
+		return nil, err
+	}
+
+	replied := false
+	if sequence.ExitOnReply && e.FirstSentAt != nil {
+		var count int64
+		if err := tx.Model(&models.Message{}).
+			Where("contact_id = ? AND direction = ? AND created_at > ?", contact.ID, models.DirectionIncoming, e.FirstSentAt).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		replied = count > 0
+	}
+	if reason := sequenceExitReason(sequence, &contact, replied); reason != "" {
+		return nil, exitEnrollment(tx, e, reason)
+	}
+
+	if e.NextStep >= len(sequence.Steps) {
+		return nil, finishEnrollment(tx, e, now)
+	}
+	step := &sequence.Steps[e.NextStep]
+
+	recipient := models.BulkMessageRecipient{
+		CampaignID:     step.CampaignID,
+		PhoneNumber:    contact.PhoneNumber,
+		RecipientName:  contact.ProfileName,
+		TemplateParams: segmentRecipientParams(step.TemplateParams, &contact, fields),
+		HeaderParams:   models.JSONB{},
+		Status:         models.MessageStatusPending,
+	}
+	if err := tx.Create(&recipient).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.BulkMessageCampaign{}).Where("id = ?", step.CampaignID).
+		Update("total_recipients", gorm.Expr("total_recipients + 1")).Error; err != nil {
+		return nil, err
+	}
+
+	e.NextStep++
+	if e.FirstSentAt == nil {
+		e.FirstSentAt = &now
+	}
+	if e.NextStep >= len(sequence.Steps) {
+		if err := finishEnrollment(tx, e, now); err != nil {
+			return nil, err
+		}
+	} else {
+		next := e.CreatedAt.Add(time.Duration(sequence.Steps[e.NextStep].DelayMinutes) * time.Minute)
+		e.NextRunAt = &next
+		if err := tx.Model(e).Updates(map[string]any{
+			"next_step":     e.NextStep,
+			"next_run_at":   next,
+			"first_sent_at": e.FirstSentAt,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return recipientJobs(sequence.OrganizationID, step.CampaignID, []models.BulkMessageRecipient{recipient})[0], nil
+}
+
+// sequenceExitReason is why contact should leave sequence before its next
+// step, or "" if it should carry on.
+func sequenceExitReason(sequence *models.CampaignSequence, contact *models.Contact, replied bool) string {
+	switch {
+	case sequence.ExitOnOptOut && contact.MarketingOptOut:
+		return models.SequenceExitOptedOut
+	case sequence.ExitOnTagRemoved != "" && !slices.Contains(contact.Tags, any(sequence.ExitOnTagRemoved)):
+		return models.SequenceExitTagRemoved
+	case replied:
+		return models.SequenceExitReplied
+	}
+	return ""
+}
+
+// finishEnrollment marks an enrollment that has been sent every step
+// completed.
+func finishEnrollment(tx *gorm.DB, e *models.SequenceEnrollment, now time.Time) error {
+	e.Status = models.EnrollmentStatusCompleted
+	e.NextRunAt = nil
+	e.FinishedAt = &now
+	return tx.Model(e).Updates(map[string]any{
+		"status":        e.Status,
+		"next_step":     e.NextStep,
+		"next_run_at":   nil,
+		"first_sent_at": e.FirstSentAt,
+		"finished_at":   now,
+	}).Error
+}
+
+func (p *CampaignScheduleProcessor) processDueRecurringCampaigns(ctx context.Context) {
+	var campaigns []models.BulkMessageCampaign
+	if err := p.app.DB.
+		Where("recurrence <> '' AND status = ? AND next_run_at <= ?", models.CampaignStatusScheduled, time.Now().UTC()).
+		Find(&campaigns).Error; err != nil {
+		p.app.Log.Error("Failed to load due recurring campaigns", "error", err)
+		return
+	}
+
+	for i := range campaigns {
+		if err := p.app.runRecurringCampaign(ctx, &campaigns[i]); err != nil {
+			p.app.Log.Error("Failed to run recurring campaign", "error", err, "campaign_id", campaigns[i].ID)
+		}
+	}
+}
+
+// processDeferredRecipients queues recipients of running campaigns whose
+// send window has opened. Paused campaigns keep theirs until resumed.
+func (p *CampaignScheduleProcessor) processDeferredRecipients(ctx context.Context) {
+	now := time.Now().UTC()
+	batchSize := 500
+
+	for {
+		var recipients []models.BulkMessageRecipient
+		err := p.app.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ? AND send_after <= ?", models.MessageStatusPending, now).
+				Where("campaign_id IN (SELECT id FROM bulk_message_campaigns WHERE status = ? AND deleted_at IS NULL)", models.CampaignStatusProcessing).
+				Order("send_after ASC").
+				Limit(batchSize).
+				Find(&recipients).Error; err != nil {
+				return err
+			}
+			if len(recipients) == 0 {
+				return nil
+			}
+
+			ids := make([]uuid.UUID, len(recipients))
+			for i := range recipients {
+				ids[i] = recipients[i].ID
+			}
+			if err := tx.Model(&models.BulkMessageRecipient{}).Where("id IN ?", ids).Update("send_after", nil).Error; err != nil {
+				return err
+			}
+			// Start the A/B test clock in the same transaction, so the test
+			// processor never sees the slice released with the old clock
+			return p.app.startABTestClock(tx, testSliceCampaigns(recipients), now)
+		})
+		if err != nil {
+			p.app.Log.Error("Failed to release deferred recipients", "error", err)
+			return
+		}
+		if len(recipients) == 0 {
+			return
+		}
+
+		byCampaign := map[uuid.UUID][]models.BulkMessageRecipient{}
+		for _, r := range recipients {
+			byCampaign[r.CampaignID] = append(byCampaign[r.CampaignID], r)
+		}
+		for campaignID, batch := range byCampaign {
+			var campaign models.BulkMessageCampaign
+			if err := p.app.DB.Select("id", "organization_id").Where("id = ?", campaignID).First(&campaign).Error; err != nil {
+				p.app.Log.Error("Failed to load campaign for deferred recipients", "error", err, "campaign_id", campaignID)
+				continue
+			}
+			if err := p.app.Queue.EnqueueRecipients(ctx, recipientJobs(campaign.OrganizationID, campaignID, batch)); err != nil {
+				p.app.Log.Error("Failed to enqueue deferred recipients", "error", err, "campaign_id", campaignID)
+				// Put them back to be retried on the next tick
+				ids := make([]uuid.UUID, len(batch))
+				for i := range batch {
+					ids[i] = batch[i].ID
+				}
+				p.app.DB.Model(&models.BulkMessageRecipient{}).Where("id IN ?", ids).Update("send_after", now.Add(p.interval))
+				continue
+			}
+			p.app.Log.Info("Send window opened; recipients queued", "campaign_id", campaignID, "count", len(batch))
+		}
+
+		if len(recipients) < batchSize {
+			return
+		}
+	}
+}
+
+// testSliceCampaigns returns the campaigns of the recipients that belong
+// to an A/B test slice.
+func testSliceCampaigns(recipients []models.BulkMessageRecipient) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	var ids []uuid.UUID
+	for i := range recipients {
+		if recipients[i].VariantID != nil && !seen[recipients[i].CampaignID] {
+			seen[recipients[i].CampaignID] = true
+			ids = append(ids, recipients[i].CampaignID)
+		}
+	}
+	return ids
+}
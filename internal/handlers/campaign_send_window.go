@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/sendwindow"
+)
+
+// CampaignSendWindow limits sends to a daily window of each recipient's
+// local time. Start and End are "HH:MM"; a window with End before Start
+// runs past midnight.
+type CampaignSendWindow struct {
+	Start           string                `json:"start"`
+	End             string                `json:"end"`
+	TimezoneSource  models.TimezoneSource `json:"timezone_source"`            // Defaults to phone
+	TimezoneField   string                `json:"timezone_field,omitempty"`   // Contact field key, for contact_field
+	DefaultTimezone string                `json:"default_timezone,omitempty"` // When a recipient's timezone is unknown; defaults to UTC
+}
+
+// recipientBatchSize bounds the IDs in one UPDATE ... WHERE id IN statement.
+const recipientBatchSize = 1000
+
+// applySendWindow validates req and copies it onto campaign. An empty
+// Start and End removes the window. Returns an error message for a 400,
+// or "".
+func (a *App) applySendWindow(orgID uuid.UUID, campaign *models.BulkMessageCampaign, req *CampaignSendWindow) string {
+	start, end := strings.TrimSpace(req.Start), strings.TrimSpace(req.End)
+	if start == "" && end == "" {
+		campaign.SendWindowStart, campaign.SendWindowEnd = "", ""
+		campaign.TimezoneSource, campaign.TimezoneField, campaign.DefaultTimezone = "", "", ""
+		return ""
+	}
+	if _, err := sendwindow.Parse(start, end); err != nil {
+		return "send_window: " + err.Error()
+	}
+
+	source := req.TimezoneSource
+	field := strings.TrimSpace(req.TimezoneField)
+	switch source {
+	case "", models.TimezoneSourcePhone:
+		source = models.TimezoneSourcePhone
+		field = ""
+	case models.TimezoneSourceContactField:
+		if field == "" {
+			return "send_window: timezone_field is required for contact_field"
+		}
+		var count int64
+		a.DB.Model(&models.ContactField{}).Where("organization_id = ? AND key = ?", orgID, field).Count(&count)
+		if count == 0 {
+			return fmt.Sprintf("send_window: contact field %q not found", field)
+		}
+	default:
+		return "send_window: invalid timezone_source. Valid sources: phone, contact_field"
+	}
+
+	defaultTZ := strings.TrimSpace(req.DefaultTimezone)
+	if defaultTZ != "" {
+		if _, err := time.LoadLocation(defaultTZ); err != nil {
+			return fmt.Sprintf("send_window: invalid default_timezone %q", defaultTZ)
+		}
+	}
+
+	campaign.SendWindowStart = start
+	campaign.SendWindowEnd = end
+	campaign.TimezoneSource = source
+	campaign.TimezoneField = field
+	campaign.DefaultTimezone = defaultTZ
+	return ""
+}
+
+// campaignSendWindowResponse returns campaign's send window, or nil if it
+// has none.
+func campaignSendWindowResponse(campaign *models.BulkMessageCampaign) *CampaignSendWindow {
+	if campaign.SendWindowStart == "" {
+		return nil
+	}
+	return &CampaignSendWindow{
+		Start:           campaign.SendWindowStart,
+		End:             campaign.SendWindowEnd,
+		TimezoneSource:  campaign.TimezoneSource,
+		TimezoneField:   campaign.TimezoneField,
+		DefaultTimezone: campaign.DefaultTimezone,
+	}
+}
+
+// applyCampaignSendWindow adds campaign's send window to resp, with how
+// many pending recipients wait for it and when the last of them is due.
+// Campaigns that haven't started are projected as if started now.
+func (a *App) applyCampaignSendWindow(resp *CampaignResponse, campaign *models.BulkMessageCampaign) {
+	resp.SendWindow = campaignSendWindowResponse(campaign)
+	if resp.SendWindow == nil {
+		return
+	}
+
+	switch campaign.Status {
+	case models.CampaignStatusDraft, models.CampaignStatusScheduled:
+		if campaign.Recurrence != "" {
+			return
+		}
+		var recipients []models.BulkMessageRecipient
+		if err := a.DB.Select("id", "phone_number").
+			Where("campaign_id = ? AND status = ?", campaign.ID, models.MessageStatusPending).
+			Find(&recipients).Error; err != nil {
+			return
+		}
+		window, err := sendwindow.ForCampaign(campaign)
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		for _, group := range a.recipientTimezones(campaign, recipients) {
+			next := window.Next(now.In(sendwindow.Location(group.timezone)))
+			if next.After(now) {
+				resp.DeferredRecipients += int64(len(group.ids))
+				if resp.ProjectedCompletionAt == nil || next.After(*resp.ProjectedCompletionAt) {
+					resp.ProjectedCompletionAt = &next
+				}
+			}
+		}
+	case models.CampaignStatusProcessing, models.CampaignStatusPaused:
+		var projection struct {
+			Deferred int64
+			Last     *time.Time
+		}
+		a.DB.Model(&models.BulkMessageRecipient{}).
+			Select("COUNT(*) AS deferred, MAX(send_after) AS last").
+			Where("campaign_id = ? AND status = ? AND send_after IS NOT NULL", campaign.ID, models.MessageStatusPending).
+			Scan(&projection)
+		resp.DeferredRecipients = projection.Deferred
+		resp.ProjectedCompletionAt = projection.Last
+	}
+	if resp.ProjectedCompletionAt != nil {
+		t := resp.ProjectedCompletionAt.UTC()
+		resp.ProjectedCompletionAt = &t
+	}
+}
+
+// timezoneRecipients are the recipients that share a timezone.
+type timezoneRecipients struct {
+	timezone string
+	ids      []uuid.UUID
+}
+
+// recipientTimezones groups recipients by their timezone for campaign's
+// send window.
+func (a *App) recipientTimezones(campaign *models.BulkMessageCampaign, recipients []models.BulkMessageRecipient) map[string]*timezoneRecipients {
+	var metadata map[string]models.JSONB
+	if campaign.TimezoneSource == models.TimezoneSourceContactField {
+		metadata = a.contactMetadataByPhone(campaign.OrganizationID, recipients)
+	}
+
+	zones := map[string]*timezoneRecipients{}
+	for _, recipient := range recipients {
+		tz := sendwindow.RecipientTimezone(campaign, recipient.PhoneNumber,
+			metadata[strings.TrimPrefix(recipient.PhoneNumber, "+")])
+		group, ok := zones[tz]
+		if !ok {
+			group = &timezoneRecipients{timezone: tz}
+			zones[tz] = group
+		}
+		group.ids = append(group.ids, recipient.ID)
+	}
+	return zones
+}
+
+// contactMetadataByPhone loads the metadata of the contacts matching
+// recipients, keyed by phone number without a leading "+".
+func (a *App) contactMetadataByPhone(orgID uuid.UUID, recipients []models.BulkMessageRecipient) map[string]models.JSONB {
+	out := make(map[string]models.JSONB, len(recipients))
+	for i := 0; i < len(recipients); i += recipientBatchSize {
+		batch := recipients[i:min(i+recipientBatchSize, len(recipients))]
+		phones := make([]string, 0, 2*len(batch))
+		for _, r := range batch {
+			phone := strings.TrimPrefix(r.PhoneNumber, "+")
+			phones = append(phones, phone, "+"+phone)
+		}
+		var contacts []models.Contact
+		if err := a.DB.Select("phone_number", "metadata").
+			Where("organization_id = ? AND phone_number IN ?", orgID, phones).
+			Find(&contacts).Error; err != nil {
+			a.Log.Error("Failed to load contacts for send window", "error", err)
+			continue
+		}
+		for _, c := range contacts {
+			out[strings.TrimPrefix(c.PhoneNumber, "+")] = c.Metadata
+		}
+	}
+	return out
+}
+
+// dispatchRecipients queues recipients of campaign for sending. With a
+// send window, recipients outside it are given a SendAfter instead, for
+// the schedule processor to queue when their window opens. Returns how
+// many were deferred.
+func (a *App) dispatchRecipients(ctx context.Context, campaign *models.BulkMessageCampaign, recipients []models.BulkMessageRecipient) (int, error) {
+	window, err := sendwindow.ForCampaign(campaign)
+	if err != nil {
+		return 0, err
+	}
+	if window == nil {
+		return 0, a.Queue.EnqueueRecipients(ctx, recipientJobs(campaign.OrganizationID, campaign.ID, recipients))
+	}
+
+	now := time.Now()
+	dueIDs := map[uuid.UUID]bool{}
+	deferred := 0
+	for _, group := range a.recipientTimezones(campaign, recipients) {
+		next := window.Next(now.In(sendwindow.Location(group.timezone)))
+		var sendAfter *time.Time
+		if next.After(now) {
+			sendAfter = &next
+			deferred += len(group.ids)
+		} else {
+			for _, id := range group.ids {
+				dueIDs[id] = true
+			}
+		}
+		for i := 0; i < len(group.ids); i += recipientBatchSize {
+			ids := group.ids[i:min(i+recipientBatchSize, len(group.ids))]
+			if err := a.DB.Model(&models.BulkMessageRecipient{}).Where("id IN ?", ids).
+				Updates(map[string]any{"timezone": group.timezone, "send_after": sendAfter}).Error; err != nil {
+				return 0, fmt.Errorf("defer recipients: %w", err)
+			}
+		}
+	}
+
+	due := make([]models.BulkMessageRecipient, 0, len(dueIDs))
+	for _, recipient := range recipients {
+		if dueIDs[recipient.ID] {
+			due = append(due, recipient)
+		}
+	}
+	if len(due) > 0 {
+		if err := a.Queue.EnqueueRecipients(ctx, recipientJobs(campaign.OrganizationID, campaign.ID, due)); err != nil {
+			return deferred, err
+		}
+	}
+	if deferred > 0 {
+		a.Log.Info("Recipients deferred to their send window", "campaign_id", campaign.ID, "count", deferred)
+	}
+	return deferred, nil
+}
@@ -24,9 +24,28 @@ import (
 type CampaignRequest struct {
 	Name            string     `json:"name" validate:"required"`
 	WhatsAppAccount string     `json:"whatsapp_account" validate:"required"`
-	TemplateID      string     `json:"template_id" validate:"required"`
+	TemplateID      string     `json:"template_id"`
 	HeaderMediaID   string     `json:"header_media_id"`
 	ScheduledAt     *time.Time `json:"scheduled_at"`
+	// SegmentID targets a saved contact segment; recipients are resolved
+	// when the campaign starts. On update, nil leaves it unchanged and ""
+	// clears it. SegmentParams maps template params to contact fields.
+	SegmentID     *string        `json:"segment_id"`
+	SegmentParams map[string]any `json:"segment_params"`
+	// Variants turns the campaign into an A/B test of 2-4 templates; the
+	// first variant's template is used as TemplateID. On update, nil
+	// leaves variants unchanged and an empty list removes them.
+	Variants []CampaignVariantRequest `json:"variants"`
+	ABTest   *CampaignABTestRequest   `json:"ab_test"`
+	// Recurrence is a cron expression that makes this a recurring campaign
+	// against its segment. On update, nil leaves it unchanged and ""
+	// clears it. RecurrenceTimezone defaults to UTC.
+	Recurrence         *string `json:"recurrence"`
+	RecurrenceTimezone *string `json:"recurrence_timezone"`
+	// SendWindow limits sends to a window of each recipient's local time.
+	// On update, nil leaves it unchanged and an empty start and end
+	// removes it.
+	SendWindow *CampaignSendWindow `json:"send_window"`
 }
 
 // CampaignResponse represents campaign in API responses
@@ -46,12 +65,31 @@ type CampaignResponse struct {
 	ReadCount           int                   `json:"read_count"`
 	FailedCount         int                   `json:"failed_count"`
 	ScheduledAt         *time.Time            `json:"scheduled_at,omitempty"`
+	SegmentID           *uuid.UUID            `json:"segment_id,omitempty"`
+	SegmentName         string                `json:"segment_name,omitempty"`
+	SegmentParams       models.JSONB          `json:"segment_params,omitempty"`
 	StartedAt           *time.Time            `json:"started_at,omitempty"`
 	CompletedAt         *time.Time            `json:"completed_at,omitempty"`
 	CreatedByName       string                `json:"created_by_name,omitempty"`
 	UpdatedByName       string                `json:"updated_by_name,omitempty"`
 	CreatedAt           time.Time             `json:"created_at"`
 	UpdatedAt           time.Time             `json:"updated_at"`
+
+	// A/B test variants and settings, only set for A/B tested campaigns
+	Variants []CampaignVariantResponse `json:"variants,omitempty"`
+	ABTest   *CampaignABTestResponse   `json:"ab_test,omitempty"`
+
+	// Recurring campaigns and the runs they start
+	Recurrence         string     `json:"recurrence,omitempty"`
+	RecurrenceTimezone string     `json:"recurrence_timezone,omitempty"`
+	NextRunAt          *time.Time `json:"next_run_at,omitempty"`
+	ParentCampaignID   *uuid.UUID `json:"parent_campaign_id,omitempty"`
+
+	// Send window, with the pending recipients waiting for it and when the
+	// last of them is due
+	SendWindow            *CampaignSendWindow `json:"send_window,omitempty"`
+	DeferredRecipients    int64               `json:"deferred_recipients,omitempty"`
+	ProjectedCompletionAt *time.Time          `json:"projected_completion_at,omitempty"`
 }
 
 // RecipientRequest represents recipient import request
@@ -79,11 +117,15 @@ func (a *App) ListCampaigns(r *fastglue.Request) error {
 	whatsappAccount := string(r.RequestCtx.QueryArgs().Peek("whatsapp_account"))
 	search := string(r.RequestCtx.QueryArgs().Peek("search"))
 
-	baseQuery := a.DB.Where("organization_id = ?", orgID)
+	// Sequence step campaigns are shown on their sequence
+	baseQuery := a.DB.Where("organization_id = ? AND sequence_id IS NULL", orgID)
 
 	if search != "" {
 		baseQuery = baseQuery.Where("name ILIKE ?", "%"+search+"%")
 	}
+	if parentID := string(r.RequestCtx.QueryArgs().Peek("parent_campaign_id")); parentID != "" {
+		baseQuery = baseQuery.Where("parent_campaign_id = ?", parentID)
+	}
 
 	if status != "" {
 		baseQuery = baseQuery.Where("status = ?", status)
@@ -105,6 +147,7 @@ func (a *App) ListCampaigns(r *fastglue.Request) error {
 	var campaigns []models.BulkMessageCampaign
 	if err := pg.Apply(baseQuery.
 		Preload("Template").
+		Preload("Segment").
 		Order("created_at DESC")).
 		Find(&campaigns).Error; err != nil {
 		a.Log.Error("Failed to list campaigns", "error", err)
@@ -129,6 +172,12 @@ func (a *App) ListCampaigns(r *fastglue.Request) error {
 			ReadCount:           c.ReadCount,
 			FailedCount:         c.FailedCount,
 			ScheduledAt:         c.ScheduledAt,
+			SegmentID:           c.SegmentID,
+			SegmentParams:       c.SegmentParams,
+			Recurrence:          c.Recurrence,
+			RecurrenceTimezone:  c.RecurrenceTimezone,
+			NextRunAt:           c.NextRunAt,
+			ParentCampaignID:    c.ParentCampaignID,
 			StartedAt:           c.StartedAt,
 			CompletedAt:         c.CompletedAt,
 			CreatedAt:           c.CreatedAt,
@@ -137,6 +186,10 @@ func (a *App) ListCampaigns(r *fastglue.Request) error {
 		if c.Template != nil {
 			response[i].TemplateName = c.Template.Name
 		}
+		if c.Segment != nil {
+			response[i].SegmentName = c.Segment.Name
+		}
+		response[i].SendWindow = campaignSendWindowResponse(&c)
 	}
 
 	return r.SendEnvelope(listEnvelope("campaigns", response, total, pg))
@@ -154,6 +207,14 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		return nil
 	}
 
+	var variants []models.CampaignVariant
+	if len(req.Variants) > 0 {
+		if variants, err = a.campaignVariants(r, orgID, req.Variants); err != nil {
+			return nil
+		}
+		req.TemplateID = variants[0].TemplateID.String()
+	}
+
 	// Validate template exists
 	templateID, err := uuid.Parse(req.TemplateID)
 	if err != nil {
@@ -170,6 +231,13 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
 	}
 
+	var segment *models.ContactSegment
+	if req.SegmentID != nil && *req.SegmentID != "" {
+		if segment, err = a.campaignSegment(r, orgID, *req.SegmentID); err != nil {
+			return nil
+		}
+	}
+
 	campaign := models.BulkMessageCampaign{
 		OrganizationID:  orgID,
 		WhatsAppAccount: req.WhatsAppAccount,
@@ -178,21 +246,50 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		HeaderMediaID:   req.HeaderMediaID,
 		Status:          models.CampaignStatusDraft,
 		ScheduledAt:     req.ScheduledAt,
+		SegmentParams:   models.JSONB(req.SegmentParams),
 		CreatedBy:       userID,
 		UpdatedByID:     &userID,
 	}
+	if segment != nil {
+		campaign.SegmentID = &segment.ID
+	}
+	if len(variants) > 0 {
+		if msg := applyABTestSettings(&campaign, req.ABTest); msg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+		}
+	}
+	if req.Recurrence != nil {
+		campaign.Recurrence = strings.TrimSpace(*req.Recurrence)
+	}
+	if req.RecurrenceTimezone != nil {
+		campaign.RecurrenceTimezone = strings.TrimSpace(*req.RecurrenceTimezone)
+	}
+	if msg := validateCampaignRecurrence(&campaign, len(variants)); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+	if req.SendWindow != nil {
+		if msg := a.applySendWindow(orgID, &campaign, req.SendWindow); msg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+		}
+	}
 
-	if err := a.DB.Create(&campaign).Error; err != nil {
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&campaign).Error; err != nil {
+			return err
+		}
+		return replaceCampaignVariants(tx, campaign.ID, variants)
+	}); err != nil {
 		a.Log.Error("Failed to create campaign", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create campaign", nil, "")
 	}
+	campaign.Variants = variants
 
 	a.logAudit(orgID, userID,
 		"campaign", campaign.ID, models.AuditActionCreated, nil, &campaign)
 
 	a.Log.Info("Campaign created", "campaign_id", campaign.ID, "name", campaign.Name)
 
-	return r.SendEnvelope(CampaignResponse{
+	response := CampaignResponse{
 		ID:                  campaign.ID,
 		Name:                campaign.Name,
 		WhatsAppAccount:     campaign.WhatsAppAccount,
@@ -208,9 +305,22 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		ReadCount:           campaign.ReadCount,
 		FailedCount:         campaign.FailedCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		SegmentID:           campaign.SegmentID,
+		SegmentParams:       campaign.SegmentParams,
+		Recurrence:          campaign.Recurrence,
+		RecurrenceTimezone:  campaign.RecurrenceTimezone,
+		NextRunAt:           campaign.NextRunAt,
+		ParentCampaignID:    campaign.ParentCampaignID,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
-	})
+	}
+	if segment != nil {
+		response.SegmentName = segment.Name
+	}
+	applyCampaignVariants(&response, &campaign)
+	a.applyCampaignSendWindow(&response, &campaign)
+
+	return r.SendEnvelope(response)
 }
 
 // GetCampaign implements getting a single campaign
@@ -226,8 +336,9 @@ func (a *App) GetCampaign(r *fastglue.Request) error {
 	}
 
 	var campaign models.BulkMessageCampaign
-	if err := a.DB.Where("id = ? AND organization_id = ?", id, orgID).
+	if err := preloadCampaignVariants(a.DB).Where("id = ? AND organization_id = ?", id, orgID).
 		Preload("Template").
+		Preload("Segment").
 		Preload("Creator").
 		Preload("UpdatedBy").
 		First(&campaign).Error; err != nil {
@@ -249,6 +360,12 @@ func (a *App) GetCampaign(r *fastglue.Request) error {
 		ReadCount:           campaign.ReadCount,
 		FailedCount:         campaign.FailedCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		SegmentID:           campaign.SegmentID,
+		SegmentParams:       campaign.SegmentParams,
+		Recurrence:          campaign.Recurrence,
+		RecurrenceTimezone:  campaign.RecurrenceTimezone,
+		NextRunAt:           campaign.NextRunAt,
+		ParentCampaignID:    campaign.ParentCampaignID,
 		StartedAt:           campaign.StartedAt,
 		CompletedAt:         campaign.CompletedAt,
 		CreatedAt:           campaign.CreatedAt,
@@ -257,12 +374,17 @@ func (a *App) GetCampaign(r *fastglue.Request) error {
 	if campaign.Template != nil {
 		response.TemplateName = campaign.Template.Name
 	}
+	if campaign.Segment != nil {
+		response.SegmentName = campaign.Segment.Name
+	}
 	if campaign.Creator != nil {
 		response.CreatedByName = campaign.Creator.FullName
 	}
 	if campaign.UpdatedBy != nil {
 		response.UpdatedByName = campaign.UpdatedBy.FullName
 	}
+	applyCampaignVariants(&response, &campaign)
+	a.applyCampaignSendWindow(&response, &campaign)
 
 	return r.SendEnvelope(response)
 }
@@ -303,6 +425,23 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		"updated_by_id": userID,
 	}
 
+	var variants []models.CampaignVariant
+	if len(req.Variants) > 0 {
+		if variants, err = a.campaignVariants(r, orgID, req.Variants); err != nil {
+			return nil
+		}
+		req.TemplateID = variants[0].TemplateID.String()
+	}
+	if req.Variants != nil || req.ABTest != nil {
+		abCampaign := *campaign
+		if msg := applyABTestSettings(&abCampaign, req.ABTest); msg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+		}
+		updates["ab_test_percent"] = abCampaign.ABTestPercent
+		updates["ab_test_metric"] = abCampaign.ABTestMetric
+		updates["ab_test_window_minutes"] = abCampaign.ABTestWindowMinutes
+	}
+
 	if req.TemplateID != "" {
 		templateID, err := uuid.Parse(req.TemplateID)
 		if err != nil {
@@ -315,13 +454,71 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		updates["whats_app_account"] = req.WhatsAppAccount
 	}
 
-	if err := a.DB.Model(campaign).Updates(updates).Error; err != nil {
+	if req.SegmentID != nil {
+		if *req.SegmentID == "" {
+			updates["segment_id"] = nil
+		} else {
+			segment, err := a.campaignSegment(r, orgID, *req.SegmentID)
+			if err != nil {
+				return nil
+			}
+			updates["segment_id"] = segment.ID
+		}
+	}
+	if req.SegmentParams != nil {
+		updates["segment_params"] = models.JSONB(req.SegmentParams)
+	}
+
+	// Recurrence is checked against the campaign as it will be saved
+	updated := *campaign
+	if segmentID, ok := updates["segment_id"].(uuid.UUID); ok {
+		updated.SegmentID = &segmentID
+	} else if _, ok := updates["segment_id"]; ok {
+		updated.SegmentID = nil
+	}
+	if req.Recurrence != nil {
+		updated.Recurrence = strings.TrimSpace(*req.Recurrence)
+		updates["recurrence"] = updated.Recurrence
+	}
+	if req.RecurrenceTimezone != nil {
+		updated.RecurrenceTimezone = strings.TrimSpace(*req.RecurrenceTimezone)
+		updates["recurrence_timezone"] = updated.RecurrenceTimezone
+	}
+	if updated.Recurrence != "" {
+		variantCount := int64(len(variants))
+		if req.Variants == nil {
+			a.DB.Model(&models.CampaignVariant{}).Where("campaign_id = ?", id).Count(&variantCount)
+		}
+		if msg := validateCampaignRecurrence(&updated, int(variantCount)); msg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+		}
+	}
+	if req.SendWindow != nil {
+		if msg := a.applySendWindow(orgID, &updated, req.SendWindow); msg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+		}
+		updates["send_window_start"] = updated.SendWindowStart
+		updates["send_window_end"] = updated.SendWindowEnd
+		updates["timezone_source"] = updated.TimezoneSource
+		updates["timezone_field"] = updated.TimezoneField
+		updates["default_timezone"] = updated.DefaultTimezone
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(campaign).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Variants == nil {
+			return nil
+		}
+		return replaceCampaignVariants(tx, campaign.ID, variants)
+	}); err != nil {
 		a.Log.Error("Failed to update campaign", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update campaign", nil, "")
 	}
 
 	// Reload campaign
-	a.DB.Where("id = ?", id).Preload("Template").Preload("Creator").Preload("UpdatedBy").First(campaign)
+	preloadCampaignVariants(a.DB).Where("id = ?", id).Preload("Template").Preload("Segment").Preload("Creator").Preload("UpdatedBy").First(campaign)
 
 	a.logAudit(orgID, userID,
 		"campaign", campaign.ID, models.AuditActionUpdated, &oldCampaign, campaign)
@@ -341,22 +538,44 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		ReadCount:           campaign.ReadCount,
 		FailedCount:         campaign.FailedCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		SegmentID:           campaign.SegmentID,
+		SegmentParams:       campaign.SegmentParams,
+		Recurrence:          campaign.Recurrence,
+		RecurrenceTimezone:  campaign.RecurrenceTimezone,
+		NextRunAt:           campaign.NextRunAt,
+		ParentCampaignID:    campaign.ParentCampaignID,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
 	}
 	if campaign.Template != nil {
 		response.TemplateName = campaign.Template.Name
 	}
+	if campaign.Segment != nil {
+		response.SegmentName = campaign.Segment.Name
+	}
 	if campaign.Creator != nil {
 		response.CreatedByName = campaign.Creator.FullName
 	}
 	if campaign.UpdatedBy != nil {
 		response.UpdatedByName = campaign.UpdatedBy.FullName
 	}
+	applyCampaignVariants(&response, campaign)
+	a.applyCampaignSendWindow(&response, campaign)
 
 	return r.SendEnvelope(response)
 }
 
+// campaignSegment loads the segment a campaign request refers to, sending
+// a 400 when the id is malformed and a 404 when it doesn't exist.
+func (a *App) campaignSegment(r *fastglue.Request, orgID uuid.UUID, rawID string) (*models.ContactSegment, error) {
+	segmentID, err := uuid.Parse(rawID)
+	if err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid segment ID", nil, "")
+		return nil, errEnvelopeSent
+	}
+	return findByIDAndOrg[models.ContactSegment](a.DB, r, segmentID, orgID, "Segment")
+}
+
 // DeleteCampaign implements campaign deletion
 func (a *App) DeleteCampaign(r *fastglue.Request) error {
 	orgID, userID, err := a.getOrgAndUserID(r)
@@ -423,6 +642,27 @@ func (a *App) StartCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign cannot be started in current state", nil, "")
 	}
 
+	// Starting a recurring campaign turns its schedule on
+	if campaign.Recurrence != "" {
+		return a.scheduleRecurringCampaign(r, campaign)
+	}
+
+	// Resolve the segment audience now so the campaign reaches the contacts
+	// that match at send time. A resumed campaign keeps the audience it
+	// started with.
+	if campaign.SegmentID != nil && campaign.Status != models.CampaignStatusPaused {
+		var segment models.ContactSegment
+		if err := a.DB.Where("id = ? AND organization_id = ?", *campaign.SegmentID, orgID).First(&segment).Error; err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign segment no longer exists", nil, "")
+		}
+		added, err := a.addSegmentRecipients(campaign, &segment)
+		if err != nil {
+			a.Log.Error("Failed to resolve campaign segment", "error", err, "campaign_id", id)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to resolve campaign segment", nil, "")
+		}
+		a.Log.Info("Segment recipients added", "campaign_id", id, "segment_id", segment.ID, "count", added)
+	}
+
 	// Get all pending recipients
 	var recipients []models.BulkMessageRecipient
 	if err := a.DB.Where("campaign_id = ? AND status = ?", id, models.MessageStatusPending).Find(&recipients).Error; err != nil {
@@ -449,40 +689,109 @@ func (a *App) StartCampaign(r *fastglue.Request) error {
 		"started_at": now,
 	}
 
+	// A/B tests first send each variant to a random slice of the audience;
+	// the rest wait for the winner. A resumed test only sends what's left
+	// of its slice.
+	var variants []models.CampaignVariant
+	if err := a.DB.Where("campaign_id = ?", id).Preload("Template").Order("position ASC").Find(&variants).Error; err != nil {
+		a.Log.Error("Failed to load campaign variants", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load campaign variants", nil, "")
+	}
+	abTestStarted := false
+	if len(variants) > 0 {
+		for _, v := range variants {
+			if v.Template == nil {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Variant template no longer exists", nil, "")
+			}
+		}
+		switch campaign.ABTestStatus {
+		case models.ABTestStatusNone:
+			if recipients, err = a.startABTest(campaign, variants, recipients); err != nil {
+				a.Log.Error("Failed to assign A/B test variants", "error", err)
+				return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to start campaign", nil, "")
+			}
+			abTestStarted = true
+			updates["ab_test_status"] = models.ABTestStatusTesting
+			updates["ab_test_ends_at"] = now.Add(time.Duration(campaign.ABTestWindowMinutes) * time.Minute)
+		case models.ABTestStatusTesting:
+			testing := recipients[:0]
+			for _, recipient := range recipients {
+				if recipient.VariantID != nil {
+					testing = append(testing, recipient)
+				}
+			}
+			recipients = testing
+		case models.ABTestStatusDecided:
+			for i := range recipients {
+				if recipients[i].VariantID == nil {
+					recipients[i].VariantID = campaign.WinnerVariantID
+				}
+			}
+		}
+	}
+
 	if err := a.DB.Model(campaign).Updates(updates).Error; err != nil {
 		a.Log.Error("Failed to start campaign", "error", err)
+		if abTestStarted {
+			a.resetABTest(id)
+		}
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to start campaign", nil, "")
 	}
 
 	a.Log.Info("Campaign started", "campaign_id", id, "recipients", len(recipients))
 
-	// Enqueue all recipients as individual jobs for parallel processing
+	// Enqueue all recipients as individual jobs for parallel processing;
+	// those outside the send window wait for it to open
+	deferred, err := a.dispatchRecipients(r.RequestCtx, campaign, recipients)
+	if err != nil {
+		a.Log.Error("Failed to enqueue recipients", "error", err)
+		// Revert status on failure
+		a.DB.Model(campaign).Update("status", models.CampaignStatusDraft)
+		if abTestStarted {
+			a.resetABTest(id)
+		}
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to queue recipients", nil, "")
+	}
+
+	a.Log.Info("Recipients enqueued for processing", "campaign_id", id, "count", len(recipients)-deferred, "deferred", deferred)
+
+	// Part of the A/B test slice waits for its send window, so the test
+	// clock starts when processDeferredRecipients queues the last of it
+	if deferred > 0 && (abTestStarted || campaign.ABTestStatus == models.ABTestStatusTesting) {
+		if err := a.DB.Model(campaign).Update("ab_test_ends_at", nil).Error; err != nil {
+			a.Log.Error("Failed to hold A/B test clock", "error", err, "campaign_id", id)
+		}
+	}
+
+	resp := map[string]any{
+		"message": "Campaign started",
+		"status":  models.CampaignStatusProcessing,
+	}
+	if campaign.SendWindowStart != "" {
+		var projection CampaignResponse
+		a.applyCampaignSendWindow(&projection, campaign)
+		resp["deferred_recipients"] = projection.DeferredRecipients
+		resp["projected_completion_at"] = projection.ProjectedCompletionAt
+	}
+	return r.SendEnvelope(resp)
+}
+
+// recipientJobs builds the queue jobs that send recipients of a campaign.
+func recipientJobs(orgID, campaignID uuid.UUID, recipients []models.BulkMessageRecipient) []*queue.RecipientJob {
 	jobs := make([]*queue.RecipientJob, len(recipients))
 	for i, recipient := range recipients {
 		jobs[i] = &queue.RecipientJob{
-			CampaignID:     id,
+			CampaignID:     campaignID,
 			RecipientID:    recipient.ID,
 			OrganizationID: orgID,
 			PhoneNumber:    recipient.PhoneNumber,
 			RecipientName:  recipient.RecipientName,
 			TemplateParams: recipient.TemplateParams,
 			HeaderParams:   recipient.HeaderParams,
+			VariantID:      recipient.VariantID,
 		}
 	}
-
-	if err := a.Queue.EnqueueRecipients(r.RequestCtx, jobs); err != nil {
-		a.Log.Error("Failed to enqueue recipients", "error", err)
-		// Revert status on failure
-		a.DB.Model(campaign).Update("status", models.CampaignStatusDraft)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to queue recipients", nil, "")
-	}
-
-	a.Log.Info("Recipients enqueued for processing", "campaign_id", id, "count", len(jobs))
-
-	return r.SendEnvelope(map[string]any{
-		"message": "Campaign started",
-		"status":  models.CampaignStatusProcessing,
-	})
+	return jobs
 }
 
 // PauseCampaign implements pausing a campaign
@@ -502,7 +811,11 @@ func (a *App) PauseCampaign(r *fastglue.Request) error {
 		return nil
 	}
 
-	if campaign.Status != models.CampaignStatusProcessing && campaign.Status != models.CampaignStatusQueued {
+	if campaign.SequenceID != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign is managed by its sequence", nil, "")
+	}
+	recurring := campaign.Recurrence != "" && campaign.Status == models.CampaignStatusScheduled
+	if campaign.Status != models.CampaignStatusProcessing && campaign.Status != models.CampaignStatusQueued && !recurring {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign is not running", nil, "")
 	}
 
@@ -536,6 +849,9 @@ func (a *App) CancelCampaign(r *fastglue.Request) error {
 		return nil
 	}
 
+	if campaign.SequenceID != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign is managed by its sequence", nil, "")
+	}
 	if campaign.Status == models.CampaignStatusCompleted || campaign.Status == models.CampaignStatusCancelled {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign already finished", nil, "")
 	}
@@ -619,25 +935,13 @@ func (a *App) RetryFailed(r *fastglue.Request) error {
 	a.Log.Info("Retrying failed messages", "campaign_id", id, "failed_count", len(failedRecipients))
 
 	// Enqueue failed recipients as individual jobs for parallel processing
-	jobs := make([]*queue.RecipientJob, len(failedRecipients))
-	for i, recipient := range failedRecipients {
-		jobs[i] = &queue.RecipientJob{
-			CampaignID:     id,
-			RecipientID:    recipient.ID,
-			OrganizationID: orgID,
-			PhoneNumber:    recipient.PhoneNumber,
-			RecipientName:  recipient.RecipientName,
-			TemplateParams: recipient.TemplateParams,
-			HeaderParams:   recipient.HeaderParams,
-		}
-	}
-
-	if err := a.Queue.EnqueueRecipients(r.RequestCtx, jobs); err != nil {
+	deferred, err := a.dispatchRecipients(r.RequestCtx, campaign, failedRecipients)
+	if err != nil {
 		a.Log.Error("Failed to enqueue recipients for retry", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to queue recipients", nil, "")
 	}
 
-	a.Log.Info("Failed recipients enqueued for retry", "campaign_id", id, "count", len(jobs))
+	a.Log.Info("Failed recipients enqueued for retry", "campaign_id", id, "count", len(failedRecipients)-deferred, "deferred", deferred)
 
 	return r.SendEnvelope(map[string]any{
 		"message":     "Retrying failed messages",
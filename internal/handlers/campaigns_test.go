@@ -1,7 +1,9 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -940,3 +942,469 @@ func TestApp_Campaign_CrossOrgIsolation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, fasthttp.StatusNotFound, testutil.GetResponseStatusCode(req))
 }
+
+func TestApp_CampaignABTest_TestsSliceThenRollsOutWinner(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("ab-test")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("ab-test-account"))
+	templateA := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	templateB := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "A/B Campaign",
+		"whatsapp_account": account.Name,
+		"variants": []map[string]any{
+			{"template_id": templateA.ID.String()},
+			{"name": "Short copy", "template_id": templateB.ID.String()},
+		},
+		"ab_test": map[string]any{"test_percent": 20, "metric": "reply_rate", "window_minutes": 60},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var resp struct {
+		Data handlers.CampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, templateA.ID, resp.Data.TemplateID)
+	require.Len(t, resp.Data.Variants, 2)
+	assert.Equal(t, "A", resp.Data.Variants[0].Name)
+	assert.Equal(t, "Short copy", resp.Data.Variants[1].Name)
+	require.NotNil(t, resp.Data.ABTest)
+	assert.Equal(t, models.ABTestMetricReplyRate, resp.Data.ABTest.Metric)
+
+	campaignID := resp.Data.ID
+	for i := 0; i < 10; i++ {
+		createTestRecipient(t, app, campaignID, fmt.Sprintf("+1555000%04d", i), models.MessageStatusPending)
+	}
+
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaignID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	// Only the 20% test slice is sent, split across both variants
+	jobs := mockQueue.GetJobs()
+	require.Len(t, jobs, 2)
+	assert.NotNil(t, jobs[0].VariantID)
+	assert.NotNil(t, jobs[1].VariantID)
+	assert.NotEqual(t, *jobs[0].VariantID, *jobs[1].VariantID)
+
+	var campaign models.BulkMessageCampaign
+	require.NoError(t, app.DB.Where("id = ?", campaignID).First(&campaign).Error)
+	assert.Equal(t, models.ABTestStatusTesting, campaign.ABTestStatus)
+	require.NotNil(t, campaign.ABTestEndsAt)
+
+	// End the test window and let the processor pick a winner
+	require.NoError(t, app.DB.Model(&campaign).Update("ab_test_ends_at", time.Now().Add(-time.Minute)).Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignABTestProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 10
+	}, 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, app.DB.Where("id = ?", campaignID).First(&campaign).Error)
+	assert.Equal(t, models.ABTestStatusDecided, campaign.ABTestStatus)
+	require.NotNil(t, campaign.WinnerVariantID)
+	for _, job := range mockQueue.GetJobs()[2:] {
+		require.NotNil(t, job.VariantID)
+		assert.Equal(t, *campaign.WinnerVariantID, *job.VariantID)
+	}
+
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaignID.String())
+	require.NoError(t, app.GetCampaignABTestResults(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var results struct {
+		Data handlers.CampaignABTestResultsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &results))
+	require.Len(t, results.Data.Variants, 2)
+	for _, v := range results.Data.Variants {
+		assert.Equal(t, int64(1), v.Recipients, "rollout sends are not part of the test")
+		assert.Equal(t, v.VariantID == *campaign.WinnerVariantID, v.IsWinner)
+	}
+}
+
+func TestApp_CampaignABTest_RolloutSkipsPendingTestSlice(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("ab-rollout")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("ab-rollout-account"))
+	templateA := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	templateB := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "A/B rollout",
+		"whatsapp_account": account.Name,
+		"variants": []map[string]any{
+			{"template_id": templateA.ID.String()},
+			{"template_id": templateB.ID.String()},
+		},
+		"ab_test": map[string]any{"test_percent": 20, "metric": "read_rate", "window_minutes": 1},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var resp struct {
+		Data handlers.CampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	campaignID := resp.Data.ID
+	for i := 0; i < 10; i++ {
+		createTestRecipient(t, app, campaignID, fmt.Sprintf("+999200%04d", i), models.MessageStatusPending)
+	}
+
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaignID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	require.Equal(t, 2, mockQueue.JobCount())
+	slice := map[uuid.UUID]bool{}
+	for _, job := range mockQueue.GetJobs() {
+		slice[job.RecipientID] = true
+	}
+
+	// The window closes while the test slice is still queued, unsent
+	require.NoError(t, app.DB.Model(&models.BulkMessageCampaign{}).Where("id = ?", campaignID).
+		Update("ab_test_ends_at", time.Now().Add(-time.Minute)).Error)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignABTestProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() >= 10
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Never(t, func() bool {
+		return mockQueue.JobCount() > 10
+	}, 200*time.Millisecond, 20*time.Millisecond)
+	for _, job := range mockQueue.GetJobs()[2:] {
+		assert.False(t, slice[job.RecipientID], "a pending test-slice recipient was queued again")
+	}
+}
+
+func TestApp_CampaignABTest_ClockStartsWhenDeferredSliceIsSent(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("ab-window")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("ab-window-account"))
+	templateA := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	templateB := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	now := time.Now().UTC()
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "A/B in window",
+		"whatsapp_account": account.Name,
+		"variants": []map[string]any{
+			{"template_id": templateA.ID.String()},
+			{"template_id": templateB.ID.String()},
+		},
+		"ab_test": map[string]any{"test_percent": 20, "metric": "read_rate", "window_minutes": 60},
+		"send_window": map[string]any{
+			"start":            now.Add(2 * time.Hour).Format("15:04"),
+			"end":              now.Add(3 * time.Hour).Format("15:04"),
+			"default_timezone": "UTC",
+		},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var resp struct {
+		Data handlers.CampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	campaignID := resp.Data.ID
+	for i := 0; i < 10; i++ {
+		createTestRecipient(t, app, campaignID, fmt.Sprintf("+999000%04d", i), models.MessageStatusPending)
+	}
+
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaignID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	assert.Zero(t, mockQueue.JobCount(), "the test slice waits for the window")
+
+	var campaign models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&campaign, campaignID).Error)
+	assert.Equal(t, models.ABTestStatusTesting, campaign.ABTestStatus)
+	assert.Nil(t, campaign.ABTestEndsAt, "the test clock hasn't started")
+
+	// Even with an elapsed clock, no winner is picked from an unsent slice
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, app.DB.Model(&campaign).Update("ab_test_ends_at", past).Error)
+	abCtx, stopAB := context.WithCancel(context.Background())
+	go handlers.NewCampaignABTestProcessor(app, 10*time.Millisecond).Start(abCtx)
+	assert.Never(t, func() bool {
+		var c models.BulkMessageCampaign
+		app.DB.First(&c, campaignID)
+		return c.ABTestStatus != models.ABTestStatusTesting
+	}, 200*time.Millisecond, 20*time.Millisecond)
+	stopAB()
+	require.NoError(t, app.DB.Model(&campaign).Update("ab_test_ends_at", nil).Error)
+
+	// The window opens: the slice is queued and the clock starts
+	require.NoError(t, app.DB.Model(&models.BulkMessageRecipient{}).
+		Where("campaign_id = ? AND variant_id IS NOT NULL", campaignID).
+		Update("send_after", past).Error)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		app.DB.First(&campaign, campaignID)
+		return campaign.ABTestEndsAt != nil
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *campaign.ABTestEndsAt, time.Minute)
+}
+
+func TestApp_CampaignABTest_ClockExtendsWhenWorkerDefersSlice(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("ab-late")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("ab-late-account"))
+	templateA := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	templateB := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "A/B late slice",
+		"whatsapp_account": account.Name,
+		"variants": []map[string]any{
+			{"template_id": templateA.ID.String()},
+			{"template_id": templateB.ID.String()},
+		},
+		"ab_test": map[string]any{"test_percent": 20, "metric": "read_rate", "window_minutes": 60},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var resp struct {
+		Data handlers.CampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	campaignID := resp.Data.ID
+	for i := 0; i < 10; i++ {
+		createTestRecipient(t, app, campaignID, fmt.Sprintf("+999100%04d", i), models.MessageStatusPending)
+	}
+
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaignID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	require.Equal(t, 2, mockQueue.JobCount())
+
+	var campaign models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&campaign, campaignID).Error)
+	require.NotNil(t, campaign.ABTestEndsAt, "nothing was held back, so the clock started")
+
+	// The worker deferred one of the slice to its send window after the
+	// clock started, and the window has since elapsed
+	var slice models.BulkMessageRecipient
+	require.NoError(t, app.DB.Where("campaign_id = ? AND variant_id IS NOT NULL", campaignID).First(&slice).Error)
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, app.DB.Model(&slice).Update("send_after", past).Error)
+	require.NoError(t, app.DB.Model(&campaign).Update("ab_test_ends_at", past).Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+	go handlers.NewCampaignABTestProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 3
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		app.DB.First(&campaign, campaignID)
+		return campaign.ABTestEndsAt != nil && campaign.ABTestEndsAt.After(time.Now())
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *campaign.ABTestEndsAt, time.Minute)
+
+	// The late send gets a full window before a winner is picked
+	assert.Never(t, func() bool {
+		var c models.BulkMessageCampaign
+		app.DB.First(&c, campaignID)
+		return c.ABTestStatus != models.ABTestStatusTesting
+	}, 200*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestApp_RecurringCampaign_StartsRunsOnSchedule(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("recurring")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("recurring-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	member := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15550000031"))
+	require.NoError(t, app.DB.Model(member).Update("tags", models.JSONBArray{"weekly"}).Error)
+	segment := &models.ContactSegment{
+		OrganizationID: org.ID,
+		Name:           "Weekly digest",
+		Rules: models.JSONB{"match": "all", "conditions": []any{
+			map[string]any{"field": "tags", "operator": "has", "value": "weekly"},
+		}},
+		CreatedByID: user.ID,
+	}
+	require.NoError(t, app.DB.Create(segment).Error)
+
+	// A recurring campaign needs a segment
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Weekly digest",
+		"whatsapp_account": account.Name,
+		"template_id":      template.ID.String(),
+		"recurrence":       "0 9 * * mon",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateCampaign(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"name":                "Weekly digest",
+		"whatsapp_account":    account.Name,
+		"template_id":         template.ID.String(),
+		"segment_id":          segment.ID.String(),
+		"recurrence":          "0 9 * * mon",
+		"recurrence_timezone": "Asia/Kolkata",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var resp struct {
+		Data handlers.CampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	parentID := resp.Data.ID
+
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", parentID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	assert.Zero(t, mockQueue.JobCount(), "nothing is sent until the schedule is due")
+
+	var parent models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&parent, parentID).Error)
+	assert.Equal(t, models.CampaignStatusScheduled, parent.Status)
+	require.NotNil(t, parent.NextRunAt)
+	assert.Equal(t, time.Monday, parent.NextRunAt.In(time.FixedZone("IST", 5*3600+1800)).Weekday())
+
+	// Make the run due and let the processor start it
+	require.NoError(t, app.DB.Model(&parent).Update("next_run_at", time.Now().Add(-time.Minute)).Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, member.PhoneNumber, mockQueue.GetJobs()[0].PhoneNumber)
+
+	var run models.BulkMessageCampaign
+	require.NoError(t, app.DB.Where("parent_campaign_id = ?", parentID).First(&run).Error)
+	assert.Equal(t, run.ID, mockQueue.GetJobs()[0].CampaignID)
+	assert.Equal(t, 1, run.TotalRecipients)
+
+	require.NoError(t, app.DB.First(&parent, parentID).Error)
+	assert.Equal(t, models.CampaignStatusScheduled, parent.Status)
+	require.NotNil(t, parent.NextRunAt)
+	assert.True(t, parent.NextRunAt.After(time.Now()))
+}
+
+func TestApp_SendWindow_DefersUntilWindowOpens(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("send-window")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("send-window-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+	// No timezone for this calling code, so the default applies
+	recipient := createTestRecipient(t, app, campaign.ID, "+9990000001", models.MessageStatusPending)
+
+	// A window that opens two hours from now, UTC
+	now := time.Now().UTC()
+	window := map[string]any{
+		"start":            now.Add(2 * time.Hour).Format("15:04"),
+		"end":              now.Add(3 * time.Hour).Format("15:04"),
+		"default_timezone": "UTC",
+	}
+
+	window["timezone_source"] = "contact_field"
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             campaign.Name,
+		"whatsapp_account": account.Name,
+		"template_id":      template.ID.String(),
+		"send_window":      window,
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+	require.NoError(t, app.UpdateCampaign(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req), "contact_field needs a field")
+
+	delete(window, "timezone_source")
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"name":             campaign.Name,
+		"whatsapp_account": account.Name,
+		"template_id":      template.ID.String(),
+		"send_window":      window,
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+	require.NoError(t, app.UpdateCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var resp struct {
+		Data handlers.CampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	require.NotNil(t, resp.Data.SendWindow)
+	assert.Equal(t, models.TimezoneSourcePhone, resp.Data.SendWindow.TimezoneSource)
+	assert.EqualValues(t, 1, resp.Data.DeferredRecipients)
+
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	assert.Zero(t, mockQueue.JobCount(), "nothing is sent outside the window")
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, app.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, "UTC", updated.Timezone)
+	require.NotNil(t, updated.SendAfter)
+	assert.WithinDuration(t, now.Add(2*time.Hour), *updated.SendAfter, time.Minute)
+
+	// Once the window opens the processor queues it
+	require.NoError(t, app.DB.Model(&updated).Update("send_after", time.Now().Add(-time.Minute)).Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, recipient.ID, mockQueue.GetJobs()[0].RecipientID)
+
+	require.NoError(t, app.DB.First(&updated, recipient.ID).Error)
+	assert.Nil(t, updated.SendAfter)
+}
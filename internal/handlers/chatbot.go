@@ -2,35 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/audit"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/redact"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 )
 
 // ChatbotSettingsResponse represents the response for chatbot settings
 type ChatbotSettingsResponse struct {
-	Enabled                      bool              `json:"enabled"`
-	GreetingMessage              string            `json:"greeting_message"`
-	GreetingButtons              []map[string]any  `json:"greeting_buttons"`
-	FallbackMessage              string            `json:"fallback_message"`
-	FallbackButtons              []map[string]any  `json:"fallback_buttons"`
-	SessionTimeoutMinutes        int               `json:"session_timeout_minutes"`
-	BusinessHoursEnabled         bool              `json:"business_hours_enabled"`
-	BusinessHours                []map[string]any  `json:"business_hours"`
-	OutOfHoursMessage            string            `json:"out_of_hours_message"`
-	AllowAutomatedOutsideHours   bool              `json:"allow_automated_outside_hours"`
-	AllowAgentQueuePickup        bool              `json:"allow_agent_queue_pickup"`
-	AssignToSameAgent            bool              `json:"assign_to_same_agent"`
-	AgentCurrentConversationOnly bool              `json:"agent_current_conversation_only"`
-	AIEnabled                    bool              `json:"ai_enabled"`
-	AIProvider                   models.AIProvider `json:"ai_provider"`
-	AIModel                      string            `json:"ai_model"`
-	AIMaxTokens                  int               `json:"ai_max_tokens"`
-	AISystemPrompt               string            `json:"ai_system_prompt"`
+	Enabled                      bool                `json:"enabled"`
+	GreetingMessage              string              `json:"greeting_message"`
+	GreetingButtons              []map[string]any    `json:"greeting_buttons"`
+	FallbackMessage              string              `json:"fallback_message"`
+	FallbackButtons              []map[string]any    `json:"fallback_buttons"`
+	SessionTimeoutMinutes        int                 `json:"session_timeout_minutes"`
+	InterruptRules               []map[string]any    `json:"interrupt_rules"`
+	BusinessHoursEnabled         bool                `json:"business_hours_enabled"`
+	BusinessHours                []map[string]any    `json:"business_hours"`
+	OutOfHoursMessage            string              `json:"out_of_hours_message"`
+	AllowAutomatedOutsideHours   bool                `json:"allow_automated_outside_hours"`
+	AllowAgentQueuePickup        bool                `json:"allow_agent_queue_pickup"`
+	AssignToSameAgent            bool                `json:"assign_to_same_agent"`
+	AgentCurrentConversationOnly bool                `json:"agent_current_conversation_only"`
+	AIEnabled                    bool                `json:"ai_enabled"`
+	AIProvider                   models.AIProvider   `json:"ai_provider"`
+	AIBaseURL                    string              `json:"ai_base_url"`
+	AIHeaders                    map[string]string   `json:"ai_headers"` // values masked
+	AIModel                      string              `json:"ai_model"`
+	AIMaxTokens                  int                 `json:"ai_max_tokens"`
+	AISystemPrompt               string              `json:"ai_system_prompt"`
+	AIEmbeddingModel             string              `json:"ai_embedding_model"`
+	AIKnowledgeTopK              int                 `json:"ai_knowledge_top_k"`
+	AIMaxToolIterations          int                 `json:"ai_max_tool_iterations"`
+	AIIntentRoutingEnabled       bool                `json:"ai_intent_routing_enabled"`
+	AIIntentMethod               models.IntentMethod `json:"ai_intent_method"`
+	AIIntentThreshold            float64             `json:"ai_intent_threshold"`
+	AISummariesEnabled           bool                `json:"ai_summaries_enabled"`
+	AIReplySuggestionsEnabled    bool                `json:"ai_reply_suggestions_enabled"`
+	AIRedactionEnabled           bool                `json:"ai_redaction_enabled"`
+	AIRedactionTypes             []string            `json:"ai_redaction_types"`
+	AIRedactionPatterns          []redact.Pattern    `json:"ai_redaction_patterns"`
 	// SLA Settings
 	SLAEnabled             bool     `json:"sla_enabled"`
 	SLAResponseMinutes     int      `json:"sla_response_minutes"`
@@ -70,6 +88,9 @@ type KeywordRuleResponse struct {
 	ResponseContent json.RawMessage     `json:"response_content"`
 	Priority        int                 `json:"priority"`
 	Enabled         bool                `json:"enabled"`
+	Conditions      string              `json:"conditions,omitempty"`
+	ActiveFrom      *time.Time          `json:"active_from,omitempty"`
+	ActiveUntil     *time.Time          `json:"active_until,omitempty"`
 	CreatedByName   string              `json:"created_by_name,omitempty"`
 	UpdatedByName   string              `json:"updated_by_name,omitempty"`
 	CreatedAt       string              `json:"created_at"`
@@ -144,6 +165,13 @@ func (a *App) GetChatbotSettings(r *fastglue.Request) error {
 		}
 	}
 
+	interruptRules := make([]map[string]any, 0)
+	for _, rule := range settings.InterruptRules {
+		if ruleMap, ok := rule.(map[string]any); ok {
+			interruptRules = append(interruptRules, ruleMap)
+		}
+	}
+
 	// Convert business hours array
 	businessHours := make([]map[string]any, 0)
 	if settings.BusinessHours.Hours != nil {
@@ -161,6 +189,7 @@ func (a *App) GetChatbotSettings(r *fastglue.Request) error {
 		FallbackMessage:       settings.FallbackMessage,
 		FallbackButtons:       fallbackButtons,
 		SessionTimeoutMinutes: settings.SessionTimeoutMins,
+		InterruptRules:        interruptRules,
 		// Business Hours
 		BusinessHoursEnabled:       settings.BusinessHours.Enabled,
 		BusinessHours:              businessHours,
@@ -171,11 +200,24 @@ func (a *App) GetChatbotSettings(r *fastglue.Request) error {
 		AssignToSameAgent:            settings.AgentAssignment.AssignToSameAgent,
 		AgentCurrentConversationOnly: settings.AgentAssignment.CurrentConversationOnly,
 		// AI
-		AIEnabled:      settings.AI.Enabled,
-		AIProvider:     settings.AI.Provider,
-		AIModel:        settings.AI.Model,
-		AIMaxTokens:    settings.AI.MaxTokens,
-		AISystemPrompt: settings.AI.SystemPrompt,
+		AIEnabled:                 settings.AI.Enabled,
+		AIProvider:                settings.AI.Provider,
+		AIBaseURL:                 settings.AI.BaseURL,
+		AIHeaders:                 maskAIHeaders(settings.AI.Headers),
+		AIModel:                   settings.AI.Model,
+		AIMaxTokens:               settings.AI.MaxTokens,
+		AISystemPrompt:            settings.AI.SystemPrompt,
+		AIEmbeddingModel:          settings.AI.EmbeddingModel,
+		AIKnowledgeTopK:           settings.AI.KnowledgeTopK,
+		AIMaxToolIterations:       settings.AI.MaxToolIterations,
+		AIIntentRoutingEnabled:    settings.AI.IntentRoutingEnabled,
+		AIIntentMethod:            intentMethodOrDefault(settings.AI.IntentMethod),
+		AIIntentThreshold:         settings.AI.IntentThreshold,
+		AISummariesEnabled:        settings.AI.SummariesEnabled,
+		AIReplySuggestionsEnabled: settings.AI.ReplySuggestionsEnabled,
+		AIRedactionEnabled:        settings.AI.RedactionEnabled,
+		AIRedactionTypes:          append([]string{}, settings.AI.RedactionTypes...),
+		AIRedactionPatterns:       redactionPatternsFromJSONB(settings.AI.RedactionPatterns),
 		// SLA Settings
 		SLAEnabled:             settings.SLA.Enabled,
 		SLAResponseMinutes:     settings.SLA.ResponseMinutes,
@@ -209,6 +251,7 @@ func chatbotMessagesSnapshot(s *models.ChatbotSettings) map[string]any {
 		"fallback_message":        s.FallbackMessage,
 		"fallback_buttons":        s.FallbackButtons,
 		"session_timeout_minutes": s.SessionTimeoutMins,
+		"interrupt_rules":         s.InterruptRules,
 	}
 }
 
@@ -252,15 +295,33 @@ func chatbotSLASnapshot(s *models.ChatbotSettings) map[string]any {
 }
 
 // chatbotAISnapshot captures the fields shown on the Chatbot "AI" tab.
-// The API key is intentionally excluded — it's a secret, not a user-facing
-// change the activity log should surface.
+// The API key and header values are intentionally excluded — they're
+// secrets, not user-facing changes the activity log should surface.
 func chatbotAISnapshot(s *models.ChatbotSettings) map[string]any {
+	headerNames := make([]string, 0, len(s.AI.Headers))
+	for k := range s.AI.Headers {
+		headerNames = append(headerNames, k)
+	}
+	sort.Strings(headerNames)
 	return map[string]any{
-		"ai_enabled":       s.AI.Enabled,
-		"ai_provider":      s.AI.Provider,
-		"ai_model":         s.AI.Model,
-		"ai_max_tokens":    s.AI.MaxTokens,
-		"ai_system_prompt": s.AI.SystemPrompt,
+		"ai_enabled":                   s.AI.Enabled,
+		"ai_provider":                  s.AI.Provider,
+		"ai_base_url":                  s.AI.BaseURL,
+		"ai_headers":                   strings.Join(headerNames, ", "),
+		"ai_model":                     s.AI.Model,
+		"ai_max_tokens":                s.AI.MaxTokens,
+		"ai_system_prompt":             s.AI.SystemPrompt,
+		"ai_embedding_model":           s.AI.EmbeddingModel,
+		"ai_knowledge_top_k":           s.AI.KnowledgeTopK,
+		"ai_max_tool_iterations":       s.AI.MaxToolIterations,
+		"ai_intent_routing_enabled":    s.AI.IntentRoutingEnabled,
+		"ai_intent_method":             s.AI.IntentMethod,
+		"ai_intent_threshold":          s.AI.IntentThreshold,
+		"ai_summaries_enabled":         s.AI.SummariesEnabled,
+		"ai_reply_suggestions_enabled": s.AI.ReplySuggestionsEnabled,
+		"ai_redaction_enabled":         s.AI.RedactionEnabled,
+		"ai_redaction_types":           strings.Join(s.AI.RedactionTypes, ", "),
+		"ai_redaction_patterns":        len(s.AI.RedactionPatterns),
 	}
 }
 
@@ -271,25 +332,39 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	}
 
 	var req struct {
-		Enabled                      *bool              `json:"enabled"`
-		GreetingMessage              *string            `json:"greeting_message"`
-		GreetingButtons              *[]map[string]any  `json:"greeting_buttons"`
-		FallbackMessage              *string            `json:"fallback_message"`
-		FallbackButtons              *[]map[string]any  `json:"fallback_buttons"`
-		SessionTimeoutMinutes        *int               `json:"session_timeout_minutes"`
-		BusinessHoursEnabled         *bool              `json:"business_hours_enabled"`
-		BusinessHours                *[]map[string]any  `json:"business_hours"`
-		OutOfHoursMessage            *string            `json:"out_of_hours_message"`
-		AllowAutomatedOutsideHours   *bool              `json:"allow_automated_outside_hours"`
-		AllowAgentQueuePickup        *bool              `json:"allow_agent_queue_pickup"`
-		AssignToSameAgent            *bool              `json:"assign_to_same_agent"`
-		AgentCurrentConversationOnly *bool              `json:"agent_current_conversation_only"`
-		AIEnabled                    *bool              `json:"ai_enabled"`
-		AIProvider                   *models.AIProvider `json:"ai_provider"`
-		AIAPIKey                     *string            `json:"ai_api_key"`
-		AIModel                      *string            `json:"ai_model"`
-		AIMaxTokens                  *int               `json:"ai_max_tokens"`
-		AISystemPrompt               *string            `json:"ai_system_prompt"`
+		Enabled                      *bool                `json:"enabled"`
+		GreetingMessage              *string              `json:"greeting_message"`
+		GreetingButtons              *[]map[string]any    `json:"greeting_buttons"`
+		FallbackMessage              *string              `json:"fallback_message"`
+		FallbackButtons              *[]map[string]any    `json:"fallback_buttons"`
+		SessionTimeoutMinutes        *int                 `json:"session_timeout_minutes"`
+		InterruptRules               *[]map[string]any    `json:"interrupt_rules"`
+		BusinessHoursEnabled         *bool                `json:"business_hours_enabled"`
+		BusinessHours                *[]map[string]any    `json:"business_hours"`
+		OutOfHoursMessage            *string              `json:"out_of_hours_message"`
+		AllowAutomatedOutsideHours   *bool                `json:"allow_automated_outside_hours"`
+		AllowAgentQueuePickup        *bool                `json:"allow_agent_queue_pickup"`
+		AssignToSameAgent            *bool                `json:"assign_to_same_agent"`
+		AgentCurrentConversationOnly *bool                `json:"agent_current_conversation_only"`
+		AIEnabled                    *bool                `json:"ai_enabled"`
+		AIProvider                   *models.AIProvider   `json:"ai_provider"`
+		AIAPIKey                     *string              `json:"ai_api_key"`
+		AIBaseURL                    *string              `json:"ai_base_url"`
+		AIHeaders                    *map[string]string   `json:"ai_headers"`
+		AIModel                      *string              `json:"ai_model"`
+		AIMaxTokens                  *int                 `json:"ai_max_tokens"`
+		AISystemPrompt               *string              `json:"ai_system_prompt"`
+		AIEmbeddingModel             *string              `json:"ai_embedding_model"`
+		AIKnowledgeTopK              *int                 `json:"ai_knowledge_top_k"`
+		AIMaxToolIterations          *int                 `json:"ai_max_tool_iterations"`
+		AIIntentRoutingEnabled       *bool                `json:"ai_intent_routing_enabled"`
+		AIIntentMethod               *models.IntentMethod `json:"ai_intent_method"`
+		AIIntentThreshold            *float64             `json:"ai_intent_threshold"`
+		AISummariesEnabled           *bool                `json:"ai_summaries_enabled"`
+		AIReplySuggestionsEnabled    *bool                `json:"ai_reply_suggestions_enabled"`
+		AIRedactionEnabled           *bool                `json:"ai_redaction_enabled"`
+		AIRedactionTypes             *[]string            `json:"ai_redaction_types"`
+		AIRedactionPatterns          *[]redact.Pattern    `json:"ai_redaction_patterns"`
 		// SLA Settings
 		SLAEnabled             *bool     `json:"sla_enabled"`
 		SLAResponseMinutes     *int      `json:"sla_response_minutes"`
@@ -336,7 +411,8 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	// for tabs the user actually submitted.
 	messagesTouched := req.Enabled != nil || req.GreetingMessage != nil ||
 		req.GreetingButtons != nil || req.FallbackMessage != nil ||
-		req.FallbackButtons != nil || req.SessionTimeoutMinutes != nil
+		req.FallbackButtons != nil || req.SessionTimeoutMinutes != nil ||
+		req.InterruptRules != nil
 	agentsTouched := req.AllowAgentQueuePickup != nil || req.AssignToSameAgent != nil ||
 		req.AgentCurrentConversationOnly != nil
 	hoursTouched := req.BusinessHoursEnabled != nil || req.BusinessHours != nil ||
@@ -349,7 +425,12 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 		req.ClientReminderMessage != nil || req.ClientAutoCloseMinutes != nil ||
 		req.ClientAutoCloseMessage != nil
 	aiTouched := req.AIEnabled != nil || req.AIProvider != nil || req.AIAPIKey != nil ||
-		req.AIModel != nil || req.AIMaxTokens != nil || req.AISystemPrompt != nil
+		req.AIBaseURL != nil || req.AIHeaders != nil ||
+		req.AIModel != nil || req.AIMaxTokens != nil || req.AISystemPrompt != nil ||
+		req.AIEmbeddingModel != nil || req.AIKnowledgeTopK != nil || req.AIMaxToolIterations != nil ||
+		req.AIIntentRoutingEnabled != nil || req.AIIntentMethod != nil || req.AIIntentThreshold != nil ||
+		req.AISummariesEnabled != nil || req.AIReplySuggestionsEnabled != nil ||
+		req.AIRedactionEnabled != nil || req.AIRedactionTypes != nil || req.AIRedactionPatterns != nil
 
 	// Update fields if provided
 	if req.Enabled != nil {
@@ -378,6 +459,13 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	if req.SessionTimeoutMinutes != nil {
 		settings.SessionTimeoutMins = *req.SessionTimeoutMinutes
 	}
+	if req.InterruptRules != nil {
+		rules, err := validateChatInterruptRules(*req.InterruptRules, false)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		settings.InterruptRules = rules
+	}
 	// Business Hours
 	if req.BusinessHoursEnabled != nil {
 		settings.BusinessHours.Enabled = *req.BusinessHoursEnabled
@@ -417,6 +505,26 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	if req.AIAPIKey != nil && *req.AIAPIKey != "" {
 		settings.AI.APIKey = *req.AIAPIKey
 	}
+	if req.AIBaseURL != nil {
+		baseURL := strings.TrimSpace(*req.AIBaseURL)
+		if baseURL != "" {
+			if err := a.validateAIBaseURL(baseURL); err != nil {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+			}
+		}
+		settings.AI.BaseURL = baseURL
+	}
+	if req.AIHeaders != nil {
+		settings.AI.Headers = mergeAIHeaders(settings.AI.Headers, *req.AIHeaders)
+	}
+	if err := settings.AI.EncryptSecrets(a.Config.App.EncryptionKey); err != nil {
+		a.Log.Error("Failed to encrypt AI secrets", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save settings", nil, "")
+	}
+	if aiTouched && settings.AI.Enabled &&
+		settings.AI.Provider == models.AIProviderOpenAICompatible && settings.AI.BaseURL == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Base URL is required for the OpenAI-compatible provider", nil, "")
+	}
 	if req.AIModel != nil {
 		settings.AI.Model = *req.AIModel
 	}
@@ -426,6 +534,66 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	if req.AISystemPrompt != nil {
 		settings.AI.SystemPrompt = *req.AISystemPrompt
 	}
+	if req.AIEmbeddingModel != nil {
+		settings.AI.EmbeddingModel = strings.TrimSpace(*req.AIEmbeddingModel)
+	}
+	if req.AIKnowledgeTopK != nil {
+		if *req.AIKnowledgeTopK < 0 || *req.AIKnowledgeTopK > 20 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "ai_knowledge_top_k must be between 0 and 20", nil, "")
+		}
+		settings.AI.KnowledgeTopK = *req.AIKnowledgeTopK
+	}
+	if req.AIMaxToolIterations != nil {
+		if *req.AIMaxToolIterations < 0 || *req.AIMaxToolIterations > maxAIToolIterations {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+				fmt.Sprintf("ai_max_tool_iterations must be between 0 and %d", maxAIToolIterations), nil, "")
+		}
+		settings.AI.MaxToolIterations = *req.AIMaxToolIterations
+	}
+	if req.AIIntentRoutingEnabled != nil {
+		settings.AI.IntentRoutingEnabled = *req.AIIntentRoutingEnabled
+	}
+	if req.AIIntentMethod != nil {
+		if *req.AIIntentMethod != models.IntentMethodLLM && *req.AIIntentMethod != models.IntentMethodEmbedding {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "ai_intent_method must be llm or embedding", nil, "")
+		}
+		settings.AI.IntentMethod = *req.AIIntentMethod
+	}
+	if req.AIIntentThreshold != nil {
+		if *req.AIIntentThreshold < 0 || *req.AIIntentThreshold > 1 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "ai_intent_threshold must be between 0 and 1", nil, "")
+		}
+		settings.AI.IntentThreshold = *req.AIIntentThreshold
+	}
+	if req.AISummariesEnabled != nil {
+		settings.AI.SummariesEnabled = *req.AISummariesEnabled
+	}
+	if req.AIReplySuggestionsEnabled != nil {
+		settings.AI.ReplySuggestionsEnabled = *req.AIReplySuggestionsEnabled
+	}
+	if req.AIRedactionEnabled != nil {
+		settings.AI.RedactionEnabled = *req.AIRedactionEnabled
+	}
+	if req.AIRedactionTypes != nil {
+		for _, t := range *req.AIRedactionTypes {
+			if !redact.IsDetector(t) {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Unknown redaction type %q", t), nil, "")
+			}
+		}
+		settings.AI.RedactionTypes = models.StringArray(*req.AIRedactionTypes)
+	}
+	if req.AIRedactionPatterns != nil {
+		for _, p := range *req.AIRedactionPatterns {
+			if err := redact.ValidatePattern(p); err != nil {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid redaction pattern: "+err.Error(), nil, "")
+			}
+		}
+		settings.AI.RedactionPatterns = redactionPatternsToJSONB(*req.AIRedactionPatterns)
+	}
+	if aiTouched && settings.AI.IntentRoutingEnabled &&
+		settings.AI.IntentMethod == models.IntentMethodEmbedding && settings.AI.EmbeddingModel == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Embedding intent classification requires an embedding model", nil, "")
+	}
 
 	// SLA Settings
 	if req.SLAEnabled != nil {
@@ -576,6 +744,9 @@ func (a *App) ListKeywordRules(r *fastglue.Request) error {
 			ResponseContent: responseContent,
 			Priority:        rule.Priority,
 			Enabled:         rule.IsEnabled,
+			Conditions:      rule.Conditions,
+			ActiveFrom:      rule.ActiveFrom,
+			ActiveUntil:     rule.ActiveUntil,
 			CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
 			UpdatedAt:       rule.UpdatedAt.Format(time.RFC3339),
 		}
@@ -606,6 +777,9 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 		ResponseContent map[string]any      `json:"response_content"`
 		Priority        int                 `json:"priority"`
 		Enabled         bool                `json:"enabled"`
+		Conditions      string              `json:"conditions"`
+		ActiveFrom      *string             `json:"active_from"`
+		ActiveUntil     *string             `json:"active_until"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -637,9 +811,19 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 		ResponseContent: models.JSONB(req.ResponseContent),
 		Priority:        req.Priority,
 		IsEnabled:       req.Enabled,
+		Conditions:      strings.TrimSpace(req.Conditions),
 		CreatedByID:     &userID,
 		UpdatedByID:     &userID,
 	}
+	if rule.ActiveFrom, err = parseOptionalRFC3339(req.ActiveFrom); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid active_from format. Use RFC3339 format", nil, "")
+	}
+	if rule.ActiveUntil, err = parseOptionalRFC3339(req.ActiveUntil); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid active_until format. Use RFC3339 format", nil, "")
+	}
+	if err := a.validateKeywordRule(&rule); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	if err := a.DB.Create(&rule).Error; err != nil {
 		a.Log.Error("Failed to create keyword rule", "error", err)
@@ -686,6 +870,9 @@ func (a *App) GetKeywordRule(r *fastglue.Request) error {
 		ResponseContent: responseContent,
 		Priority:        rule.Priority,
 		Enabled:         rule.IsEnabled,
+		Conditions:      rule.Conditions,
+		ActiveFrom:      rule.ActiveFrom,
+		ActiveUntil:     rule.ActiveUntil,
 		CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:       rule.UpdatedAt.Format(time.RFC3339),
 	}
@@ -727,6 +914,9 @@ func (a *App) UpdateKeywordRule(r *fastglue.Request) error {
 		ResponseContent map[string]any       `json:"response_content"`
 		Priority        *int                 `json:"priority"`
 		Enabled         *bool                `json:"enabled"`
+		Conditions      *string              `json:"conditions"`
+		ActiveFrom      *string              `json:"active_from"`  // "" clears
+		ActiveUntil     *string              `json:"active_until"` // "" clears
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -755,6 +945,22 @@ func (a *App) UpdateKeywordRule(r *fastglue.Request) error {
 	if req.Enabled != nil {
 		rule.IsEnabled = *req.Enabled
 	}
+	if req.Conditions != nil {
+		rule.Conditions = strings.TrimSpace(*req.Conditions)
+	}
+	if req.ActiveFrom != nil {
+		if rule.ActiveFrom, err = parseOptionalRFC3339(req.ActiveFrom); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid active_from format. Use RFC3339 format", nil, "")
+		}
+	}
+	if req.ActiveUntil != nil {
+		if rule.ActiveUntil, err = parseOptionalRFC3339(req.ActiveUntil); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid active_until format. Use RFC3339 format", nil, "")
+		}
+	}
+	if err := a.validateKeywordRule(rule); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 	rule.UpdatedByID = &userID
 
 	if err := a.DB.Save(rule).Error; err != nil {
@@ -864,16 +1070,17 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 	}
 
 	var req struct {
-		Name              string         `json:"name"`
-		Description       string         `json:"description"`
-		TriggerKeywords   []string       `json:"trigger_keywords"`
-		InitialMessage    string         `json:"initial_message"`
-		CompletionMessage string         `json:"completion_message"`
-		OnCompleteAction  string         `json:"on_complete_action"`
-		CompletionConfig  map[string]any `json:"completion_config"`
-		PanelConfig       map[string]any `json:"panel_config"`
-		Graph             map[string]any `json:"graph"`
-		Enabled           bool           `json:"enabled"`
+		Name              string           `json:"name"`
+		Description       string           `json:"description"`
+		TriggerKeywords   []string         `json:"trigger_keywords"`
+		InitialMessage    string           `json:"initial_message"`
+		CompletionMessage string           `json:"completion_message"`
+		OnCompleteAction  string           `json:"on_complete_action"`
+		CompletionConfig  map[string]any   `json:"completion_config"`
+		PanelConfig       map[string]any   `json:"panel_config"`
+		InterruptRules    []map[string]any `json:"interrupt_rules"`
+		Graph             map[string]any   `json:"graph"`
+		Enabled           bool             `json:"enabled"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -883,6 +1090,13 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 	if req.Name == "" {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Name is required", nil, "")
 	}
+	interruptRules, err := validateChatInterruptRules(req.InterruptRules, true)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := a.validateChatGraphMedia(orgID, req.Graph); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	flow := models.ChatbotFlow{
 		BaseModel:         models.BaseModel{ID: uuid.New()},
@@ -895,6 +1109,7 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 		OnCompleteAction:  req.OnCompleteAction,
 		CompletionConfig:  models.JSONB(req.CompletionConfig),
 		PanelConfig:       models.JSONB(req.PanelConfig),
+		InterruptRules:    interruptRules,
 		Graph:             models.JSONB(req.Graph),
 		IsEnabled:         req.Enabled,
 		CreatedByID:       &userID,
@@ -968,16 +1183,17 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 	oldFlow := *flow // value copy for audit
 
 	var req struct {
-		Name              *string        `json:"name"`
-		Description       *string        `json:"description"`
-		TriggerKeywords   []string       `json:"trigger_keywords"`
-		InitialMessage    *string        `json:"initial_message"`
-		CompletionMessage *string        `json:"completion_message"`
-		OnCompleteAction  *string        `json:"on_complete_action"`
-		CompletionConfig  map[string]any `json:"completion_config"`
-		PanelConfig       map[string]any `json:"panel_config"`
-		Graph             map[string]any `json:"graph"`
-		Enabled           *bool          `json:"enabled"`
+		Name              *string           `json:"name"`
+		Description       *string           `json:"description"`
+		TriggerKeywords   []string          `json:"trigger_keywords"`
+		InitialMessage    *string           `json:"initial_message"`
+		CompletionMessage *string           `json:"completion_message"`
+		OnCompleteAction  *string           `json:"on_complete_action"`
+		CompletionConfig  map[string]any    `json:"completion_config"`
+		PanelConfig       map[string]any    `json:"panel_config"`
+		InterruptRules    *[]map[string]any `json:"interrupt_rules"`
+		Graph             map[string]any    `json:"graph"`
+		Enabled           *bool             `json:"enabled"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -1008,7 +1224,17 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 	if req.PanelConfig != nil {
 		flow.PanelConfig = models.JSONB(req.PanelConfig)
 	}
+	if req.InterruptRules != nil {
+		rules, err := validateChatInterruptRules(*req.InterruptRules, true)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		flow.InterruptRules = rules
+	}
 	if req.Graph != nil {
+		if err := a.validateChatGraphMedia(orgID, req.Graph); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
 		flow.Graph = models.JSONB(req.Graph)
 	}
 	if req.Enabled != nil {
@@ -1412,7 +1638,7 @@ func (a *App) getChatbotStats(orgID uuid.UUID) ChatbotStatsResponse {
 	// Messages handled (from chatbot_session_messages)
 	a.DB.Model(&models.ChatbotSessionMessage{}).
 		Joins("JOIN chatbot_sessions ON chatbot_sessions.id = chatbot_session_messages.session_id").
-		Where("chatbot_sessions.organization_id = ?", orgID).
+		Where("chatbot_sessions.organization_id = ? AND COALESCE(chatbot_session_messages.tool_name, '') = ''", orgID).
 		Count(&stats.MessagesHandled)
 
 	// Agent transfers
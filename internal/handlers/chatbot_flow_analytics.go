@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// ChatbotFlowAnalyticsResponse summarizes a flow's sessions over a period,
+// with a per-variant breakdown for each split node.
+type ChatbotFlowAnalyticsResponse struct {
+	From      string                  `json:"from"`
+	To        string                  `json:"to"`
+	Sessions  int64                   `json:"sessions"`
+	Completed int64                   `json:"completed"`
+	Splits    []ChatbotSplitAnalytics `json:"splits"`
+}
+
+// ChatbotSplitAnalytics is the outcome of one split node.
+type ChatbotSplitAnalytics struct {
+	NodeID   string                    `json:"node_id"`
+	Label    string                    `json:"label"`
+	GoalNode string                    `json:"goal_node,omitempty"`
+	Variants []ChatbotVariantAnalytics `json:"variants"`
+}
+
+// ChatbotVariantAnalytics counts the sessions assigned to one variant.
+// Converted is only tracked when the split node has a goal_node.
+type ChatbotVariantAnalytics struct {
+	Variant        string  `json:"variant"`
+	Label          string  `json:"label"`
+	Weight         int     `json:"weight"`
+	Sessions       int64   `json:"sessions"`
+	Completed      int64   `json:"completed"`
+	Converted      int64   `json:"converted"`
+	CompletionRate float64 `json:"completion_rate"` // completed / sessions, percent
+	ConversionRate float64 `json:"conversion_rate"` // converted / sessions, percent
+}
+
+// GetChatbotFlowAnalytics reports session completion for a flow and how
+// each variant of its split nodes performs. Defaults to the last 30 days;
+// from/to (YYYY-MM-DD) select another period by session start.
+func (a *App) GetChatbotFlowAnalytics(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceFlowsChatbot, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, id, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	periodEnd := time.Now()
+	periodStart := periodEnd.AddDate(0, 0, -30)
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+	if fromStr != "" && toStr != "" {
+		start, end, errMsg := parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+		periodStart, periodEnd = start, end
+	}
+
+	flowID := flow.ID.String()
+	scope := func() *gorm.DB {
+		return a.DB.Model(&models.ChatbotSession{}).
+			Where("organization_id = ? AND started_at >= ? AND started_at <= ?", orgID, periodStart, periodEnd)
+	}
+
+	resp := ChatbotFlowAnalyticsResponse{
+		From:   periodStart.Format("2006-01-02"),
+		To:     periodEnd.Format("2006-01-02"),
+		Splits: []ChatbotSplitAnalytics{},
+	}
+	var totals struct {
+		Sessions  int64
+		Completed int64
+	}
+	// A session counts for the flow it started in, the one it's in, and
+	// flows whose splits it went through in a call_flow
+	if err := scope().
+		Select("COUNT(*) AS sessions, COUNT(*) FILTER (WHERE status = ?) AS completed", models.SessionStatusCompleted).
+		Where("current_flow_id = ? OR session_data->>'_flow_id' = ? OR jsonb_exists(session_data->'__splits__', ?)",
+			flow.ID, flowID, flowID).
+		Scan(&totals).Error; err != nil {
+		a.Log.Error("Failed to load flow analytics", "error", err, "flow", flow.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load flow analytics", nil, "")
+	}
+	resp.Sessions, resp.Completed = totals.Sessions, totals.Completed
+
+	graph, err := parseChatGraph(flow.Graph)
+	if err != nil || graph == nil {
+		return r.SendEnvelope(resp)
+	}
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Type != ChatNodeSplit {
+			continue
+		}
+		split, err := a.chatSplitAnalytics(scope(), flowID, node)
+		if err != nil {
+			a.Log.Error("Failed to load split analytics", "error", err, "flow", flow.ID, "node", node.ID)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load flow analytics", nil, "")
+		}
+		resp.Splits = append(resp.Splits, split)
+	}
+	return r.SendEnvelope(resp)
+}
+
+// chatSplitAnalytics counts sessions per variant of a split node. Variants
+// that no longer exist on the node are still reported so history isn't
+// hidden after an edit.
+func (a *App) chatSplitAnalytics(scope *gorm.DB, flowID string, node *ChatNode) (ChatbotSplitAnalytics, error) {
+	split := ChatbotSplitAnalytics{
+		NodeID:   node.ID,
+		Label:    node.Label,
+		GoalNode: stringFromConfig(node.Config, "goal_node"),
+		Variants: []ChatbotVariantAnalytics{},
+	}
+
+	// The goal must be reached in this flow, not at a node of a called
+	// flow with the same ID. Paths from before entries carried flow_id
+	// only count when the session never called another flow.
+	converted := "0"
+	args := []any{flowID, node.ID, models.SessionStatusCompleted}
+	if split.GoalNode != "" {
+		goal, _ := json.Marshal([]map[string]string{{"node": split.GoalNode, "flow_id": flowID}})
+		legacyGoal, _ := json.Marshal([]map[string]string{{"node": split.GoalNode}})
+		converted = "COUNT(*) FILTER (WHERE session_data->'__path__' @> ?::jsonb OR " +
+			"(session_data->'__path__' @> ?::jsonb AND NOT jsonb_path_exists(session_data->'__path__', '$[*].flow_id') " +
+			"AND NOT session_data->'__path__' @> '[{\"action\": \"call_flow\"}]'::jsonb))"
+		args = append(args, string(goal), string(legacyGoal))
+	}
+	var rows []struct {
+		Variant   string
+		Sessions  int64
+		Completed int64
+		Converted int64
+	}
+	if err := scope.
+		Select("COALESCE(session_data->'__splits__'->?::text->?::text->>'variant', "+
+			"session_data->'__splits__'->?::text->>?::text) AS variant, COUNT(*) AS sessions, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS completed, "+converted+" AS converted",
+			append([]any{flowID, node.ID}, args...)...).
+		Where("session_data->'__splits__'->?::text->>?::text IS NOT NULL", flowID, node.ID).
+		Group("variant").
+		Scan(&rows).Error; err != nil {
+		return split, err
+	}
+
+	byVariant := make(map[string]int, len(rows))
+	for i, row := range rows {
+		byVariant[row.Variant] = i
+	}
+	add := func(id, label string, weight int) {
+		v := ChatbotVariantAnalytics{Variant: id, Label: label, Weight: weight}
+		if i, ok := byVariant[id]; ok {
+			row := rows[i]
+			v.Sessions, v.Completed, v.Converted = row.Sessions, row.Completed, row.Converted
+			delete(byVariant, id)
+		}
+		if v.Sessions > 0 {
+			v.CompletionRate = float64(v.Completed) / float64(v.Sessions) * 100
+			v.ConversionRate = float64(v.Converted) / float64(v.Sessions) * 100
+		}
+		split.Variants = append(split.Variants, v)
+	}
+	for _, v := range chatSplitVariants(node.Config) {
+		add(v.ID, v.Label, v.Weight)
+	}
+	for _, row := range rows {
+		if _, ok := byVariant[row.Variant]; ok {
+			add(row.Variant, "", 0)
+		}
+	}
+	return split, nil
+}
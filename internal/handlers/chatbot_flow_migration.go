@@ -171,7 +171,7 @@ func stepsToGraph(steps []models.ChatbotFlowStep, canvasLayout models.JSONB) mod
 // with the TS V2_SUPPORTED_MESSAGE_TYPES set.
 func v2SupportedMessageType(mt models.FlowStepType) bool {
 	switch string(mt) {
-	case "text", "buttons", "end", "condition", "timing", "goto_flow", "api_fetch", "whatsapp_flow", "transfer":
+	case "text", "template", "buttons", "end", "condition", "timing", "goto_flow", "api_fetch", "whatsapp_flow", "transfer":
 		return true
 	}
 	return false
@@ -208,6 +208,8 @@ func messageTypeToNodeTypeGo(messageType string) string {
 		return "whatsapp_flow"
 	case "transfer":
 		return "transfer"
+	case "template":
+		return "template"
 	}
 	return messageType
 }
@@ -254,6 +256,10 @@ func buildNodeConfig(nodeType string, step *models.ChatbotFlowStep) map[string]a
 		if step.MaxRetries > 0 {
 			config["max_retries"] = step.MaxRetries
 		}
+	case "template":
+		if step.TemplateID != nil {
+			config["template_id"] = step.TemplateID.String()
+		}
 	case "buttons":
 		config["body"] = step.Message
 		config["buttons"] = jsonbArrayToSlice(step.Buttons)
@@ -357,7 +363,7 @@ func buildEdges(sorted []models.ChatbotFlowStep, stepNames map[string]struct{})
 		case "transfer", "end", "goto_flow":
 			// Terminal — no outgoing edges.
 		default:
-			// message / api_fetch / whatsapp_flow — sequential fallthrough.
+			// message / template / api_fetch / whatsapp_flow — sequential fallthrough.
 			target := step.NextStep
 			if target == "" {
 				target = nextSequential
@@ -303,3 +303,18 @@ func TestBackfillChatbotFlowGraph_FillsNullGraphsAndLeavesOthersAlone(t *testing
 }
 
 var _ = testutil.NopLogger // silence unused-import warning when read in isolation
+
+func TestStepsToGraph_TemplateStepKeepsTemplateID(t *testing.T) {
+	tplID := uuid.New()
+	steps := []models.ChatbotFlowStep{
+		{StepName: "t1", StepOrder: 1, MessageType: "template", TemplateID: &tplID},
+		{StepName: "t2", StepOrder: 2, MessageType: "text", Message: "Thanks"},
+	}
+	g := stepsToGraph(steps, nil)
+	nodes := graphNodes(t, g)
+	require.Len(t, nodes, 3)
+	assert.Equal(t, "template", nodes[1]["type"])
+	cfg, _ := nodes[1]["config"].(map[string]any)
+	assert.Equal(t, tplID.String(), cfg["template_id"])
+	assert.Contains(t, graphEdges(t, g), map[string]any{"from": "t1", "to": "t2", "condition": "default"})
+}
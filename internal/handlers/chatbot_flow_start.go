@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// StartChatbotFlowRequest starts a flow for a phone number from an
+// external system, e.g. with an API key.
+type StartChatbotFlowRequest struct {
+	PhoneNumber string       `json:"phone_number"`
+	AccountName string       `json:"account_name"`
+	Variables   models.JSONB `json:"variables"`
+	// Template opens the conversation when the customer service window is
+	// closed: { "template_id": "<uuid>", "params": { "1": "{{order_id}}" } }.
+	// Defaults to the flow's initial template.
+	Template models.JSONB `json:"template"`
+	// Force cancels the contact's active session instead of rejecting.
+	Force bool `json:"force"`
+}
+
+// Start statuses returned by StartChatbotFlow.
+const (
+	flowStartStarted       = "started"
+	flowStartAwaitingReply = "awaiting_reply"
+)
+
+// StartChatbotFlow starts a chatbot flow for a contact without waiting for
+// an inbound message. Variables seed the session. If the customer service
+// window is open the flow runs immediately; otherwise the opening template
+// is sent and the flow runs from its entry node if the contact replies
+// within 24 hours, however short the session timeout.
+// A contact with an active session is rejected with 409 unless force is
+// set.
+func (a *App) StartChatbotFlow(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceFlowsChatbot, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	var req StartChatbotFlowRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+	if req.PhoneNumber == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "phone_number is required", nil, "")
+	}
+	for key := range req.Variables {
+		if key == "" || strings.HasPrefix(key, "_") {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid variable name: "+key, nil, "")
+		}
+	}
+
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, id, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+	if !flow.IsEnabled {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Flow is disabled", nil, "")
+	}
+	if flow.Graph == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Flow has no graph", nil, "")
+	}
+
+	accountName := req.AccountName
+	if accountName == "" {
+		accountName = flow.WhatsAppAccount
+	}
+	account, err := a.resolveWhatsAppAccount(orgID, accountName)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	settings, err := a.getChatbotSettingsCached(orgID, account.Name)
+	if err != nil {
+		a.Log.Error("Failed to load chatbot settings", "error", err, "account", account.Name)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot settings", nil, "")
+	}
+	if !settings.IsEnabled {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Chatbot is disabled for this account", nil, "")
+	}
+
+	contact, _, err := contactutil.GetOrCreateContact(a.DB, orgID, req.PhoneNumber, "")
+	if err != nil {
+		a.Log.Error("Failed to get or create contact", "error", err, "phone", req.PhoneNumber)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact", nil, "")
+	}
+	if a.hasActiveAgentTransfer(orgID, contact.ID) {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact is being handled by an agent", nil, "")
+	}
+
+	if existing, err := a.findActiveSession(orgID, contact.ID, account.Name, settings.SessionTimeoutMins); err == nil {
+		if !req.Force {
+			return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact already has an active chatbot session", map[string]any{
+				"session_id": existing.ID,
+			}, "")
+		}
+		a.cancelChatWait(existing)
+		existing.Status = models.SessionStatusCancelled
+		if err := a.persistChatSession(existing); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to cancel active session", nil, "")
+		}
+	}
+
+	template := req.Template
+	if len(template) == 0 && flow.InitialTemplateID != nil {
+		template = models.JSONB{"template_id": flow.InitialTemplateID.String()}
+	}
+	windowOpen := chatServiceWindowOpen(contact, time.Now())
+	if !windowOpen && len(template) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusUnprocessableEntity,
+			"Customer service window is closed; a template is required to start the flow", nil, "")
+	}
+
+	session, _ := a.getOrCreateSession(orgID, contact.ID, account.Name, contact.PhoneNumber, settings.SessionTimeoutMins)
+	a.enterChatFlow(session, flow)
+	for key, v := range req.Variables {
+		session.SessionData[key] = v
+	}
+	a.logSessionMessage(session.ID, models.DirectionIncoming, "Flow started via API", "api_trigger")
+
+	status := flowStartStarted
+	if windowOpen {
+		if err := a.runChatGraph(account, contact, session, flow, "", "", nil); err != nil {
+			a.Log.Error("Chat graph runner failed at API flow start", "error", err, "session", session.ID, "flow", flow.ID)
+		}
+	} else {
+		sent, err := a.sendChatbotTemplate(account, contact, session, template)
+		if err != nil {
+			a.Log.Error("Failed to send opening template", "error", err, "session", session.ID, "flow", flow.ID)
+			session.Status = models.SessionStatusCancelled
+			_ = a.persistChatSession(session)
+			return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Failed to send template: "+err.Error(), nil, "")
+		}
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, sent, "api_trigger")
+		// Parked at the entry node: the contact's reply opens the window
+		// and runs the flow. The session outlives its timeout until the
+		// template's conversation window would have closed.
+		until := time.Now().Add(customerServiceWindow)
+		session.AwaitingReplyUntil = &until
+		if err := a.persistChatSession(session); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save session", nil, "")
+		}
+		status = flowStartAwaitingReply
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"session_id": session.ID,
+		"contact_id": contact.ID,
+		"flow_id":    flow.ID,
+		"status":     status,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrCreateSession_AwaitingReplyOutlivesTimeout(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "delivery", models.JSONB{
+		"version":    2,
+		"entry_node": "n",
+		"nodes": []any{
+			map[string]any{"id": "n", "type": "set_variable", "config": map[string]any{
+				"set": map[string]any{"summary": "Order {{order_id}}"},
+			}},
+		},
+	})
+	// As left by StartChatbotFlow after sending the opening template.
+	app.enterChatFlow(session, flow)
+	session.SessionData["order_id"] = "42"
+	until := time.Now().Add(customerServiceWindow)
+	session.AwaitingReplyUntil = &until
+	require.NoError(t, app.persistChatSession(session))
+	require.NoError(t, app.DB.Model(session).Update("last_activity_at", time.Now().Add(-2*time.Hour)).Error)
+
+	got, isNew := app.getOrCreateSession(org.ID, contact.ID, account.Name, contact.PhoneNumber, 30)
+	require.False(t, isNew, "the reply after the session timeout resumes the started flow")
+	assert.Equal(t, session.ID, got.ID)
+	assert.Nil(t, got.AwaitingReplyUntil)
+
+	require.NoError(t, app.runChatGraph(account, contact, got, flow, "hi", "", nil))
+	assert.Equal(t, "Order 42", got.SessionData["summary"])
+
+	var stored models.ChatbotSession
+	require.NoError(t, app.DB.First(&stored, session.ID).Error)
+	assert.Nil(t, stored.AwaitingReplyUntil, "normal timeouts apply once the contact replied")
+}
+
+func TestGetOrCreateSession_AwaitingReplyExpires(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	until := time.Now().Add(-time.Minute)
+	session.AwaitingReplyUntil = &until
+	require.NoError(t, app.persistChatSession(session))
+	require.NoError(t, app.DB.Model(session).Update("last_activity_at", time.Now().Add(-25*time.Hour)).Error)
+
+	got, isNew := app.getOrCreateSession(org.ID, contact.ID, account.Name, contact.PhoneNumber, 30)
+	assert.True(t, isNew)
+	assert.NotEqual(t, session.ID, got.ID)
+}
+
+func TestGetOrCreateSession_IgnoresAwaitingReplyVariable(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	// A flow variable that happens to use the old reserved name must not
+	// break the session lookup
+	session.SessionData["__awaiting_reply_until__"] = "not a timestamp"
+	require.NoError(t, app.persistChatSession(session))
+
+	got, isNew := app.getOrCreateSession(org.ID, contact.ID, account.Name, contact.PhoneNumber, 30)
+	assert.False(t, isNew)
+	assert.Equal(t, session.ID, got.ID)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// maxChatCallDepth bounds nested call_flow frames. A sub-flow that calls
+// itself (directly or through others) fails its call node once the stack
+// is this deep instead of growing the session without limit.
+const maxChatCallDepth = 5
+
+// Reserved SessionData keys. These and chatSplitsKey survive a call_flow
+// scope switch so the audit trail, the return stack and split
+// assignments are never lost.
+const (
+	chatPathKey      = "__path__"
+	chatCallStackKey = "__call_stack__"
+)
+
+// chatCallFrame is one pending call_flow return, stored in
+// SessionData["__call_stack__"] so it survives between inbound messages.
+type chatCallFrame struct {
+	FlowID  uuid.UUID         // caller flow
+	NodeID  string            // call_flow node to resume from
+	Outputs map[string]string // caller variable -> sub-flow variable
+	Vars    map[string]any    // caller variables, restored on return
+}
+
+func (f chatCallFrame) toJSONB() map[string]any {
+	outputs := make(map[string]any, len(f.Outputs))
+	for k, v := range f.Outputs {
+		outputs[k] = v
+	}
+	return map[string]any{
+		"flow_id": f.FlowID.String(),
+		"node_id": f.NodeID,
+		"outputs": outputs,
+		"vars":    f.Vars,
+	}
+}
+
+func chatCallFrameFromJSONB(raw any) (chatCallFrame, bool) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return chatCallFrame{}, false
+	}
+	flowIDStr, _ := m["flow_id"].(string)
+	flowID, err := uuid.Parse(flowIDStr)
+	if err != nil {
+		return chatCallFrame{}, false
+	}
+	frame := chatCallFrame{FlowID: flowID, Outputs: map[string]string{}, Vars: map[string]any{}}
+	frame.NodeID, _ = m["node_id"].(string)
+	if outputs, ok := m["outputs"].(map[string]any); ok {
+		for k, v := range outputs {
+			if s, ok := v.(string); ok && s != "" {
+				frame.Outputs[k] = s
+			}
+		}
+	}
+	if vars, ok := m["vars"].(map[string]any); ok {
+		frame.Vars = vars
+	}
+	return frame, true
+}
+
+// chatCallStack returns the raw frames, innermost last.
+func chatCallStack(s *models.ChatbotSession) []any {
+	stack, _ := s.SessionData[chatCallStackKey].([]any)
+	return stack
+}
+
+// execChatCallFlow runs another flow as a subroutine. Unlike goto_flow the
+// caller resumes when the sub-flow finishes: a frame holding the caller's
+// variables is pushed on the session, the sub-flow starts with only the
+// built-in variables and the mapped inputs, and on return the mapped
+// outputs are copied back into the caller's variables.
+//
+// The caller continues on the edge "return:<outcome>", where outcome is
+// the "outcome" configured on the sub-flow's end node, falling back to
+// the default edge. A missing or invalid target, or exceeding
+// maxChatCallDepth, takes the "error" edge; without one the flow ends.
+//
+// Config:
+//
+//	{
+//	  "flow_id": "<uuid>",
+//	  "inputs":  { "phone": "{{phone_number}}" },   // sub-flow var -> template
+//	  "outputs": { "verified_name": "name" }        // caller var -> sub-flow var
+//	}
+func (a *App) execChatCallFlow(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	stack := chatCallStack(ctx.session)
+	if len(stack) >= maxChatCallDepth {
+		a.Log.Warn("call_flow depth limit reached",
+			"node", node.ID, "session", ctx.session.ID, "depth", len(stack))
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	target := a.chatFlowTarget(node, ctx)
+	if target == nil {
+		return nodeOutcome{outcome: "error"}, nil
+	}
+
+	frame := chatCallFrame{
+		FlowID:  ctx.flowID,
+		NodeID:  node.ID,
+		Outputs: map[string]string{},
+		Vars:    map[string]any{},
+	}
+	if outputs, ok := node.Config["outputs"].(map[string]any); ok {
+		for callerVar, raw := range outputs {
+			if subVar, ok := raw.(string); ok && callerVar != "" && subVar != "" {
+				frame.Outputs[callerVar] = subVar
+			}
+		}
+	}
+	for k, v := range ctx.session.SessionData {
+		if k != chatPathKey && k != chatCallStackKey && k != chatSplitsKey {
+			frame.Vars[k] = v
+		}
+	}
+
+	// The sub-flow gets a fresh scope: built-ins plus mapped inputs.
+	scope := models.JSONB{
+		chatPathKey:        ctx.session.SessionData[chatPathKey],
+		chatCallStackKey:   append(stack, frame.toJSONB()),
+		chatSplitsKey:      ctx.session.SessionData[chatSplitsKey],
+		"phone_number":     ctx.session.PhoneNumber,
+		"contact_name":     ctx.session.SessionData["contact_name"],
+		"contact_language": ctx.session.SessionData["contact_language"],
+	}
+	// _flow_id stays the flow the session started in, for analytics
+	for _, key := range []string{"_flow_id", "_flow_name"} {
+		if v, ok := ctx.session.SessionData[key]; ok {
+			scope[key] = v
+		}
+	}
+	if inputs, ok := node.Config["inputs"].(map[string]any); ok {
+		for subVar, raw := range inputs {
+			if subVar == "" || strings.HasPrefix(subVar, "__") {
+				continue
+			}
+			if tmpl, ok := raw.(string); ok {
+				scope[subVar] = processTemplate(tmpl, ctx.session.SessionData)
+			} else {
+				scope[subVar] = raw
+			}
+		}
+	}
+	ctx.session.SessionData = scope
+
+	path, _ := scope[chatPathKey].([]any)
+	scope[chatPathKey] = append(path, map[string]any{
+		"action":  "call_flow",
+		"flow":    target.Name,
+		"flow_id": target.ID.String(),
+	})
+
+	// The runner sees the CurrentFlowID change and starts the target at
+	// its entry node, exactly like goto_flow.
+	ctx.session.CurrentFlowID = &target.ID
+	return nodeOutcome{outcome: "call"}, nil
+}
+
+// returnFromChatCall is called when a flow reaches a terminal node. With a
+// pending call frame it restores the caller's variables, applies the
+// output mapping and returns the caller flow and the node to continue at.
+// A caller whose call node has no matching edge is itself finished, so
+// frames keep unwinding with the "default" outcome. next is "" when the
+// stack is empty and the session is done.
+func (a *App) returnFromChatCall(session *models.ChatbotSession, outcome string) (*models.ChatbotFlow, *ChatGraph, string, error) {
+	for {
+		stack := chatCallStack(session)
+		if len(stack) == 0 {
+			return nil, nil, "", nil
+		}
+		frame, ok := chatCallFrameFromJSONB(stack[len(stack)-1])
+		if !ok {
+			return nil, nil, "", fmt.Errorf("call_flow: corrupt return frame")
+		}
+		caller, err := a.getChatbotFlowByIDCached(session.OrganizationID, frame.FlowID)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("call_flow: load caller: %w", err)
+		}
+		graph, err := parseChatGraph(caller.Graph)
+		if err != nil || graph == nil {
+			return nil, nil, "", fmt.Errorf("call_flow: parse caller graph: %w", err)
+		}
+
+		sub := session.SessionData
+		restored := models.JSONB(frame.Vars)
+		if restored == nil {
+			restored = models.JSONB{}
+		}
+		// Built-ins may have changed in the sub-flow (e.g. a language
+		// switch), so they follow the sub-flow rather than the snapshot.
+		for _, key := range []string{"phone_number", "contact_name", "contact_language"} {
+			if v, ok := sub[key]; ok {
+				restored[key] = v
+			}
+		}
+		for callerVar, subVar := range frame.Outputs {
+			if v, ok := sub[subVar]; ok {
+				restored[callerVar] = v
+			}
+		}
+		if splits, ok := sub[chatSplitsKey]; ok {
+			restored[chatSplitsKey] = splits
+		}
+		path, _ := sub[chatPathKey].([]any)
+		restored[chatPathKey] = append(path, map[string]any{
+			"action":  "return",
+			"flow":    caller.Name,
+			"flow_id": caller.ID.String(),
+			"outcome": outcome,
+		})
+		if len(stack) > 1 {
+			restored[chatCallStackKey] = stack[:len(stack)-1]
+		}
+		session.SessionData = restored
+		session.CurrentFlowID = &caller.ID
+
+		if next := graph.ResolveEdge(frame.NodeID, "return:"+outcome); next != "" {
+			return caller, graph, next, nil
+		}
+		outcome = "default"
+	}
+}
+
+// resolveExactEdge is ResolveEdge without the "default" fallback, for
+// outcomes like call_flow's "error" that must not silently take the
+// happy path.
+func resolveExactEdge(graph *ChatGraph, fromID, outcome string) string {
+	for _, e := range graph.OutgoingEdges(fromID) {
+		if e.Condition == outcome {
+			return e.To
+		}
+	}
+	return ""
+}
+
+// chatOutcomeIsExact reports whether a node's outcome must match an edge
+// exactly: a failed call or an expired wait ends the flow rather than
+// continuing down the default edge.
+func chatOutcomeIsExact(t ChatNodeType, outcome string) bool {
+	switch t {
+	case ChatNodeCallFlow:
+		return outcome == "error"
+	case ChatNodeWait, ChatNodeWaitForReply:
+		return outcome == "timeout" || outcome == "window_closed"
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createGraphFlow(t *testing.T, app *App, org *models.Organization, account *models.WhatsAppAccount, id uuid.UUID, name string, graph models.JSONB) *models.ChatbotFlow {
+	t.Helper()
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: id},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            name,
+		IsEnabled:       true,
+		Graph:           graph,
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+	return flow
+}
+
+func outgoingSessionMessages(t *testing.T, app *App, session *models.ChatbotSession) []string {
+	t.Helper()
+	var msgs []models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND direction = ?", session.ID, models.DirectionOutgoing).
+		Order("created_at").Find(&msgs).Error)
+	out := make([]string, len(msgs))
+	for i, m := range msgs {
+		out[i] = m.Message
+	}
+	return out
+}
+
+// TestRunChatGraph_CallFlow_ReturnsWithOutputs: the sub-flow runs in its
+// own scope, and its end outcome picks the caller's return edge.
+func TestRunChatGraph_CallFlow_ReturnsWithOutputs(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+
+	sub := createGraphFlow(t, app, org, account, uuid.New(), "verify", models.JSONB{
+		"version":    2,
+		"entry_node": "ask",
+		"nodes": []any{
+			map[string]any{"id": "ask", "type": "prompt", "config": map[string]any{"body": "{{greeting}}, your name?", "store_as": "name"}},
+			map[string]any{"id": "done", "type": "end", "config": map[string]any{"outcome": "verified"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "ask", "to": "done", "condition": "default"},
+		},
+	})
+	caller := createGraphFlow(t, app, org, account, uuid.New(), "main", models.JSONB{
+		"version":    2,
+		"entry_node": "call",
+		"nodes": []any{
+			map[string]any{"id": "call", "type": "call_flow", "config": map[string]any{
+				"flow_id": sub.ID.String(),
+				"inputs":  map[string]any{"greeting": "Hi {{tier}} customer"},
+				"outputs": map[string]any{"verified_name": "name"},
+			}},
+			map[string]any{"id": "ok", "type": "message", "config": map[string]any{"message": "Thanks {{verified_name}}"}},
+			map[string]any{"id": "other", "type": "message", "config": map[string]any{"message": "Not verified"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "call", "to": "ok", "condition": "return:verified"},
+			map[string]any{"from": "call", "to": "other", "condition": "default"},
+		},
+	})
+	session.CurrentFlowID = &caller.ID
+	session.SessionData = models.JSONB{"tier": "gold"}
+
+	require.NoError(t, app.runChatGraph(account, contact, session, caller, "start", "", nil))
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, sub.ID, *session.CurrentFlowID)
+	assert.Equal(t, "ask", session.CurrentStep)
+	assert.NotContains(t, session.SessionData, "tier", "caller variables are not visible to the sub-flow")
+	assert.Len(t, chatCallStack(session), 1)
+
+	require.NoError(t, app.runChatGraph(account, contact, session, sub, "Asha", "", nil))
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, models.SessionStatusCompleted, session.Status)
+	assert.Equal(t, caller.ID, *session.CurrentFlowID)
+	assert.Equal(t, "gold", session.SessionData["tier"])
+	assert.Equal(t, "Asha", session.SessionData["verified_name"])
+	assert.NotContains(t, session.SessionData, "name", "only mapped outputs come back")
+	assert.Empty(t, chatCallStack(session))
+	assert.Equal(t, []string{"Hi gold customer, your name?", "Thanks Asha"}, outgoingSessionMessages(t, app, session))
+}
+
+// TestRunChatGraph_CallFlow_DepthLimit: a flow calling itself stops at
+// maxChatCallDepth and takes the error edge.
+func TestRunChatGraph_CallFlow_DepthLimit(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+
+	id := uuid.New()
+	flow := createGraphFlow(t, app, org, account, id, "recursive", models.JSONB{
+		"version":    2,
+		"entry_node": "call",
+		"nodes": []any{
+			map[string]any{"id": "call", "type": "call_flow", "config": map[string]any{"flow_id": id.String()}},
+			map[string]any{"id": "fail", "type": "message", "config": map[string]any{"message": "Too deep"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "call", "to": "fail", "condition": "error"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, models.SessionStatusCompleted, session.Status)
+	assert.Equal(t, []string{"Too deep"}, outgoingSessionMessages(t, app, session))
+	assert.Empty(t, chatCallStack(session), "frames unwind once the innermost flow ends")
+}
+
+// TestRunChatGraph_CallFlow_MissingTargetWithoutErrorEdgeEnds: a failed
+// call never falls through to the default (success) edge.
+func TestRunChatGraph_CallFlow_MissingTargetWithoutErrorEdgeEnds(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "broken", models.JSONB{
+		"version":    2,
+		"entry_node": "call",
+		"nodes": []any{
+			map[string]any{"id": "call", "type": "call_flow", "config": map[string]any{"flow_id": uuid.New().String()}},
+			map[string]any{"id": "next", "type": "message", "config": map[string]any{"message": "Continued"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "call", "to": "next", "condition": "default"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, models.SessionStatusCompleted, session.Status)
+	assert.Empty(t, outgoingSessionMessages(t, app, session))
+}
+
+func TestChatCallFrameRoundTrip(t *testing.T) {
+	frame := chatCallFrame{
+		FlowID:  uuid.New(),
+		NodeID:  "call",
+		Outputs: map[string]string{"verified_name": "name"},
+		Vars:    map[string]any{"tier": "gold"},
+	}
+	got, ok := chatCallFrameFromJSONB(frame.toJSONB())
+	require.True(t, ok)
+	assert.Equal(t, frame, got)
+
+	_, ok = chatCallFrameFromJSONB(map[string]any{"flow_id": "nope"})
+	assert.False(t, ok)
+}
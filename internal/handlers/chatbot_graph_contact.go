@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/assignment"
+	"github.com/shridarpatil/whatomate/internal/audit"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// chatbotAuditActor is the user name on audit entries for changes made by
+// chatbot flows. Their user ID is uuid.Nil.
+const chatbotAuditActor = "Chatbot"
+
+// Contact-mutation nodes change the contact itself rather than session
+// variables. Each takes the "default" edge on success and "error" (falling
+// back to default) when the change can't be applied, so a CRM hiccup
+// doesn't strand the conversation. Every change is audited as the chatbot.
+
+// execChatTagContact adds and removes contact tags. Tag names may use
+// {{var}} placeholders. Tags that don't exist in the organization are
+// skipped unless create_missing is set.
+//
+// Config: { "add": ["vip", "{{plan}}"], "remove": ["lead"], "create_missing": false }
+func (a *App) execChatTagContact(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if ctx.contact == nil {
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	orgID := ctx.session.OrganizationID
+	render := func(key string) []string {
+		var out []string
+		for _, name := range stringsFromConfig(node.Config, key) {
+			if name = strings.TrimSpace(processTemplate(name, ctx.session.SessionData)); name != "" && len(name) <= 50 {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	add, remove := render("add"), render("remove")
+
+	if len(add) > 0 {
+		var existing []string
+		a.DB.Model(&models.Tag{}).Where("organization_id = ? AND name IN ?", orgID, add).Pluck("name", &existing)
+		createMissing, _ := node.Config["create_missing"].(bool)
+		var allowed []string
+		for _, name := range add {
+			switch {
+			case slices.Contains(existing, name):
+				allowed = append(allowed, name)
+			case createMissing:
+				if err := a.DB.Create(&models.Tag{OrganizationID: orgID, Name: name}).Error; err != nil {
+					a.Log.Error("Failed to create tag from chatbot flow", "error", err, "tag", name)
+					return nodeOutcome{outcome: "error"}, nil
+				}
+				a.InvalidateTagsCache(orgID)
+				allowed = append(allowed, name)
+			default:
+				a.Log.Warn("Chatbot tag does not exist; skipping", "tag", name, "node", node.ID)
+			}
+		}
+		add = allowed
+	}
+
+	return a.updateChatContact(node, ctx, map[string]any{"tags": chatTagsExpr(add, remove)}), nil
+}
+
+// chatTagsExpr edits the stored tags in SQL rather than rewriting them from
+// the flow's copy of the contact, so tags changed by agents or other flows
+// since it was loaded are kept. Removed and re-added tags are dropped in
+// order, then added ones are appended.
+func chatTagsExpr(add, remove []string) clause.Expr {
+	drop, _ := json.Marshal(append(append([]string{}, remove...), add...))
+	added, _ := json.Marshal(append([]string{}, add...))
+	return gorm.Expr(`COALESCE((SELECT jsonb_agg(e.tag ORDER BY e.pos)
+		FROM jsonb_array_elements(COALESCE(tags, '[]'::jsonb)) WITH ORDINALITY AS e(tag, pos)
+		WHERE NOT jsonb_exists(?::jsonb, e.tag #>> '{}')), '[]'::jsonb) || ?::jsonb`, string(drop), string(added))
+}
+
+// execChatSetContactField writes custom fields into Contact.Metadata.
+// String values may use {{var}} placeholders; unset removes fields.
+//
+// Config: { "fields": { "plan": "{{plan}}", "score": 10 }, "unset": ["trial_ends"] }
+func (a *App) execChatSetContactField(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if ctx.contact == nil {
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	fields := models.JSONB{}
+	if cfg, ok := node.Config["fields"].(map[string]any); ok {
+		for key, v := range cfg {
+			if key == "" {
+				continue
+			}
+			if s, ok := v.(string); ok {
+				v = processTemplate(s, ctx.session.SessionData)
+			}
+			fields[key] = v
+		}
+	}
+	return a.setChatContactFields(node, ctx, fields, stringsFromConfig(node.Config, "unset")), nil
+}
+
+// setChatContactFields merges fields into the contact's metadata and
+// removes the unset keys. Custom field values must match their type;
+// defaults and required fields are left to the API and imports. A value
+// that normalizes to empty clears the field.
+func (a *App) setChatContactFields(node *ChatNode, ctx *chatNodeCtx, fields models.JSONB, unset []string) nodeOutcome {
+	if ctx.contact == nil {
+		return nodeOutcome{outcome: "error"}
+	}
+	normalized, err := contactutil.NormalizeMetadata(a.DB, ctx.contact.OrganizationID, ctx.contact.ID, fields, contactutil.MetadataOptions{})
+	if err != nil {
+		a.Log.Warn("Chatbot flow wrote an invalid contact field", "error", err, "node", node.ID, "contact", ctx.contact.ID)
+		return nodeOutcome{outcome: "error"}
+	}
+	for key := range fields {
+		if _, ok := normalized[key]; !ok {
+			unset = append(unset, key)
+		}
+	}
+	return a.updateChatContact(node, ctx, map[string]any{"metadata": chatMetadataExpr(normalized, unset)})
+}
+
+// chatMetadataExpr merges fields into the stored metadata and removes the
+// unset keys in SQL, leaving other fields as they are in the database.
+func chatMetadataExpr(fields models.JSONB, unset []string) clause.Expr {
+	set, _ := json.Marshal(fields)
+	drop, _ := json.Marshal(append([]string{}, unset...))
+	return gorm.Expr(`(COALESCE(metadata, '{}'::jsonb) || ?::jsonb) - ARRAY(SELECT jsonb_array_elements_text(?::jsonb))`, string(set), string(drop))
+}
+
+// execChatSetOptOut sets the contact's marketing opt-out, e.g. from a
+// "Stop promotions" button.
+//
+// Config: { "opt_out": true }
+func (a *App) execChatSetOptOut(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	optOut, ok := node.Config["opt_out"].(bool)
+	if !ok {
+		optOut = true
+	}
+	return a.updateChatContact(node, ctx, map[string]any{"marketing_opt_out": optOut}), nil
+}
+
+// execChatAssignContact assigns the contact to a user, to an agent picked
+// by a team's assignment strategy, or clears the assignment. A team with
+// no available agent takes the "no_agent" edge (falling back to default)
+// and leaves the assignment unchanged.
+//
+// Config: { "user_id": "<uuid>" } | { "team_id": "<uuid>" } | { "unassign": true }
+func (a *App) execChatAssignContact(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	orgID := ctx.session.OrganizationID
+	if unassign, _ := node.Config["unassign"].(bool); unassign {
+		return a.updateChatContact(node, ctx, map[string]any{"assigned_user_id": nil}), nil
+	}
+
+	var agentID *uuid.UUID
+	if raw := processTemplate(stringFromConfig(node.Config, "user_id"), ctx.session.SessionData); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			a.Log.Warn("assign_contact: invalid user_id", "node", node.ID, "user_id", raw)
+			return nodeOutcome{outcome: "error"}, nil
+		}
+		var count int64
+		a.DB.Model(&models.User{}).Where("id = ? AND organization_id = ? AND is_active = ?", id, orgID, true).Count(&count)
+		if count == 0 {
+			a.Log.Warn("assign_contact: user not found", "node", node.ID, "user_id", raw)
+			return nodeOutcome{outcome: "error"}, nil
+		}
+		agentID = &id
+	} else if raw := stringFromConfig(node.Config, "team_id"); raw != "" {
+		teamID, err := uuid.Parse(raw)
+		if err != nil {
+			a.Log.Warn("assign_contact: invalid team_id", "node", node.ID, "team_id", raw)
+			return nodeOutcome{outcome: "error"}, nil
+		}
+		if a.Assigner != nil {
+			agentID = a.Assigner.AssignToTeam(teamID, orgID, nil, assignment.ChatLoadCounter)
+		}
+		if agentID == nil {
+			return nodeOutcome{outcome: "no_agent"}, nil
+		}
+	} else {
+		a.Log.Warn("assign_contact: no user_id or team_id configured", "node", node.ID)
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	return a.updateChatContact(node, ctx, map[string]any{"assigned_user_id": *agentID}), nil
+}
+
+// execChatAddNote adds a conversation note to the contact, visible to
+// agents in the chat sidebar. Content may use {{var}} placeholders.
+//
+// Config: { "content": "Customer wants a callback about {{topic}}", "pin": false }
+func (a *App) execChatAddNote(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if ctx.contact == nil {
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	content := strings.TrimSpace(processTemplate(stringFromConfig(node.Config, "content"), ctx.session.SessionData))
+	if content == "" {
+		return nodeOutcome{outcome: "default"}, nil
+	}
+	pin, _ := node.Config["pin"].(bool)
+	note := models.ConversationNote{
+		OrganizationID: ctx.session.OrganizationID,
+		ContactID:      ctx.contact.ID,
+		Content:        content,
+		Source:         models.NoteSourceChatbot,
+		IsPinned:       pin,
+	}
+	if err := a.DB.Create(&note).Error; err != nil {
+		a.Log.Error("Failed to create conversation note from chatbot flow", "error", err, "node", node.ID)
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	a.logChatbotAudit(note.OrganizationID, "conversation_note", note.ID, models.AuditActionCreated, nil, &note)
+	if a.WSHub != nil {
+		a.WSHub.BroadcastToContact(note.OrganizationID, note.ContactID, websocket.WSMessage{
+			Type:    websocket.TypeConversationNoteCreated,
+			Payload: noteToResponse(note),
+		})
+	}
+	return nodeOutcome{outcome: "default"}, nil
+}
+
+// updateChatContact applies updates to the flow's contact, refreshes it in
+// place so later nodes see the change, audits the diff and pushes the new
+// state to agents.
+func (a *App) updateChatContact(node *ChatNode, ctx *chatNodeCtx, updates map[string]any) nodeOutcome {
+	if ctx.contact == nil {
+		return nodeOutcome{outcome: "error"}
+	}
+	// The row is locked so the audited before/after pair brackets exactly
+	// this change.
+	var old, fresh models.Contact
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ctx.contact.ID).First(&old).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Contact{}).Where("id = ?", ctx.contact.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", ctx.contact.ID).First(&fresh).Error
+	})
+	if err != nil {
+		a.Log.Error("Failed to update contact from chatbot flow", "error", err, "node", node.ID, "contact", ctx.contact.ID)
+		return nodeOutcome{outcome: "error"}
+	}
+	*ctx.contact = fresh
+	a.logChatbotAudit(fresh.OrganizationID, "contact", fresh.ID, models.AuditActionUpdated, &old, &fresh)
+	if _, ok := updates["tags"]; ok {
+		a.triggerTagSequences(fresh.OrganizationID, fresh.ID, old.Tags, fresh.Tags)
+	}
+	a.broadcastContactUpdate(&fresh)
+	return nodeOutcome{outcome: "default"}
+}
+
+// logChatbotAudit records an audit entry with the chatbot as the actor.
+func (a *App) logChatbotAudit(orgID uuid.UUID, resourceType string, resourceID uuid.UUID, action models.AuditAction, oldData, newData any) {
+	audit.LogAudit(a.DB, orgID, uuid.Nil, chatbotAuditActor, resourceType, resourceID, action, oldData, newData)
+}
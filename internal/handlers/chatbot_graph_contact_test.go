@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runSingleChatNode runs a one-node flow and returns the reloaded contact.
+func runSingleChatNode(t *testing.T, nodeType string, cfg map[string]any) (*App, *models.Contact, *models.ChatbotSession) {
+	t.Helper()
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	session.SessionData["plan"] = "gold"
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "crm", models.JSONB{
+		"version":    2,
+		"entry_node": "n",
+		"nodes": []any{
+			map[string]any{"id": "n", "type": nodeType, "config": cfg},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	var fresh models.Contact
+	require.NoError(t, app.DB.First(&fresh, contact.ID).Error)
+	return app, &fresh, session
+}
+
+func TestRunChatGraph_TagContact(t *testing.T) {
+	t.Run("adds existing tags and skips unknown ones", func(t *testing.T) {
+		app, org, account, contact, session := newGraphTestFixtures(t)
+		require.NoError(t, app.DB.Create(&models.Tag{OrganizationID: org.ID, Name: "vip"}).Error)
+		require.NoError(t, app.DB.Model(contact).Update("tags", models.JSONBArray{"lead", "new"}).Error)
+		contact.Tags = models.JSONBArray{"lead", "new"}
+
+		flow := createGraphFlow(t, app, org, account, uuid.New(), "crm", models.JSONB{
+			"version":    2,
+			"entry_node": "tag",
+			"nodes": []any{
+				map[string]any{"id": "tag", "type": "tag_contact", "config": map[string]any{
+					"add": []any{"vip", "unknown"}, "remove": []any{"lead"},
+				}},
+			},
+		})
+		session.CurrentFlowID = &flow.ID
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+		var fresh models.Contact
+		require.NoError(t, app.DB.First(&fresh, contact.ID).Error)
+		assert.ElementsMatch(t, models.JSONBArray{"new", "vip"}, fresh.Tags)
+
+		var logs []models.AuditLog
+		require.NoError(t, app.DB.Where("resource_type = ? AND resource_id = ?", "contact", contact.ID).Find(&logs).Error)
+		require.Len(t, logs, 1)
+		assert.Equal(t, chatbotAuditActor, logs[0].UserName)
+	})
+
+	t.Run("creates missing tags when allowed", func(t *testing.T) {
+		app, contact, session := runSingleChatNode(t, "tag_contact", map[string]any{
+			"add": []any{"plan-{{plan}}"}, "create_missing": true,
+		})
+		assert.Equal(t, models.JSONBArray{"plan-gold"}, contact.Tags)
+		var count int64
+		app.DB.Model(&models.Tag{}).Where("organization_id = ? AND name = ?", session.OrganizationID, "plan-gold").Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestRunChatGraph_SetContactField(t *testing.T) {
+	_, contact, _ := runSingleChatNode(t, "set_contact_field", map[string]any{
+		"fields": map[string]any{"plan": "{{plan}}", "score": float64(10)},
+		"unset":  []any{"missing"},
+	})
+	assert.Equal(t, "gold", contact.Metadata["plan"])
+	assert.EqualValues(t, 10, contact.Metadata["score"])
+}
+
+func TestRunChatGraph_ContactUpdatesKeepConcurrentChanges(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	require.NoError(t, app.DB.Create(&models.Tag{OrganizationID: org.ID, Name: "vip"}).Error)
+	// An agent edits the contact after the flow loaded it.
+	require.NoError(t, app.DB.Model(contact).Updates(map[string]any{
+		"tags":     models.JSONBArray{"lead", "agent-tag"},
+		"metadata": models.JSONB{"agent_note": "keep", "stale": "x"},
+	}).Error)
+
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "crm", models.JSONB{
+		"version":    2,
+		"entry_node": "tag",
+		"nodes": []any{
+			map[string]any{"id": "tag", "type": "tag_contact", "config": map[string]any{
+				"add": []any{"vip"}, "remove": []any{"lead"},
+			}},
+			map[string]any{"id": "field", "type": "set_contact_field", "config": map[string]any{
+				"fields": map[string]any{"plan": "gold"}, "unset": []any{"stale"},
+			}},
+		},
+		"edges": []any{
+			map[string]any{"from": "tag", "to": "field", "condition": "default"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	var fresh models.Contact
+	require.NoError(t, app.DB.First(&fresh, contact.ID).Error)
+	assert.Equal(t, models.JSONBArray{"agent-tag", "vip"}, fresh.Tags)
+	assert.Equal(t, models.JSONB{"agent_note": "keep", "plan": "gold"}, fresh.Metadata)
+}
+
+func TestRunChatGraph_SetOptOut(t *testing.T) {
+	_, contact, _ := runSingleChatNode(t, "set_opt_out", map[string]any{})
+	assert.True(t, contact.MarketingOptOut)
+}
+
+func TestRunChatGraph_AssignContact(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "crm", models.JSONB{
+		"version":    2,
+		"entry_node": "assign",
+		"nodes": []any{
+			map[string]any{"id": "assign", "type": "assign_contact", "config": map[string]any{"user_id": agent.ID.String()}},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	var fresh models.Contact
+	require.NoError(t, app.DB.First(&fresh, contact.ID).Error)
+	require.NotNil(t, fresh.AssignedUserID)
+	assert.Equal(t, agent.ID, *fresh.AssignedUserID)
+}
+
+func TestRunChatGraph_AssignContact_UnknownUserTakesErrorEdge(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "crm", models.JSONB{
+		"version":    2,
+		"entry_node": "assign",
+		"nodes": []any{
+			map[string]any{"id": "assign", "type": "assign_contact", "config": map[string]any{"user_id": uuid.New().String()}},
+			map[string]any{"id": "ok", "type": "message", "config": map[string]any{"message": "Connecting you"}},
+			map[string]any{"id": "fail", "type": "message", "config": map[string]any{"message": "No one is free"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "assign", "to": "ok", "condition": "default"},
+			map[string]any{"from": "assign", "to": "fail", "condition": "error"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	assert.Equal(t, []string{"No one is free"}, outgoingSessionMessages(t, app, session))
+}
+
+func TestRunChatGraph_AddNote(t *testing.T) {
+	app, contact, _ := runSingleChatNode(t, "add_note", map[string]any{
+		"content": "Interested in the {{plan}} plan", "pin": true,
+	})
+	var note models.ConversationNote
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&note).Error)
+	assert.Equal(t, "Interested in the gold plan", note.Content)
+	assert.Equal(t, models.NoteSourceChatbot, note.Source)
+	assert.True(t, note.IsPinned)
+	assert.Nil(t, note.CreatedByID)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// chatLoopsKey holds foreach cursors as { "<node_id>": <next index> }. It is
+// an ordinary session variable, so a call_flow inside a loop body saves and
+// restores it with the rest of the caller's variables.
+const chatLoopsKey = "__loops__"
+
+const (
+	defaultChatForeachIterations = 20
+	// maxChatForeachIterations stays below maxChatGraphIterations so a
+	// loop of non-blocking nodes can finish within one inbound message.
+	maxChatForeachIterations = 50
+)
+
+// execChatForeach iterates an array variable, e.g. the orders mapped from
+// an api_call response. Each visit exposes the next element as item_as
+// and its zero-based position as index_as, then takes the "item" edge;
+// the loop body routes back to this node to advance. When the list is
+// exhausted (or max_iterations is reached) the cursor is cleared and the
+// node takes "done". A missing or empty list takes "empty", falling back
+// to "done" and then "default".
+//
+// The list is re-read on every visit, so a body that removes elements
+// from it shifts the remaining items.
+//
+// Config: { "list": "orders" | "response.items", "item_as": "item",
+// "index_as": "index", "max_iterations": 20 }
+func (a *App) execChatForeach(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	data := ctx.session.SessionData
+	items := chatForeachItems(data, stringFromConfig(node.Config, "list"))
+	limit := intFromConfig(node.Config, "max_iterations", defaultChatForeachIterations)
+	if limit <= 0 {
+		limit = defaultChatForeachIterations
+	}
+	items = items[:min(len(items), limit, maxChatForeachIterations)]
+
+	loops, _ := data[chatLoopsKey].(map[string]any)
+	index := -1
+	if loops != nil {
+		if v, ok := loops[node.ID].(float64); ok {
+			index = int(v)
+		} else if v, ok := loops[node.ID].(int); ok {
+			index = v
+		}
+	}
+
+	if index < 0 && len(items) == 0 {
+		return nodeOutcome{outcome: "empty"}, nil
+	}
+	index++
+	if index >= len(items) {
+		delete(loops, node.ID)
+		if len(loops) == 0 {
+			delete(data, chatLoopsKey)
+		}
+		return nodeOutcome{outcome: "done"}, nil
+	}
+
+	if loops == nil {
+		loops = map[string]any{}
+		data[chatLoopsKey] = loops
+	}
+	loops[node.ID] = index
+	itemAs := stringFromConfig(node.Config, "item_as")
+	if itemAs == "" {
+		itemAs = "item"
+	}
+	indexAs := stringFromConfig(node.Config, "index_as")
+	if indexAs == "" {
+		indexAs = "index"
+	}
+	data[itemAs] = items[index]
+	data[indexAs] = index
+	return nodeOutcome{outcome: "item"}, nil
+}
+
+// chatForeachItems resolves a list variable, which may be a dotted path
+// into a mapped response or a JSON-encoded array string. Anything else
+// yields no items.
+func chatForeachItems(data map[string]any, path string) []any {
+	path = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(path), "{{"), "}}")
+	switch v := getNestedValue(data, strings.TrimSpace(path)).(type) {
+	case []any:
+		return v
+	case string:
+		var items []any
+		if json.Unmarshal([]byte(v), &items) == nil {
+			return items
+		}
+	}
+	return nil
+}
+
+// resolveForeachEdge lets "empty" fall back to the "done" edge before the
+// usual default fallback, so a loop only needs item and done wired.
+func resolveForeachEdge(graph *ChatGraph, nodeID, outcome string) string {
+	if outcome == "empty" {
+		if next := resolveExactEdge(graph, nodeID, "empty"); next != "" {
+			return next
+		}
+		outcome = "done"
+	}
+	return graph.ResolveEdge(nodeID, outcome)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecChatForeach_IteratesAndClearsCursor(t *testing.T) {
+	app := &App{}
+	node := &ChatNode{ID: "loop", Type: ChatNodeForeach, Config: map[string]any{"list": "resp.orders", "item_as": "order"}}
+	session := &models.ChatbotSession{SessionData: models.JSONB{
+		"resp": map[string]any{"orders": []any{"A", "B"}},
+	}}
+	ctx := &chatNodeCtx{session: session}
+
+	var outcomes []string
+	var seen []any
+	for range 3 {
+		res, err := app.execChatForeach(node, ctx)
+		require.NoError(t, err)
+		outcomes = append(outcomes, res.outcome)
+		if res.outcome == "item" {
+			seen = append(seen, session.SessionData["order"])
+		}
+	}
+	assert.Equal(t, []string{"item", "item", "done"}, outcomes)
+	assert.Equal(t, []any{"A", "B"}, seen)
+	assert.Equal(t, 1, session.SessionData["index"])
+	assert.NotContains(t, session.SessionData, chatLoopsKey)
+
+	// A fresh visit after done starts over.
+	res, _ := app.execChatForeach(node, ctx)
+	assert.Equal(t, "item", res.outcome)
+	assert.Equal(t, "A", session.SessionData["order"])
+}
+
+func TestExecChatForeach_EmptyAndCapped(t *testing.T) {
+	app := &App{}
+	session := &models.ChatbotSession{SessionData: models.JSONB{"ids": `[1, 2, 3]`}}
+	ctx := &chatNodeCtx{session: session}
+
+	res, err := app.execChatForeach(&ChatNode{ID: "n", Config: map[string]any{"list": "missing"}}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "empty", res.outcome)
+
+	capped := &ChatNode{ID: "c", Config: map[string]any{"list": "{{ids}}", "max_iterations": 2}}
+	var outcomes []string
+	for range 3 {
+		res, _ := app.execChatForeach(capped, ctx)
+		outcomes = append(outcomes, res.outcome)
+	}
+	assert.Equal(t, []string{"item", "item", "done"}, outcomes)
+}
+
+func TestRunChatGraph_Foreach(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	session.SessionData["orders"] = []any{
+		map[string]any{"id": "A-1"},
+		map[string]any{"id": "A-2"},
+	}
+	require.NoError(t, app.DB.Save(session).Error)
+
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "orders", models.JSONB{
+		"version":    2,
+		"entry_node": "loop",
+		"nodes": []any{
+			map[string]any{"id": "loop", "type": "foreach", "config": map[string]any{"list": "orders", "item_as": "order"}},
+			map[string]any{"id": "ask", "type": "buttons", "config": map[string]any{
+				"body":    "Cancel order {{order.id}}?",
+				"buttons": []any{map[string]any{"id": "yes", "title": "Yes"}, map[string]any{"id": "no", "title": "No"}},
+			}},
+			map[string]any{"id": "bye", "type": "message", "config": map[string]any{"message": "All done"}},
+			map[string]any{"id": "none", "type": "message", "config": map[string]any{"message": "No orders"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "loop", "to": "ask", "condition": "item"},
+			map[string]any{"from": "loop", "to": "bye", "condition": "done"},
+			map[string]any{"from": "loop", "to": "none", "condition": "empty"},
+			map[string]any{"from": "ask", "to": "loop", "condition": "default"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+	assert.Equal(t, "ask", session.CurrentStep)
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "Yes", "yes", nil))
+	assert.Equal(t, "ask", session.CurrentStep)
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "No", "no", nil))
+
+	assert.Equal(t, []string{"Cancel order A-1?", "Cancel order A-2?", "All done"}, outgoingSessionMessages(t, app, session))
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.NotContains(t, session.SessionData, chatLoopsKey)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// chatbotMediaDir is the media storage subdirectory for files uploaded to
+// chatbot media and template nodes. Each org's files live in a
+// subdirectory named after its ID.
+const chatbotMediaDir = "chatbot"
+
+// maxChatbotMediaUpload caps files uploaded for chatbot nodes.
+const maxChatbotMediaUpload = 16 << 20
+
+// execChatMedia sends an image, video, audio or document. The file is
+// either uploaded in the editor (media_path) or fetched from media_url,
+// which may use {{var}} placeholders like the caption. A failed send takes
+// the "error" edge (falling back to default).
+//
+// Config: { "media_type": "image", "media_path": "chatbot/<org_id>/<file>" | "media_url": "https://...",
+// "caption": "Your invoice, {{name}}", "filename": "invoice.pdf" }
+func (a *App) execChatMedia(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	switch models.MessageType(stringFromConfig(node.Config, "media_type")) {
+	case models.MessageTypeImage, models.MessageTypeVideo, models.MessageTypeAudio, models.MessageTypeDocument:
+	default:
+		a.Log.Warn("media node has no valid media_type; skipping", "node", node.ID, "session", ctx.session.ID)
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	sent, err := a.sendChatbotMedia(ctx.account, ctx.contact, ctx.session, node.Config)
+	if err != nil {
+		a.Log.Error("Failed to send chatbot media", "error", err, "node", node.ID, "session", ctx.session.ID)
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	a.logSessionMessage(ctx.session.ID, models.DirectionOutgoing, sent, node.ID)
+	return nodeOutcome{outcome: "default"}, nil
+}
+
+// execChatTemplate sends an approved template, which unlike other messages
+// can be delivered outside the 24-hour customer service window. Params
+// map template variables to values with {{var}} placeholders. A missing
+// or unapproved template takes the "error" edge (falling back to default).
+//
+// Config: { "template_id": "<uuid>", "params": { "1": "{{order_id}}" },
+// "header_media_path": "chatbot/<org_id>/<file>" }
+func (a *App) execChatTemplate(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	sent, err := a.sendChatbotTemplate(ctx.account, ctx.contact, ctx.session, node.Config)
+	if err != nil {
+		a.Log.Error("Failed to send chatbot template", "error", err, "node", node.ID, "session", ctx.session.ID)
+		return nodeOutcome{outcome: "error"}, nil
+	}
+	a.logSessionMessage(ctx.session.ID, models.DirectionOutgoing, sent, node.ID)
+	return nodeOutcome{outcome: "default"}, nil
+}
+
+// UploadChatbotMedia stores a file for a chatbot media node or template
+// header. The returned media_path goes into the node config.
+func (a *App) UploadChatbotMedia(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceFlowsChatbot, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	form, err := r.RequestCtx.MultipartForm()
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid multipart form", nil, "")
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No file provided", nil, "")
+	}
+	fileHeader := files[0]
+	file, err := fileHeader.Open()
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Failed to open file", nil, "")
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxChatbotMediaUpload+1))
+	if err != nil {
+		a.Log.Error("Failed to read chatbot media", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
+	}
+	if len(data) > maxChatbotMediaUpload {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "File too large. Maximum size is 16MB", nil, "")
+	}
+
+	mimeType := fileHeader.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	ext := getExtensionFromMimeType(mimeType)
+	if ext == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Unsupported file type: "+mimeType, nil, "")
+	}
+
+	orgDir := filepath.Join(chatbotMediaDir, orgID.String())
+	if err := a.ensureMediaDir(orgDir); err != nil {
+		a.Log.Error("Failed to create chatbot media directory", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save file", nil, "")
+	}
+	mediaPath := filepath.Join(orgDir, uuid.New().String()+ext)
+	if err := os.WriteFile(filepath.Join(a.getMediaStoragePath(), mediaPath), data, 0644); err != nil {
+		a.Log.Error("Failed to save chatbot media", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save file", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"media_path": filepath.ToSlash(mediaPath),
+		"filename":   sanitizeFilename(fileHeader.Filename),
+		"mime_type":  mimeType,
+		"size":       len(data),
+	})
+}
+
+// readChatbotMedia loads a file saved by UploadChatbotMedia. Paths outside
+// the org's chatbot media directory are rejected.
+func (a *App) readChatbotMedia(orgID uuid.UUID, mediaPath string) ([]byte, string, error) {
+	baseDir, err := filepath.Abs(filepath.Join(a.getMediaStoragePath(), chatbotMediaDir, orgID.String()))
+	if err != nil {
+		return nil, "", fmt.Errorf("resolve media directory: %w", err)
+	}
+	fullPath, err := filepath.Abs(filepath.Join(a.getMediaStoragePath(), filepath.Clean(mediaPath)))
+	if err != nil || !strings.HasPrefix(fullPath, baseDir+string(os.PathSeparator)) {
+		return nil, "", fmt.Errorf("invalid media path %q", mediaPath)
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("read media: %w", err)
+	}
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(fullPath)), ";")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return data, mimeType, nil
+}
+
+// validateChatGraphMedia checks the media sources of a flow's media and
+// template nodes when it's saved: uploaded files must belong to the org,
+// and media_url must be an http(s) URL that doesn't point at an internal
+// address. URLs are checked again at send time, once placeholders are
+// filled in.
+func (a *App) validateChatGraphMedia(orgID uuid.UUID, graph models.JSONB) error {
+	b, err := json.Marshal(graph)
+	if err != nil {
+		return errors.New("invalid graph")
+	}
+	var g ChatGraph
+	if err := json.Unmarshal(b, &g); err != nil {
+		return errors.New("invalid graph")
+	}
+
+	orgDir := path.Join(chatbotMediaDir, orgID.String()) + "/"
+	for _, node := range g.Nodes {
+		var mediaPath string
+		switch node.Type {
+		case ChatNodeMedia:
+			mediaPath = stringFromConfig(node.Config, "media_path")
+			mediaURL := stringFromConfig(node.Config, "media_url")
+			if stringFromConfig(node.Config, "media_id") == "" && mediaPath == "" && mediaURL != "" {
+				u, err := url.Parse(mediaURL)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("node %q: media_url must be an http(s) URL", node.ID)
+				}
+				if err := validateWebhookURL(u.String(), a.Config.App.AllowInternalWebhookURLs); err != nil {
+					return fmt.Errorf("node %q: invalid media_url: %v", node.ID, err)
+				}
+			}
+		case ChatNodeTemplate:
+			mediaPath = stringFromConfig(node.Config, "header_media_path")
+		}
+		if mediaPath != "" && !strings.HasPrefix(path.Clean(mediaPath), orgDir) {
+			return fmt.Errorf("node %q: media file not found", node.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadChatbotMedia(t *testing.T) {
+	root := t.TempDir()
+	app := &App{Config: &config.Config{Storage: config.StorageConfig{LocalPath: root}}}
+	orgID, otherOrgID := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{orgID, otherOrgID} {
+		dir := filepath.Join(root, chatbotMediaDir, id.String())
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "menu.pdf"), []byte("%PDF-1.4"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, chatbotMediaDir, "legacy.pdf"), []byte("%PDF-1.4"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("nope"), 0644))
+
+	data, mimeType, err := app.readChatbotMedia(orgID, "chatbot/"+orgID.String()+"/menu.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(data))
+	assert.Equal(t, "application/pdf", mimeType)
+
+	for _, p := range []string{
+		"secret.txt", "chatbot/../secret.txt", "/etc/passwd", "chatbot", "chatbot/legacy.pdf",
+		"chatbot/" + otherOrgID.String() + "/menu.pdf",
+		"chatbot/" + orgID.String() + "/../" + otherOrgID.String() + "/menu.pdf",
+	} {
+		_, _, err := app.readChatbotMedia(orgID, p)
+		assert.Error(t, err, p)
+	}
+}
+
+func TestValidateChatGraphMedia(t *testing.T) {
+	app := &App{Config: &config.Config{}}
+	orgID := uuid.New()
+	graph := func(node map[string]any) models.JSONB {
+		return models.JSONB{"version": 2, "entry_node": "n", "nodes": []any{node}}
+	}
+	media := func(config map[string]any) models.JSONB {
+		return graph(map[string]any{"id": "n", "type": "media", "config": config})
+	}
+
+	assert.NoError(t, app.validateChatGraphMedia(orgID, media(map[string]any{"media_url": "https://cdn.example.com/{{order_id}}.pdf"})))
+	assert.NoError(t, app.validateChatGraphMedia(orgID, media(map[string]any{"media_path": "chatbot/" + orgID.String() + "/a.pdf"})))
+	assert.NoError(t, app.validateChatGraphMedia(orgID, nil))
+
+	for name, g := range map[string]models.JSONB{
+		"internal url":     media(map[string]any{"media_url": "http://169.254.169.254/latest/meta-data"}),
+		"file url":         media(map[string]any{"media_url": "file:///etc/passwd"}),
+		"other org's file": media(map[string]any{"media_path": "chatbot/" + uuid.New().String() + "/a.pdf"}),
+		"traversal":        media(map[string]any{"media_path": "chatbot/" + orgID.String() + "/../x/a.pdf"}),
+		"template header":  graph(map[string]any{"id": "n", "type": "template", "config": map[string]any{"header_media_path": "chatbot/a.pdf"}}),
+	} {
+		assert.Error(t, app.validateChatGraphMedia(orgID, g), name)
+	}
+}
+
+func TestRunChatGraph_Template(t *testing.T) {
+	graph := func(templateID string) models.JSONB {
+		return models.JSONB{
+			"version":    2,
+			"entry_node": "tpl",
+			"nodes": []any{
+				map[string]any{"id": "tpl", "type": "template", "config": map[string]any{
+					"template_id": templateID,
+					"params":      map[string]any{"1": "{{order_id}}"},
+				}},
+				map[string]any{"id": "fail", "type": "message", "config": map[string]any{"message": "Sorry, something went wrong"}},
+			},
+			"edges": []any{
+				map[string]any{"from": "tpl", "to": "fail", "condition": "error"},
+			},
+		}
+	}
+
+	t.Run("sends an approved template", func(t *testing.T) {
+		app, org, account, contact, session := newGraphTestFixtures(t)
+		session.SessionData["order_id"] = "A-42"
+		tpl := &models.Template{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			WhatsAppAccount: account.Name,
+			Name:            "order_update",
+			Language:        "en",
+			Category:        "UTILITY",
+			Status:          "APPROVED",
+			BodyContent:     "Your order {{1}} has shipped.",
+		}
+		require.NoError(t, app.DB.Create(tpl).Error)
+		flow := createGraphFlow(t, app, org, account, uuid.New(), "notify", graph(tpl.ID.String()))
+		session.CurrentFlowID = &flow.ID
+
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+		msgs := outgoingSessionMessages(t, app, session)
+		require.Len(t, msgs, 1)
+		assert.Contains(t, msgs[0], "A-42")
+	})
+
+	t.Run("missing template takes the error edge", func(t *testing.T) {
+		app, org, account, contact, session := newGraphTestFixtures(t)
+		flow := createGraphFlow(t, app, org, account, uuid.New(), "notify", graph(uuid.New().String()))
+		session.CurrentFlowID = &flow.ID
+
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+		assert.Equal(t, []string{"Sorry, something went wrong"}, outgoingSessionMessages(t, app, session))
+	})
+}
+
+func TestRunChatGraph_Media_InvalidPathTakesErrorEdge(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "brochure", models.JSONB{
+		"version":    2,
+		"entry_node": "doc",
+		"nodes": []any{
+			map[string]any{"id": "doc", "type": "media", "config": map[string]any{
+				"media_type": "document", "media_path": "../config.toml",
+			}},
+			map[string]any{"id": "fail", "type": "message", "config": map[string]any{"message": "Brochure unavailable"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "doc", "to": "fail", "condition": "error"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	assert.Equal(t, []string{"Brochure unavailable"}, outgoingSessionMessages(t, app, session))
+}
@@ -34,6 +34,9 @@ type chatNodeCtx struct {
 	userInput        string
 	buttonID         string
 	flowResponseData map[string]any // form fields from a WhatsApp Flow submission
+	flowID           uuid.UUID      // flow currently executing (changes on goto/call)
+	language         string         // contact language used to pick node translations
+	wakeupID         string         // wake-up being delivered by the wake-up processor
 	consumed         bool
 }
 
@@ -86,6 +89,7 @@ func (a *App) runChatGraph(
 		userInput:        userInput,
 		buttonID:         buttonID,
 		flowResponseData: flowResponseData,
+		flowID:           flow.ID,
 	}
 
 	// Seed built-in template variables so {{phone_number}} / {{contact_name}}
@@ -96,6 +100,12 @@ func (a *App) runChatGraph(
 	session.SessionData["phone_number"] = session.PhoneNumber
 	if contact != nil {
 		session.SessionData["contact_name"] = contact.ProfileName
+		session.SessionData["contact_language"] = contact.Language
+		ctx.language = contact.Language
+	}
+	if id, ok := session.SessionData[chatWakeKey].(string); ok {
+		ctx.wakeupID = id
+		delete(session.SessionData, chatWakeKey)
 	}
 
 	if session.CurrentStep == "" {
@@ -123,11 +133,17 @@ func (a *App) runChatGraph(
 				a.Log.Warn("skip_condition failed; ignoring",
 					"node", node.ID, "session", session.ID, "expression", expr, "error", err)
 			} else if matched {
-				appendChatPath(session, node, "skipped")
+				appendChatPath(session, flow.ID, node, "skipped")
 				next := graph.ResolveEdge(node.ID, "default")
 				if next == "" {
-					session.Status = models.SessionStatusCompleted
-					return a.persistChatSession(session)
+					caller, callerGraph, resume, err := a.returnFromChatCall(session, "default")
+					if err != nil || resume == "" {
+						session.Status = models.SessionStatusCompleted
+						_ = a.persistChatSession(session)
+						return err
+					}
+					flow, graph, next = caller, callerGraph, resume
+					ctx.flowID = flow.ID
 				}
 				session.CurrentStep = next
 				continue
@@ -140,18 +156,20 @@ func (a *App) runChatGraph(
 			return err
 		}
 
-		appendChatPath(session, node, res.outcome)
+		appendChatPath(session, flow.ID, node, res.outcome)
 
 		if res.yield {
 			// Stay at this node; next inbound resumes here.
 			return a.persistChatSession(session)
 		}
 
-		// goto_flow may have switched the session to a different flow.
-		// Reload graph + flow and continue at the new entry node within
-		// the same run, mirroring IVR's executeGotoFlow recursion. The
-		// outer loop's max-iteration guard prevents A→B→A pathologies.
-		if session.CurrentFlowID != nil && *session.CurrentFlowID != flow.ID {
+		// goto_flow and call_flow switch the session to another flow (a
+		// call may target the running flow itself). Reload graph + flow
+		// and continue at the new entry node within the same run,
+		// mirroring IVR's executeGotoFlow recursion. The outer loop's
+		// max-iteration guard prevents A→B→A pathologies.
+		called := node.Type == ChatNodeCallFlow && res.outcome == "call"
+		if called || (session.CurrentFlowID != nil && *session.CurrentFlowID != flow.ID) {
 			newFlow, err := a.getChatbotFlowByIDCached(account.OrganizationID, *session.CurrentFlowID)
 			if err != nil {
 				_ = a.persistChatSession(session)
@@ -168,15 +186,34 @@ func (a *App) runChatGraph(
 			}
 			flow = newFlow
 			graph = newGraph
+			ctx.flowID = flow.ID
 			session.CurrentStep = newGraph.EntryNode
 			continue
 		}
 
-		next := graph.ResolveEdge(node.ID, res.outcome)
+		var next string
+		if chatOutcomeIsExact(node.Type, res.outcome) {
+			next = resolveExactEdge(graph, node.ID, res.outcome)
+		} else if node.Type == ChatNodeForeach {
+			next = resolveForeachEdge(graph, node.ID, res.outcome)
+		} else {
+			next = graph.ResolveEdge(node.ID, res.outcome)
+		}
 		if next == "" {
-			// No matching edge → terminal.
-			session.Status = models.SessionStatusCompleted
-			return a.persistChatSession(session)
+			// No matching edge → terminal, unless this flow was called
+			// by another one: then the caller resumes.
+			outcome := "default"
+			if node.Type == ChatNodeEnd && res.outcome != "" {
+				outcome = res.outcome
+			}
+			caller, callerGraph, resume, err := a.returnFromChatCall(session, outcome)
+			if err != nil || resume == "" {
+				session.Status = models.SessionStatusCompleted
+				_ = a.persistChatSession(session)
+				return err
+			}
+			flow, graph, next = caller, callerGraph, resume
+			ctx.flowID = flow.ID
 		}
 		session.CurrentStep = next
 	}
@@ -185,10 +222,20 @@ func (a *App) runChatGraph(
 	return errChatGraphRunaway
 }
 
-// executeChatNode dispatches by node type. Phase 1 implements only
-// message, buttons, and end. Other types return an error until their
-// PR lands.
+// executeChatNode dispatches by node type: sends (message, media,
+// template, buttons, WhatsApp flow), input and waits (prompt, wait,
+// wait_for_reply), logic (condition, timing, split, set_variable,
+// foreach, script), integrations (api_call, webhook, ai_response),
+// contact updates (tag, field, opt-out, assign, note), and control
+// (transfer, goto_flow, call_flow, end). Unknown types yield with an
+// error. Executors see the node's config already translated into the
+// contact's language (see localizeChatConfig).
 func (a *App) executeChatNode(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if cfg := localizeChatConfig(node.Config, ctx.language); cfg != nil {
+		localized := *node
+		localized.Config = cfg
+		node = &localized
+	}
 	switch node.Type {
 	case ChatNodeStart:
 		// Always-present entry sentinel. No side effect — falls through.
@@ -197,6 +244,10 @@ func (a *App) executeChatNode(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, er
 		return nodeOutcome{outcome: "default"}, nil
 	case ChatNodeMessage:
 		return a.execChatMessage(node, ctx)
+	case ChatNodeMedia:
+		return a.execChatMedia(node, ctx)
+	case ChatNodeTemplate:
+		return a.execChatTemplate(node, ctx)
 	case ChatNodeButtons:
 		return a.execChatButtons(node, ctx)
 	case ChatNodePrompt:
@@ -217,13 +268,35 @@ func (a *App) executeChatNode(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, er
 		return a.execChatWebhook(node, ctx)
 	case ChatNodeGotoFlow:
 		return a.execChatGotoFlow(node, ctx)
+	case ChatNodeCallFlow:
+		return a.execChatCallFlow(node, ctx)
 	case ChatNodeWhatsAppFlow:
 		return a.execChatWhatsAppFlow(node, ctx)
+	case ChatNodeWait:
+		return a.execChatWait(node, ctx)
+	case ChatNodeWaitForReply:
+		return a.execChatWaitForReply(node, ctx)
+	case ChatNodeSplit:
+		return a.execChatSplit(node, ctx)
+	case ChatNodeForeach:
+		return a.execChatForeach(node, ctx)
+	case ChatNodeScript:
+		return a.execChatScript(node, ctx)
+	case ChatNodeTagContact:
+		return a.execChatTagContact(node, ctx)
+	case ChatNodeSetContactField:
+		return a.execChatSetContactField(node, ctx)
+	case ChatNodeSetOptOut:
+		return a.execChatSetOptOut(node, ctx)
+	case ChatNodeAssignContact:
+		return a.execChatAssignContact(node, ctx)
+	case ChatNodeAddNote:
+		return a.execChatAddNote(node, ctx)
 	case ChatNodeEnd:
 		return a.execChatEnd(node, ctx)
 	default:
 		return nodeOutcome{outcome: "", yield: true},
-			fmt.Errorf("chat node type %q not implemented in this phase", node.Type)
+			fmt.Errorf("unknown chat node type %q", node.Type)
 	}
 }
 
@@ -601,15 +674,35 @@ type scheduleLogger interface {
 // "greeting" = "Hello {{customer_name}}!"). Non-blocking; outcome
 // "default".
 //
+// The optional "language" key sets the contact's language (templated, so
+// it can come from a button or prompt answer). Later nodes in this and
+// future sessions use the matching translations; an unrecognised code is
+// logged and ignored.
+//
 // Config:
 //
 //	{
 //	  "set": {
 //	    "greeting":  "Hello {{customer_name}}!",
 //	    "tier":      "premium"
-//	  }
+//	  },
+//	  "language": "{{chosen_language}}"
 //	}
 func (a *App) execChatSetVariable(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if rawLang := stringFromConfig(node.Config, "language"); rawLang != "" && ctx.contact != nil {
+		lang := processTemplate(rawLang, ctx.session.SessionData)
+		if a.setContactLanguage(ctx.contact, lang, models.LanguageSourceFlow) {
+			ctx.language = ctx.contact.Language
+			if ctx.session.SessionData == nil {
+				ctx.session.SessionData = models.JSONB{}
+			}
+			ctx.session.SessionData["contact_language"] = ctx.language
+		} else {
+			a.Log.Warn("set_variable node has invalid language",
+				"node", node.ID, "session", ctx.session.ID, "language", lang)
+		}
+	}
+
 	assignments, _ := node.Config["set"].(map[string]any)
 	if len(assignments) == 0 {
 		return nodeOutcome{outcome: "default"}, nil
@@ -646,9 +739,25 @@ func (a *App) execChatSetVariable(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome
 //     user text (e.g. "Summarise the customer's situation: {{summary}}").
 //  2. ctx.userInput — the user's latest message.
 //
+// The node may override the model and system prompt of the org settings,
+// e.g. to route one step to a larger model on the same endpoint. The
+// provider, credentials and base URL always come from the settings.
+//
+// All enabled AI tools are offered unless config.tools names a subset;
+// an empty list disables tool calling for the node.
+//
 // Outcome is always "default". AI failures, empty replies, or AI being
 // disabled all advance via the default edge and log a warning — the
 // graph author can route to a fallback message there.
+//
+// Config:
+//
+//	{
+//	  "prompt_template": "Summarise: {{summary}}",  // optional; templated
+//	  "model":           "llama3:70b",              // optional override
+//	  "system_prompt":   "Answer in one sentence.", // optional override
+//	  "tools":           ["lookup_order"]           // optional subset of AI tools
+//	}
 func (a *App) execChatAIResponse(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
 	settings, err := a.getChatbotSettingsCached(ctx.account.OrganizationID, ctx.account.Name)
 	if err != nil {
@@ -656,13 +765,22 @@ func (a *App) execChatAIResponse(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome,
 			"node", node.ID, "session", ctx.session.ID, "error", err)
 		return nodeOutcome{outcome: "default"}, nil
 	}
-	if !settings.AI.Enabled || settings.AI.Provider == "" || settings.AI.APIKey == "" {
+	if !aiConfigured(settings.AI) {
 		a.Log.Warn("ai_response node hit but AI not configured",
 			"node", node.ID, "session", ctx.session.ID,
 			"ai_enabled", settings.AI.Enabled, "has_provider", settings.AI.Provider != "")
 		return nodeOutcome{outcome: "default"}, nil
 	}
 
+	// Copy before applying overrides — settings is shared via the cache.
+	nodeSettings := *settings
+	if model := stringFromConfig(node.Config, "model"); model != "" {
+		nodeSettings.AI.Model = model
+	}
+	if prompt := stringFromConfig(node.Config, "system_prompt"); prompt != "" {
+		nodeSettings.AI.SystemPrompt = prompt
+	}
+
 	userMessage := ctx.userInput
 	if tmpl := stringFromConfig(node.Config, "prompt_template", "prompt"); tmpl != "" {
 		if ctx.session.SessionData == nil {
@@ -671,22 +789,25 @@ func (a *App) execChatAIResponse(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome,
 		userMessage = processTemplate(tmpl, ctx.session.SessionData)
 	}
 
-	answer, err := a.generateAIResponse(settings, ctx.session, userMessage)
+	answer, err := a.generateAIResponse(&nodeSettings, ctx.session, userMessage, stringsFromConfig(node.Config, "tools"))
 	if err != nil {
 		a.Log.Error("ai_response node generateAIResponse failed",
 			"node", node.ID, "session", ctx.session.ID, "error", err)
+		if errors.Is(err, errAIBudgetExceeded) {
+			a.sendAIBudgetFallback(ctx.account, ctx.contact, ctx.session)
+		}
 		return nodeOutcome{outcome: "default"}, nil
 	}
-	if answer == "" {
+	if answer.Content == "" {
 		a.Log.Warn("ai_response node got empty answer from provider",
 			"node", node.ID, "session", ctx.session.ID)
 		return nodeOutcome{outcome: "default"}, nil
 	}
 
-	if err := a.sendAndSaveTextMessage(ctx.account, ctx.contact, answer); err != nil {
+	if err := a.sendAndSaveAIReply(ctx.account, ctx.contact, answer); err != nil {
 		return nodeOutcome{}, fmt.Errorf("send ai response: %w", err)
 	}
-	a.logSessionMessage(ctx.session.ID, models.DirectionOutgoing, answer, node.ID)
+	a.logSessionMessage(ctx.session.ID, models.DirectionOutgoing, answer.Content, node.ID)
 	return nodeOutcome{outcome: "default"}, nil
 }
 
@@ -792,39 +913,8 @@ func (a *App) execChatWebhook(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, er
 // is logged and terminates the source flow gracefully rather than
 // erroring the inbound webhook.
 func (a *App) execChatGotoFlow(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
-	targetIDStr := stringFromConfig(node.Config, "flow_id")
-	if targetIDStr == "" {
-		a.Log.Warn("goto_flow node missing flow_id",
-			"node", node.ID, "session", ctx.session.ID)
-		return nodeOutcome{}, nil
-	}
-	targetID, err := uuid.Parse(targetIDStr)
-	if err != nil {
-		a.Log.Warn("goto_flow node has invalid flow_id",
-			"node", node.ID, "flow_id", targetIDStr, "error", err)
-		return nodeOutcome{}, nil
-	}
-
-	target, err := a.getChatbotFlowByIDCached(ctx.account.OrganizationID, targetID)
-	if err != nil || target == nil {
-		a.Log.Warn("goto_flow target not found",
-			"node", node.ID, "flow_id", targetID, "error", err)
-		return nodeOutcome{}, nil
-	}
-	if !target.IsEnabled {
-		a.Log.Warn("goto_flow target is disabled",
-			"node", node.ID, "flow_id", targetID)
-		return nodeOutcome{}, nil
-	}
-	if target.WhatsAppAccount != ctx.session.WhatsAppAccount {
-		a.Log.Warn("goto_flow target belongs to a different WA account; refusing",
-			"node", node.ID, "target_account", target.WhatsAppAccount,
-			"session_account", ctx.session.WhatsAppAccount)
-		return nodeOutcome{}, nil
-	}
-	if target.Graph == nil {
-		a.Log.Warn("goto_flow target has no v2 graph",
-			"node", node.ID, "flow_id", targetID)
+	target := a.chatFlowTarget(node, ctx)
+	if target == nil {
 		return nodeOutcome{}, nil
 	}
 
@@ -847,6 +937,49 @@ func (a *App) execChatGotoFlow(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, e
 	return nodeOutcome{outcome: "goto"}, nil
 }
 
+// chatFlowTarget loads the flow named by node.Config["flow_id"] for
+// goto_flow and call_flow. Returns nil (after logging why) when the id is
+// missing or invalid, or the target is disabled, belongs to another WA
+// account, or has no v2 graph.
+func (a *App) chatFlowTarget(node *ChatNode, ctx *chatNodeCtx) *models.ChatbotFlow {
+	targetIDStr := stringFromConfig(node.Config, "flow_id")
+	if targetIDStr == "" {
+		a.Log.Warn("flow node missing flow_id",
+			"type", node.Type, "node", node.ID, "session", ctx.session.ID)
+		return nil
+	}
+	targetID, err := uuid.Parse(targetIDStr)
+	if err != nil {
+		a.Log.Warn("flow node has invalid flow_id",
+			"type", node.Type, "node", node.ID, "flow_id", targetIDStr, "error", err)
+		return nil
+	}
+
+	target, err := a.getChatbotFlowByIDCached(ctx.account.OrganizationID, targetID)
+	if err != nil || target == nil {
+		a.Log.Warn("flow node target not found",
+			"type", node.Type, "node", node.ID, "flow_id", targetID, "error", err)
+		return nil
+	}
+	if !target.IsEnabled {
+		a.Log.Warn("flow node target is disabled",
+			"type", node.Type, "node", node.ID, "flow_id", targetID)
+		return nil
+	}
+	if target.WhatsAppAccount != ctx.session.WhatsAppAccount {
+		a.Log.Warn("flow node target belongs to a different WA account; refusing",
+			"type", node.Type, "node", node.ID, "target_account", target.WhatsAppAccount,
+			"session_account", ctx.session.WhatsAppAccount)
+		return nil
+	}
+	if target.Graph == nil {
+		a.Log.Warn("flow node target has no v2 graph",
+			"type", node.Type, "node", node.ID, "flow_id", targetID)
+		return nil
+	}
+	return target
+}
+
 // execChatWhatsAppFlow sends an interactive WhatsApp Flow form on first
 // entry (yields to wait for the user's submission). On a later inbound
 // that carries a parsed flow_response_data, merges those fields into
@@ -885,28 +1018,7 @@ func (a *App) execChatWhatsAppFlow(node *ChatNode, ctx *chatNodeCtx) (nodeOutcom
 	header := processTemplate(stringFromConfig(node.Config, "header"), ctx.session.SessionData)
 	cta := processTemplate(stringFromConfig(node.Config, "cta"), ctx.session.SessionData)
 
-	// Look up the first screen — same pattern as the legacy executor so
-	// existing WhatsAppFlow rows continue to work.
-	firstScreen := ""
-	var waFlow models.WhatsAppFlow
-	if err := a.DB.Where("meta_flow_id = ?", flowID).First(&waFlow).Error; err == nil {
-		if len(waFlow.Screens) > 0 {
-			if screenMap, ok := waFlow.Screens[0].(map[string]any); ok {
-				if id, ok := screenMap["id"].(string); ok {
-					firstScreen = id
-				}
-			}
-		}
-		if firstScreen == "" && waFlow.FlowJSON != nil {
-			if screens, ok := waFlow.FlowJSON["screens"].([]any); ok && len(screens) > 0 {
-				if screenMap, ok := screens[0].(map[string]any); ok {
-					if id, ok := screenMap["id"].(string); ok {
-						firstScreen = id
-					}
-				}
-			}
-		}
-	}
+	firstScreen := a.whatsAppFlowFirstScreen(flowID)
 
 	flowToken := fmt.Sprintf("chatbot_%s_%s_%d", ctx.session.ID.String(), node.ID, time.Now().UnixNano())
 	if err := a.sendAndSaveFlowMessage(ctx.account, ctx.contact, flowID, header, body, cta, flowToken, firstScreen); err != nil {
@@ -916,10 +1028,39 @@ func (a *App) execChatWhatsAppFlow(node *ChatNode, ctx *chatNodeCtx) (nodeOutcom
 	return nodeOutcome{yield: true}, nil
 }
 
-// execChatEnd optionally sends a final message and returns an empty
-// outcome. The runner sees no matching edge and marks the session
-// completed.
-// Config: { "message": "..." } (optional)
+// whatsAppFlowFirstScreen returns the id of the first screen of the stored
+// WhatsApp Flow with the given Meta flow ID, or "" when it isn't known
+// locally. Screens come from the flow's screen list, falling back to its
+// flow JSON.
+func (a *App) whatsAppFlowFirstScreen(metaFlowID string) string {
+	var waFlow models.WhatsAppFlow
+	if err := a.DB.Where("meta_flow_id = ?", metaFlowID).First(&waFlow).Error; err != nil {
+		return ""
+	}
+	if len(waFlow.Screens) > 0 {
+		if screenMap, ok := waFlow.Screens[0].(map[string]any); ok {
+			if id, ok := screenMap["id"].(string); ok && id != "" {
+				return id
+			}
+		}
+	}
+	if waFlow.FlowJSON != nil {
+		if screens, ok := waFlow.FlowJSON["screens"].([]any); ok && len(screens) > 0 {
+			if screenMap, ok := screens[0].(map[string]any); ok {
+				if id, ok := screenMap["id"].(string); ok {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// execChatEnd optionally sends a final message and returns the configured
+// outcome (empty by default). The runner sees no matching edge and marks
+// the session completed, or, inside a call_flow, hands the outcome back to
+// the caller.
+// Config: { "message": "...", "outcome": "verified" } (both optional)
 func (a *App) execChatEnd(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
 	if msg := stringFromConfig(node.Config, "message"); msg != "" {
 		msg = processTemplate(msg, ctx.session.SessionData)
@@ -928,7 +1069,7 @@ func (a *App) execChatEnd(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error)
 		}
 		a.logSessionMessage(ctx.session.ID, models.DirectionOutgoing, msg, node.ID)
 	}
-	return nodeOutcome{}, nil
+	return nodeOutcome{outcome: stringFromConfig(node.Config, "outcome")}, nil
 }
 
 // persistChatSession writes the running session state back to the DB.
@@ -936,7 +1077,8 @@ func (a *App) execChatEnd(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error)
 // + dedicated columns. Called after every yield and on the completion path.
 func (a *App) persistChatSession(s *models.ChatbotSession) error {
 	s.LastActivityAt = time.Now()
-	if s.Status == models.SessionStatusCompleted && s.CompletedAt == nil {
+	completed := s.Status == models.SessionStatusCompleted && s.CompletedAt == nil
+	if completed {
 		now := time.Now()
 		s.CompletedAt = &now
 	}
@@ -944,13 +1086,17 @@ func (a *App) persistChatSession(s *models.ChatbotSession) error {
 		a.Log.Error("persist chat session", "session", s.ID, "error", err)
 		return err
 	}
+	if completed && s.CurrentFlowID != nil {
+		a.triggerSequences(s.OrganizationID, s.ContactID, models.SequenceTriggerFlowCompleted, s.CurrentFlowID.String())
+	}
 	return nil
 }
 
 // appendChatPath records the executed node + outcome in SessionData["__path__"].
 // The shape mirrors IVRContext.Path so frontends and audit tooling can
-// render either domain's trail with the same code.
-func appendChatPath(s *models.ChatbotSession, node *ChatNode, outcome string) {
+// render either domain's trail with the same code. flow_id tells apart
+// nodes of called flows that share an ID with the caller's.
+func appendChatPath(s *models.ChatbotSession, flowID uuid.UUID, node *ChatNode, outcome string) {
 	if s.SessionData == nil {
 		s.SessionData = models.JSONB{}
 	}
@@ -959,6 +1105,7 @@ func appendChatPath(s *models.ChatbotSession, node *ChatNode, outcome string) {
 		"type":    string(node.Type),
 		"label":   node.Label,
 		"outcome": outcome,
+		"flow_id": flowID.String(),
 	}
 	path, _ := s.SessionData["__path__"].([]any)
 	path = append(path, entry)
@@ -988,6 +1135,23 @@ func intFromConfig(cfg map[string]any, key string, def int) int {
 	return def
 }
 
+// stringsFromConfig returns the strings in a JSON array at key. A missing
+// key yields nil; a present but empty array yields an empty, non-nil slice
+// so callers can tell "not configured" from "explicitly none".
+func stringsFromConfig(cfg map[string]any, key string) []string {
+	raw, ok := cfg[key].([]any)
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 // buttonsFromConfig normalizes node.Config["buttons"] into the shape the
 // existing sendAndSaveInteractiveButtons helper expects.
 // Accepts: [{"id": "...", "title": "...", "type": "..."(optional)}, ...]
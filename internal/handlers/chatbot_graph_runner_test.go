@@ -1041,6 +1041,108 @@ func TestRunChatGraph_AIResponse_MissingAPIKeyFallsThrough(t *testing.T) {
 	assert.Equal(t, models.SessionStatusCompleted, session.Status)
 }
 
+// TestRunChatGraph_AIResponse_OpenAICompatible verifies the node reaches a
+// self-hosted OpenAI-compatible endpoint without an API key, applies the
+// node-level model override, and sends the answer.
+func TestRunChatGraph_AIResponse_OpenAICompatible(t *testing.T) {
+	var gotModel, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotModel, _ = body["model"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"Local answer"}}]}`))
+	}))
+	defer server.Close()
+
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	createChatbotSettings(t, app, org.ID, account.Name, models.AIConfig{
+		Enabled:   true,
+		Provider:  models.AIProviderOpenAICompatible,
+		BaseURL:   server.URL + "/v1",
+		Model:     "llama3",
+		MaxTokens: 100,
+	})
+	flow := newAIResponseFlow(t, app, org, account, "")
+	flow.Graph["nodes"].([]any)[0].(map[string]any)["config"] = map[string]any{"model": "llama3:70b"}
+	require.NoError(t, app.DB.Save(flow).Error)
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "hi", "", nil))
+	assert.Equal(t, "llama3:70b", gotModel)
+	assert.Empty(t, gotAuth)
+
+	var msgs []models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND direction = ?", session.ID, models.DirectionOutgoing).Find(&msgs).Error)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Local answer", msgs[0].Message)
+}
+
+func TestRunChatGraph_AIResponse_ToolCalling(t *testing.T) {
+	var toolPath string
+	toolServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		toolPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"status":"shipped"}`))
+	}))
+	defer toolServer.Close()
+
+	var calls int
+	var lastMessages []any
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		lastMessages, _ = body["messages"].([]any)
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"tool_calls":[
+				{"id":"call_1","type":"function","function":{"name":"lookup_order","arguments":"{\"order_id\":\"42\"}"}}
+			]}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"Order 42 has shipped."}}]}`))
+	}))
+	defer llm.Close()
+
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	createChatbotSettings(t, app, org.ID, account.Name, models.AIConfig{
+		Enabled:           true,
+		Provider:          models.AIProviderOpenAICompatible,
+		BaseURL:           llm.URL,
+		Model:             "llama3",
+		MaxTokens:         100,
+		MaxToolIterations: 3,
+	})
+	require.NoError(t, app.DB.Create(&models.AITool{
+		OrganizationID: org.ID,
+		Name:           "lookup_order",
+		Description:    "Look up an order",
+		Parameters:     models.JSONB{"type": "object"},
+		ApiConfig:      models.JSONB{"url": toolServer.URL + "/orders/{{order_id}}", "method": "GET"},
+		TimeoutSeconds: 5,
+		IsEnabled:      true,
+	}).Error)
+	flow := newAIResponseFlow(t, app, org, account, "")
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "where is order 42?", "", nil))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "/orders/42", toolPath)
+	require.NotEmpty(t, lastMessages)
+	toolMsg := lastMessages[len(lastMessages)-1].(map[string]any)
+	assert.Equal(t, "tool", toolMsg["role"])
+	assert.Equal(t, `{"status":"shipped"}`, toolMsg["content"])
+
+	var toolRows []models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND tool_name = ?", session.ID, "lookup_order").Find(&toolRows).Error)
+	require.Len(t, toolRows, 1)
+	assert.Equal(t, "call_1", toolRows[0].ToolCallID)
+	assert.Equal(t, "42", toolRows[0].ToolArguments["order_id"])
+
+	var replies []models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND COALESCE(tool_name, '') = ''", session.ID).Find(&replies).Error)
+	require.Len(t, replies, 1)
+	assert.Equal(t, "Order 42 has shipped.", replies[0].Message)
+}
+
 // newTransferFlow builds a single-node graph (transfer) with caller-
 // supplied config. Transfer is terminal so no outgoing edges.
 func newTransferFlow(t *testing.T, app *App, org *models.Organization, account *models.WhatsAppAccount, cfg map[string]any) *models.ChatbotFlow {
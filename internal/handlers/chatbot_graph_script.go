@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// Script node limits. goja can't cap a single VM's heap, so memory is
+// bounded on a best-effort basis:
+//
+//   - the input and output are capped at chatScriptMaxInput and
+//     chatScriptMaxOutput bytes;
+//   - the builtins that build large strings or arrays in one call
+//     (repeat, padStart, fill, join, concat, ...) are capped per call, and
+//     what they allocate is counted against chatScriptMaxAllocBytes for
+//     the whole script;
+//   - at most chatScriptMaxConcurrent scripts run at once, so their
+//     combined footprint stays bounded under load.
+//
+// Allocations the guards can't see, like doubling a string with + in a
+// loop, are left to the time limit and to a watchdog that interrupts the
+// script when the live heap grows past chatScriptMaxHeapGrowth while it
+// runs. The live heap is process-wide, so the watchdog is a last resort
+// for runaway scripts rather than a per-script quota.
+const (
+	chatScriptDefaultTimeout = 500 * time.Millisecond
+	chatScriptMaxTimeout     = 2 * time.Second
+	chatScriptMaxCodeLen     = 20000
+	chatScriptMaxInput       = 256 << 10
+	chatScriptMaxOutput      = 256 << 10
+	chatScriptMaxStringLen   = 1 << 20
+	chatScriptMaxArrayLen    = 100000
+	chatScriptMaxAllocBytes  = 32 << 20
+	chatScriptMaxConcurrent  = 8
+	chatScriptMaxHeapGrowth  = 64 << 20
+	chatScriptMaxCallStack   = 256
+)
+
+// chatScriptSlots holds a token per running script.
+var chatScriptSlots = make(chan struct{}, chatScriptMaxConcurrent)
+
+var errChatScriptMemory = errors.New("heap grew past the script memory watchdog")
+
+// execChatScript runs JavaScript in a sandboxed goja VM to transform
+// session data. The script sees:
+//
+//   - vars: the session variables (reserved __ keys excluded); changes,
+//     additions and deletions are written back.
+//   - contact: { id, phone_number, name, language, tags, metadata,
+//     marketing_opt_out }; only metadata changes are saved, audited as the
+//     chatbot.
+//   - helpers: formatDate(date, "YYYY-MM-DD HH:mm", "Asia/Kolkata"),
+//     sha256(text), md5(text).
+//   - console.log, which writes to the server log.
+//
+// There is no network, filesystem or module access. A returned string
+// picks the outgoing edge (falling back to default). Exceptions, timeouts
+// and limit violations take the "error" edge (also falling back to
+// default) and leave variables unchanged.
+//
+// Config: { "code": "vars.total = vars.items.length; return vars.total ? 'some' : 'none'",
+// "timeout_ms": 500 }
+func (a *App) execChatScript(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	code := stringFromConfig(node.Config, "code")
+	if strings.TrimSpace(code) == "" {
+		return nodeOutcome{outcome: "default"}, nil
+	}
+	timeout := time.Duration(intFromConfig(node.Config, "timeout_ms", 0)) * time.Millisecond
+	if timeout <= 0 {
+		timeout = chatScriptDefaultTimeout
+	}
+	timeout = min(timeout, chatScriptMaxTimeout)
+
+	vars := models.JSONB{}
+	for k, v := range ctx.session.SessionData {
+		if !strings.HasPrefix(k, "__") {
+			vars[k] = v
+		}
+	}
+	var contact map[string]any
+	if ctx.contact != nil {
+		contact = map[string]any{
+			"id":                ctx.contact.ID.String(),
+			"phone_number":      ctx.contact.PhoneNumber,
+			"name":              ctx.contact.ProfileName,
+			"language":          ctx.contact.Language,
+			"tags":              ctx.contact.Tags,
+			"metadata":          ctx.contact.Metadata,
+			"marketing_opt_out": ctx.contact.MarketingOptOut,
+		}
+	}
+
+	res, err := runChatScript(code, vars, contact, timeout, func(args ...any) {
+		a.Log.Info("chatbot script: "+fmt.Sprint(args...), "node", node.ID, "session", ctx.session.ID)
+	})
+	if err != nil {
+		a.Log.Warn("chatbot script failed", "error", err, "node", node.ID, "session", ctx.session.ID)
+		return nodeOutcome{outcome: "error"}, nil
+	}
+
+	data := models.JSONB{}
+	for k, v := range ctx.session.SessionData {
+		if strings.HasPrefix(k, "__") {
+			data[k] = v
+		}
+	}
+	for k, v := range res.Vars {
+		if !strings.HasPrefix(k, "__") {
+			data[k] = v
+		}
+	}
+	ctx.session.SessionData = data
+
+	if ctx.contact != nil && res.Metadata != nil {
+		// Only the keys the script changed are written, so fields edited
+		// elsewhere while it ran are kept.
+		before := normalizeJSON(ctx.contact.Metadata)
+		fields := models.JSONB{}
+		for k, v := range res.Metadata {
+			if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+				fields[k] = v
+			}
+		}
+		var unset []string
+		for k := range before {
+			if _, ok := res.Metadata[k]; !ok {
+				unset = append(unset, k)
+			}
+		}
+		if len(fields) > 0 || len(unset) > 0 {
+			if out := a.setChatContactFields(node, ctx, fields, unset); out.outcome != "default" {
+				return out, nil
+			}
+		}
+	}
+
+	if res.Outcome != "" {
+		return nodeOutcome{outcome: res.Outcome}, nil
+	}
+	return nodeOutcome{outcome: "default"}, nil
+}
+
+// chatScriptResult is what a script hands back to the runner.
+type chatScriptResult struct {
+	Vars     map[string]any `json:"vars"`
+	Metadata map[string]any `json:"metadata"`
+	Outcome  string         `json:"outcome"`
+}
+
+// runChatScript executes code with the given variables and contact and
+// returns their state afterwards. Values cross the VM boundary as JSON, so
+// scripts see plain JavaScript objects.
+func runChatScript(code string, vars map[string]any, contact map[string]any, timeout time.Duration, logFn func(...any)) (*chatScriptResult, error) {
+	if len(code) > chatScriptMaxCodeLen {
+		return nil, fmt.Errorf("script is longer than %d characters", chatScriptMaxCodeLen)
+	}
+	varsJSON, err := json.Marshal(vars)
+	if err != nil {
+		return nil, fmt.Errorf("encode vars: %w", err)
+	}
+	contactJSON, err := json.Marshal(contact)
+	if err != nil {
+		return nil, fmt.Errorf("encode contact: %w", err)
+	}
+
+	if len(varsJSON)+len(contactJSON) > chatScriptMaxInput {
+		return nil, fmt.Errorf("script input is larger than %d bytes", chatScriptMaxInput)
+	}
+
+	// A script that can't get a slot within its time limit fails
+	wait := time.NewTimer(timeout)
+	select {
+	case chatScriptSlots <- struct{}{}:
+		wait.Stop()
+	case <-wait.C:
+		return nil, fmt.Errorf("too many scripts running; no slot within %s", timeout)
+	}
+	defer func() { <-chatScriptSlots }()
+
+	vm := goja.New()
+	vm.SetMaxCallStackSize(chatScriptMaxCallStack)
+	if _, err := vm.RunString(chatScriptGuards); err != nil {
+		return nil, fmt.Errorf("install script guards: %w", err)
+	}
+	_ = vm.Set("__vars", string(varsJSON))
+	_ = vm.Set("__contact", string(contactJSON))
+	_ = vm.Set("helpers", chatScriptHelpers(vm))
+	_ = vm.Set("console", map[string]any{"log": logFn})
+
+	// Compile first so syntax errors don't count against the time limit.
+	prog, err := goja.Compile("script", `(function() {
+	var vars = JSON.parse(__vars) || {};
+	var contact = JSON.parse(__contact);
+	var __ret = (function(vars, contact, helpers) {
+`+code+`
+	})(vars, contact, helpers);
+	return JSON.stringify({
+		vars: vars,
+		metadata: contact ? contact.metadata : null,
+		outcome: typeof __ret === "string" ? __ret : ""
+	});
+})()`, true)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go watchChatScript(vm, timeout, done)
+
+	val, err := vm.RunProgram(prog)
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			if e, ok := interrupted.Value().(error); ok {
+				return nil, e
+			}
+		}
+		return nil, err
+	}
+	out := val.String()
+	if len(out) > chatScriptMaxOutput {
+		return nil, fmt.Errorf("script output is larger than %d bytes", chatScriptMaxOutput)
+	}
+	var res chatScriptResult
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		return nil, fmt.Errorf("decode script result: %w", err)
+	}
+	if res.Vars == nil {
+		res.Vars = map[string]any{}
+	}
+	return &res, nil
+}
+
+// chatScriptGuards wraps the builtins that allocate in proportion to an
+// argument so one call can't build a string longer than
+// chatScriptMaxStringLen or an array longer than chatScriptMaxArrayLen,
+// and counts what they build against chatScriptMaxAllocBytes, estimated
+// at two bytes per character and eight per array element.
+var chatScriptGuards = fmt.Sprintf(`(function() {
+	var maxString = %d, maxArray = %d, maxAlloc = %d, allocated = 0;
+	function charge(bytes) {
+		allocated += bytes;
+		if (allocated > maxAlloc) throw new RangeError("script allocated more than " + maxAlloc + " bytes");
+	}
+	function checkString(n) {
+		if (n > maxString) throw new RangeError("string longer than " + maxString + " characters");
+		charge(n * 2);
+	}
+	function checkArray(n) {
+		if (n > maxArray) throw new RangeError("array longer than " + maxArray + " elements");
+		charge(n * 8);
+	}
+	function lengthOf(v, fallback) {
+		return v != null && typeof v.length === "number" ? v.length : fallback;
+	}
+	function guard(proto, name, check) {
+		var orig = proto[name];
+		Object.defineProperty(proto, name, {
+			value: function() {
+				check(this, arguments);
+				return orig.apply(this, arguments);
+			},
+			writable: true,
+			configurable: true
+		});
+	}
+	guard(String.prototype, "repeat", function(s, args) { checkString(String(s).length * (Number(args[0]) || 0)); });
+	guard(String.prototype, "padStart", function(s, args) { checkString(Number(args[0]) || 0); });
+	guard(String.prototype, "padEnd", function(s, args) { checkString(Number(args[0]) || 0); });
+	guard(String.prototype, "concat", function(s, args) {
+		var n = String(s).length;
+		for (var i = 0; i < args.length; i++) n += String(args[i]).length;
+		checkString(n);
+	});
+	guard(Array.prototype, "fill", function(a) { checkArray(a.length); });
+	guard(Array.prototype, "concat", function(a, args) {
+		var n = a.length;
+		for (var i = 0; i < args.length; i++) n += Array.isArray(args[i]) ? args[i].length : 1;
+		checkArray(n);
+	});
+	guard(Array.prototype, "join", function(a, args) {
+		checkArray(a.length);
+		checkString((a.length - 1) * (args[0] === undefined ? 1 : String(args[0]).length));
+	});
+	guard(Array, "from", function(_, args) { if (args[0] != null) checkArray(Number(lengthOf(args[0], 0)) || 0); });
+	var OrigArray = Array;
+	Array = function(n) {
+		if (arguments.length === 1 && typeof n === "number") checkArray(n);
+		return OrigArray.apply(this, arguments);
+	};
+	Array.prototype = OrigArray.prototype;
+	Object.setPrototypeOf(Array, OrigArray);
+	Object.defineProperty(OrigArray.prototype, "constructor", {value: Array, writable: true, configurable: true});
+})();`, chatScriptMaxStringLen, chatScriptMaxArrayLen, chatScriptMaxAllocBytes)
+
+// watchChatScript interrupts vm when it runs past timeout or the live heap
+// grows by more than chatScriptMaxHeapGrowth, until done is closed. The
+// live heap is measured at the end of each GC, so garbage left by other
+// goroutines doesn't count, but their live data does; see the limits
+// above.
+func watchChatScript(vm *goja.Runtime, timeout time.Duration, done <-chan struct{}) {
+	sample := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(sample)
+	baseline := sample[0].Value.Uint64()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-deadline.C:
+			vm.Interrupt(fmt.Errorf("script timed out after %s", timeout))
+			return
+		case <-tick.C:
+			metrics.Read(sample)
+			if heap := sample[0].Value.Uint64(); heap > baseline && heap-baseline > chatScriptMaxHeapGrowth {
+				vm.Interrupt(errChatScriptMemory)
+				return
+			}
+		}
+	}
+}
+
+// chatScriptHelpers are the deterministic utilities exposed as helpers.*.
+func chatScriptHelpers(vm *goja.Runtime) map[string]any {
+	return map[string]any{
+		"formatDate": func(call goja.FunctionCall) goja.Value {
+			t, ok := chatScriptTime(call.Argument(0))
+			if !ok {
+				panic(vm.NewTypeError("formatDate: invalid date"))
+			}
+			if tz := call.Argument(2); !goja.IsUndefined(tz) && tz.String() != "" {
+				loc, err := time.LoadLocation(tz.String())
+				if err != nil {
+					panic(vm.NewTypeError("formatDate: unknown timezone " + tz.String()))
+				}
+				t = t.In(loc)
+			}
+			layout := time.RFC3339
+			if f := call.Argument(1); !goja.IsUndefined(f) && f.String() != "" {
+				layout = chatScriptDateLayout.Replace(f.String())
+			}
+			return vm.ToValue(t.Format(layout))
+		},
+		"sha256": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"md5": func(s string) string {
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+	}
+}
+
+// chatScriptDateLayout maps moment-style tokens to Go layouts. Longer
+// tokens come first so "MMMM" isn't read as two "MM"s.
+var chatScriptDateLayout = strings.NewReplacer(
+	"YYYY", "2006", "YY", "06",
+	"MMMM", "January", "MMM", "Jan", "MM", "01", "M", "1",
+	"dddd", "Monday", "ddd", "Mon",
+	"DD", "02", "D", "2",
+	"HH", "15", "hh", "03", "h", "3",
+	"mm", "04", "ss", "05", "A", "PM",
+)
+
+// chatScriptTime reads a Date, epoch milliseconds or an ISO string.
+func chatScriptTime(v goja.Value) (time.Time, bool) {
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return time.Time{}, false
+	}
+	switch x := v.Export().(type) {
+	case time.Time:
+		return x, true
+	case int64:
+		return time.UnixMilli(x).UTC(), true
+	case float64:
+		return time.UnixMilli(int64(x)).UTC(), true
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, x); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// normalizeJSON round-trips v through JSON so it compares equal to values
+// decoded from a script.
+func normalizeJSON(v map[string]any) map[string]any {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return maps.Clone(v)
+	}
+	var out map[string]any
+	_ = json.Unmarshal(b, &out)
+	return out
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scriptTestCtx(data models.JSONB) *chatNodeCtx {
+	return &chatNodeCtx{session: &models.ChatbotSession{SessionData: data}}
+}
+
+func TestExecChatScript_TransformsVariables(t *testing.T) {
+	app := &App{Log: testutil.NopLogger()}
+	ctx := scriptTestCtx(models.JSONB{
+		"orders":    []any{map[string]any{"total": 10.5}, map[string]any{"total": 4.5}},
+		"stale":     "x",
+		chatPathKey: []any{"a"},
+	})
+	node := &ChatNode{ID: "s", Type: ChatNodeScript, Config: map[string]any{"code": `
+		vars.sum = vars.orders.reduce(function(s, o) { return s + o.total; }, 0);
+		delete vars.stale;
+		vars.__path__ = "overwritten";
+		return vars.sum > 10 ? "big" : "small";
+	`}}
+
+	res, err := app.execChatScript(node, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "big", res.outcome)
+	assert.EqualValues(t, 15, ctx.session.SessionData["sum"])
+	assert.NotContains(t, ctx.session.SessionData, "stale")
+	assert.Equal(t, []any{"a"}, ctx.session.SessionData[chatPathKey], "reserved keys are not writable")
+}
+
+func TestExecChatScript_ErrorsLeaveVariablesUnchanged(t *testing.T) {
+	app := &App{Log: testutil.NopLogger()}
+	for name, code := range map[string]string{
+		"exception":    `vars.x = 2; throw new Error("boom");`,
+		"syntax error": `vars.x = ;`,
+		"timeout":      `vars.x = 2; while (true) {}`,
+		"recursion":    `function f() { return f(); } f();`,
+		"no network":   `fetch("https://example.com");`,
+		"huge string":  `vars.x = "x".repeat(1e9);`,
+		"huge padding": `vars.x = "x".padStart(1e9);`,
+		"huge array":   `vars.x = new Array(1e9).fill(0);`,
+		"huge join":    `var a = []; a.length = 1e8; vars.x = a.join("-");`,
+		"many allocs":  `var a = []; for (var i = 0; i < 100; i++) a.push("x".repeat(1e6)); vars.x = a.length;`,
+		"huge concat":  `var a = new Array(60000).fill(0); vars.x = a.concat(a).length;`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := scriptTestCtx(models.JSONB{"x": 1})
+			node := &ChatNode{ID: "s", Config: map[string]any{"code": code, "timeout_ms": 50}}
+			res, err := app.execChatScript(node, ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "error", res.outcome)
+			assert.Equal(t, 1, ctx.session.SessionData["x"])
+		})
+	}
+}
+
+func TestRunChatScript_RejectsLargeInput(t *testing.T) {
+	vars := map[string]any{"blob": strings.Repeat("x", chatScriptMaxInput)}
+	_, err := runChatScript(`return "ok";`, vars, nil, time.Second, func(...any) {})
+	assert.ErrorContains(t, err, "input is larger")
+}
+
+func TestRunChatScript_WaitsForSlot(t *testing.T) {
+	for range chatScriptMaxConcurrent {
+		chatScriptSlots <- struct{}{}
+	}
+	_, err := runChatScript(`return "ok";`, map[string]any{}, nil, 20*time.Millisecond, func(...any) {})
+	assert.ErrorContains(t, err, "too many scripts running")
+
+	<-chatScriptSlots
+	res, err := runChatScript(`return "ok";`, map[string]any{}, nil, time.Second, func(...any) {})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res.Outcome)
+	for range chatScriptMaxConcurrent - 1 {
+		<-chatScriptSlots
+	}
+}
+
+func TestRunChatScript_GuardedBuiltinsStillWork(t *testing.T) {
+	res, err := runChatScript(`
+		vars.s = "ab".repeat(2) + "7".padStart(3, "0") + [1, 2].join("-") + "c".concat("d", 1);
+		vars.c = [1].concat([2, 3], 4).length;
+		vars.n = Array(3).length + new Array(1, 2).length + Array.from([1]).length;
+		vars.isArray = [] instanceof Array && Array.isArray(Array.of(1));
+	`, map[string]any{}, nil, time.Second, func(...any) {})
+	require.NoError(t, err)
+	assert.Equal(t, "abab0071-2cd1", res.Vars["s"])
+	assert.EqualValues(t, 4, res.Vars["c"])
+	assert.EqualValues(t, 6, res.Vars["n"])
+	assert.Equal(t, true, res.Vars["isArray"])
+}
+
+func TestRunChatScript_Helpers(t *testing.T) {
+	contact := map[string]any{"id": uuid.Nil.String(), "metadata": map[string]any{"tier": "gold"}}
+	res, err := runChatScript(`
+		vars.date = helpers.formatDate("2026-03-01T18:30:00Z", "DD/MM/YYYY HH:mm", "Asia/Kolkata");
+		vars.ms = helpers.formatDate(0, "YYYY-MM-DD");
+		vars.sha = helpers.sha256("abc");
+		vars.md5 = helpers.md5("abc");
+		contact.metadata.tier = "platinum";
+	`, map[string]any{}, contact, time.Second, func(...any) {})
+	require.NoError(t, err)
+
+	assert.Equal(t, "02/03/2026 00:00", res.Vars["date"])
+	assert.Equal(t, "1970-01-01", res.Vars["ms"])
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", res.Vars["sha"])
+	assert.Equal(t, "900150983cd24fb0d6963f7d28e17f72", res.Vars["md5"])
+	assert.Equal(t, map[string]any{"tier": "platinum"}, res.Metadata)
+	assert.Empty(t, res.Outcome)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"hash/fnv"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// chatSplitsKey holds split assignments as
+// { "<flow_id>": { "<node_id>": { "variant": "<variant_id>", "flow_id": "<outer_flow_id>" } } },
+// keyed by the flow the split node is in. flow_id is the flow the session
+// started in, which differs when the split ran in a called flow. Like the
+// path and the call stack it survives call_flow scope switches, so flow
+// analytics see assignments made inside sub-flows too. Sessions from
+// before flow_id was recorded hold the bare variant ID.
+const chatSplitsKey = "__splits__"
+
+// chatSplitVariant is one weighted branch of a split node.
+type chatSplitVariant struct {
+	ID     string
+	Label  string
+	Weight int
+}
+
+// chatSplitVariants reads the node's variants, skipping unnamed ones and
+// those without a positive weight.
+func chatSplitVariants(cfg map[string]any) []chatSplitVariant {
+	raw, _ := cfg["variants"].([]any)
+	out := make([]chatSplitVariant, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		v := chatSplitVariant{
+			ID:     stringFromConfig(m, "id"),
+			Label:  stringFromConfig(m, "label"),
+			Weight: intFromConfig(m, "weight", 0),
+		}
+		if v.ID != "" && v.Weight > 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// pickChatSplitVariant deterministically maps key onto the variants in
+// proportion to their weights, so the same contact always lands in the
+// same variant while the weights are unchanged.
+func pickChatSplitVariant(variants []chatSplitVariant, key string) string {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	bucket := int(h.Sum32() % uint32(total))
+	for _, v := range variants {
+		if bucket < v.Weight {
+			return v.ID
+		}
+		bucket -= v.Weight
+	}
+	return variants[len(variants)-1].ID
+}
+
+// execChatSplit routes the contact to one of the node's weighted variants
+// via the "variant:<id>" edge (falling back to default). Assignment is
+// sticky per contact: it hashes the contact, flow and node, so retries
+// and later sessions land in the same variant. The choice is recorded
+// under SessionData["__splits__"] for flow analytics and, with store_as,
+// in a regular variable.
+//
+// Config:
+//
+//	{
+//	  "variants": [
+//	    { "id": "a", "label": "Friendly", "weight": 50 },
+//	    { "id": "b", "label": "Formal", "weight": 50 }
+//	  ],
+//	  "store_as": "greeting_variant",   // optional
+//	  "goal_node": "thanks"             // optional; reaching it counts as a conversion
+//	}
+func (a *App) execChatSplit(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	variants := chatSplitVariants(node.Config)
+	if len(variants) == 0 {
+		a.Log.Warn("split node has no variants; continuing",
+			"node", node.ID, "session", ctx.session.ID)
+		return nodeOutcome{outcome: "default"}, nil
+	}
+
+	key := ctx.session.ContactID.String() + ":" + ctx.flowID.String() + ":" + node.ID
+	variant := pickChatSplitVariant(variants, key)
+	recordChatSplit(ctx.session, ctx.flowID, node.ID, variant)
+	if storeAs := stringFromConfig(node.Config, "store_as"); storeAs != "" {
+		ctx.session.SessionData[storeAs] = variant
+	}
+	return nodeOutcome{outcome: "variant:" + variant}, nil
+}
+
+// recordChatSplit stores a split assignment in the session.
+func recordChatSplit(s *models.ChatbotSession, flowID uuid.UUID, nodeID, variant string) {
+	outerFlowID, _ := s.SessionData["_flow_id"].(string)
+	if outerFlowID == "" {
+		outerFlowID = flowID.String()
+	}
+	splits, _ := s.SessionData[chatSplitsKey].(map[string]any)
+	if splits == nil {
+		splits = map[string]any{}
+	}
+	byNode, _ := splits[flowID.String()].(map[string]any)
+	if byNode == nil {
+		byNode = map[string]any{}
+	}
+	byNode[nodeID] = map[string]any{"variant": variant, "flow_id": outerFlowID}
+	splits[flowID.String()] = byNode
+	s.SessionData[chatSplitsKey] = splits
+}
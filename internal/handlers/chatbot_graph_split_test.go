@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickChatSplitVariant(t *testing.T) {
+	variants := []chatSplitVariant{{ID: "a", Weight: 70}, {ID: "b", Weight: 30}}
+
+	counts := map[string]int{}
+	for i := range 10000 {
+		key := fmt.Sprintf("contact-%d", i)
+		v := pickChatSplitVariant(variants, key)
+		assert.Equal(t, v, pickChatSplitVariant(variants, key), "assignment must be sticky")
+		counts[v]++
+	}
+	assert.InDelta(t, 7000, counts["a"], 300)
+	assert.InDelta(t, 3000, counts["b"], 300)
+
+	assert.Equal(t, "b", pickChatSplitVariant([]chatSplitVariant{{ID: "b", Weight: 1}}, "x"))
+	assert.Empty(t, pickChatSplitVariant(nil, "x"))
+}
+
+func TestChatSplitVariants_SkipsInvalid(t *testing.T) {
+	got := chatSplitVariants(map[string]any{"variants": []any{
+		map[string]any{"id": "a", "label": "A", "weight": float64(50)},
+		map[string]any{"id": "", "weight": float64(50)},
+		map[string]any{"id": "off", "weight": float64(0)},
+		"junk",
+	}})
+	assert.Equal(t, []chatSplitVariant{{ID: "a", Label: "A", Weight: 50}}, got)
+}
+
+func TestRunChatGraph_Split(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "greeting", models.JSONB{
+		"version":    2,
+		"entry_node": "split",
+		"nodes": []any{
+			map[string]any{"id": "split", "type": "split", "config": map[string]any{
+				"store_as": "greeting_variant",
+				"variants": []any{
+					map[string]any{"id": "a", "weight": 50},
+					map[string]any{"id": "b", "weight": 50},
+				},
+			}},
+			map[string]any{"id": "hi_a", "type": "message", "config": map[string]any{"message": "Hey there!"}},
+			map[string]any{"id": "hi_b", "type": "message", "config": map[string]any{"message": "Good day."}},
+		},
+		"edges": []any{
+			map[string]any{"from": "split", "to": "hi_a", "condition": "variant:a"},
+			map[string]any{"from": "split", "to": "hi_b", "condition": "variant:b"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	variant := session.SessionData["greeting_variant"]
+	want := pickChatSplitVariant([]chatSplitVariant{{ID: "a", Weight: 50}, {ID: "b", Weight: 50}},
+		contact.ID.String()+":"+flow.ID.String()+":split")
+	assert.Equal(t, want, variant)
+	splits, _ := session.SessionData[chatSplitsKey].(map[string]any)
+	assert.Equal(t, map[string]any{"split": map[string]any{"variant": want, "flow_id": flow.ID.String()}}, splits[flow.ID.String()])
+
+	expected := map[string]string{"a": "Hey there!", "b": "Good day."}[want]
+	assert.Equal(t, []string{expected}, outgoingSessionMessages(t, app, session))
+}
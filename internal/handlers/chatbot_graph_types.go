@@ -16,6 +16,8 @@ type ChatNodeType string
 const (
 	ChatNodeStart        ChatNodeType = "start"
 	ChatNodeMessage      ChatNodeType = "message"
+	ChatNodeMedia        ChatNodeType = "media"
+	ChatNodeTemplate     ChatNodeType = "template"
 	ChatNodeButtons      ChatNodeType = "buttons"
 	ChatNodePrompt       ChatNodeType = "prompt"
 	ChatNodeAPICall      ChatNodeType = "api_call"
@@ -26,14 +28,32 @@ const (
 	ChatNodeTransfer     ChatNodeType = "transfer"
 	ChatNodeWebhook      ChatNodeType = "webhook"
 	ChatNodeGotoFlow     ChatNodeType = "goto_flow"
+	ChatNodeCallFlow     ChatNodeType = "call_flow"
 	ChatNodeWhatsAppFlow ChatNodeType = "whatsapp_flow"
-	ChatNodeEnd          ChatNodeType = "end"
+	ChatNodeWait         ChatNodeType = "wait"
+	ChatNodeWaitForReply ChatNodeType = "wait_for_reply"
+	ChatNodeSplit        ChatNodeType = "split"
+	ChatNodeForeach      ChatNodeType = "foreach"
+	ChatNodeScript       ChatNodeType = "script"
+
+	// Contact-mutation nodes (see chatbot_graph_contact.go).
+	ChatNodeTagContact      ChatNodeType = "tag_contact"
+	ChatNodeSetContactField ChatNodeType = "set_contact_field"
+	ChatNodeSetOptOut       ChatNodeType = "set_opt_out"
+	ChatNodeAssignContact   ChatNodeType = "assign_contact"
+	ChatNodeAddNote         ChatNodeType = "add_note"
+	ChatNodeEnd             ChatNodeType = "end"
 )
 
 // ChatNode, ChatEdge and ChatGraph are the chatbot domain's views of the shared
 // flow-graph types, specialized to ChatNodeType. Edge conditions for the chat
 // engine include "default", "button:<id>", "input:<val>", "http:2xx",
-// "http:non2xx", "validation_failed", "max_retries", "in_hours", "out_of_hours".
+// "http:non2xx", "validation_failed", "max_retries", "in_hours", "out_of_hours",
+// "return:<outcome>", "error" (call_flow), "reply" and "timeout"
+// (wait_for_reply), "window_closed" (wait, wait_for_reply),
+// "variant:<id>" (split), "item", "done" and "empty" (foreach), "error"
+// (media, template, script and contact-mutation nodes, falling back to
+// default), "no_agent" (assign_contact) and any string a script returns.
 // Traversal (BuildMaps/Node/ResolveEdge) lives in internal/flowgraph.
 type (
 	ChatNode  = flowgraph.Node[ChatNodeType]
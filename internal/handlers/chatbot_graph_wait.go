@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// Reserved SessionData keys for parked sessions. chatWaitKey describes the
+// pending wake-up while a session sits at a wait node; chatWakeKey is set
+// by the wake-up processor for the single run that resumes it.
+const (
+	chatWaitKey = "__wait__"
+	chatWakeKey = "__wake__"
+)
+
+// customerServiceWindow is how long after the customer's last message
+// WhatsApp accepts free-form (non-template) messages.
+const customerServiceWindow = 24 * time.Hour
+
+// execChatWait pauses the flow for a fixed duration or until a clock time,
+// then continues on the default edge. Messages the customer sends while
+// the flow waits don't end the wait.
+//
+// When the wait ends outside the 24-hour customer service window the
+// optional fallback_template is sent instead and the node takes the
+// "window_closed" edge; without one the flow ends, since later free-form
+// messages would be rejected.
+//
+// Config:
+//
+//	{ "mode": "duration", "amount": 2, "unit": "hours" }            // minutes, hours, days
+//	{ "mode": "until", "time": "09:00", "days": ["monday"],          // days optional
+//	  "timezone": "Asia/Kolkata" }                                  // default: org timezone
+//	"fallback_template": { "template_id": "<uuid>", "params": { "1": "{{contact_name}}" } }
+func (a *App) execChatWait(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if a.chatWaitWoken(node, ctx) {
+		return a.chatWaitExpired(node, ctx, "default"), nil
+	}
+	if chatWaitPending(node, ctx) {
+		return nodeOutcome{yield: true}, nil
+	}
+
+	var (
+		wakeAt time.Time
+		err    error
+	)
+	now := time.Now()
+	if stringFromConfig(node.Config, "mode") == "until" {
+		loc := a.chatWaitLocation(node.Config, ctx.session.OrganizationID)
+		days := stringsFromConfig(node.Config, "days")
+		wakeAt, err = nextWaitUntil(now, stringFromConfig(node.Config, "time"), days, loc)
+	} else if d := chatWaitDuration(node.Config, "amount", "unit"); d > 0 {
+		wakeAt = now.Add(d)
+	} else {
+		err = fmt.Errorf("no duration configured")
+	}
+	if err != nil {
+		a.Log.Warn("wait node misconfigured; continuing",
+			"node", node.ID, "session", ctx.session.ID, "error", err)
+		return nodeOutcome{outcome: "default"}, nil
+	}
+	if err := a.scheduleChatWakeup(node, ctx, wakeAt); err != nil {
+		return nodeOutcome{}, err
+	}
+	return nodeOutcome{yield: true}, nil
+}
+
+// execChatWaitForReply waits for the customer's next message. A reply
+// takes the "reply" edge (falling back to default) and is stored under
+// store_as; if none arrives within the timeout the node takes the
+// "timeout" edge, or ends the flow when there is none. Outside the
+// customer service window the timeout behaves like execChatWait's
+// "window_closed".
+//
+// Config:
+//
+//	{
+//	  "timeout_amount": 2, "timeout_unit": "hours",   // minutes, hours, days
+//	  "store_as": "reply",                             // optional
+//	  "fallback_template": { ... }                     // optional, see execChatWait
+//	}
+func (a *App) execChatWaitForReply(node *ChatNode, ctx *chatNodeCtx) (nodeOutcome, error) {
+	if a.chatWaitWoken(node, ctx) {
+		return a.chatWaitExpired(node, ctx, "timeout"), nil
+	}
+	if chatWaitPending(node, ctx) {
+		hasInput := ctx.userInput != "" || ctx.buttonID != "" || len(ctx.flowResponseData) > 0
+		if ctx.consumed || !hasInput {
+			return nodeOutcome{yield: true}, nil
+		}
+		ctx.consumed = true
+		a.cancelChatWait(ctx.session)
+		if storeAs := stringFromConfig(node.Config, "store_as"); storeAs != "" {
+			ctx.session.SessionData[storeAs] = ctx.userInput
+		}
+		return nodeOutcome{outcome: "reply"}, nil
+	}
+
+	timeout := chatWaitDuration(node.Config, "timeout_amount", "timeout_unit")
+	if timeout <= 0 {
+		a.Log.Warn("wait_for_reply node has no timeout; continuing",
+			"node", node.ID, "session", ctx.session.ID)
+		return nodeOutcome{outcome: "timeout"}, nil
+	}
+	if err := a.scheduleChatWakeup(node, ctx, time.Now().Add(timeout)); err != nil {
+		return nodeOutcome{}, err
+	}
+	return nodeOutcome{yield: true}, nil
+}
+
+// chatWaitDuration reads an amount + unit pair from config.
+func chatWaitDuration(cfg map[string]any, amountKey, unitKey string) time.Duration {
+	amount := time.Duration(intFromConfig(cfg, amountKey, 0))
+	switch stringFromConfig(cfg, unitKey) {
+	case "days":
+		return amount * 24 * time.Hour
+	case "hours":
+		return amount * time.Hour
+	default:
+		return amount * time.Minute
+	}
+}
+
+// nextWaitUntil returns the first moment after now at the "HH:MM" clock
+// time in loc, on one of days (lowercase weekday names; empty means any
+// day).
+func nextWaitUntil(now time.Time, clock string, days []string, loc *time.Location) (time.Time, error) {
+	at, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", clock)
+	}
+	allowed := map[string]bool{}
+	for _, d := range days {
+		allowed[strings.ToLower(d)] = true
+	}
+	local := now.In(loc)
+	for i := range 8 {
+		day := local.AddDate(0, 0, i)
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+		if !candidate.After(now) {
+			continue
+		}
+		if len(allowed) == 0 || allowed[strings.ToLower(candidate.Weekday().String())] {
+			return candidate, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no valid day in %v", days)
+}
+
+// chatWaitLocation resolves the timezone for "until" waits: the node's
+// own timezone, then the organization's, then the server's.
+func (a *App) chatWaitLocation(cfg map[string]any, orgID uuid.UUID) *time.Location {
+	name := stringFromConfig(cfg, "timezone")
+	if name == "" {
+		var org models.Organization
+		if err := a.DB.Select("settings").Where("id = ?", orgID).First(&org).Error; err == nil {
+			name, _ = org.Settings["timezone"].(string)
+		}
+	}
+	if name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// scheduleChatWakeup persists a wake-up for node and parks the session on
+// it.
+func (a *App) scheduleChatWakeup(node *ChatNode, ctx *chatNodeCtx, wakeAt time.Time) error {
+	wakeup := models.ChatbotWakeup{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: ctx.session.OrganizationID,
+		SessionID:      ctx.session.ID,
+		FlowID:         ctx.flowID,
+		NodeID:         node.ID,
+		WakeAt:         wakeAt.UTC(),
+		Status:         models.WakeupStatusPending,
+	}
+	if err := a.DB.Create(&wakeup).Error; err != nil {
+		return fmt.Errorf("schedule wake-up: %w", err)
+	}
+	ctx.session.SessionData[chatWaitKey] = map[string]any{
+		"wakeup_id": wakeup.ID.String(),
+		"flow_id":   ctx.flowID.String(),
+		"node_id":   node.ID,
+		"wake_at":   wakeup.WakeAt.Format(time.RFC3339),
+	}
+	return nil
+}
+
+// chatWaitState returns the session's pending wait, if any.
+func chatWaitState(s *models.ChatbotSession) map[string]any {
+	state, _ := s.SessionData[chatWaitKey].(map[string]any)
+	return state
+}
+
+// chatWaitPending reports whether the session is parked at this node.
+func chatWaitPending(node *ChatNode, ctx *chatNodeCtx) bool {
+	state := chatWaitState(ctx.session)
+	return state != nil && state["node_id"] == node.ID && state["flow_id"] == ctx.flowID.String()
+}
+
+// chatWaitWoken reports whether this run is the wake-up the node is
+// waiting for, and clears the wait if so.
+func (a *App) chatWaitWoken(node *ChatNode, ctx *chatNodeCtx) bool {
+	if ctx.wakeupID == "" || !chatWaitPending(node, ctx) {
+		return false
+	}
+	if chatWaitState(ctx.session)["wakeup_id"] != ctx.wakeupID {
+		return false
+	}
+	ctx.wakeupID = ""
+	delete(ctx.session.SessionData, chatWaitKey)
+	return true
+}
+
+// cancelChatWait drops the session's pending wait and its wake-up.
+func (a *App) cancelChatWait(s *models.ChatbotSession) {
+	state := chatWaitState(s)
+	if state == nil {
+		return
+	}
+	delete(s.SessionData, chatWaitKey)
+	id, _ := state["wakeup_id"].(string)
+	if err := a.DB.Model(&models.ChatbotWakeup{}).
+		Where("id = ? AND status = ?", id, models.WakeupStatusPending).
+		Update("status", models.WakeupStatusCancelled).Error; err != nil {
+		a.Log.Error("Failed to cancel chatbot wake-up", "error", err, "wakeup", id)
+	}
+}
+
+// chatWaitExpired picks the outcome for a finished wait. Inside the
+// customer service window that's outcome; outside it the fallback
+// template is sent and the node takes "window_closed".
+func (a *App) chatWaitExpired(node *ChatNode, ctx *chatNodeCtx, outcome string) nodeOutcome {
+	if chatServiceWindowOpen(ctx.contact, time.Now()) {
+		return nodeOutcome{outcome: outcome}
+	}
+	if tpl, ok := node.Config["fallback_template"].(map[string]any); ok && stringFromConfig(tpl, "template_id", "template_name") != "" {
+		sent, err := a.sendChatbotTemplate(ctx.account, ctx.contact, ctx.session, tpl)
+		if err != nil {
+			a.Log.Error("Failed to send wait fallback template",
+				"error", err, "node", node.ID, "session", ctx.session.ID)
+		} else {
+			a.logSessionMessage(ctx.session.ID, models.DirectionOutgoing, sent, node.ID)
+		}
+	}
+	return nodeOutcome{outcome: "window_closed"}
+}
+
+// chatServiceWindowOpen reports whether free-form messages can still be
+// sent to contact.
+func chatServiceWindowOpen(contact *models.Contact, now time.Time) bool {
+	return contact != nil && contact.LastInboundAt != nil && now.Sub(*contact.LastInboundAt) < customerServiceWindow
+}
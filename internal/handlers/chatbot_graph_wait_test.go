@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dueChatWakeup returns the session's pending wake-up, moved into the past
+// so the processor picks it up.
+func dueChatWakeup(t *testing.T, app *App, session *models.ChatbotSession) models.ChatbotWakeup {
+	t.Helper()
+	var w models.ChatbotWakeup
+	require.NoError(t, app.DB.Where("session_id = ? AND status = ?", session.ID, models.WakeupStatusPending).First(&w).Error)
+	require.NoError(t, app.DB.Model(&w).Update("wake_at", time.Now().Add(-time.Minute)).Error)
+	return w
+}
+
+func markContactInbound(t *testing.T, app *App, contact *models.Contact, at time.Time) {
+	t.Helper()
+	contact.LastInboundAt = &at
+	require.NoError(t, app.DB.Model(contact).Update("last_inbound_at", at).Error)
+}
+
+func TestRunChatGraph_Wait_ResumesOnWakeup(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	markContactInbound(t, app, contact, time.Now())
+
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "follow-up", models.JSONB{
+		"version":    2,
+		"entry_node": "hi",
+		"nodes": []any{
+			map[string]any{"id": "hi", "type": "message", "config": map[string]any{"message": "Thanks!"}},
+			map[string]any{"id": "pause", "type": "wait", "config": map[string]any{"amount": 2, "unit": "hours"}},
+			map[string]any{"id": "later", "type": "message", "config": map[string]any{"message": "How was it?"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "hi", "to": "pause", "condition": "default"},
+			map[string]any{"from": "pause", "to": "later", "condition": "default"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+	assert.Equal(t, "pause", session.CurrentStep)
+	w := dueChatWakeup(t, app, session)
+	assert.WithinDuration(t, time.Now().Add(-time.Minute), w.WakeAt, time.Minute)
+
+	// Messages during the wait don't end it.
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "hello?", "", nil))
+	assert.Equal(t, "pause", session.CurrentStep)
+	assert.Equal(t, []string{"Thanks!"}, outgoingSessionMessages(t, app, session))
+
+	(&ChatbotWakeupProcessor{app: app}).processDueWakeups()
+
+	assert.Equal(t, []string{"Thanks!", "How was it?"}, outgoingSessionMessages(t, app, session))
+	require.NoError(t, app.DB.First(&w, w.ID).Error)
+	assert.Equal(t, models.WakeupStatusDone, w.Status)
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, models.SessionStatusCompleted, session.Status)
+	assert.NotContains(t, session.SessionData, chatWaitKey)
+}
+
+func TestRunChatGraph_WaitForReply(t *testing.T) {
+	graph := models.JSONB{
+		"version":    2,
+		"entry_node": "ask",
+		"nodes": []any{
+			map[string]any{"id": "ask", "type": "message", "config": map[string]any{"message": "Did it arrive?"}},
+			map[string]any{"id": "wait", "type": "wait_for_reply", "config": map[string]any{
+				"timeout_amount": 30, "timeout_unit": "minutes", "store_as": "answer",
+			}},
+			map[string]any{"id": "got", "type": "message", "config": map[string]any{"message": "You said {{answer}}"}},
+			map[string]any{"id": "nudge", "type": "message", "config": map[string]any{"message": "Still there?"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "ask", "to": "wait", "condition": "default"},
+			map[string]any{"from": "wait", "to": "got", "condition": "reply"},
+			map[string]any{"from": "wait", "to": "nudge", "condition": "timeout"},
+		},
+	}
+
+	t.Run("reply cancels the timeout", func(t *testing.T) {
+		app, org, account, contact, session := newGraphTestFixtures(t)
+		flow := createGraphFlow(t, app, org, account, uuid.New(), "check", graph)
+		session.CurrentFlowID = &flow.ID
+
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+		assert.Equal(t, "wait", session.CurrentStep)
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "yes", "", nil))
+
+		assert.Equal(t, []string{"Did it arrive?", "You said yes"}, outgoingSessionMessages(t, app, session))
+		var w models.ChatbotWakeup
+		require.NoError(t, app.DB.Where("session_id = ?", session.ID).First(&w).Error)
+		assert.Equal(t, models.WakeupStatusCancelled, w.Status)
+	})
+
+	t.Run("timeout takes the timeout edge", func(t *testing.T) {
+		app, org, account, contact, session := newGraphTestFixtures(t)
+		markContactInbound(t, app, contact, time.Now())
+		flow := createGraphFlow(t, app, org, account, uuid.New(), "check", graph)
+		session.CurrentFlowID = &flow.ID
+
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+		dueChatWakeup(t, app, session)
+		(&ChatbotWakeupProcessor{app: app}).processDueWakeups()
+
+		assert.Equal(t, []string{"Did it arrive?", "Still there?"}, outgoingSessionMessages(t, app, session))
+	})
+
+	t.Run("closed window sends the fallback template", func(t *testing.T) {
+		app, org, account, contact, session := newGraphTestFixtures(t)
+		markContactInbound(t, app, contact, time.Now().Add(-25*time.Hour))
+		tpl := &models.Template{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			WhatsAppAccount: account.Name,
+			Name:            "check_in",
+			Language:        "en",
+			Category:        "UTILITY",
+			Status:          "APPROVED",
+			BodyContent:     "Hi, checking in on your order.",
+		}
+		require.NoError(t, app.DB.Create(tpl).Error)
+
+		closed := models.JSONB{}
+		for k, v := range graph {
+			closed[k] = v
+		}
+		closed["nodes"] = append([]any{}, graph["nodes"].([]any)...)
+		closed["nodes"].([]any)[1] = map[string]any{"id": "wait", "type": "wait_for_reply", "config": map[string]any{
+			"timeout_amount": 30, "timeout_unit": "minutes",
+			"fallback_template": map[string]any{"template_id": tpl.ID.String()},
+		}}
+		flow := createGraphFlow(t, app, org, account, uuid.New(), "check", closed)
+		session.CurrentFlowID = &flow.ID
+
+		require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+		dueChatWakeup(t, app, session)
+		(&ChatbotWakeupProcessor{app: app}).processDueWakeups()
+
+		msgs := outgoingSessionMessages(t, app, session)
+		require.Len(t, msgs, 2)
+		assert.NotEqual(t, "Still there?", msgs[1], "free-form follow-up must not be sent outside the window")
+		require.NoError(t, app.DB.First(session, session.ID).Error)
+		assert.Equal(t, models.SessionStatusCompleted, session.Status, "no window_closed edge ends the flow")
+	})
+}
+
+func TestChatbotWakeup_StaleIsCancelled(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "follow-up", models.JSONB{
+		"version":    2,
+		"entry_node": "pause",
+		"nodes": []any{
+			map[string]any{"id": "pause", "type": "wait", "config": map[string]any{"amount": 1, "unit": "days"}},
+			map[string]any{"id": "later", "type": "message", "config": map[string]any{"message": "Hello again"}},
+		},
+		"edges": []any{
+			map[string]any{"from": "pause", "to": "later", "condition": "default"},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+	w := dueChatWakeup(t, app, session)
+
+	app.exitFlow(session)
+	(&ChatbotWakeupProcessor{app: app}).processDueWakeups()
+
+	require.NoError(t, app.DB.First(&w, w.ID).Error)
+	assert.Equal(t, models.WakeupStatusCancelled, w.Status)
+	assert.Empty(t, outgoingSessionMessages(t, app, session))
+}
+
+func TestGetOrCreateSession_KeepsWaitingSession(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "follow-up", models.JSONB{
+		"version":    2,
+		"entry_node": "pause",
+		"nodes": []any{
+			map[string]any{"id": "pause", "type": "wait", "config": map[string]any{"amount": 3, "unit": "days"}},
+		},
+	})
+	session.CurrentFlowID = &flow.ID
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+	require.NoError(t, app.DB.Model(session).Update("last_activity_at", time.Now().Add(-2*time.Hour)).Error)
+
+	got, isNew := app.getOrCreateSession(org.ID, contact.ID, account.Name, contact.PhoneNumber, 30)
+	assert.False(t, isNew)
+	assert.Equal(t, session.ID, got.ID)
+}
+
+func TestNextWaitUntil(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	// Wednesday 2024-05-15 10:00 IST.
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, loc)
+
+	tests := []struct {
+		name  string
+		clock string
+		days  []string
+		want  time.Time
+	}{
+		{"later today", "18:30", nil, time.Date(2024, 5, 15, 18, 30, 0, 0, loc)},
+		{"already passed today", "09:00", nil, time.Date(2024, 5, 16, 9, 0, 0, 0, loc)},
+		{"next allowed day", "09:00", []string{"Monday"}, time.Date(2024, 5, 20, 9, 0, 0, 0, loc)},
+		{"same weekday next week", "09:00", []string{"wednesday"}, time.Date(2024, 5, 22, 9, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextWaitUntil(now.UTC(), tt.clock, tt.days, loc)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s, want %s", got, tt.want)
+		})
+	}
+
+	_, err := nextWaitUntil(now, "9am", nil, loc)
+	assert.Error(t, err)
+	_, err = nextWaitUntil(now, "09:00", []string{"someday"}, loc)
+	assert.Error(t, err)
+}
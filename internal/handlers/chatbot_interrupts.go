@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// Interrupt actions. chatInterruptIgnore is only meaningful on a flow,
+// where it switches off the settings rule with the same id.
+const (
+	chatInterruptCancel   = "cancel"
+	chatInterruptRestart  = "restart"
+	chatInterruptTransfer = "transfer"
+	chatInterruptOptOut   = "opt_out"
+	chatInterruptIgnore   = "ignore"
+)
+
+// chatFlowCancelRuleID is the id given to a flow's legacy cancel_keywords.
+const chatFlowCancelRuleID = "flow_cancel_keywords"
+
+// chatInterruptRule is an escape hatch checked before the active flow sees
+// a message, so "stop", "agent" or "menu" work everywhere without being
+// rebuilt in every flow.
+type chatInterruptRule struct {
+	ID        string           `json:"id"`
+	Keywords  []string         `json:"keywords"`
+	MatchType models.MatchType `json:"match_type"` // exact (default), contains or starts_with; case-insensitive
+	IntentID  string           `json:"intent_id"`
+	Action    string           `json:"action"`
+	FlowID    string           `json:"flow_id"` // restart: flow to start; defaults to the session's outermost flow
+	TeamID    string           `json:"team_id"` // transfer: team queue; defaults to the general queue
+	Message   string           `json:"message"`
+}
+
+// parseChatInterruptRules decodes stored rules, skipping malformed entries.
+func parseChatInterruptRules(raw models.JSONBArray) []chatInterruptRule {
+	rules := make([]chatInterruptRule, 0, len(raw))
+	for _, item := range raw {
+		b, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		var rule chatInterruptRule
+		if err := json.Unmarshal(b, &rule); err != nil || rule.ID == "" {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// validateChatInterruptRules checks rules submitted through the API and
+// returns them in storage form. allowIgnore is set for flow overrides.
+func validateChatInterruptRules(raw []map[string]any, allowIgnore bool) (models.JSONBArray, error) {
+	out := make(models.JSONBArray, 0, len(raw))
+	seen := map[string]bool{}
+	for i, item := range raw {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("interrupt rule %d is invalid", i+1)
+		}
+		var rule chatInterruptRule
+		if err := json.Unmarshal(b, &rule); err != nil {
+			return nil, fmt.Errorf("interrupt rule %d is invalid", i+1)
+		}
+		rule.ID = strings.TrimSpace(rule.ID)
+		if rule.ID == "" {
+			return nil, fmt.Errorf("interrupt rule %d needs an id", i+1)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("duplicate interrupt rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		switch rule.Action {
+		case chatInterruptCancel, chatInterruptRestart, chatInterruptTransfer, chatInterruptOptOut:
+		case chatInterruptIgnore:
+			if !allowIgnore {
+				return nil, fmt.Errorf("interrupt rule %q: ignore is only valid on a flow", rule.ID)
+			}
+			out = append(out, map[string]any{"id": rule.ID, "action": rule.Action})
+			continue
+		default:
+			return nil, fmt.Errorf("interrupt rule %q has unknown action %q", rule.ID, rule.Action)
+		}
+		switch rule.MatchType {
+		case "", models.MatchTypeExact, models.MatchTypeContains, models.MatchTypeStartsWith:
+		default:
+			return nil, fmt.Errorf("interrupt rule %q: match_type must be exact, contains or starts_with", rule.ID)
+		}
+		keywords := make([]string, 0, len(rule.Keywords))
+		for _, kw := range rule.Keywords {
+			if kw = strings.TrimSpace(kw); kw != "" {
+				keywords = append(keywords, kw)
+			}
+		}
+		rule.Keywords = keywords
+		if len(keywords) == 0 && rule.IntentID == "" {
+			return nil, fmt.Errorf("interrupt rule %q needs keywords or an intent", rule.ID)
+		}
+		for field, id := range map[string]string{"intent_id": rule.IntentID, "flow_id": rule.FlowID, "team_id": rule.TeamID} {
+			if id != "" {
+				if _, err := uuid.Parse(id); err != nil {
+					return nil, fmt.Errorf("interrupt rule %q has an invalid %s", rule.ID, field)
+				}
+			}
+		}
+
+		b, _ = json.Marshal(rule)
+		var stored map[string]any
+		_ = json.Unmarshal(b, &stored)
+		out = append(out, stored)
+	}
+	return out, nil
+}
+
+// effectiveChatInterruptRules merges the settings rules with flow's
+// overrides. A flow rule replaces the settings rule with the same id (or
+// removes it when its action is ignore); other flow rules are added after
+// them. The flow's cancel_keywords act as one more cancel rule.
+func effectiveChatInterruptRules(global models.JSONBArray, flow *models.ChatbotFlow) []chatInterruptRule {
+	rules := parseChatInterruptRules(global)
+	if flow == nil {
+		return rules
+	}
+	overrides := parseChatInterruptRules(flow.InterruptRules)
+	if len(flow.CancelKeywords) > 0 {
+		overrides = append(overrides, chatInterruptRule{
+			ID:       chatFlowCancelRuleID,
+			Keywords: flow.CancelKeywords,
+			Action:   chatInterruptCancel,
+		})
+	}
+
+	byID := make(map[string]chatInterruptRule, len(overrides))
+	for _, o := range overrides {
+		byID[o.ID] = o
+	}
+	merged := make([]chatInterruptRule, 0, len(rules)+len(overrides))
+	for _, r := range rules {
+		if o, ok := byID[r.ID]; ok {
+			delete(byID, r.ID)
+			if o.Action == chatInterruptIgnore {
+				continue
+			}
+			r = o
+		}
+		merged = append(merged, r)
+	}
+	for _, o := range overrides {
+		if _, pending := byID[o.ID]; pending && o.Action != chatInterruptIgnore {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// matchesKeyword reports whether text triggers one of the rule's keywords.
+func (r chatInterruptRule) matchesKeyword(text string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return false
+	}
+	for _, kw := range r.Keywords {
+		kw = strings.ToLower(strings.TrimSpace(kw))
+		if kw == "" {
+			continue
+		}
+		switch r.MatchType {
+		case models.MatchTypeContains:
+			if strings.Contains(text, kw) {
+				return true
+			}
+		case models.MatchTypeStartsWith:
+			if strings.HasPrefix(text, kw) {
+				return true
+			}
+		default:
+			if text == kw {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleChatInterrupt runs the first interrupt rule the message triggers.
+// Keywords are checked first; intents are only classified when a rule
+// uses one and AI is configured. Button replies never trigger intents.
+// Returns true when the message was consumed.
+func (a *App) handleChatInterrupt(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, settings *models.ChatbotSettings, chatbotInput, buttonID string) bool {
+	var flow *models.ChatbotFlow
+	if session.CurrentFlowID != nil {
+		flow, _ = a.getChatbotFlowByIDCached(account.OrganizationID, *session.CurrentFlowID)
+	}
+	rules := effectiveChatInterruptRules(settings.InterruptRules, flow)
+	if len(rules) == 0 {
+		return false
+	}
+
+	for i := range rules {
+		if rules[i].matchesKeyword(chatbotInput) {
+			return a.runChatInterrupt(account, contact, session, settings, &rules[i], chatbotInput)
+		}
+	}
+	if buttonID != "" {
+		return false
+	}
+	if rule := a.matchChatInterruptIntent(account, session, settings, rules, chatbotInput); rule != nil {
+		return a.runChatInterrupt(account, contact, session, settings, rule, chatbotInput)
+	}
+	return false
+}
+
+// matchChatInterruptIntent classifies text against the intents the rules
+// reference and returns the rule for a confident match.
+func (a *App) matchChatInterruptIntent(account *models.WhatsAppAccount, session *models.ChatbotSession, settings *models.ChatbotSettings, rules []chatInterruptRule, text string) *chatInterruptRule {
+	if !aiConfigured(settings.AI) || strings.TrimSpace(text) == "" {
+		return nil
+	}
+	var ids []string
+	for _, r := range rules {
+		if r.IntentID != "" {
+			ids = append(ids, r.IntentID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var intents []models.ChatbotIntent
+	if err := a.DB.Where("id IN ? AND organization_id = ? AND is_enabled = true", ids, account.OrganizationID).
+		Find(&intents).Error; err != nil || len(intents) == 0 {
+		return nil
+	}
+
+	match, err := a.classifyIntent(settings, sessionUsageScope(account.OrganizationID, session, models.AIFeatureIntent), intents, text)
+	if err != nil {
+		a.Log.Error("Interrupt intent classification failed", "error", err, "org_id", account.OrganizationID)
+		return nil
+	}
+	if match == nil || match.Intent == nil || match.Confidence < settings.AI.IntentThreshold {
+		return nil
+	}
+	for i := range rules {
+		if rules[i].IntentID == match.Intent.ID.String() {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// runChatInterrupt performs rule's action and sends its message. Cancel,
+// transfer and opt-out end the session; restart enters the menu flow
+// afresh. Returns false only when the action can't run, so normal routing
+// takes the message instead.
+func (a *App) runChatInterrupt(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, settings *models.ChatbotSettings, rule *chatInterruptRule, chatbotInput string) bool {
+	a.Log.Info("Interrupt rule matched", "rule", rule.ID, "action", rule.Action, "contact", contact.PhoneNumber, "session", session.ID)
+	stepName := "interrupt:" + rule.ID
+
+	sendMessage := func() {
+		if rule.Message == "" {
+			return
+		}
+		if err := a.sendAndSaveTextMessage(account, contact, rule.Message); err != nil {
+			a.Log.Error("Failed to send interrupt message", "error", err, "contact", contact.PhoneNumber)
+			return
+		}
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, rule.Message, stepName)
+	}
+	endSession := func() {
+		a.cancelChatSession(session)
+		if err := a.persistChatSession(session); err != nil {
+			a.Log.Error("Failed to end session after interrupt", "error", err, "session", session.ID)
+		}
+	}
+
+	switch rule.Action {
+	case chatInterruptCancel:
+		endSession()
+		sendMessage()
+
+	case chatInterruptRestart:
+		flowID := rule.FlowID
+		if flowID == "" && session.CurrentFlowID != nil {
+			flowID = session.CurrentFlowID.String()
+			if stack := chatCallStack(session); len(stack) > 0 {
+				if frame, ok := chatCallFrameFromJSONB(stack[0]); ok {
+					flowID = frame.FlowID.String()
+				}
+			}
+		}
+		id, err := uuid.Parse(flowID)
+		if err != nil {
+			return false
+		}
+		flow, err := a.getChatbotFlowByIDCached(account.OrganizationID, id)
+		if err != nil || flow == nil || flow.Graph == nil {
+			a.Log.Warn("Interrupt restart flow not loadable", "rule", rule.ID, "flow", flowID, "error", err)
+			return false
+		}
+		sendMessage()
+		a.startChatFlow(account, contact, session, flow, chatbotInput, "", nil)
+
+	case chatInterruptTransfer:
+		endSession()
+		a.sendTransferMessage(account, contact, settings, rule.Message)
+		if teamID, err := uuid.Parse(rule.TeamID); err == nil {
+			a.createTransferToTeam(account, contact, teamID, "Interrupt: "+rule.ID, models.TransferSourceKeyword)
+		} else {
+			a.createTransferToQueue(account, contact, models.TransferSourceKeyword)
+		}
+
+	case chatInterruptOptOut:
+		if err := a.DB.Model(contact).Update("marketing_opt_out", true).Error; err != nil {
+			a.Log.Error("Failed to update marketing opt-out", "error", err, "contact_id", contact.ID)
+		} else {
+			contact.MarketingOptOut = true
+		}
+		endSession()
+		sendMessage()
+
+	default:
+		return false
+	}
+	return true
+}
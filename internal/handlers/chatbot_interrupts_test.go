@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveChatInterruptRules_FlowOverrides(t *testing.T) {
+	global := models.JSONBArray{
+		map[string]any{"id": "agent", "keywords": []any{"agent"}, "action": "transfer"},
+		map[string]any{"id": "menu", "keywords": []any{"menu"}, "action": "restart"},
+		map[string]any{"id": "stop", "keywords": []any{"stop"}, "action": "cancel"},
+	}
+	flow := &models.ChatbotFlow{
+		InterruptRules: models.JSONBArray{
+			map[string]any{"id": "menu", "action": "ignore"},
+			map[string]any{"id": "agent", "keywords": []any{"human"}, "action": "transfer", "team_id": "t"},
+			map[string]any{"id": "skip", "keywords": []any{"skip"}, "action": "cancel"},
+		},
+		CancelKeywords: models.StringArray{"quit"},
+	}
+
+	rules := effectiveChatInterruptRules(global, flow)
+	ids := make([]string, len(rules))
+	for i, r := range rules {
+		ids[i] = r.ID
+	}
+	assert.Equal(t, []string{"agent", "stop", "skip", chatFlowCancelRuleID}, ids)
+	assert.Equal(t, []string{"human"}, rules[0].Keywords, "flow rule replaces the settings rule")
+
+	assert.Len(t, effectiveChatInterruptRules(global, nil), 3)
+}
+
+func TestChatInterruptRule_MatchesKeyword(t *testing.T) {
+	exact := chatInterruptRule{Keywords: []string{"Stop"}}
+	assert.True(t, exact.matchesKeyword("  stop "))
+	assert.False(t, exact.matchesKeyword("please stop"))
+
+	contains := chatInterruptRule{Keywords: []string{"agent"}, MatchType: models.MatchTypeContains}
+	assert.True(t, contains.matchesKeyword("Talk to an AGENT please"))
+
+	prefix := chatInterruptRule{Keywords: []string{"menu"}, MatchType: models.MatchTypeStartsWith}
+	assert.True(t, prefix.matchesKeyword("Menu please"))
+	assert.False(t, prefix.matchesKeyword(""))
+}
+
+func TestValidateChatInterruptRules(t *testing.T) {
+	rules, err := validateChatInterruptRules([]map[string]any{
+		{"id": " stop ", "keywords": []any{"stop", " "}, "action": "cancel"},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "stop", rules[0].(map[string]any)["id"])
+	assert.Equal(t, []any{"stop"}, rules[0].(map[string]any)["keywords"])
+
+	for name, rule := range map[string]map[string]any{
+		"missing id":       {"keywords": []any{"x"}, "action": "cancel"},
+		"unknown action":   {"id": "a", "keywords": []any{"x"}, "action": "explode"},
+		"no trigger":       {"id": "a", "action": "cancel"},
+		"bad match type":   {"id": "a", "keywords": []any{"x"}, "action": "cancel", "match_type": "regex"},
+		"bad team":         {"id": "a", "keywords": []any{"x"}, "action": "transfer", "team_id": "nope"},
+		"ignore on global": {"id": "a", "action": "ignore"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := validateChatInterruptRules([]map[string]any{rule}, false)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = validateChatInterruptRules([]map[string]any{{"id": "a", "action": "ignore"}}, true)
+	assert.NoError(t, err)
+}
+
+func TestProcessIncomingMessageFull_InterruptCancelsActiveFlow(t *testing.T) {
+	app := newProcessorTestApp(t)
+	if app.Redis == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping cached processor test")
+	}
+	org, account := createProcessorTestOrg(t, app)
+
+	require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+		BaseModel:          models.BaseModel{ID: uuid.New()},
+		OrganizationID:     org.ID,
+		WhatsAppAccount:    account.Name,
+		IsEnabled:          true,
+		GreetingButtons:    models.JSONBArray{},
+		FallbackButtons:    models.JSONBArray{},
+		SessionTimeoutMins: 30,
+		InterruptRules: models.JSONBArray{
+			map[string]any{"id": "stop", "keywords": []any{"stop"}, "action": "cancel", "message": "Okay, stopped."},
+		},
+	}).Error)
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Survey",
+		IsEnabled:       true,
+		Graph: models.JSONB{
+			"version":    2,
+			"entry_node": "ask",
+			"nodes": []any{
+				map[string]any{"id": "ask", "type": "input", "config": map[string]any{"prompt": "Rate us", "store_as": "rating"}},
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+
+	from := "+15550002222"
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     from,
+		Status:          models.SessionStatusActive,
+		CurrentFlowID:   &flow.ID,
+		CurrentStep:     "ask",
+		SessionData:     models.JSONB{},
+		StartedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}
+	incoming := IncomingTextMessage{From: from, ID: "wamid.stop_" + uuid.New().String()[:8], Timestamp: "1234567890", Type: "text"}
+	incoming.Text = &struct {
+		Body string `json:"body"`
+	}{Body: "STOP"}
+
+	contact, _, err := contactutil.GetOrCreateContact(app.DB, org.ID, from, "")
+	require.NoError(t, err)
+	session.ContactID = contact.ID
+	require.NoError(t, app.DB.Create(session).Error)
+
+	app.processIncomingMessageFull(account.PhoneID, incoming, "Interrupt User")
+
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, models.SessionStatusCancelled, session.Status)
+	assert.NotContains(t, session.SessionData, "rating", "the flow never saw the message")
+
+	var reply models.Message
+	require.NoError(t, app.DB.
+		Where("organization_id = ? AND direction = ? AND content = ?", org.ID, models.DirectionOutgoing, "Okay, stopped.").
+		First(&reply).Error)
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/langutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// maxKeywordMediaBytes caps media downloaded for a media_url.
+// It matches WhatsApp's largest (document) upload limit.
+const maxKeywordMediaBytes = 100 << 20
+
+// keywordRuleActive reports whether now falls inside the rule's optional
+// active window. Both bounds are inclusive.
+func keywordRuleActive(rule *models.KeywordRule, now time.Time) bool {
+	if rule.ActiveFrom != nil && now.Before(*rule.ActiveFrom) {
+		return false
+	}
+	if rule.ActiveUntil != nil && now.After(*rule.ActiveUntil) {
+		return false
+	}
+	return true
+}
+
+// parseOptionalRFC3339 parses an optional request timestamp; nil or ""
+// means no bound.
+func parseOptionalRFC3339(v *string) (*time.Time, error) {
+	if v == nil || *v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// keywordConditionEnv builds the variables a rule's Conditions expression
+// can use, e.g. `"vip" in tags && language == "es"` or
+// `metadata.plan == "pro"`. A nil contact yields the same keys with zero
+// values, which is also what expressions are validated against.
+func keywordConditionEnv(contact *models.Contact, messageText string) models.JSONB {
+	env := models.JSONB{
+		"message":           messageText,
+		"name":              "",
+		"phone_number":      "",
+		"language":          "",
+		"whatsapp_account":  "",
+		"marketing_opt_out": false,
+		"tags":              []string{},
+		"metadata":          map[string]any{},
+	}
+	if contact == nil {
+		return env
+	}
+	env["name"] = contact.ProfileName
+	env["phone_number"] = contact.PhoneNumber
+	env["language"] = contact.Language
+	env["whatsapp_account"] = contact.WhatsAppAccount
+	env["marketing_opt_out"] = contact.MarketingOptOut
+	tags := make([]string, 0, len(contact.Tags))
+	for _, t := range contact.Tags {
+		if s, ok := t.(string); ok {
+			tags = append(tags, s)
+		}
+	}
+	env["tags"] = tags
+	if contact.Metadata != nil {
+		env["metadata"] = map[string]any(contact.Metadata)
+	}
+	return env
+}
+
+// keywordRuleConditionsMatch evaluates the rule's Conditions for contact.
+// A rule without conditions always matches; one whose expression fails to
+// evaluate never does, so a broken rule can't fire for everyone.
+func (a *App) keywordRuleConditionsMatch(rule *models.KeywordRule, contact *models.Contact, messageText string) bool {
+	if strings.TrimSpace(rule.Conditions) == "" {
+		return true
+	}
+	ok, err := evaluateConditionExpression(rule.Conditions, keywordConditionEnv(contact, messageText))
+	if err != nil {
+		a.Log.Warn("Keyword rule conditions failed to evaluate", "error", err, "rule", rule.ID)
+		return false
+	}
+	return ok
+}
+
+// validateKeywordRule checks the parts of a rule the processor can't
+// recover from at send time: the conditions expression, the active window
+// and the content each response type needs.
+func (a *App) validateKeywordRule(rule *models.KeywordRule) error {
+	if strings.TrimSpace(rule.Conditions) != "" {
+		if _, err := expr.Compile(rule.Conditions, expr.Env(keywordConditionEnv(nil, "")), expr.AllowUndefinedVariables()); err != nil {
+			return fmt.Errorf("invalid conditions: %v", err)
+		}
+	}
+	if rule.ActiveFrom != nil && rule.ActiveUntil != nil && !rule.ActiveUntil.After(*rule.ActiveFrom) {
+		return errors.New("active_until must be after active_from")
+	}
+
+	content := rule.ResponseContent
+	switch rule.ResponseType {
+	case models.ResponseTypeTemplate:
+		if stringFromConfig(content, "template_id", "template_name") == "" {
+			return errors.New("template_id or template_name is required for template responses")
+		}
+		if id := stringFromConfig(content, "template_id"); id != "" {
+			if _, err := uuid.Parse(id); err != nil {
+				return errors.New("invalid template_id")
+			}
+		}
+	case models.ResponseTypeMedia:
+		switch models.MessageType(stringFromConfig(content, "media_type")) {
+		case models.MessageTypeImage, models.MessageTypeVideo, models.MessageTypeAudio, models.MessageTypeDocument:
+		default:
+			return errors.New("media_type must be image, video, audio or document")
+		}
+		if stringFromConfig(content, "media_id") == "" {
+			u, err := url.Parse(stringFromConfig(content, "media_url"))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return errors.New("media_id or an http(s) media_url is required for media responses")
+			}
+			if err := validateWebhookURL(u.String(), a.Config.App.AllowInternalWebhookURLs); err != nil {
+				return fmt.Errorf("invalid media_url: %v", err)
+			}
+		}
+	case models.ResponseTypeFlow:
+		if stringFromConfig(content, "flow_id") == "" {
+			return errors.New("flow_id is required for WhatsApp Flow responses")
+		}
+	case models.ResponseTypeChatbotFlow:
+		flowID, err := uuid.Parse(stringFromConfig(content, "flow_id"))
+		if err != nil {
+			return errors.New("a valid flow_id is required for chatbot flow responses")
+		}
+		var count int64
+		a.DB.Model(&models.ChatbotFlow{}).Where("id = ? AND organization_id = ?", flowID, rule.OrganizationID).Count(&count)
+		if count == 0 {
+			return errors.New("chatbot flow not found")
+		}
+	}
+	return nil
+}
+
+// keywordReplyVars are the variables available to {{...}} placeholders in
+// rich keyword replies: the session's variables plus the contact built-ins.
+func keywordReplyVars(contact *models.Contact, session *models.ChatbotSession) models.JSONB {
+	vars := models.JSONB{}
+	maps.Copy(vars, session.SessionData)
+	vars["phone_number"] = contact.PhoneNumber
+	vars["contact_name"] = contact.ProfileName
+	vars["contact_language"] = contact.Language
+	return vars
+}
+
+// sendChatbotTemplate sends an approved template, in the contact's
+// language when a variant exists. Used by keyword rules, template nodes and
+// by wait nodes once the customer service window has closed. Returns the
+// text to log on the session. header_media_path is a file uploaded with
+// UploadChatbotMedia; it is uploaded to WhatsApp on each send since media
+// IDs expire.
+//
+// Content: { "template_id": "<uuid>" | "template_name": "order_update",
+// "params": { "name": "{{contact_name}}" }, "header_media_id": "..." |
+// "header_media_path": "chatbot/<org_id>/<file>", "header_media_filename": "invoice.pdf" }
+func (a *App) sendChatbotTemplate(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, content models.JSONB) (string, error) {
+	var template models.Template
+	query := a.DB.Where("organization_id = ?", account.OrganizationID)
+	if id := stringFromConfig(content, "template_id"); id != "" {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("whats_app_account = ? AND name = ?", account.Name, stringFromConfig(content, "template_name"))
+	}
+	if err := query.Order("created_at").First(&template).Error; err != nil {
+		return "", fmt.Errorf("template not found: %w", err)
+	}
+	variant := langutil.TemplateVariant(a.DB, &template, contact.Language)
+	if variant.Status != "APPROVED" {
+		return "", fmt.Errorf("template %s is not approved (status: %s)", variant.Name, variant.Status)
+	}
+
+	vars := keywordReplyVars(contact, session)
+	params := map[string]string{}
+	if raw, ok := content["params"].(map[string]any); ok {
+		for name, v := range raw {
+			params[name] = processTemplate(fmt.Sprint(v), vars)
+		}
+	}
+	headerMediaID := stringFromConfig(content, "header_media_id")
+	headerFilename := stringFromConfig(content, "header_media_filename")
+	if mediaPath := stringFromConfig(content, "header_media_path"); headerMediaID == "" && mediaPath != "" {
+		data, mimeType, err := a.readChatbotMedia(account.OrganizationID, mediaPath)
+		if err != nil {
+			return "", err
+		}
+		if headerFilename == "" {
+			headerFilename = path.Base(mediaPath)
+		}
+		headerMediaID, err = a.WhatsApp.UploadMedia(context.Background(), a.toWhatsAppAccount(account), data, mimeType, headerFilename)
+		if err != nil {
+			return "", fmt.Errorf("upload header media: %w", err)
+		}
+	}
+	msg, err := a.SendOutgoingMessage(context.Background(), OutgoingMessageRequest{
+		Account:             account,
+		Contact:             contact,
+		Type:                models.MessageTypeTemplate,
+		Template:            variant,
+		BodyParams:          params,
+		HeaderMediaID:       headerMediaID,
+		HeaderMediaFilename: headerFilename,
+	}, ChatbotSendOptions())
+	if err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
+
+// sendChatbotMedia sends an image, video, audio or document for keyword
+// rules and media nodes. media_id is an already-uploaded WhatsApp media ID;
+// media_path is a file uploaded with UploadChatbotMedia; otherwise
+// media_url (which may use {{var}} placeholders) is downloaded, stored
+// locally and uploaded.
+//
+// Content: { "media_type": "image", "media_id": "..." | "media_path": "chatbot/<org_id>/<file>" |
+// "media_url": "https://...", "caption": "Hi {{contact_name}}", "filename": "menu.pdf" }
+func (a *App) sendChatbotMedia(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, content models.JSONB) (string, error) {
+	mediaType := models.MessageType(stringFromConfig(content, "media_type"))
+	vars := keywordReplyVars(contact, session)
+	caption := processTemplate(stringFromConfig(content, "caption"), vars)
+	req := OutgoingMessageRequest{
+		Account:       account,
+		Contact:       contact,
+		Type:          mediaType,
+		MediaID:       stringFromConfig(content, "media_id"),
+		MediaFilename: stringFromConfig(content, "filename"),
+		Caption:       caption,
+	}
+	if mediaPath := stringFromConfig(content, "media_path"); req.MediaID == "" && mediaPath != "" {
+		data, mimeType, err := a.readChatbotMedia(account.OrganizationID, mediaPath)
+		if err != nil {
+			return "", err
+		}
+		if req.MediaFilename == "" {
+			req.MediaFilename = path.Base(mediaPath)
+		}
+		req.MediaData = data
+		req.MediaURL = mediaPath
+		req.MediaMimeType = mimeType
+	} else if req.MediaID == "" {
+		mediaURL := processTemplate(stringFromConfig(content, "media_url"), vars)
+		data, mimeType, err := a.fetchChatbotMedia(mediaURL)
+		if err != nil {
+			return "", err
+		}
+		if req.MediaFilename == "" {
+			if u, err := url.Parse(mediaURL); err == nil {
+				req.MediaFilename = path.Base(u.Path)
+			}
+		}
+		localPath, err := a.saveMediaLocally(data, mimeType, req.MediaFilename)
+		if err != nil {
+			return "", fmt.Errorf("save media: %w", err)
+		}
+		req.MediaData = data
+		req.MediaURL = localPath
+		req.MediaMimeType = mimeType
+	}
+	if _, err := a.SendOutgoingMessage(context.Background(), req, ChatbotSendOptions()); err != nil {
+		return "", err
+	}
+	if caption != "" {
+		return caption, nil
+	}
+	return fmt.Sprintf("[%s]", mediaType), nil
+}
+
+// fetchChatbotMedia downloads a media_url, returning its bytes and MIME
+// type. The URL is checked again here because placeholders are only filled
+// in at send time, and the download goes through the SSRF-safe HTTPClient.
+func (a *App) fetchChatbotMedia(mediaURL string) ([]byte, string, error) {
+	if err := validateWebhookURL(mediaURL, a.Config.App.AllowInternalWebhookURLs); err != nil {
+		return nil, "", fmt.Errorf("media URL: %w", err)
+	}
+	resp, err := a.HTTPClient.Get(mediaURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("download media: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("media URL returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxKeywordMediaBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("read media: %w", err)
+	}
+	if len(data) > maxKeywordMediaBytes {
+		return nil, "", errors.New("media exceeds the WhatsApp size limit")
+	}
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return data, mimeType, nil
+}
+
+// sendKeywordWhatsAppFlow sends a WhatsApp Flow form.
+//
+// Content: { "flow_id": "<meta_flow_id>", "header": "...", "body": "...", "cta": "Open form" }
+func (a *App) sendKeywordWhatsAppFlow(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, content models.JSONB) (string, error) {
+	flowID := stringFromConfig(content, "flow_id")
+	vars := keywordReplyVars(contact, session)
+	body := processTemplate(stringFromConfig(content, "body"), vars)
+	header := processTemplate(stringFromConfig(content, "header"), vars)
+	cta := processTemplate(stringFromConfig(content, "cta"), vars)
+	flowToken := fmt.Sprintf("keyword_%s_%d", session.ID.String(), time.Now().UnixNano())
+	if err := a.sendAndSaveFlowMessage(account, contact, flowID, header, body, cta, flowToken, a.whatsAppFlowFirstScreen(flowID)); err != nil {
+		return "", err
+	}
+	return body, nil
+}
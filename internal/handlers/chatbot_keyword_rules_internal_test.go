@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywordRuleActive(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	assert.True(t, keywordRuleActive(&models.KeywordRule{}, now), "no window is always active")
+	assert.True(t, keywordRuleActive(&models.KeywordRule{ActiveFrom: &before, ActiveUntil: &after}, now))
+	assert.True(t, keywordRuleActive(&models.KeywordRule{ActiveFrom: &now, ActiveUntil: &now}, now), "bounds are inclusive")
+	assert.False(t, keywordRuleActive(&models.KeywordRule{ActiveFrom: &after}, now))
+	assert.False(t, keywordRuleActive(&models.KeywordRule{ActiveUntil: &before}, now))
+}
+
+func TestKeywordConditionEnv(t *testing.T) {
+	contact := &models.Contact{
+		ProfileName: "Asha",
+		Language:    "hi",
+		Tags:        models.JSONBArray{"vip", 42},
+		Metadata:    models.JSONB{"city": "Pune"},
+	}
+	for _, tc := range []struct {
+		expr string
+		want bool
+	}{
+		{`"vip" in tags`, true},
+		{`language == "hi" && metadata.city == "Pune"`, true},
+		{`message contains "refund"`, true},
+		{`"wholesale" in tags`, false},
+		{`metadata.missing == "x"`, false},
+	} {
+		got, err := evaluateConditionExpression(tc.expr, keywordConditionEnv(contact, "I want a refund"))
+		assert.NoError(t, err, tc.expr)
+		assert.Equal(t, tc.want, got, tc.expr)
+	}
+
+	got, err := evaluateConditionExpression(`"vip" in tags`, keywordConditionEnv(nil, ""))
+	assert.NoError(t, err)
+	assert.False(t, got, "a missing contact has no tags")
+}
+
+func TestValidateKeywordRule(t *testing.T) {
+	a := &App{Config: &config.Config{}}
+	from := time.Now()
+	until := from.Add(-time.Minute)
+
+	for name, rule := range map[string]models.KeywordRule{
+		"bad expression":      {Conditions: `tags in in`},
+		"inverted window":     {ActiveFrom: &from, ActiveUntil: &until},
+		"template without id": {ResponseType: models.ResponseTypeTemplate, ResponseContent: models.JSONB{}},
+		"media bad type":      {ResponseType: models.ResponseTypeMedia, ResponseContent: models.JSONB{"media_type": "sticker", "media_id": "1"}},
+		"media bad url":       {ResponseType: models.ResponseTypeMedia, ResponseContent: models.JSONB{"media_type": "image", "media_url": "file:///etc/passwd"}},
+		"media internal url":  {ResponseType: models.ResponseTypeMedia, ResponseContent: models.JSONB{"media_type": "image", "media_url": "http://169.254.169.254/latest/meta-data"}},
+		"flow without id":     {ResponseType: models.ResponseTypeFlow, ResponseContent: models.JSONB{}},
+		"chatbot flow bad id": {ResponseType: models.ResponseTypeChatbotFlow, ResponseContent: models.JSONB{"flow_id": "nope"}},
+	} {
+		assert.Error(t, a.validateKeywordRule(&rule), name)
+	}
+
+	assert.NoError(t, a.validateKeywordRule(&models.KeywordRule{
+		Conditions:      `"vip" in tags`,
+		ResponseType:    models.ResponseTypeMedia,
+		ResponseContent: models.JSONB{"media_type": "image", "media_url": "https://example.com/menu.png"},
+	}))
+}
+
+func TestFetchChatbotMedia_RejectsInternalURLs(t *testing.T) {
+	a := &App{Config: &config.Config{}, HTTPClient: &http.Client{Timeout: time.Second}}
+
+	// media_url placeholders are filled from session variables, so the
+	// final URL is only known at send time
+	for _, u := range []string{"http://127.0.0.1:8080/admin", "http://localhost/", "http://[::1]/", "ftp://example.com/a.png"} {
+		_, _, err := a.fetchChatbotMedia(u)
+		assert.Error(t, err, u)
+	}
+}
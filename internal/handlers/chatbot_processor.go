@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,8 +13,10 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
 	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/redact"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 )
 
@@ -176,6 +178,7 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 			ContactName:     contact.ProfileName,
 			WhatsAppAccount: account.Name,
 		})
+		a.triggerSequences(account.OrganizationID, contact.ID, models.SequenceTriggerContactCreated, "")
 	}
 
 	// Get message content - handle text, button replies, list replies, and media
@@ -192,6 +195,7 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 		replyToWAMID = msg.Context.ID
 	}
 	a.saveIncomingMessage(account, contact, msg.ID, messageType, messageText, mediaInfo, replyToWAMID)
+	a.detectContactLanguage(contact, messageType, messageText)
 
 	// Clear chatbot tracking since client has replied
 	a.ClearContactChatbotTracking(contact.ID)
@@ -250,8 +254,14 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	// Log incoming message to session
 	a.logSessionMessage(session.ID, models.DirectionIncoming, chatbotInput, "keyword_check")
 
+	// Interrupt rules ("stop", "agent", "menu") win over everything else,
+	// including the node the active flow is waiting on.
+	if a.handleChatInterrupt(account, contact, session, settings, chatbotInput, buttonID) {
+		return
+	}
+
 	// Check for transfer keyword BEFORE sending greeting (transfer takes priority)
-	keywordResponse, keywordMatched := a.matchKeywordRules(account.OrganizationID, account.Name, chatbotInput)
+	keywordResponse, keywordMatched := a.matchKeywordRules(account.OrganizationID, account.Name, contact, chatbotInput)
 	if keywordMatched && keywordResponse.ResponseType == models.ResponseTypeTransfer {
 		a.Log.Info("Transfer keyword matched", "response", keywordResponse.Body)
 		// Check business hours - if outside hours, send out of hours message instead
@@ -297,22 +307,19 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 		return
 	}
 
+	// Classify against AI intents before keyword-based routing. Button
+	// replies carry an explicit choice and skip classification.
+	if buttonID == "" && a.routeByIntent(account, contact, session, settings, chatbotInput) {
+		return
+	}
+
 	// Try to match flow trigger keywords first (before greeting to avoid duplicate messages)
 	if flow := a.matchFlowTrigger(account.OrganizationID, chatbotInput); flow != nil {
 		if flow.Graph == nil {
 			a.Log.Error("Triggered chatbot flow has no v2 graph; ignoring", "flow", flow.ID)
 			return
 		}
-		session.CurrentFlowID = &flow.ID
-		session.CurrentStep = ""
-		session.StepRetries = 0
-		session.SessionData = models.JSONB{
-			"_flow_id":   flow.ID.String(),
-			"_flow_name": flow.Name,
-		}
-		if err := a.runChatGraph(account, contact, session, flow, chatbotInput, buttonID, flowResponseData); err != nil {
-			a.Log.Error("Chat graph runner failed at flow start", "error", err, "session", session.ID, "flow", flow.ID)
-		}
+		a.startChatFlow(account, contact, session, flow, chatbotInput, buttonID, flowResponseData)
 		return
 	}
 
@@ -347,41 +354,32 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	// Handle non-transfer keyword matches (transfer was already handled above)
 	if keywordMatched && keywordResponse.ResponseType != models.ResponseTypeTransfer {
 		a.Log.Info("Keyword rule matched", "response_type", keywordResponse.ResponseType, "response", keywordResponse.Body)
-
-		// Handle regular text response
-		if len(keywordResponse.Buttons) > 0 {
-			if err := a.sendAndSaveInteractiveButtons(account, contact, keywordResponse.Body, keywordResponse.Buttons); err != nil {
-				a.Log.Error("Failed to send interactive buttons", "error", err, "contact", contact.PhoneNumber)
-			}
-		} else {
-			if err := a.sendAndSaveTextMessage(account, contact, keywordResponse.Body); err != nil {
-				a.Log.Error("Failed to send text message", "error", err, "contact", contact.PhoneNumber)
-			}
-		}
-		// Log outgoing message
-		a.logSessionMessage(session.ID, models.DirectionOutgoing, keywordResponse.Body, "keyword_response")
+		a.sendKeywordResponse(account, contact, session, keywordResponse, chatbotInput, "keyword_response")
 		return
 	}
 
 	// If no keyword matched, try AI response if enabled
-	if settings.AI.Enabled && settings.AI.Provider != "" && settings.AI.APIKey != "" {
+	if aiConfigured(settings.AI) {
 		a.Log.Info("Attempting AI response", "provider", settings.AI.Provider, "model", settings.AI.Model)
-		aiResponse, err := a.generateAIResponse(settings, session, chatbotInput)
+		aiResponse, err := a.generateAIResponse(settings, session, chatbotInput, nil)
 		if err != nil {
 			a.Log.Error("AI response failed", "error", err, "provider", settings.AI.Provider, "model", settings.AI.Model)
+			if errors.Is(err, errAIBudgetExceeded) && a.sendAIBudgetFallback(account, contact, session) {
+				return
+			}
 			// Fall through to default response
-		} else if aiResponse != "" {
-			a.Log.Info("AI response generated successfully", "response_length", len(aiResponse))
-			if err := a.sendAndSaveTextMessage(account, contact, aiResponse); err != nil {
+		} else if aiResponse.Content != "" {
+			a.Log.Info("AI response generated successfully", "response_length", len(aiResponse.Content), "sources", len(aiResponse.Sources))
+			if err := a.sendAndSaveAIReply(account, contact, aiResponse); err != nil {
 				a.Log.Error("Failed to send AI response", "error", err, "contact", contact.PhoneNumber)
 			}
-			a.logSessionMessage(session.ID, models.DirectionOutgoing, aiResponse, "ai_response")
+			a.logSessionMessage(session.ID, models.DirectionOutgoing, aiResponse.Content, "ai_response")
 			return
 		} else {
 			a.Log.Warn("AI returned empty response")
 		}
 	} else {
-		a.Log.Info("AI not configured", "ai_enabled", settings.AI.Enabled, "has_provider", settings.AI.Provider != "", "has_api_key", settings.AI.APIKey != "")
+		a.Log.Info("AI not configured", "ai_enabled", settings.AI.Enabled, "has_provider", settings.AI.Provider != "", "has_api_key", settings.AI.APIKey != "", "has_base_url", settings.AI.BaseURL != "")
 	}
 
 	// If no AI response or AI not enabled, send fallback message (for existing sessions)
@@ -415,15 +413,79 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	}
 }
 
+// startChatFlow enters flow from its entry node, resetting the session's
+// flow state and dropping any wait the previous flow was parked on.
+func (a *App) startChatFlow(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, flow *models.ChatbotFlow, chatbotInput, buttonID string, flowResponseData map[string]any) {
+	a.enterChatFlow(session, flow)
+	if err := a.runChatGraph(account, contact, session, flow, chatbotInput, buttonID, flowResponseData); err != nil {
+		a.Log.Error("Chat graph runner failed at flow start", "error", err, "session", session.ID, "flow", flow.ID)
+	}
+}
+
+// enterChatFlow points session at flow's entry node without running it.
+func (a *App) enterChatFlow(session *models.ChatbotSession, flow *models.ChatbotFlow) {
+	a.cancelChatWait(session)
+	session.CurrentFlowID = &flow.ID
+	session.CurrentStep = ""
+	session.StepRetries = 0
+	session.SessionData = models.JSONB{
+		"_flow_id":   flow.ID.String(),
+		"_flow_name": flow.Name,
+	}
+}
+
+// sendKeywordResponse sends a non-transfer keyword reply and logs it to the
+// session under stepName. Text replies carry optional buttons; template,
+// media and WhatsApp Flow replies are built from the rule's content, and
+// chatbot_flow replies start the configured flow with chatbotInput.
+func (a *App) sendKeywordResponse(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, response *KeywordResponse, chatbotInput, stepName string) {
+	var (
+		logged = response.Body
+		err    error
+	)
+	switch response.ResponseType {
+	case models.ResponseTypeTemplate:
+		logged, err = a.sendChatbotTemplate(account, contact, session, response.Content)
+	case models.ResponseTypeMedia:
+		logged, err = a.sendChatbotMedia(account, contact, session, response.Content)
+	case models.ResponseTypeFlow:
+		logged, err = a.sendKeywordWhatsAppFlow(account, contact, session, response.Content)
+	case models.ResponseTypeChatbotFlow:
+		flowID, _ := uuid.Parse(stringFromConfig(response.Content, "flow_id"))
+		flow, ferr := a.getChatbotFlowByIDCached(account.OrganizationID, flowID)
+		if ferr != nil || flow == nil || flow.Graph == nil {
+			a.Log.Error("Keyword rule chatbot flow not loadable", "error", ferr, "flow", flowID)
+			return
+		}
+		a.startChatFlow(account, contact, session, flow, chatbotInput, "", nil)
+		return
+	default:
+		if len(response.Buttons) > 0 {
+			err = a.sendAndSaveInteractiveButtons(account, contact, response.Body, response.Buttons)
+		} else {
+			err = a.sendAndSaveTextMessage(account, contact, response.Body)
+		}
+	}
+	if err != nil {
+		a.Log.Error("Failed to send keyword response", "error", err, "response_type", response.ResponseType, "contact", contact.PhoneNumber)
+		return
+	}
+	// Log outgoing message
+	a.logSessionMessage(session.ID, models.DirectionOutgoing, logged, stepName)
+}
+
 // KeywordResponse holds the response content and optional buttons
 type KeywordResponse struct {
 	Body         string
 	Buttons      []map[string]any
-	ResponseType models.ResponseType // text, transfer
+	ResponseType models.ResponseType
+	Content      models.JSONB // rule content for template, media and flow replies
 }
 
-// matchKeywordRules checks if the message matches any keyword rules
-func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string) (*KeywordResponse, bool) {
+// matchKeywordRules checks if the message matches any keyword rules. A rule
+// whose keywords match is skipped outside its active window or when its
+// conditions don't hold for contact (which may be nil).
+func (a *App) matchKeywordRules(orgID uuid.UUID, accountName string, contact *models.Contact, messageText string) (*KeywordResponse, bool) {
 	// Use cached keyword rules (includes both account-specific and global rules)
 	rules, err := a.getKeywordRulesCached(orgID, accountName)
 	if err != nil {
@@ -432,8 +494,12 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 	}
 
 	messageLower := strings.ToLower(messageText)
+	now := time.Now()
 
 	for _, rule := range rules {
+		if !keywordRuleActive(&rule, now) {
+			continue
+		}
 		for _, keyword := range rule.Keywords {
 			keywordLower := strings.ToLower(keyword)
 			matched := false
@@ -468,41 +534,60 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 			}
 
 			if matched {
-				response := &KeywordResponse{
-					ResponseType: rule.ResponseType,
+				if !a.keywordRuleConditionsMatch(&rule, contact, messageText) {
+					break
 				}
-
-				// For transfer type, use body as the transfer message
-				if rule.ResponseType == models.ResponseTypeTransfer {
-					if body, ok := rule.ResponseContent["body"].(string); ok {
-						response.Body = body
-					}
+				if response := keywordRuleResponse(rule); response != nil {
 					return response, true
 				}
+			}
+		}
+	}
 
-				// Get response body
-				if body, ok := rule.ResponseContent["body"].(string); ok {
-					response.Body = body
-				}
+	return nil, false
+}
 
-				// Get buttons if present
-				if buttons, ok := rule.ResponseContent["buttons"].([]any); ok && len(buttons) > 0 {
-					response.Buttons = make([]map[string]any, 0, len(buttons))
-					for _, btn := range buttons {
-						if btnMap, ok := btn.(map[string]any); ok {
-							response.Buttons = append(response.Buttons, btnMap)
-						}
-					}
-				}
+// keywordRuleResponse builds the reply for a matched rule, or nil when a
+// text rule has no body to send.
+func keywordRuleResponse(rule models.KeywordRule) *KeywordResponse {
+	response := &KeywordResponse{
+		ResponseType: rule.ResponseType,
+		Content:      rule.ResponseContent,
+	}
 
-				if response.Body != "" {
-					return response, true
-				}
+	// For transfer type, use body as the transfer message
+	if rule.ResponseType == models.ResponseTypeTransfer {
+		if body, ok := rule.ResponseContent["body"].(string); ok {
+			response.Body = body
+		}
+		return response
+	}
+
+	// Rich replies are built from Content when sent
+	switch rule.ResponseType {
+	case models.ResponseTypeTemplate, models.ResponseTypeMedia, models.ResponseTypeFlow, models.ResponseTypeChatbotFlow:
+		return response
+	}
+
+	// Get response body
+	if body, ok := rule.ResponseContent["body"].(string); ok {
+		response.Body = body
+	}
+
+	// Get buttons if present
+	if buttons, ok := rule.ResponseContent["buttons"].([]any); ok && len(buttons) > 0 {
+		response.Buttons = make([]map[string]any, 0, len(buttons))
+		for _, btn := range buttons {
+			if btnMap, ok := btn.(map[string]any); ok {
+				response.Buttons = append(response.Buttons, btnMap)
 			}
 		}
 	}
 
-	return nil, false
+	if response.Body == "" {
+		return nil
+	}
+	return response
 }
 
 // sendAndSaveTextMessage sends a text message and saves it to the database
@@ -518,6 +603,27 @@ func (a *App) sendAndSaveTextMessage(account *models.WhatsAppAccount, contact *m
 	return err
 }
 
+// sendAndSaveAIReply sends an AI answer as text, recording the knowledge
+// chunks it cited in the message metadata.
+func (a *App) sendAndSaveAIReply(account *models.WhatsAppAccount, contact *models.Contact, reply *aiReply) error {
+	var metadata models.JSONB
+	if len(reply.Sources) > 0 {
+		ids := make([]string, len(reply.Sources))
+		for i, id := range reply.Sources {
+			ids[i] = id.String()
+		}
+		metadata = models.JSONB{"knowledge_chunk_ids": ids}
+	}
+	_, err := a.SendOutgoingMessage(context.Background(), OutgoingMessageRequest{
+		Account:  account,
+		Contact:  contact,
+		Type:     models.MessageTypeText,
+		Content:  reply.Content,
+		Metadata: metadata,
+	}, ChatbotSendOptions())
+	return err
+}
+
 // sendAndSaveInteractiveButtons sends an interactive button message and saves it to the database.
 // Buttons with type "url" are automatically separated and sent as CTA URL messages,
 // since WhatsApp doesn't allow mixing reply buttons and URL buttons in the same message.
@@ -657,20 +763,21 @@ func (a *App) sendAndSaveFlowMessage(account *models.WhatsAppAccount, contact *m
 func (a *App) getOrCreateSession(orgID, contactID uuid.UUID, accountName, phoneNumber string, timeoutMins int) (*models.ChatbotSession, bool) {
 	now := time.Now()
 
-	// Look for an active session that hasn't timed out
-	var session models.ChatbotSession
-	timeout := now.Add(-time.Duration(timeoutMins) * time.Minute)
-	result := a.DB.Where("organization_id = ? AND contact_id = ? AND whats_app_account = ? AND status = ? AND last_activity_at > ?",
-		orgID, contactID, accountName, models.SessionStatusActive, timeout).First(&session)
-
-	if result.Error == nil {
+	if existing, err := a.findActiveSession(orgID, contactID, accountName, timeoutMins); err == nil {
 		// Update last activity
-		a.DB.Model(&session).Update("last_activity_at", now)
-		return &session, false // existing session
+		updates := map[string]any{"last_activity_at": now}
+		if existing.AwaitingReplyUntil != nil {
+			// The contact replied to the opening template; the normal
+			// timeout applies from here on.
+			existing.AwaitingReplyUntil = nil
+			updates["awaiting_reply_until"] = nil
+		}
+		a.DB.Model(existing).Updates(updates)
+		return existing, false // existing session
 	}
 
 	// Create new session
-	session = models.ChatbotSession{
+	session := models.ChatbotSession{
 		BaseModel:       models.BaseModel{ID: uuid.New()},
 		OrganizationID:  orgID,
 		ContactID:       contactID,
@@ -687,6 +794,26 @@ func (a *App) getOrCreateSession(orgID, contactID uuid.UUID, accountName, phoneN
 	return &session, true // new session
 }
 
+// findActiveSession returns the contact's active session that hasn't timed
+// out. A session parked at a wait node stays current however long the
+// wait is, and one started by API outside the customer service window
+// stays current until its awaiting-reply deadline.
+func (a *App) findActiveSession(orgID, contactID uuid.UUID, accountName string, timeoutMins int) (*models.ChatbotSession, error) {
+	var session models.ChatbotSession
+	now := time.Now()
+	timeout := now.Add(-time.Duration(timeoutMins) * time.Minute)
+	err := a.DB.Where("organization_id = ? AND contact_id = ? AND whats_app_account = ? AND status = ?",
+		orgID, contactID, accountName, models.SessionStatusActive).
+		Where("last_activity_at > ? OR EXISTS (SELECT 1 FROM chatbot_wakeups w WHERE w.session_id = chatbot_sessions.id AND w.status = ?) OR awaiting_reply_until > ?",
+			timeout, models.WakeupStatusPending, now).
+		Order("last_activity_at DESC").
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
 // logSessionMessage logs a message to the chatbot session
 func (a *App) logSessionMessage(sessionID uuid.UUID, direction models.Direction, message, stepName string) {
 	msg := models.ChatbotSessionMessage{
@@ -742,6 +869,12 @@ func (a *App) exitFlow(session *models.ChatbotSession) {
 // replaceVar is called to substitute variables in the URL, body, and header values.
 // Returns the response body and status code.
 func (a *App) executeConfiguredAPI(apiConfig models.JSONB, replaceVar func(string) string) ([]byte, int, error) {
+	return a.executeConfiguredAPIContext(context.Background(), apiConfig, replaceVar)
+}
+
+// executeConfiguredAPIContext is executeConfiguredAPI bounded by ctx, for
+// callers that need a per-request timeout.
+func (a *App) executeConfiguredAPIContext(ctx context.Context, apiConfig models.JSONB, replaceVar func(string) string) ([]byte, int, error) {
 	apiURL, ok := apiConfig["url"].(string)
 	if !ok || apiURL == "" {
 		return nil, 0, fmt.Errorf("API URL is required")
@@ -759,7 +892,7 @@ func (a *App) executeConfiguredAPI(apiConfig models.JSONB, replaceVar func(strin
 		bodyReader = strings.NewReader(replaceVar(bodyTemplate))
 	}
 
-	req, err := http.NewRequest(method, apiURL, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -819,29 +952,143 @@ type ApiResponse struct {
 	ResponseData map[string]any // Full API response data
 }
 
-// fetchApiResponse fetches a response from an external API, supporting message + buttons
-// and response_mapping for storing API data in session variables.
+// aiReply is a generated answer plus the knowledge-base chunks it was
+// grounded on, so the sent message can record its citations.
+type aiReply struct {
+	Content string
+	Sources []uuid.UUID
+}
+
+// generateAIResponse answers userMessage with the org's configured LLM
+// provider. The system prompt is extended with the AI context entries, and
+// recent session history is included when enabled.
+//
+// The org's AI tools are offered to the model (only those named in
+// toolNames when it is non-nil). Each requested call is executed and fed
+// back until the model answers in text or ai_max_tool_iterations is hit.
 //
-// Mirrors fetchAPIContext in seeding implicit variables (phone_number) so flow-step
-// API templates can interpolate {{phone_number}} just like AI-context API templates.
-func (a *App) generateAIResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string) (string, error) {
+// With redaction enabled, customer data (history, the message, API context
+// and tool results) is masked before it is sent; tool arguments and the
+// final answer are re-hydrated with the original values.
+func (a *App) generateAIResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, toolNames []string) (*aiReply, error) {
+	scope := sessionUsageScope(settings.OrganizationID, session, models.AIFeatureReply)
+	provider, err := a.newMeteredAIProvider(settings.AI, scope)
+	if err != nil {
+		return nil, err
+	}
+	redactor := a.newAIRedactor(settings.AI)
+	defer a.logRedactions(scope, redactor)
+
 	// Build context from AIContext entries
-	contextData := a.buildAIContext(settings.OrganizationID, session, userMessage)
-
-	switch settings.AI.Provider {
-	case models.AIProviderOpenAI:
-		return a.generateOpenAIResponse(settings, session, userMessage, contextData)
-	case models.AIProviderAnthropic:
-		return a.generateAnthropicResponse(settings, session, userMessage, contextData)
-	case models.AIProviderGoogle:
-		return a.generateGoogleResponse(settings, session, userMessage, contextData)
-	default:
-		return "", fmt.Errorf("unsupported AI provider: %s", settings.AI.Provider)
+	contextData := a.buildAIContext(settings.OrganizationID, session, userMessage, redactor)
+
+	systemPrompt := settings.AI.SystemPrompt
+	if contextData != "" {
+		if systemPrompt != "" {
+			systemPrompt = systemPrompt + "\n\n" + contextData
+		} else {
+			systemPrompt = contextData
+		}
+	}
+
+	// Retrieval failures degrade to an ungrounded answer rather than no answer.
+	hits, err := a.retrieveKnowledge(settings, scope, redactor.Redact(userMessage))
+	if err != nil {
+		a.Log.Warn("Knowledge retrieval failed", "error", err, "org_id", settings.OrganizationID)
+	}
+	if kb := formatKnowledgeContext(hits); kb != "" {
+		if systemPrompt != "" {
+			systemPrompt = systemPrompt + "\n\n" + kb
+		} else {
+			systemPrompt = kb
+		}
+	}
+
+	var messages []ai.Message
+	if settings.AI.IncludeHistory && session != nil {
+		for _, msg := range a.getSessionHistory(session.ID, settings.AI.HistoryLimit) {
+			role := ai.RoleUser
+			if msg.Direction == models.DirectionOutgoing {
+				role = ai.RoleAssistant
+			}
+			messages = append(messages, ai.Message{Role: role, Content: redactor.Redact(msg.Message)})
+		}
+	}
+	messages = append(messages, ai.Message{Role: ai.RoleUser, Content: redactor.Redact(userMessage)})
+
+	var tools []models.AITool
+	maxIterations := min(settings.AI.MaxToolIterations, maxAIToolIterations)
+	if maxIterations > 0 {
+		tools = a.loadAITools(settings.OrganizationID, scope.Account, toolNames)
+	}
+	toolsByName := make(map[string]*models.AITool, len(tools))
+	for i := range tools {
+		toolsByName[tools[i].Name] = &tools[i]
 	}
+
+	req := ai.Request{
+		Model:        settings.AI.Model,
+		SystemPrompt: systemPrompt,
+		Messages:     messages,
+		Tools:        aiToolDefinitions(tools),
+		MaxTokens:    settings.AI.MaxTokens,
+		Temperature:  settings.AI.Temperature,
+	}
+	resp, err := provider.Complete(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	for iteration := 0; len(resp.ToolCalls) > 0 && len(tools) > 0; iteration++ {
+		limitReached := iteration >= maxIterations
+		req.Messages = append(req.Messages, ai.Message{
+			Role:      ai.RoleAssistant,
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			// The tool gets the real values; the model only sees placeholders.
+			call.Arguments = redactor.Restore(call.Arguments)
+			var result string
+			switch tool := toolsByName[call.Name]; {
+			case limitReached:
+				result = "error: tool call limit reached; answer with the information you already have"
+			case tool == nil:
+				result = fmt.Sprintf("error: unknown tool %q", call.Name)
+			default:
+				a.Log.Info("Executing AI tool", "tool", call.Name, "org_id", settings.OrganizationID)
+				result = a.executeAITool(tool, call, session)
+			}
+			if session != nil {
+				a.logAIToolCall(session.ID, call, result)
+			}
+			req.Messages = append(req.Messages, ai.Message{
+				Role:       ai.RoleTool,
+				Content:    redactor.Redact(result),
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+			})
+		}
+
+		resp, err = provider.Complete(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		if limitReached {
+			break
+		}
+	}
+
+	reply := &aiReply{Content: redactor.Restore(resp.Content)}
+	for _, h := range hits {
+		reply.Sources = append(reply.Sources, h.Chunk.ID)
+	}
+	return reply, nil
 }
 
-// buildAIContext fetches and combines all AI context data
-func (a *App) buildAIContext(orgID uuid.UUID, session *models.ChatbotSession, userMessage string) string {
+// buildAIContext fetches and combines all AI context data. Data fetched
+// from context APIs is passed through redactor; static content is the
+// org's own text and is left as is.
+func (a *App) buildAIContext(orgID uuid.UUID, session *models.ChatbotSession, userMessage string, redactor *redact.Redactor) string {
 	// Get WhatsApp account for cache key
 	whatsAppAccount := ""
 	if session != nil {
@@ -873,6 +1120,7 @@ func (a *App) buildAIContext(orgID uuid.UUID, session *models.ChatbotSession, us
 				a.Log.Error("Failed to fetch API context", "context_name", ctx.Name, "error", err)
 				// Still use static content if API fails
 			} else if apiContent != "" {
+				apiContent = redactor.Redact(apiContent)
 				if content != "" {
 					content = content + "\n\nData:\n" + apiContent
 				} else {
@@ -933,332 +1181,12 @@ func (a *App) fetchAPIContext(apiConfig models.JSONB, session *models.ChatbotSes
 	return string(respBody), nil
 }
 
-// generateOpenAIResponse generates a response using OpenAI API
-func (a *App) generateOpenAIResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, contextData string) (string, error) {
-	url := "https://api.openai.com/v1/chat/completions"
-
-	// Build messages array
-	messages := []map[string]string{}
-
-	// Build system prompt with context
-	systemPrompt := settings.AI.SystemPrompt
-	if contextData != "" {
-		if systemPrompt != "" {
-			systemPrompt = systemPrompt + "\n\n" + contextData
-		} else {
-			systemPrompt = contextData
-		}
-	}
-
-	// Add system prompt if configured
-	if systemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": systemPrompt,
-		})
-	}
-
-	// Add conversation history if enabled
-	if settings.AI.IncludeHistory && session != nil {
-		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
-		for _, msg := range history {
-			role := "user"
-			if msg.Direction == models.DirectionOutgoing {
-				role = "assistant"
-			}
-			messages = append(messages, map[string]string{
-				"role":    role,
-				"content": msg.Message,
-			})
-		}
-	}
-
-	// Add current user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userMessage,
-	})
-
-	payload := map[string]any{
-		"model":      settings.AI.Model,
-		"messages":   messages,
-		"max_tokens": settings.AI.MaxTokens,
-	}
-
-	if settings.AI.Temperature > 0 {
-		payload["temperature"] = settings.AI.Temperature
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+settings.AI.APIKey)
-
-	resp, err := a.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		_ = json.Unmarshal(body, &errResp)
-		return "", fmt.Errorf("OpenAI API error: %s", errResp.Error.Message)
-	}
-
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Choices) > 0 {
-		return strings.TrimSpace(result.Choices[0].Message.Content), nil
-	}
-
-	return "", fmt.Errorf("no response from OpenAI")
-}
-
-// generateAnthropicResponse generates a response using Anthropic API
-func (a *App) generateAnthropicResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, contextData string) (string, error) {
-	url := "https://api.anthropic.com/v1/messages"
-
-	// Build messages array
-	messages := []map[string]string{}
-
-	// Add conversation history if enabled
-	if settings.AI.IncludeHistory && session != nil {
-		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
-		for _, msg := range history {
-			role := "user"
-			if msg.Direction == models.DirectionOutgoing {
-				role = "assistant"
-			}
-			messages = append(messages, map[string]string{
-				"role":    role,
-				"content": msg.Message,
-			})
-		}
-	}
-
-	// Add current user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userMessage,
-	})
-
-	payload := map[string]any{
-		"model":      settings.AI.Model,
-		"messages":   messages,
-		"max_tokens": settings.AI.MaxTokens,
-	}
-
-	// Build system prompt with context
-	systemPrompt := settings.AI.SystemPrompt
-	if contextData != "" {
-		if systemPrompt != "" {
-			systemPrompt = systemPrompt + "\n\n" + contextData
-		} else {
-			systemPrompt = contextData
-		}
-	}
-
-	// Add system prompt if configured
-	if systemPrompt != "" {
-		payload["system"] = systemPrompt
-	}
-
-	if settings.AI.Temperature > 0 {
-		payload["temperature"] = settings.AI.Temperature
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", settings.AI.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := a.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		_ = json.Unmarshal(body, &errResp)
-		return "", fmt.Errorf("anthropic API error: %s", errResp.Error.Message)
-	}
-
-	var result struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	for _, content := range result.Content {
-		if content.Type == "text" {
-			return strings.TrimSpace(content.Text), nil
-		}
-	}
-
-	return "", fmt.Errorf("no text response from Anthropic")
-}
-
-// generateGoogleResponse generates a response using Google Gemini API
-func (a *App) generateGoogleResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, contextData string) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		settings.AI.Model, settings.AI.APIKey)
-
-	// Build contents array
-	contents := []map[string]any{}
-
-	// Add conversation history if enabled
-	if settings.AI.IncludeHistory && session != nil {
-		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
-		for _, msg := range history {
-			role := "user"
-			if msg.Direction == models.DirectionOutgoing {
-				role = "model"
-			}
-			contents = append(contents, map[string]any{
-				"role": role,
-				"parts": []map[string]string{
-					{"text": msg.Message},
-				},
-			})
-		}
-	}
-
-	// Add current user message
-	contents = append(contents, map[string]any{
-		"role": "user",
-		"parts": []map[string]string{
-			{"text": userMessage},
-		},
-	})
-
-	payload := map[string]any{
-		"contents": contents,
-		"generationConfig": map[string]any{
-			"maxOutputTokens": settings.AI.MaxTokens,
-		},
-	}
-
-	// Build system prompt with context
-	systemPrompt := settings.AI.SystemPrompt
-	if contextData != "" {
-		if systemPrompt != "" {
-			systemPrompt = systemPrompt + "\n\n" + contextData
-		} else {
-			systemPrompt = contextData
-		}
-	}
-
-	// Add system instruction if configured
-	if systemPrompt != "" {
-		payload["systemInstruction"] = map[string]any{
-			"parts": []map[string]string{
-				{"text": systemPrompt},
-			},
-		}
-	}
-
-	if settings.AI.Temperature > 0 {
-		payload["generationConfig"].(map[string]any)["temperature"] = settings.AI.Temperature
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		_ = json.Unmarshal(body, &errResp)
-		return "", fmt.Errorf("google AI API error: %s", errResp.Error.Message)
-	}
-
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Candidates) > 0 && len(result.Candidates[0].Content.Parts) > 0 {
-		return strings.TrimSpace(result.Candidates[0].Content.Parts[0].Text), nil
-	}
-
-	return "", fmt.Errorf("no response from Google AI")
-}
-
 // getSessionHistory retrieves recent messages from the session
 func (a *App) getSessionHistory(sessionID uuid.UUID, limit int) []models.ChatbotSessionMessage {
 	var messages []models.ChatbotSessionMessage
-	a.DB.Where("session_id = ?", sessionID).
+	// Tool-call rows are an implementation detail of a single reply; only
+	// the conversation itself is replayed.
+	a.DB.Where("session_id = ? AND COALESCE(tool_name, '') = ''", sessionID).
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&messages)
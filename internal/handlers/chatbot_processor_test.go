@@ -90,19 +90,19 @@ func TestMatchKeywordRules_ExactMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hello")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "hello")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Hello response", resp.Body)
 
 	// Different case should also match (case insensitive by default)
-	resp2, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELLO")
+	resp2, matched2 := app.matchKeywordRules(org.ID, account.Name, nil, "HELLO")
 	assert.True(t, matched2)
 	require.NotNil(t, resp2)
 	assert.Equal(t, "Hello response", resp2.Body)
 
 	// Partial should NOT match exact
-	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "hello world")
+	_, matched3 := app.matchKeywordRules(org.ID, account.Name, nil, "hello world")
 	assert.False(t, matched3)
 }
 
@@ -125,10 +125,10 @@ func TestMatchKeywordRules_ExactMatch_CaseSensitive(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	_, matched := app.matchKeywordRules(org.ID, account.Name, "Hello")
+	_, matched := app.matchKeywordRules(org.ID, account.Name, nil, "Hello")
 	assert.True(t, matched)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "hello")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, nil, "hello")
 	assert.False(t, matched2)
 }
 
@@ -150,15 +150,15 @@ func TestMatchKeywordRules_ContainsMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I need help please")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "I need help please")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Help response", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELP ME")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, nil, "HELP ME")
 	assert.True(t, matched2)
 
-	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "goodbye")
+	_, matched3 := app.matchKeywordRules(org.ID, account.Name, nil, "goodbye")
 	assert.False(t, matched3)
 }
 
@@ -180,12 +180,12 @@ func TestMatchKeywordRules_StartsWithMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hi there")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "hi there")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Hi response", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "say hi")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, nil, "say hi")
 	assert.False(t, matched2)
 }
 
@@ -207,12 +207,12 @@ func TestMatchKeywordRules_RegexMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I have order #12345")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "I have order #12345")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Order lookup", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "where is my package")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, nil, "where is my package")
 	assert.False(t, matched2)
 }
 
@@ -234,7 +234,7 @@ func TestMatchKeywordRules_NoMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "random message")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "random message")
 	assert.False(t, matched)
 	assert.Nil(t, resp)
 }
@@ -274,7 +274,7 @@ func TestMatchKeywordRules_Priority(t *testing.T) {
 	require.NoError(t, app.DB.Create(highRule).Error)
 
 	// The higher priority rule should be returned (rules are ORDER BY priority DESC)
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "this is a test")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "this is a test")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "High priority", resp.Body)
@@ -300,7 +300,7 @@ func TestMatchKeywordRules_DisabledRuleIgnored(t *testing.T) {
 	// Explicitly disable: GORM skips zero-value bools with default:true on INSERT.
 	require.NoError(t, app.DB.Model(rule).Update("is_enabled", false).Error)
 
-	_, matched := app.matchKeywordRules(org.ID, account.Name, "disabled")
+	_, matched := app.matchKeywordRules(org.ID, account.Name, nil, "disabled")
 	assert.False(t, matched)
 }
 
@@ -322,7 +322,7 @@ func TestMatchKeywordRules_TransferType(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "agent")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "agent")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, models.ResponseTypeTransfer, resp.ResponseType)
@@ -353,13 +353,115 @@ func TestMatchKeywordRules_WithButtons(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "menu")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "menu")
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Choose an option:", resp.Body)
 	assert.Len(t, resp.Buttons, 2)
 }
 
+func TestMatchKeywordRules_ActiveWindow(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	past := time.Now().Add(-48 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	tomorrow := time.Now().Add(24 * time.Hour)
+	for _, rule := range []*models.KeywordRule{
+		{Name: "expired", Keywords: models.StringArray{"sale"}, Priority: 30, ActiveFrom: &past, ActiveUntil: &yesterday, ResponseContent: models.JSONB{"body": "Old sale"}},
+		{Name: "upcoming", Keywords: models.StringArray{"sale"}, Priority: 20, ActiveFrom: &tomorrow, ResponseContent: models.JSONB{"body": "Next sale"}},
+		{Name: "current", Keywords: models.StringArray{"sale"}, Priority: 10, ActiveUntil: &tomorrow, ResponseContent: models.JSONB{"body": "Sale on now"}},
+	} {
+		rule.ID = uuid.New()
+		rule.OrganizationID = org.ID
+		rule.WhatsAppAccount = account.Name
+		rule.MatchType = models.MatchTypeExact
+		rule.ResponseType = models.ResponseTypeText
+		rule.IsEnabled = true
+		require.NoError(t, app.DB.Create(rule).Error)
+	}
+
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "sale")
+	assert.True(t, matched)
+	require.NotNil(t, resp)
+	assert.Equal(t, "Sale on now", resp.Body)
+}
+
+func TestMatchKeywordRules_Conditions(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	for _, rule := range []*models.KeywordRule{
+		{Name: "vip", Keywords: models.StringArray{"offer"}, Priority: 20, Conditions: `"vip" in tags && metadata.plan == "pro"`, ResponseContent: models.JSONB{"body": "VIP offer"}},
+		{Name: "everyone", Keywords: models.StringArray{"offer"}, Priority: 10, ResponseContent: models.JSONB{"body": "Standard offer"}},
+	} {
+		rule.ID = uuid.New()
+		rule.OrganizationID = org.ID
+		rule.WhatsAppAccount = account.Name
+		rule.MatchType = models.MatchTypeContains
+		rule.ResponseType = models.ResponseTypeText
+		rule.IsEnabled = true
+		require.NoError(t, app.DB.Create(rule).Error)
+	}
+
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, contact, "any offer?")
+	assert.True(t, matched)
+	assert.Equal(t, "Standard offer", resp.Body)
+
+	contact.Tags = models.JSONBArray{"vip"}
+	contact.Metadata = models.JSONB{"plan": "pro"}
+	resp, matched = app.matchKeywordRules(org.ID, account.Name, contact, "any offer?")
+	assert.True(t, matched)
+	assert.Equal(t, "VIP offer", resp.Body)
+}
+
+func TestMatchKeywordRules_RichResponseWithoutBody(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	content := models.JSONB{"template_name": "order_update", "params": map[string]any{"name": "{{contact_name}}"}}
+	rule := &models.KeywordRule{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "order",
+		Keywords:        models.StringArray{"order"},
+		MatchType:       models.MatchTypeExact,
+		ResponseType:    models.ResponseTypeTemplate,
+		ResponseContent: content,
+		IsEnabled:       true,
+	}
+	require.NoError(t, app.DB.Create(rule).Error)
+
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, nil, "order")
+	assert.True(t, matched)
+	require.NotNil(t, resp)
+	assert.Equal(t, models.ResponseTypeTemplate, resp.ResponseType)
+	assert.Equal(t, "order_update", resp.Content["template_name"])
+}
+
+func TestSendKeywordResponse_ChatbotFlow(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := createGraphFlow(t, app, org, account, uuid.New(), "returns", models.JSONB{
+		"version":    2,
+		"entry_node": "hi",
+		"nodes": []any{
+			map[string]any{"id": "hi", "type": "message", "config": map[string]any{"message": "Returns desk"}},
+		},
+		"edges": []any{},
+	})
+
+	resp := &KeywordResponse{
+		ResponseType: models.ResponseTypeChatbotFlow,
+		Content:      models.JSONB{"flow_id": flow.ID.String()},
+	}
+	app.sendKeywordResponse(account, contact, session, resp, "return", "keyword_response")
+
+	require.NoError(t, app.DB.First(session, session.ID).Error)
+	assert.Equal(t, []string{"Returns desk"}, outgoingSessionMessages(t, app, session))
+}
+
 // =============================================================================
 // getOrCreateSession
 // =============================================================================
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// Session control lets supervisors inspect and steer a live chatbot
+// session. Every change is audited as the acting user and broadcast as
+// chatbot_session_update so open chats refresh.
+
+// ChatbotSessionNode is the node a session is parked at.
+type ChatbotSessionNode struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// ChatbotSessionStateResponse is a session's runtime state.
+type ChatbotSessionStateResponse struct {
+	ID             uuid.UUID            `json:"id"`
+	ContactID      uuid.UUID            `json:"contact_id"`
+	Status         models.SessionStatus `json:"status"`
+	FlowID         *uuid.UUID           `json:"flow_id"`
+	FlowName       string               `json:"flow_name"`
+	CurrentStep    string               `json:"current_step"`
+	CurrentNode    *ChatbotSessionNode  `json:"current_node"`
+	Variables      map[string]any       `json:"variables"`
+	Path           []any                `json:"path"`
+	CallDepth      int                  `json:"call_depth"`
+	Wait           map[string]any       `json:"wait"`
+	TakenOver      bool                 `json:"taken_over"`
+	StartedAt      time.Time            `json:"started_at"`
+	LastActivityAt time.Time            `json:"last_activity_at"`
+}
+
+// UpdateChatbotSessionVariablesRequest sets and removes session variables.
+type UpdateChatbotSessionVariablesRequest struct {
+	Set   map[string]any `json:"set"`
+	Unset []string       `json:"unset"`
+}
+
+// JumpChatbotSessionRequest moves a session to another node.
+type JumpChatbotSessionRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+// TakeOverChatbotSessionRequest hands a session's contact to the caller.
+type TakeOverChatbotSessionRequest struct {
+	Notes string `json:"notes"`
+}
+
+// GetChatbotSessionState returns where a session is in its flow, its
+// variables and the path it took.
+func (a *App) GetChatbotSessionState(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceFlowsChatbot, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "session")
+	if err != nil {
+		return nil
+	}
+	session, err := findByIDAndOrg[models.ChatbotSession](a.DB, r, id, orgID, "Session")
+	if err != nil {
+		return nil
+	}
+	return r.SendEnvelope(a.chatbotSessionState(session))
+}
+
+// UpdateChatbotSessionVariables edits an active session's variables.
+// Names starting with "_" are reserved.
+func (a *App) UpdateChatbotSessionVariables(r *fastglue.Request) error {
+	session, userID, err := a.loadActiveChatbotSession(r)
+	if err != nil {
+		return nil
+	}
+	var req UpdateChatbotSessionVariablesRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	for key := range req.Set {
+		if key == "" || strings.HasPrefix(key, "_") {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid variable name: "+key, nil, "")
+		}
+	}
+	for _, key := range req.Unset {
+		if strings.HasPrefix(key, "_") {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid variable name: "+key, nil, "")
+		}
+	}
+
+	old := chatbotSessionAuditView(session)
+	data := models.JSONB{}
+	maps.Copy(data, session.SessionData)
+	maps.Copy(data, req.Set)
+	for _, key := range req.Unset {
+		delete(data, key)
+	}
+	session.SessionData = data
+	return a.finishSessionControl(r, session, userID, old, "variables_updated")
+}
+
+// JumpChatbotSession moves an active session to a node of its current flow
+// and runs the flow from there, as if the previous node had routed to it.
+func (a *App) JumpChatbotSession(r *fastglue.Request) error {
+	session, userID, err := a.loadActiveChatbotSession(r)
+	if err != nil {
+		return nil
+	}
+	var req JumpChatbotSessionRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if session.CurrentFlowID == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Session is not in a flow", nil, "")
+	}
+	flow, err := a.getChatbotFlowByIDCached(session.OrganizationID, *session.CurrentFlowID)
+	if err != nil || flow == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Flow not found", nil, "")
+	}
+	graph, err := parseChatGraph(flow.Graph)
+	if err != nil || graph == nil || graph.Node(req.NodeID) == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Node not found in the session's flow", nil, "")
+	}
+
+	old := chatbotSessionAuditView(session)
+	a.cancelChatWait(session)
+	session.CurrentStep = req.NodeID
+	session.StepRetries = 0
+	a.runControlledSession(session, flow)
+	return a.finishSessionControl(r, session, userID, old, "jumped")
+}
+
+// RestartChatbotSession starts an active session's flow over from its
+// entry node with fresh variables. A session inside a called sub-flow
+// restarts the outermost flow.
+func (a *App) RestartChatbotSession(r *fastglue.Request) error {
+	session, userID, err := a.loadActiveChatbotSession(r)
+	if err != nil {
+		return nil
+	}
+	if session.CurrentFlowID == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Session is not in a flow", nil, "")
+	}
+	flowID := *session.CurrentFlowID
+	if stack := chatCallStack(session); len(stack) > 0 {
+		if frame, ok := chatCallFrameFromJSONB(stack[0]); ok {
+			flowID = frame.FlowID
+		}
+	}
+	flow, err := a.getChatbotFlowByIDCached(session.OrganizationID, flowID)
+	if err != nil || flow == nil || flow.Graph == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Flow not found", nil, "")
+	}
+
+	old := chatbotSessionAuditView(session)
+	a.enterChatFlow(session, flow)
+	a.runControlledSession(session, flow)
+	return a.finishSessionControl(r, session, userID, old, "restarted")
+}
+
+// CancelChatbotSession ends an active session. The contact's next message
+// is handled like one from a new conversation.
+func (a *App) CancelChatbotSession(r *fastglue.Request) error {
+	session, userID, err := a.loadActiveChatbotSession(r)
+	if err != nil {
+		return nil
+	}
+	old := chatbotSessionAuditView(session)
+	a.cancelChatSession(session)
+	return a.finishSessionControl(r, session, userID, old, "cancelled")
+}
+
+// TakeOverChatbotSession pauses the bot for the session's contact by
+// ending the session and opening an agent transfer assigned to the caller.
+// The bot stays paused until the transfer is resumed.
+func (a *App) TakeOverChatbotSession(r *fastglue.Request) error {
+	session, userID, err := a.loadActiveChatbotSession(r)
+	if err != nil {
+		return nil
+	}
+	if !a.HasPermission(userID, models.ResourceTransfers, models.ActionWrite, session.OrganizationID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+	var req TakeOverChatbotSessionRequest
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+	if a.hasActiveAgentTransfer(session.OrganizationID, session.ContactID) {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact already has an active transfer", nil, "")
+	}
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, session.ContactID, session.OrganizationID, "Contact")
+	if err != nil {
+		return nil
+	}
+	account, err := a.resolveWhatsAppAccount(session.OrganizationID, session.WhatsAppAccount)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	old := chatbotSessionAuditView(session)
+	a.cancelChatSession(session)
+
+	settings, _ := a.getChatbotSettingsCached(session.OrganizationID, account.Name)
+	transfer := models.AgentTransfer{
+		BaseModel:           models.BaseModel{ID: uuid.New()},
+		OrganizationID:      session.OrganizationID,
+		ContactID:           contact.ID,
+		WhatsAppAccount:     account.Name,
+		PhoneNumber:         contact.PhoneNumber,
+		Status:              models.TransferStatusActive,
+		Source:              models.TransferSourceTakeover,
+		AgentID:             &userID,
+		TransferredByUserID: &userID,
+		Notes:               req.Notes,
+		TransferredAt:       time.Now(),
+	}
+	if err := a.saveAndFinalizeTransfer(&transfer, account, contact, settings, false); err != nil {
+		a.Log.Error("Failed to create takeover transfer", "error", err, "session", session.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create transfer", nil, "")
+	}
+	a.logAudit(session.OrganizationID, userID, "agent_transfer", transfer.ID, models.AuditActionCreated, nil, &transfer)
+	return a.finishSessionControl(r, session, userID, old, "taken_over")
+}
+
+// loadActiveChatbotSession authorizes a session control request and loads
+// the session from the path, rejecting sessions that have ended.
+func (a *App) loadActiveChatbotSession(r *fastglue.Request) (*models.ChatbotSession, uuid.UUID, error) {
+	orgID, userID, err := a.requireAuth(r, models.ResourceFlowsChatbot, models.ActionWrite)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	id, err := parsePathUUID(r, "id", "session")
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	session, err := findByIDAndOrg[models.ChatbotSession](a.DB, r, id, orgID, "Session")
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	if session.Status != models.SessionStatusActive {
+		_ = r.SendErrorEnvelope(fasthttp.StatusConflict, "Session is not active", nil, "")
+		return nil, uuid.Nil, errEnvelopeSent
+	}
+	if session.SessionData == nil {
+		session.SessionData = models.JSONB{}
+	}
+	return session, userID, nil
+}
+
+// runControlledSession runs flow from the session's current step with no
+// inbound input. Failures are logged; the response shows where it stopped.
+func (a *App) runControlledSession(session *models.ChatbotSession, flow *models.ChatbotFlow) {
+	account, err := a.resolveWhatsAppAccount(session.OrganizationID, session.WhatsAppAccount)
+	if err != nil {
+		a.Log.Error("Session account not found", "error", err, "session", session.ID)
+		return
+	}
+	var contact models.Contact
+	if err := a.DB.Where("id = ? AND organization_id = ?", session.ContactID, session.OrganizationID).First(&contact).Error; err != nil {
+		a.Log.Error("Session contact not found", "error", err, "session", session.ID)
+		return
+	}
+	if err := a.runChatGraph(account, &contact, session, flow, "", "", nil); err != nil {
+		a.Log.Error("Chat graph runner failed after session control", "error", err, "session", session.ID, "flow", flow.ID)
+	}
+}
+
+// cancelChatSession marks session cancelled and drops any wait it is
+// parked on. The caller saves it.
+func (a *App) cancelChatSession(session *models.ChatbotSession) {
+	a.cancelChatWait(session)
+	session.Status = models.SessionStatusCancelled
+	now := time.Now()
+	session.CompletedAt = &now
+	a.ClearContactChatbotTracking(session.ContactID)
+}
+
+// finishSessionControl saves session, audits the change as userID,
+// broadcasts it and responds with the new state.
+func (a *App) finishSessionControl(r *fastglue.Request, session *models.ChatbotSession, userID uuid.UUID, old map[string]any, action string) error {
+	if err := a.persistChatSession(session); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save session", nil, "")
+	}
+	a.logAudit(session.OrganizationID, userID, "chatbot_session", session.ID, models.AuditActionUpdated,
+		old, chatbotSessionAuditView(session), map[string]any{"field": "action", "old_value": nil, "new_value": action})
+	a.broadcastChatbotSessionUpdate(session, userID, action)
+	return r.SendEnvelope(a.chatbotSessionState(session))
+}
+
+// chatbotSessionState builds the inspector view of session.
+func (a *App) chatbotSessionState(session *models.ChatbotSession) ChatbotSessionStateResponse {
+	state := ChatbotSessionStateResponse{
+		ID:             session.ID,
+		ContactID:      session.ContactID,
+		Status:         session.Status,
+		FlowID:         session.CurrentFlowID,
+		CurrentStep:    session.CurrentStep,
+		Variables:      chatbotSessionVariables(session),
+		Path:           []any{},
+		CallDepth:      len(chatCallStack(session)),
+		Wait:           chatWaitState(session),
+		TakenOver:      a.hasActiveAgentTransfer(session.OrganizationID, session.ContactID),
+		StartedAt:      session.StartedAt,
+		LastActivityAt: session.LastActivityAt,
+	}
+	if path, ok := session.SessionData[chatPathKey].([]any); ok {
+		state.Path = path
+	}
+	if session.CurrentFlowID == nil {
+		return state
+	}
+	flow, err := a.getChatbotFlowByIDCached(session.OrganizationID, *session.CurrentFlowID)
+	if err != nil || flow == nil {
+		return state
+	}
+	state.FlowName = flow.Name
+	if graph, err := parseChatGraph(flow.Graph); err == nil && graph != nil {
+		if node := graph.Node(session.CurrentStep); node != nil {
+			state.CurrentNode = &ChatbotSessionNode{ID: node.ID, Type: string(node.Type), Label: node.Label}
+		}
+	}
+	return state
+}
+
+// chatbotSessionVariables returns the session's variables without the
+// runner's reserved __ keys.
+func chatbotSessionVariables(session *models.ChatbotSession) map[string]any {
+	vars := map[string]any{}
+	for k, v := range session.SessionData {
+		if !strings.HasPrefix(k, "__") {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// chatbotSessionAuditView is the part of a session recorded in audit diffs.
+func chatbotSessionAuditView(session *models.ChatbotSession) map[string]any {
+	return map[string]any{
+		"status":          session.Status,
+		"current_flow_id": session.CurrentFlowID,
+		"current_step":    session.CurrentStep,
+		"variables":       chatbotSessionVariables(session),
+	}
+}
+
+func (a *App) broadcastChatbotSessionUpdate(session *models.ChatbotSession, userID uuid.UUID, action string) {
+	if a.WSHub == nil {
+		return
+	}
+	a.WSHub.BroadcastToOrg(session.OrganizationID, websocket.WSMessage{
+		Type: websocket.TypeChatbotSessionUpdate,
+		Payload: map[string]any{
+			"id":              session.ID.String(),
+			"contact_id":      session.ContactID.String(),
+			"status":          session.Status,
+			"current_flow_id": session.CurrentFlowID,
+			"current_step":    session.CurrentStep,
+			"action":          action,
+			"updated_by":      userID.String(),
+		},
+	})
+}
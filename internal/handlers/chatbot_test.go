@@ -701,6 +701,340 @@ func TestApp_GetChatbotFlow(t *testing.T) {
 	})
 }
 
+func TestApp_GetChatbotFlowAnalytics(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-viewer", getChatbotFlowPermissions(t, app))
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("flow-analytics")),
+		testutil.WithRoleID(&role.ID),
+	)
+	flow := createTestChatbotFlow(t, app, org.ID, "Greeting test")
+	flow.Graph = models.JSONB{
+		"version":    2,
+		"entry_node": "split",
+		"nodes": []any{
+			map[string]any{"id": "split", "type": "split", "label": "Greeting", "config": map[string]any{
+				"goal_node": "thanks",
+				"variants": []any{
+					map[string]any{"id": "a", "label": "Friendly", "weight": 50},
+					map[string]any{"id": "b", "label": "Formal", "weight": 50},
+				},
+			}},
+		},
+	}
+	require.NoError(t, app.DB.Save(flow).Error)
+
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	addSession := func(variant string, status models.SessionStatus, path ...string) {
+		entries := make([]any, 0, len(path))
+		for _, node := range path {
+			entries = append(entries, map[string]any{"node": node, "flow_id": flow.ID.String()})
+		}
+		require.NoError(t, app.DB.Create(&models.ChatbotSession{
+			BaseModel:      models.BaseModel{ID: uuid.New()},
+			OrganizationID: org.ID,
+			ContactID:      contact.ID,
+			PhoneNumber:    contact.PhoneNumber,
+			Status:         status,
+			CurrentFlowID:  &flow.ID,
+			SessionData: models.JSONB{
+				"_flow_id":   flow.ID.String(),
+				"__splits__": map[string]any{flow.ID.String(): map[string]any{"split": variant}},
+				"__path__":   entries,
+			},
+			StartedAt:      time.Now(),
+			LastActivityAt: time.Now(),
+		}).Error)
+	}
+	addSession("a", models.SessionStatusCompleted, "split", "thanks")
+	addSession("a", models.SessionStatusCompleted, "split")
+	addSession("b", models.SessionStatusActive, "split")
+
+	// A session that started in another flow and reached the split through
+	// call_flow; the caller also has a "thanks" node, which isn't the goal
+	caller := createTestChatbotFlow(t, app, org.ID, "Main menu")
+	require.NoError(t, app.DB.Create(&models.ChatbotSession{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		ContactID:      contact.ID,
+		PhoneNumber:    contact.PhoneNumber,
+		Status:         models.SessionStatusActive,
+		CurrentFlowID:  &caller.ID,
+		SessionData: models.JSONB{
+			"_flow_id": caller.ID.String(),
+			"__splits__": map[string]any{flow.ID.String(): map[string]any{
+				"split": map[string]any{"variant": "b", "flow_id": caller.ID.String()},
+			}},
+			"__path__": []any{
+				map[string]any{"action": "call_flow", "flow_id": flow.ID.String()},
+				map[string]any{"node": "split", "flow_id": flow.ID.String()},
+				map[string]any{"action": "return", "flow_id": caller.ID.String()},
+				map[string]any{"node": "thanks", "flow_id": caller.ID.String()},
+			},
+		},
+		StartedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", flow.ID.String())
+	require.NoError(t, app.GetChatbotFlowAnalytics(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.ChatbotFlowAnalyticsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, int64(4), resp.Data.Sessions)
+	assert.Equal(t, int64(2), resp.Data.Completed)
+	require.Len(t, resp.Data.Splits, 1)
+	variants := resp.Data.Splits[0].Variants
+	require.Len(t, variants, 2)
+	assert.Equal(t, "a", variants[0].Variant)
+	assert.Equal(t, int64(2), variants[0].Sessions)
+	assert.Equal(t, int64(2), variants[0].Completed)
+	assert.Equal(t, int64(1), variants[0].Converted)
+	assert.InDelta(t, 50.0, variants[0].ConversionRate, 0.01)
+	assert.Equal(t, "b", variants[1].Variant)
+	assert.Equal(t, int64(2), variants[1].Sessions)
+	assert.Equal(t, int64(0), variants[1].Completed)
+	assert.Equal(t, int64(0), variants[1].Converted, "the caller's thanks node isn't the goal")
+}
+
+// =============================================================================
+// StartChatbotFlow
+// =============================================================================
+
+func TestApp_StartChatbotFlow(t *testing.T) {
+	t.Parallel()
+
+	// setup returns an app with an enabled chatbot, a one-node flow that
+	// stores {{order_id}}, and a contact who wrote recently when windowOpen.
+	setup := func(t *testing.T, windowOpen bool) (*handlers.App, *models.Organization, *models.User, *models.ChatbotFlow, *models.Contact) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-api", getChatbotFlowPermissions(t, app))
+		user := testutil.CreateTestUser(t, app.DB, org.ID,
+			testutil.WithEmail(testutil.UniqueEmail("flow-start")),
+			testutil.WithRoleID(&role.ID),
+		)
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+			OrganizationID:  org.ID,
+			WhatsAppAccount: account.Name,
+			IsEnabled:       true,
+		}).Error)
+
+		flow := createTestChatbotFlow(t, app, org.ID, "Delivery failed")
+		flow.WhatsAppAccount = account.Name
+		flow.Graph = models.JSONB{
+			"version":    2,
+			"entry_node": "n",
+			"nodes": []any{
+				map[string]any{"id": "n", "type": "set_variable", "config": map[string]any{
+					"set": map[string]any{"summary": "Order {{order_id}}"},
+				}},
+			},
+		}
+		require.NoError(t, app.DB.Save(flow).Error)
+
+		contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+		if windowOpen {
+			now := time.Now()
+			require.NoError(t, app.DB.Model(contact).Update("last_inbound_at", now).Error)
+		}
+		return app, org, user, flow, contact
+	}
+	start := func(app *handlers.App, org *models.Organization, user *models.User, flow *models.ChatbotFlow, body map[string]any) *fastglue.Request {
+		req := testutil.NewJSONRequest(t, body)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", flow.ID.String())
+		require.NoError(t, app.StartChatbotFlow(req))
+		return req
+	}
+
+	t.Run("runs the flow with initial variables", func(t *testing.T) {
+		app, org, user, flow, contact := setup(t, true)
+		req := start(app, org, user, flow, map[string]any{
+			"phone_number": contact.PhoneNumber,
+			"variables":    map[string]any{"order_id": "42"},
+		})
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Data struct {
+				SessionID uuid.UUID `json:"session_id"`
+				Status    string    `json:"status"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+		assert.Equal(t, "started", resp.Data.Status)
+
+		var session models.ChatbotSession
+		require.NoError(t, app.DB.First(&session, resp.Data.SessionID).Error)
+		assert.Equal(t, "Order 42", session.SessionData["summary"])
+		assert.Equal(t, contact.ID, session.ContactID)
+	})
+
+	t.Run("rejects an active session unless forced", func(t *testing.T) {
+		app, org, user, flow, contact := setup(t, true)
+		active := &models.ChatbotSession{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: flow.WhatsAppAccount,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.SessionStatusActive,
+			SessionData:     models.JSONB{},
+			StartedAt:       time.Now(),
+			LastActivityAt:  time.Now(),
+		}
+		require.NoError(t, app.DB.Create(active).Error)
+
+		req := start(app, org, user, flow, map[string]any{"phone_number": contact.PhoneNumber})
+		assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+
+		req = start(app, org, user, flow, map[string]any{"phone_number": contact.PhoneNumber, "force": true})
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		require.NoError(t, app.DB.First(active, active.ID).Error)
+		assert.Equal(t, models.SessionStatusCancelled, active.Status)
+	})
+
+	t.Run("requires a template when the window is closed", func(t *testing.T) {
+		app, org, user, flow, contact := setup(t, false)
+		req := start(app, org, user, flow, map[string]any{"phone_number": contact.PhoneNumber})
+		assert.Equal(t, fasthttp.StatusUnprocessableEntity, testutil.GetResponseStatusCode(req))
+	})
+
+	t.Run("rejects reserved variable names", func(t *testing.T) {
+		app, org, user, flow, contact := setup(t, true)
+		req := start(app, org, user, flow, map[string]any{
+			"phone_number": contact.PhoneNumber,
+			"variables":    map[string]any{"_flow_id": "x"},
+		})
+		assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+	})
+}
+
+// =============================================================================
+// Chatbot session control
+// =============================================================================
+
+func TestApp_ChatbotSessionControl(t *testing.T) {
+	t.Parallel()
+
+	// setup returns an app with an active session parked at node "ask" of a
+	// two-node flow.
+	setup := func(t *testing.T) (*handlers.App, *models.Organization, *models.User, *models.ChatbotSession) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRole(t, app.DB, org.ID, "session-control", getChatbotFlowPermissions(t, app))
+		user := testutil.CreateTestUser(t, app.DB, org.ID,
+			testutil.WithEmail(testutil.UniqueEmail("session-control")),
+			testutil.WithRoleID(&role.ID),
+		)
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		flow := createTestChatbotFlow(t, app, org.ID, "Support")
+		flow.WhatsAppAccount = account.Name
+		flow.Graph = models.JSONB{
+			"version":    2,
+			"entry_node": "ask",
+			"nodes": []any{
+				map[string]any{"id": "ask", "type": "set_variable", "label": "Ask", "config": map[string]any{
+					"set": map[string]any{"asked": "yes"},
+				}},
+				map[string]any{"id": "done", "type": "set_variable", "config": map[string]any{
+					"set": map[string]any{"done": "yes"},
+				}},
+			},
+		}
+		require.NoError(t, app.DB.Save(flow).Error)
+		contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+
+		session := &models.ChatbotSession{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: account.Name,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.SessionStatusActive,
+			CurrentFlowID:   &flow.ID,
+			CurrentStep:     "ask",
+			SessionData:     models.JSONB{"name": "Ada", "__path__": []any{"ask"}},
+			StartedAt:       time.Now(),
+			LastActivityAt:  time.Now(),
+		}
+		require.NoError(t, app.DB.Create(session).Error)
+		return app, org, user, session
+	}
+	call := func(t *testing.T, app *handlers.App, org *models.Organization, user *models.User, session *models.ChatbotSession, body map[string]any, handler func(*fastglue.Request) error) *fastglue.Request {
+		req := testutil.NewJSONRequest(t, body)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", session.ID.String())
+		require.NoError(t, handler(req))
+		return req
+	}
+
+	t.Run("state shows node, variables and path", func(t *testing.T) {
+		app, org, user, session := setup(t)
+		req := call(t, app, org, user, session, nil, app.GetChatbotSessionState)
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Data handlers.ChatbotSessionStateResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+		require.NotNil(t, resp.Data.CurrentNode)
+		assert.Equal(t, "Ask", resp.Data.CurrentNode.Label)
+		assert.Equal(t, map[string]any{"name": "Ada"}, resp.Data.Variables)
+		assert.Equal(t, []any{"ask"}, resp.Data.Path)
+	})
+
+	t.Run("edits variables", func(t *testing.T) {
+		app, org, user, session := setup(t)
+		req := call(t, app, org, user, session, map[string]any{
+			"set":   map[string]any{"plan": "pro"},
+			"unset": []string{"name"},
+		}, app.UpdateChatbotSessionVariables)
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		require.NoError(t, app.DB.First(session, session.ID).Error)
+		assert.Equal(t, "pro", session.SessionData["plan"])
+		assert.NotContains(t, session.SessionData, "name")
+		assert.Contains(t, session.SessionData, "__path__")
+
+		req = call(t, app, org, user, session, map[string]any{"set": map[string]any{"__path__": nil}}, app.UpdateChatbotSessionVariables)
+		assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+	})
+
+	t.Run("jumps to a node and runs from there", func(t *testing.T) {
+		app, org, user, session := setup(t)
+		req := call(t, app, org, user, session, map[string]any{"node_id": "missing"}, app.JumpChatbotSession)
+		assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+
+		req = call(t, app, org, user, session, map[string]any{"node_id": "done"}, app.JumpChatbotSession)
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		require.NoError(t, app.DB.First(session, session.ID).Error)
+		assert.Equal(t, "yes", session.SessionData["done"])
+		assert.NotContains(t, session.SessionData, "asked")
+	})
+
+	t.Run("cancels and rejects further changes", func(t *testing.T) {
+		app, org, user, session := setup(t)
+		req := call(t, app, org, user, session, nil, app.CancelChatbotSession)
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		require.NoError(t, app.DB.First(session, session.ID).Error)
+		assert.Equal(t, models.SessionStatusCancelled, session.Status)
+
+		req = call(t, app, org, user, session, nil, app.RestartChatbotSession)
+		assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+	})
+}
+
 // =============================================================================
 // UpdateChatbotFlow
 // =============================================================================
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChatbotWakeupProcessor resumes chatbot sessions parked at wait and
+// wait_for_reply nodes once their wake-up is due. Wake-ups live in the
+// database, so waits survive restarts.
+type ChatbotWakeupProcessor struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewChatbotWakeupProcessor creates a new chatbot wake-up processor.
+func NewChatbotWakeupProcessor(app *App, interval time.Duration) *ChatbotWakeupProcessor {
+	return &ChatbotWakeupProcessor{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the wake-up loop.
+func (p *ChatbotWakeupProcessor) Start(ctx context.Context) {
+	p.app.Log.Info("Chatbot wake-up processor started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.app.Log.Info("Chatbot wake-up processor stopped by context")
+			return
+		case <-p.stopCh:
+			p.app.Log.Info("Chatbot wake-up processor stopped")
+			return
+		case <-ticker.C:
+			p.processDueWakeups()
+		}
+	}
+}
+
+// Stop stops the chatbot wake-up processor.
+func (p *ChatbotWakeupProcessor) Stop() {
+	close(p.stopCh)
+}
+
+func (p *ChatbotWakeupProcessor) processDueWakeups() {
+	now := time.Now().UTC()
+	staleCutoff := now.Add(-15 * time.Minute)
+	batchSize := 50
+
+	for {
+		var wakeups []models.ChatbotWakeup
+		err := p.app.DB.Transaction(func(tx *gorm.DB) error {
+			query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ? AND wake_at <= ?", models.WakeupStatusPending, now).
+				Or("status = ? AND processing_started_at <= ?", models.WakeupStatusInProgress, staleCutoff).
+				Order("wake_at ASC").
+				Limit(batchSize)
+
+			if err := query.Find(&wakeups).Error; err != nil {
+				return err
+			}
+			if len(wakeups) == 0 {
+				return nil
+			}
+
+			ids := make([]interface{}, 0, len(wakeups))
+			for _, w := range wakeups {
+				ids = append(ids, w.ID)
+			}
+			return tx.Model(&models.ChatbotWakeup{}).
+				Where("id IN ?", ids).
+				Updates(map[string]interface{}{
+					"status":                models.WakeupStatusInProgress,
+					"processing_started_at": now,
+				}).Error
+		})
+
+		if err != nil {
+			p.app.Log.Error("Failed to load chatbot wake-ups", "error", err)
+			return
+		}
+
+		if len(wakeups) == 0 {
+			return
+		}
+
+		for _, w := range wakeups {
+			p.app.processChatbotWakeup(w)
+		}
+	}
+}
+
+// processChatbotWakeup resumes one wake-up and records how it went. A
+// failed resume isn't retried: the flow may already have sent messages.
+func (a *App) processChatbotWakeup(w models.ChatbotWakeup) {
+	status := models.WakeupStatusDone
+	lastError := ""
+	if err := a.resumeChatWakeup(w); err != nil {
+		if errors.Is(err, errChatWakeupStale) {
+			status = models.WakeupStatusCancelled
+		} else {
+			a.Log.Error("Failed to resume chatbot session", "error", err, "wakeup", w.ID, "session", w.SessionID)
+		}
+		lastError = err.Error()
+	}
+	if err := a.DB.Model(&models.ChatbotWakeup{}).Where("id = ?", w.ID).Updates(map[string]any{
+		"status":     status,
+		"last_error": lastError,
+	}).Error; err != nil {
+		a.Log.Error("Failed to update chatbot wake-up", "error", err, "wakeup", w.ID)
+	}
+}
+
+// errChatWakeupStale marks a wake-up whose session has moved on: it was
+// answered, ended or switched flows since the wake-up was scheduled.
+var errChatWakeupStale = errors.New("session is no longer waiting on this wake-up")
+
+// resumeChatWakeup runs the session's flow from the node it is parked on,
+// telling that node its wait is over.
+func (a *App) resumeChatWakeup(w models.ChatbotWakeup) error {
+	var session models.ChatbotSession
+	if err := a.DB.Where("id = ?", w.SessionID).First(&session).Error; err != nil {
+		return fmt.Errorf("load session: %w", err)
+	}
+	state := chatWaitState(&session)
+	if session.Status != models.SessionStatusActive || state == nil ||
+		state["wakeup_id"] != w.ID.String() ||
+		session.CurrentFlowID == nil || *session.CurrentFlowID != w.FlowID ||
+		session.CurrentStep != w.NodeID {
+		return errChatWakeupStale
+	}
+
+	var contact models.Contact
+	if err := a.DB.Where("id = ?", session.ContactID).First(&contact).Error; err != nil {
+		return fmt.Errorf("load contact: %w", err)
+	}
+	account, err := a.resolveWhatsAppAccount(session.OrganizationID, session.WhatsAppAccount)
+	if err != nil {
+		return err
+	}
+	flow, err := a.getChatbotFlowByIDCached(session.OrganizationID, w.FlowID)
+	if err != nil {
+		return fmt.Errorf("load flow: %w", err)
+	}
+
+	session.SessionData[chatWakeKey] = w.ID.String()
+	return a.runChatGraph(account, &contact, &session, flow, "", "", nil)
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// contactFieldKeyPattern keeps field keys usable as metadata keys, CSV
+// headers and template variables.
+var contactFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ContactFieldRequest represents the request body for creating/updating a
+// contact field. Key and Type can only be set on create.
+type ContactFieldRequest struct {
+	Key          string                  `json:"key"`
+	Label        string                  `json:"label"`
+	Type         models.ContactFieldType `json:"type"`
+	Options      []string                `json:"options"`
+	DefaultValue string                  `json:"default_value"`
+	IsRequired   bool                    `json:"is_required"`
+	IsUnique     bool                    `json:"is_unique"`
+	ShowInPanel  bool                    `json:"show_in_panel"`
+	Position     int                     `json:"position"`
+}
+
+// ContactFieldResponse represents a contact field in API responses
+type ContactFieldResponse struct {
+	ID           uuid.UUID               `json:"id"`
+	Key          string                  `json:"key"`
+	Label        string                  `json:"label"`
+	Type         models.ContactFieldType `json:"type"`
+	Options      []string                `json:"options"`
+	DefaultValue string                  `json:"default_value"`
+	IsRequired   bool                    `json:"is_required"`
+	IsUnique     bool                    `json:"is_unique"`
+	ShowInPanel  bool                    `json:"show_in_panel"`
+	Position     int                     `json:"position"`
+	CreatedAt    time.Time               `json:"created_at"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+}
+
+// ListContactFields returns the organization's custom contact fields
+func (a *App) ListContactFields(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceContacts, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	fields, err := contactutil.LoadFields(a.DB, orgID)
+	if err != nil {
+		a.Log.Error("Failed to list contact fields", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list contact fields", nil, "")
+	}
+
+	result := make([]ContactFieldResponse, len(fields))
+	for i := range fields {
+		result[i] = contactFieldToResponse(&fields[i])
+	}
+
+	return r.SendEnvelope(map[string]any{"fields": result})
+}
+
+// CreateContactField defines a new custom contact field
+func (a *App) CreateContactField(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceContacts, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	var req ContactFieldRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	req.Key = strings.TrimSpace(req.Key)
+	if !contactFieldKeyPattern.MatchString(req.Key) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+			"key must start with a lowercase letter and contain only lowercase letters, digits and underscores (max 50)", nil, "")
+	}
+	if !models.IsValidContactFieldType(req.Type) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+			"invalid type. Valid types: text, number, date, enum, boolean, phone, email", nil, "")
+	}
+
+	var existing int64
+	a.DB.Model(&models.ContactField{}).Where("organization_id = ? AND key = ?", orgID, req.Key).Count(&existing)
+	if existing > 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact field with this key already exists", nil, "")
+	}
+
+	field := models.ContactField{
+		OrganizationID: orgID,
+		Key:            req.Key,
+		Type:           req.Type,
+	}
+	if err := a.applyContactFieldRequest(r, &field, &req); err != nil {
+		return nil
+	}
+
+	if err := a.DB.Create(&field).Error; err != nil {
+		a.Log.Error("Failed to create contact field", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact field", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"contact_field", field.ID, models.AuditActionCreated, nil, &field)
+
+	return r.SendEnvelope(contactFieldToResponse(&field))
+}
+
+// UpdateContactField updates a custom contact field's definition
+func (a *App) UpdateContactField(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceContacts, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "contact field")
+	if err != nil {
+		return nil
+	}
+
+	field, err := findByIDAndOrg[models.ContactField](a.DB, r, id, orgID, "Contact field")
+	if err != nil {
+		return nil
+	}
+	oldField := *field
+
+	var req ContactFieldRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if req.Key != "" && req.Key != field.Key {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "key cannot be changed", nil, "")
+	}
+	if req.Type != "" && req.Type != field.Type {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "type cannot be changed", nil, "")
+	}
+
+	if err := a.applyContactFieldRequest(r, field, &req); err != nil {
+		return nil
+	}
+
+	if field.IsUnique && !oldField.IsUnique {
+		var duplicates int64
+		a.DB.Raw(`SELECT COUNT(*) FROM (
+			SELECT 1 FROM contacts
+			WHERE organization_id = ? AND deleted_at IS NULL AND metadata->>?::text IS NOT NULL
+			GROUP BY metadata->>?::text HAVING COUNT(*) > 1
+		) dup`, orgID, field.Key, field.Key).Scan(&duplicates)
+		if duplicates > 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusConflict,
+				"Existing contacts share values for this field; resolve duplicates before making it unique", nil, "")
+		}
+	}
+
+	if err := a.DB.Save(field).Error; err != nil {
+		a.Log.Error("Failed to update contact field", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact field", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"contact_field", field.ID, models.AuditActionUpdated, &oldField, field)
+
+	return r.SendEnvelope(contactFieldToResponse(field))
+}
+
+// DeleteContactField removes a field definition. Values already stored on
+// contacts are kept as plain metadata.
+func (a *App) DeleteContactField(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceContacts, models.ActionDelete)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "contact field")
+	if err != nil {
+		return nil
+	}
+
+	field, err := findByIDAndOrg[models.ContactField](a.DB, r, id, orgID, "Contact field")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Delete(field).Error; err != nil {
+		a.Log.Error("Failed to delete contact field", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete contact field", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"contact_field", id, models.AuditActionDeleted, field, nil)
+
+	return r.SendEnvelope(map[string]string{"message": "Contact field deleted"})
+}
+
+// applyContactFieldRequest copies the editable parts of req onto field and
+// validates them against the field's type. Sends a 400 on failure.
+func (a *App) applyContactFieldRequest(r *fastglue.Request, field *models.ContactField, req *ContactFieldRequest) error {
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		label = field.Label
+	}
+	if label == "" {
+		return sendContactFieldError(r, "label is required")
+	}
+	if len(label) > 100 {
+		return sendContactFieldError(r, "label must be at most 100 characters")
+	}
+
+	options := models.StringArray{}
+	if field.Type == models.ContactFieldEnum {
+		seen := map[string]bool{}
+		for _, opt := range req.Options {
+			opt = strings.TrimSpace(opt)
+			if opt != "" && !seen[opt] {
+				seen[opt] = true
+				options = append(options, opt)
+			}
+		}
+		if len(options) == 0 {
+			return sendContactFieldError(r, "enum fields need at least one option")
+		}
+	}
+
+	field.Label = label
+	field.Options = options
+	field.DefaultValue = strings.TrimSpace(req.DefaultValue)
+	field.IsRequired = req.IsRequired
+	field.IsUnique = req.IsUnique
+	field.ShowInPanel = req.ShowInPanel
+	field.Position = req.Position
+
+	if field.DefaultValue != "" {
+		if field.IsUnique {
+			return sendContactFieldError(r, "unique fields cannot have a default value")
+		}
+		if _, err := contactutil.CoerceFieldValue(field, field.DefaultValue); err != nil {
+			return sendContactFieldError(r, "default_value "+err.Error())
+		}
+	}
+	return nil
+}
+
+func sendContactFieldError(r *fastglue.Request, msg string) error {
+	_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	return errEnvelopeSent
+}
+
+// normalizeContactMetadata validates metadata against the organization's
+// contact fields. On failure it sends a 400 (or 409 for a taken unique
+// value) and returns errEnvelopeSent.
+func (a *App) normalizeContactMetadata(r *fastglue.Request, orgID, contactID uuid.UUID, metadata models.JSONB, opts contactutil.MetadataOptions) (models.JSONB, error) {
+	out, err := contactutil.NormalizeMetadata(a.DB, orgID, contactID, metadata, opts)
+	if err == nil {
+		return out, nil
+	}
+	var fieldErr *contactutil.FieldError
+	switch {
+	case errors.Is(err, contactutil.ErrFieldValueTaken):
+		_ = r.SendErrorEnvelope(fasthttp.StatusConflict, err.Error(), nil, "")
+	case errors.As(err, &fieldErr):
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	default:
+		a.Log.Error("Failed to validate contact fields", "error", err)
+		_ = r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to validate contact fields", nil, "")
+	}
+	return nil, errEnvelopeSent
+}
+
+// filterContactFields narrows a contact query by field.<key>=value query
+// arguments. Values are coerced to the field's type so e.g. "yes" matches
+// a boolean true. Sends a 400 for unknown fields or invalid values.
+func (a *App) filterContactFields(r *fastglue.Request, query *gorm.DB, orgID uuid.UUID) (*gorm.DB, error) {
+	filters := map[string]string{}
+	r.RequestCtx.QueryArgs().VisitAll(func(k, v []byte) {
+		if key, ok := strings.CutPrefix(string(k), "field."); ok {
+			filters[key] = string(v)
+		}
+	})
+	if len(filters) == 0 {
+		return query, nil
+	}
+
+	fields, err := contactutil.LoadFields(a.DB, orgID)
+	if err != nil {
+		a.Log.Error("Failed to load contact fields", "error", err)
+		_ = r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list contacts", nil, "")
+		return nil, errEnvelopeSent
+	}
+	byKey := make(map[string]*models.ContactField, len(fields))
+	for i := range fields {
+		byKey[fields[i].Key] = &fields[i]
+	}
+
+	for key, raw := range filters {
+		field, ok := byKey[key]
+		if !ok {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Unknown contact field: "+key, nil, "")
+			return nil, errEnvelopeSent
+		}
+		value, err := contactutil.CoerceFieldValue(field, raw)
+		if err != nil {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, key+": "+err.Error(), nil, "")
+			return nil, errEnvelopeSent
+		}
+		if value == nil {
+			query = query.Where("metadata->>?::text IS NULL", key)
+			continue
+		}
+		query = query.Where("metadata->>?::text = ?", key, contactutil.FieldValueText(value))
+	}
+	return query, nil
+}
+
+func contactFieldToResponse(field *models.ContactField) ContactFieldResponse {
+	options := []string(field.Options)
+	if options == nil {
+		options = []string{}
+	}
+	return ContactFieldResponse{
+		ID:           field.ID,
+		Key:          field.Key,
+		Label:        field.Label,
+		Type:         field.Type,
+		Options:      options,
+		DefaultValue: field.DefaultValue,
+		IsRequired:   field.IsRequired,
+		IsUnique:     field.IsUnique,
+		ShowInPanel:  field.ShowInPanel,
+		Position:     field.Position,
+		CreatedAt:    field.CreatedAt,
+		UpdatedAt:    field.UpdatedAt,
+	}
+}
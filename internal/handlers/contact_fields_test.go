@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_ContactFields_EnforcedOnContacts(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("contact-fields")), testutil.WithSuperAdmin())
+
+	for _, field := range []map[string]any{
+		{"key": "tier", "label": "Tier", "type": "enum", "options": []string{"gold", "silver"}, "default_value": "silver"},
+		{"key": "member_id", "label": "Member ID", "type": "text", "is_unique": true},
+		{"key": "points", "label": "Points", "type": "number"},
+	} {
+		req := testutil.NewJSONRequest(t, field)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateContactField(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	}
+
+	// Duplicate key
+	req := testutil.NewJSONRequest(t, map[string]any{"key": "tier", "label": "Tier", "type": "text"})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateContactField(req))
+	assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+
+	// Values are coerced and defaults applied
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"phone_number": "15550002001",
+		"metadata":     map[string]any{"member_id": "M-1", "points": "40"},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateContact(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var contact models.Contact
+	require.NoError(t, app.DB.Where("organization_id = ? AND phone_number = ?", org.ID, "15550002001").First(&contact).Error)
+	assert.Equal(t, "silver", contact.Metadata["tier"])
+	assert.Equal(t, float64(40), contact.Metadata["points"])
+
+	// Unique values can't be reused
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"phone_number": "15550002002",
+		"metadata":     map[string]any{"member_id": "M-1"},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateContact(req))
+	assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+
+	// Invalid values are rejected on update
+	req = testutil.NewJSONRequest(t, map[string]any{"metadata": map[string]any{"tier": "bronze"}})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+	require.NoError(t, app.UpdateContact(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+
+	// Filter by field value
+	req = testutil.NewGETRequest(t)
+	req.RequestCtx.QueryArgs().Set("field.points", "40")
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.ListContacts(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var list struct {
+		Data struct {
+			Contacts []handlers.ContactResponse `json:"contacts"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &list))
+	require.Len(t, list.Data.Contacts, 1)
+	assert.Equal(t, contact.ID, list.Data.Contacts[0].ID)
+
+	req = testutil.NewGETRequest(t)
+	req.RequestCtx.QueryArgs().Set("field.unknown", "1")
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.ListContacts(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"maps"
+
+	"github.com/shridarpatil/whatomate/internal/langutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// detectContactLanguage sets the contact's language from an inbound text
+// message. It only runs until a language is known, and messages too short
+// or ambiguous to classify are skipped so a later message can decide.
+func (a *App) detectContactLanguage(contact *models.Contact, messageType, text string) {
+	if contact.Language != "" || messageType != string(models.MessageTypeText) {
+		return
+	}
+	if lang := langutil.Detect(text); lang != "" {
+		a.setContactLanguage(contact, lang, models.LanguageSourceDetected)
+	}
+}
+
+// setContactLanguage stores lang on the contact. Returns false when the code
+// is invalid or the write fails; the in-memory contact is left untouched then.
+func (a *App) setContactLanguage(contact *models.Contact, lang string, source models.LanguageSource) bool {
+	lang = langutil.Normalize(lang)
+	if lang == "" {
+		return false
+	}
+	if contact.Language == lang && contact.LanguageSource == source {
+		return true
+	}
+	if err := a.DB.Model(contact).Updates(map[string]any{
+		"language":        lang,
+		"language_source": source,
+	}).Error; err != nil {
+		a.Log.Error("Failed to update contact language", "error", err, "contact_id", contact.ID)
+		return false
+	}
+	contact.Language = lang
+	contact.LanguageSource = source
+	return true
+}
+
+// localizeChatConfig overlays the node's translation for lang onto its
+// config. Translations live under config.translations keyed by language
+// code, each holding the same keys as the node itself:
+//
+//	{
+//	  "message": "Hi {{contact_name}}",
+//	  "translations": {
+//	    "hi": { "message": "नमस्ते {{contact_name}}" },
+//	    "es": { "message": "Hola {{contact_name}}" }
+//	  }
+//	}
+//
+// "es_MX" falls back to "es", and keys missing from a translation keep
+// the node's default text. Translated buttons are matched by id and only
+// replace user-facing fields, so button edges keep working. Returns nil
+// when the node has no translation for lang.
+func localizeChatConfig(cfg map[string]any, lang string) map[string]any {
+	translations, _ := cfg["translations"].(map[string]any)
+	if len(translations) == 0 {
+		return nil
+	}
+	var overlay map[string]any
+	for _, candidate := range langutil.Candidates(lang) {
+		for code, raw := range translations {
+			if langutil.Normalize(code) != candidate {
+				continue
+			}
+			if m, ok := raw.(map[string]any); ok && len(m) > 0 {
+				overlay = m
+			}
+			break
+		}
+		if overlay != nil {
+			break
+		}
+	}
+	if overlay == nil {
+		return nil
+	}
+
+	out := maps.Clone(cfg)
+	for key, value := range overlay {
+		switch key {
+		case "buttons":
+			if buttons := localizeChatButtons(cfg, value); buttons != nil {
+				out["buttons"] = buttons
+			}
+		default:
+			if s, ok := value.(string); ok && s != "" {
+				out[key] = s
+			}
+		}
+	}
+	return out
+}
+
+// localizeChatButtons copies the node's buttons, replacing title, url and
+// phone_number with the translated values of the button with the same id.
+func localizeChatButtons(cfg map[string]any, translated any) []any {
+	items, _ := translated.([]any)
+	byID := make(map[string]map[string]any, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]any); ok {
+			if id, _ := m["id"].(string); id != "" {
+				byID[id] = m
+			}
+		}
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+	original := buttonsFromConfig(cfg)
+	if len(original) == 0 {
+		return nil
+	}
+	out := make([]any, len(original))
+	for i, b := range original {
+		b = maps.Clone(b)
+		id, _ := b["id"].(string)
+		for _, key := range []string{"title", "url", "phone_number"} {
+			if s, ok := byID[id][key].(string); ok && s != "" {
+				b[key] = s
+			}
+		}
+		out[i] = b
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizeChatConfig(t *testing.T) {
+	cfg := map[string]any{
+		"body": "Pick one",
+		"buttons": []any{
+			map[string]any{"id": "yes", "title": "Yes"},
+			map[string]any{"id": "no", "title": "No"},
+		},
+		"translations": map[string]any{
+			"es": map[string]any{
+				"body":    "Elige uno",
+				"buttons": []any{map[string]any{"id": "yes", "title": "Sí"}},
+			},
+			"hi": map[string]any{"body": ""},
+		},
+	}
+
+	es := localizeChatConfig(cfg, "es_MX")
+	require.NotNil(t, es, "es_MX falls back to es")
+	assert.Equal(t, "Elige uno", es["body"])
+	assert.Equal(t, []any{
+		map[string]any{"id": "yes", "title": "Sí"},
+		map[string]any{"id": "no", "title": "No"},
+	}, es["buttons"], "untranslated buttons keep their title")
+	assert.Equal(t, "Yes", buttonsFromConfig(cfg)[0]["title"], "original config is not modified")
+
+	hi := localizeChatConfig(cfg, "hi")
+	require.NotNil(t, hi)
+	assert.Equal(t, "Pick one", hi["body"], "empty translation keeps the default text")
+
+	assert.Nil(t, localizeChatConfig(cfg, "fr"))
+	assert.Nil(t, localizeChatConfig(cfg, ""))
+	assert.Nil(t, localizeChatConfig(map[string]any{"body": "x"}, "es"))
+}
+
+// TestRunChatGraph_TranslationsFollowFlowLanguage: a set_variable node sets
+// the contact language and the next message is sent in that language.
+func TestRunChatGraph_TranslationsFollowFlowLanguage(t *testing.T) {
+	app, org, account, contact, session := newGraphTestFixtures(t)
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "multilingual",
+		IsEnabled:       true,
+		Graph: models.JSONB{
+			"version":    2,
+			"entry_node": "lang",
+			"nodes": []any{
+				map[string]any{"id": "lang", "type": "set_variable", "config": map[string]any{"language": "{{choice}}"}},
+				map[string]any{"id": "m1", "type": "message", "config": map[string]any{
+					"message": "Hello",
+					"translations": map[string]any{
+						"hi": map[string]any{"message": "नमस्ते"},
+						"es": map[string]any{"message": "Hola"},
+					},
+				}},
+			},
+			"edges": []any{
+				map[string]any{"from": "lang", "to": "m1", "condition": "default"},
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+	session.SessionData = models.JSONB{"choice": "es-MX"}
+
+	require.NoError(t, app.runChatGraph(account, contact, session, flow, "start", "", nil))
+
+	var stored models.Contact
+	require.NoError(t, app.DB.First(&stored, contact.ID).Error)
+	assert.Equal(t, "es_MX", stored.Language)
+	assert.Equal(t, models.LanguageSourceFlow, stored.LanguageSource)
+
+	var msg models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND direction = ?", session.ID, models.DirectionOutgoing).First(&msg).Error)
+	assert.Equal(t, "Hola", msg.Message)
+}
+
+func TestDetectContactLanguage_KeepsExisting(t *testing.T) {
+	app, _, _, contact, _ := newGraphTestFixtures(t)
+
+	app.detectContactLanguage(contact, string(models.MessageTypeText), "ok")
+	assert.Empty(t, contact.Language, "ambiguous text is skipped")
+
+	app.detectContactLanguage(contact, string(models.MessageTypeText), "Hola, necesito ayuda")
+	assert.Equal(t, "es", contact.Language)
+	assert.Equal(t, models.LanguageSourceDetected, contact.LanguageSource)
+
+	app.detectContactLanguage(contact, string(models.MessageTypeText), "Hello, where is my order?")
+	assert.Equal(t, "es", contact.Language, "detection runs only until a language is known")
+}
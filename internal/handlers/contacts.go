@@ -14,8 +14,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/langutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/utils"
+	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -40,6 +43,8 @@ type ContactResponse struct {
 	LastInboundAt      *time.Time `json:"last_inbound_at,omitempty"`
 	ServiceWindowOpen  bool       `json:"service_window_open"`
 	MarketingOptOut    bool       `json:"marketing_opt_out"`
+	Language           string     `json:"language,omitempty"`
+	LanguageSource     string     `json:"language_source,omitempty"`
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 }
@@ -134,6 +139,12 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 		}
 	}
 
+	// Filter by custom contact fields (field.<key>=value)
+	query, err = a.filterContactFields(r, query, orgID)
+	if err != nil {
+		return nil
+	}
+
 	// Order by last message time (most recent first)
 	query = query.Order("last_message_at DESC NULLS LAST, created_at DESC")
 
@@ -191,6 +202,8 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 			LastInboundAt:      c.LastInboundAt,
 			ServiceWindowOpen:  serviceWindowOpen,
 			MarketingOptOut:    c.MarketingOptOut,
+			Language:           c.Language,
+			LanguageSource:     string(c.LanguageSource),
 			CreatedAt:          c.CreatedAt,
 			UpdatedAt:          c.UpdatedAt,
 		}
@@ -1188,6 +1201,8 @@ func (a *App) AssignContact(r *fastglue.Request) error {
 		a.Log.Error("Failed to assign contact", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to assign contact", nil, "")
 	}
+	contact.AssignedUserID = req.UserID
+	a.broadcastContactUpdate(contact)
 
 	return r.SendEnvelope(map[string]any{
 		"message":          "Contact assigned successfully",
@@ -1195,13 +1210,35 @@ func (a *App) AssignContact(r *fastglue.Request) error {
 	})
 }
 
+// broadcastContactUpdate pushes a contact's current state to the org's
+// agents so open chat lists and info panels pick up assignment, tag and
+// field changes.
+func (a *App) broadcastContactUpdate(contact *models.Contact) {
+	if a.WSHub == nil {
+		return
+	}
+	a.WSHub.BroadcastToOrg(contact.OrganizationID, websocket.WSMessage{
+		Type:    websocket.TypeContactUpdate,
+		Payload: a.buildContactResponse(contact, contact.OrganizationID),
+	})
+}
+
 // ContactSessionDataResponse represents the session data for a contact's info panel
 type ContactSessionDataResponse struct {
-	SessionID   *uuid.UUID     `json:"session_id,omitempty"`
-	FlowID      *uuid.UUID     `json:"flow_id,omitempty"`
-	FlowName    string         `json:"flow_name,omitempty"`
-	SessionData map[string]any `json:"session_data"`
-	PanelConfig map[string]any `json:"panel_config"`
+	SessionID     *uuid.UUID          `json:"session_id,omitempty"`
+	FlowID        *uuid.UUID          `json:"flow_id,omitempty"`
+	FlowName      string              `json:"flow_name,omitempty"`
+	SessionData   map[string]any      `json:"session_data"`
+	PanelConfig   map[string]any      `json:"panel_config"`
+	ContactFields []ContactFieldValue `json:"contact_fields"`
+}
+
+// ContactFieldValue is a custom contact field shown in the contact info panel
+type ContactFieldValue struct {
+	Key   string                  `json:"key"`
+	Label string                  `json:"label"`
+	Type  models.ContactFieldType `json:"type"`
+	Value any                     `json:"value"`
 }
 
 // GetContactSessionData returns session data and panel configuration for a contact
@@ -1225,8 +1262,22 @@ func (a *App) GetContactSessionData(r *fastglue.Request) error {
 	}
 
 	response := ContactSessionDataResponse{
-		SessionData: make(map[string]any),
-		PanelConfig: map[string]any{"sections": []any{}},
+		SessionData:   make(map[string]any),
+		PanelConfig:   map[string]any{"sections": []any{}},
+		ContactFields: []ContactFieldValue{},
+	}
+
+	// Custom contact fields flagged for the panel
+	contactFields, err := contactutil.LoadFields(a.DB, orgID)
+	if err != nil {
+		a.Log.Error("Failed to load contact fields", "error", err)
+	}
+	for _, f := range contactFields {
+		if f.ShowInPanel {
+			response.ContactFields = append(response.ContactFields, ContactFieldValue{
+				Key: f.Key, Label: f.Label, Type: f.Type, Value: contact.Metadata[f.Key],
+			})
+		}
 	}
 
 	// Get the most recent completed or active session for this contact
@@ -1262,29 +1313,32 @@ func (a *App) GetContactSessionData(r *fastglue.Request) error {
 				if len(flow.PanelConfig) > 0 {
 					response.PanelConfig = flow.PanelConfig
 
-					// Only include session data for configured fields (reduce payload)
-					if session.SessionData != nil {
-						configuredKeys := make(map[string]bool)
-						if sections, ok := flow.PanelConfig["sections"].([]any); ok {
-							for _, sec := range sections {
-								if section, ok := sec.(map[string]any); ok {
-									if fields, ok := section["fields"].([]any); ok {
-										for _, f := range fields {
-											if field, ok := f.(map[string]any); ok {
-												if key, ok := field["key"].(string); ok {
-													configuredKeys[key] = true
-												}
+					// Only include session data for configured fields (reduce payload).
+					// Keys prefixed with "contact." read the contact's custom fields.
+					configuredKeys := make(map[string]bool)
+					if sections, ok := flow.PanelConfig["sections"].([]any); ok {
+						for _, sec := range sections {
+							if section, ok := sec.(map[string]any); ok {
+								if fields, ok := section["fields"].([]any); ok {
+									for _, f := range fields {
+										if field, ok := f.(map[string]any); ok {
+											if key, ok := field["key"].(string); ok {
+												configuredKeys[key] = true
 											}
 										}
 									}
 								}
 							}
 						}
-						// Copy only configured fields to response
-						for key := range configuredKeys {
-							if val, exists := session.SessionData[key]; exists {
+					}
+					// Copy only configured fields to response
+					for key := range configuredKeys {
+						if fieldKey, ok := strings.CutPrefix(key, "contact."); ok {
+							if val, exists := contact.Metadata[fieldKey]; exists {
 								response.SessionData[key] = val
 							}
+						} else if val, exists := session.SessionData[key]; exists {
+							response.SessionData[key] = val
 						}
 					}
 				}
@@ -1335,10 +1389,12 @@ func (a *App) UpdateContactTags(r *fastglue.Request) error {
 	}
 
 	// Update contact tags
+	oldTags := contact.Tags
 	if err := a.DB.Model(contact).Update("tags", tagsArray).Error; err != nil {
 		a.Log.Error("Failed to update contact tags", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact tags", nil, "")
 	}
+	a.triggerTagSequences(orgID, contact.ID, oldTags, tagsArray)
 
 	// Reload contact to get updated tags
 	if err := a.DB.First(contact, contactID).Error; err != nil {
@@ -1421,13 +1477,20 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 				updates["tags"] = tagsArray
 			}
 			if req.Metadata != nil {
-				updates["metadata"] = models.JSONB(req.Metadata)
+				metadata, err := a.normalizeContactMetadata(r, orgID, existingContact.ID, models.JSONB(req.Metadata),
+					contactutil.MetadataOptions{EnforceRequired: true})
+				if err != nil {
+					return nil
+				}
+				updates["metadata"] = metadata
 			}
+			oldTags := existingContact.Tags
 			if len(updates) > 0 {
 				a.DB.Model(&existingContact).Updates(updates)
 			}
 			// Reload contact
 			a.DB.First(&existingContact, existingContact.ID)
+			a.triggerTagSequences(orgID, existingContact.ID, oldTags, existingContact.Tags)
 			return r.SendEnvelope(a.buildContactResponse(&existingContact, orgID))
 		}
 		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact with this phone number already exists", nil, "")
@@ -1450,8 +1513,13 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 		contact.Tags = tagsArray
 	}
 
-	if req.Metadata != nil {
-		contact.Metadata = models.JSONB(req.Metadata)
+	metadata, err := a.normalizeContactMetadata(r, orgID, contact.ID, models.JSONB(req.Metadata),
+		contactutil.MetadataOptions{ApplyDefaults: true, EnforceRequired: true})
+	if err != nil {
+		return nil
+	}
+	if req.Metadata != nil || len(metadata) > 0 {
+		contact.Metadata = metadata
 	}
 
 	if err := a.DB.Create(&contact).Error; err != nil {
@@ -1462,6 +1530,9 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 	a.logAudit(orgID, userID,
 		"contact", contact.ID, models.AuditActionCreated, nil, &contact)
 
+	a.triggerSequences(orgID, contact.ID, models.SequenceTriggerContactCreated, "")
+	a.triggerTagSequences(orgID, contact.ID, nil, contact.Tags)
+
 	return r.SendEnvelope(a.buildContactResponse(&contact, orgID))
 }
 
@@ -1475,6 +1546,7 @@ type UpdateContactRequest struct {
 	Metadata           *map[string]any `json:"metadata"`
 	AssignedUserID     *uuid.UUID      `json:"assigned_user_id"`
 	ClearAssignedAgent *bool           `json:"clear_assigned_agent"`
+	Language           *string         `json:"language"` // empty string clears it
 }
 
 // UpdateContact updates an existing contact
@@ -1523,7 +1595,25 @@ func (a *App) UpdateContact(r *fastglue.Request) error {
 		updates["tags"] = tagsArray
 	}
 	if req.Metadata != nil {
-		updates["metadata"] = models.JSONB(*req.Metadata)
+		metadata, err := a.normalizeContactMetadata(r, orgID, contact.ID, models.JSONB(*req.Metadata),
+			contactutil.MetadataOptions{EnforceRequired: true})
+		if err != nil {
+			return nil
+		}
+		updates["metadata"] = metadata
+	}
+	if req.Language != nil {
+		if *req.Language == "" {
+			updates["language"] = ""
+			updates["language_source"] = ""
+		} else {
+			lang := langutil.Normalize(*req.Language)
+			if lang == "" {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid language code", nil, "")
+			}
+			updates["language"] = lang
+			updates["language_source"] = models.LanguageSourceManual
+		}
 	}
 	if req.ClearAssignedAgent != nil && *req.ClearAssignedAgent {
 		updates["assigned_user_id"] = nil
@@ -1550,6 +1640,10 @@ func (a *App) UpdateContact(r *fastglue.Request) error {
 	a.logAudit(orgID, userID,
 		"contact", contact.ID, models.AuditActionUpdated, &oldContact, contact)
 
+	if req.Tags != nil {
+		a.triggerTagSequences(orgID, contact.ID, oldContact.Tags, contact.Tags)
+	}
+
 	return r.SendEnvelope(a.buildContactResponse(contact, orgID))
 }
 
@@ -1634,6 +1728,8 @@ func (a *App) buildContactResponse(contact *models.Contact, orgID uuid.UUID) Con
 		LastInboundAt:      contact.LastInboundAt,
 		ServiceWindowOpen:  serviceWindowOpen,
 		MarketingOptOut:    contact.MarketingOptOut,
+		Language:           contact.Language,
+		LanguageSource:     string(contact.LanguageSource),
 		CreatedAt:          contact.CreatedAt,
 		UpdatedAt:          contact.UpdatedAt,
 	}
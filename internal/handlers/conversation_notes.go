@@ -17,13 +17,16 @@ type ConversationNoteRequest struct {
 
 // ConversationNoteResponse represents the API response for a conversation note.
 type ConversationNoteResponse struct {
-	ID            uuid.UUID `json:"id"`
-	ContactID     uuid.UUID `json:"contact_id"`
-	CreatedByID   uuid.UUID `json:"created_by_id"`
-	CreatedByName string    `json:"created_by_name"`
-	Content       string    `json:"content"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID         `json:"id"`
+	ContactID     uuid.UUID         `json:"contact_id"`
+	CreatedByID   uuid.UUID         `json:"created_by_id"` // uuid.Nil for system notes
+	CreatedByName string            `json:"created_by_name"`
+	Content       string            `json:"content"`
+	Source        models.NoteSource `json:"source"`
+	IsPinned      bool              `json:"is_pinned"`
+	TransferID    *uuid.UUID        `json:"transfer_id,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
 }
 
 // ListConversationNotes returns paginated notes for a contact (latest at bottom).
@@ -111,8 +114,9 @@ func (a *App) CreateConversationNote(r *fastglue.Request) error {
 	note := models.ConversationNote{
 		OrganizationID: orgID,
 		ContactID:      contactID,
-		CreatedByID:    userID,
+		CreatedByID:    &userID,
 		Content:        req.Content,
+		Source:         models.NoteSourceAgent,
 	}
 
 	if err := a.DB.Create(&note).Error; err != nil {
@@ -162,7 +166,7 @@ func (a *App) UpdateConversationNote(r *fastglue.Request) error {
 	}
 
 	// Only the creator can update their own notes
-	if note.CreatedByID != userID {
+	if note.CreatedByID == nil || *note.CreatedByID != userID {
 		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You can only edit your own notes", nil, "")
 	}
 
@@ -184,7 +188,7 @@ func (a *App) UpdateConversationNote(r *fastglue.Request) error {
 
 	// Load the creator relation for the response
 	var user models.User
-	a.DB.First(&user, "id = ?", note.CreatedByID)
+	a.DB.First(&user, "id = ?", *note.CreatedByID)
 	note.CreatedBy = &user
 
 	resp := noteToResponse(*note)
@@ -222,8 +226,9 @@ func (a *App) DeleteConversationNote(r *fastglue.Request) error {
 		return nil
 	}
 
-	// Only the creator can delete their own notes
-	if note.CreatedByID != userID {
+	// Only the creator can delete their own notes; system notes (AI
+	// summaries) can be removed by anyone with chat write access.
+	if note.CreatedByID != nil && *note.CreatedByID != userID {
 		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You can only delete your own notes", nil, "")
 	}
 
@@ -254,12 +259,23 @@ func noteToResponse(n models.ConversationNote) ConversationNoteResponse {
 	if n.CreatedBy != nil {
 		createdByName = n.CreatedBy.FullName
 	}
+	var createdByID uuid.UUID
+	if n.CreatedByID != nil {
+		createdByID = *n.CreatedByID
+	}
+	source := n.Source
+	if source == "" {
+		source = models.NoteSourceAgent
+	}
 	return ConversationNoteResponse{
 		ID:            n.ID,
 		ContactID:     n.ContactID,
-		CreatedByID:   n.CreatedByID,
+		CreatedByID:   createdByID,
 		CreatedByName: createdByName,
 		Content:       n.Content,
+		Source:        source,
+		IsPinned:      n.IsPinned,
+		TransferID:    n.TransferID,
 		CreatedAt:     n.CreatedAt,
 		UpdatedAt:     n.UpdatedAt,
 	}
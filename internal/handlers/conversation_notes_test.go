@@ -33,7 +33,7 @@ func TestApp_ListConversationNotes_Success(t *testing.T) {
 			BaseModel:      models.BaseModel{ID: uuid.New()},
 			OrganizationID: org.ID,
 			ContactID:      contact.ID,
-			CreatedByID:    user.ID,
+			CreatedByID:    &user.ID,
 			Content:        "note content " + string(rune('a'+i)),
 		}).Error)
 	}
@@ -71,7 +71,7 @@ func TestApp_ListConversationNotes_CrossOrgIsolation(t *testing.T) {
 		BaseModel:      models.BaseModel{ID: uuid.New()},
 		OrganizationID: orgA.ID,
 		ContactID:      contactA.ID,
-		CreatedByID:    userA.ID,
+		CreatedByID:    &userA.ID,
 		Content:        "secret",
 	}).Error)
 
@@ -181,7 +181,7 @@ func TestApp_UpdateConversationNote_OnlyCreatorCanEdit(t *testing.T) {
 		BaseModel:      models.BaseModel{ID: uuid.New()},
 		OrganizationID: org.ID,
 		ContactID:      contact.ID,
-		CreatedByID:    creator.ID,
+		CreatedByID:    &creator.ID,
 		Content:        "original",
 	}
 	require.NoError(t, app.DB.Create(note).Error)
@@ -239,7 +239,7 @@ func TestApp_UpdateConversationNote_EmptyContentRejected(t *testing.T) {
 		BaseModel:      models.BaseModel{ID: uuid.New()},
 		OrganizationID: org.ID,
 		ContactID:      contact.ID,
-		CreatedByID:    user.ID,
+		CreatedByID:    &user.ID,
 		Content:        "before",
 	}
 	require.NoError(t, app.DB.Create(note).Error)
@@ -266,7 +266,7 @@ func TestApp_DeleteConversationNote_OnlyCreatorCanDelete(t *testing.T) {
 		BaseModel:      models.BaseModel{ID: uuid.New()},
 		OrganizationID: org.ID,
 		ContactID:      contact.ID,
-		CreatedByID:    creator.ID,
+		CreatedByID:    &creator.ID,
 		Content:        "x",
 	}
 	require.NoError(t, app.DB.Create(note).Error)
@@ -309,7 +309,7 @@ func TestApp_DeleteConversationNote_CrossOrgIsolation(t *testing.T) {
 		BaseModel:      models.BaseModel{ID: uuid.New()},
 		OrganizationID: orgA.ID,
 		ContactID:      contactA.ID,
-		CreatedByID:    userA.ID,
+		CreatedByID:    &userA.ID,
 		Content:        "x",
 	}
 	require.NoError(t, app.DB.Create(note).Error)
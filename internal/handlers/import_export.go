@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/utils"
 	"github.com/valyala/fasthttp"
@@ -36,6 +38,7 @@ type ImportConfig struct {
 	ColumnTransform map[string]func(string) (any, error)
 	UniqueColumn    string // Column to check for duplicates (e.g., "phone_number")
 	BeforeCreate    func(db *gorm.DB, orgID uuid.UUID, record map[string]any) error
+	ContactFields   bool // Map extra columns to the org's custom contact fields (stored in metadata)
 }
 
 // Supported export/import configurations
@@ -127,6 +130,7 @@ var importConfigs = map[string]ImportConfig{
 		RequiredColumns: []string{"phone_number"},
 		OptionalColumns: []string{"profile_name", "whats_app_account", "tags", "assigned_user_id"},
 		UniqueColumn:    "phone_number",
+		ContactFields:   true,
 		ColumnTransform: map[string]func(string) (any, error){
 			"phone_number": func(s string) (any, error) {
 				// Normalize phone number - remove + prefix
@@ -469,6 +473,26 @@ func (a *App) ImportData(r *fastglue.Request) error {
 		}
 	}
 
+	// Map remaining columns to custom contact fields by key or label
+	var contactFields []models.ContactField
+	fieldIndex := make(map[string]int)
+	if config.ContactFields {
+		contactFields, err = contactutil.LoadFields(a.DB, orgID)
+		if err != nil {
+			a.Log.Error("Failed to load contact fields", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load contact fields", nil, "")
+		}
+		for col, idx := range colIndex {
+			col = strings.TrimPrefix(col, "field.")
+			for _, f := range contactFields {
+				if strings.EqualFold(col, f.Key) || strings.EqualFold(col, f.Label) {
+					fieldIndex[f.Key] = idx
+					break
+				}
+			}
+		}
+	}
+
 	// Process rows (limit to 10,000)
 	const maxImportRows = 10000
 	var created, updated, skipped, errors int
@@ -557,6 +581,18 @@ func (a *App) ImportData(r *fastglue.Request) error {
 			if err == nil {
 				// Record exists
 				if updateOnDup {
+					// Merge custom field values into the contact's metadata
+					if contact, ok := existing.(*models.Contact); ok && len(fieldIndex) > 0 {
+						metadata, err := importContactFields(a.DB, orgID, contact.ID, contact.Metadata, contactFields, fieldIndex, record,
+							contactutil.MetadataOptions{})
+						if err != nil {
+							errors++
+							errorMessages = append(errorMessages, fmt.Sprintf("Row %d: %s", rowNum, err.Error()))
+							continue
+						}
+						recordMap["metadata"] = metadata
+					}
+
 					// Update existing record
 					delete(recordMap, "organization_id")
 					delete(recordMap, config.UniqueColumn)
@@ -577,6 +613,20 @@ func (a *App) ImportData(r *fastglue.Request) error {
 			}
 		}
 
+		// Validate custom fields, applying defaults and required checks
+		if config.ContactFields && len(contactFields) > 0 {
+			metadata, err := importContactFields(a.DB, orgID, uuid.Nil, nil, contactFields, fieldIndex, record,
+				contactutil.MetadataOptions{ApplyDefaults: true, EnforceRequired: true})
+			if err != nil {
+				errors++
+				errorMessages = append(errorMessages, fmt.Sprintf("Row %d: %s", rowNum, err.Error()))
+				continue
+			}
+			if len(metadata) > 0 {
+				recordMap["metadata"] = metadata
+			}
+		}
+
 		// Run BeforeCreate hook if defined
 		if config.BeforeCreate != nil {
 			if err := config.BeforeCreate(a.DB, orgID, recordMap); err != nil {
@@ -725,6 +775,23 @@ func (a *App) GetImportConfig(r *fastglue.Request) error {
 		}
 	}
 
+	// Custom contact fields can be imported as extra columns
+	if config.ContactFields {
+		fields, err := contactutil.LoadFields(a.DB, orgID)
+		if err != nil {
+			a.Log.Error("Failed to load contact fields", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load contact fields", nil, "")
+		}
+		for _, f := range fields {
+			col := map[string]string{"key": f.Key, "label": f.Label}
+			if f.IsRequired && f.DefaultValue == "" {
+				requiredCols = append(requiredCols, col)
+			} else {
+				optionalCols = append(optionalCols, col)
+			}
+		}
+	}
+
 	return r.SendEnvelope(map[string]any{
 		"table":            tableName,
 		"required_columns": requiredCols,
@@ -803,6 +870,30 @@ func formatExportValue(v any) string {
 	}
 }
 
+// importContactFields merges a CSV row's custom field columns into
+// metadata and validates the result against the field definitions.
+// Empty cells leave the existing value untouched.
+func importContactFields(db *gorm.DB, orgID, contactID uuid.UUID, metadata models.JSONB, fields []models.ContactField,
+	fieldIndex map[string]int, record []string, opts contactutil.MetadataOptions) (models.JSONB, error) {
+	merged := models.JSONB{}
+	maps.Copy(merged, metadata)
+	for key, idx := range fieldIndex {
+		if idx < len(record) {
+			if val := strings.TrimSpace(record[idx]); val != "" {
+				merged[key] = val
+			}
+		}
+	}
+	merged, err := contactutil.ApplyFields(fields, merged, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contactutil.CheckUniqueFields(db, orgID, contactID, fields, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
 // Helper method to get column label
 func (c ImportConfig) getColumnLabel(col string) string {
 	if expConfig, ok := exportConfigs[c.Resource]; ok {
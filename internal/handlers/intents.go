@@ -0,0 +1,801 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/knowledge"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+const (
+	// maxIntentExamplesInPrompt limits how many examples per intent are
+	// shown to the model; a handful is enough to convey the meaning.
+	maxIntentExamplesInPrompt = 5
+	// intentClassifyTimeout bounds classification so a slow provider
+	// doesn't hold up keyword routing.
+	intentClassifyTimeout = 15 * time.Second
+)
+
+// ChatbotIntentRequest is the request body for creating/updating an intent
+type ChatbotIntentRequest struct {
+	Name            string                  `json:"name"`
+	Description     string                  `json:"description"`
+	WhatsAppAccount string                  `json:"whatsapp_account"`
+	Examples        []string                `json:"examples"`
+	TargetType      models.IntentTargetType `json:"target_type"`
+	TargetID        *uuid.UUID              `json:"target_id"`
+	Message         string                  `json:"message"`
+	IsEnabled       *bool                   `json:"is_enabled"`
+}
+
+// ChatbotIntentResponse represents an intent for API responses
+type ChatbotIntentResponse struct {
+	ID              uuid.UUID               `json:"id"`
+	Name            string                  `json:"name"`
+	Description     string                  `json:"description"`
+	WhatsAppAccount string                  `json:"whatsapp_account"`
+	Examples        []string                `json:"examples"`
+	TargetType      models.IntentTargetType `json:"target_type"`
+	TargetID        *uuid.UUID              `json:"target_id,omitempty"`
+	Message         string                  `json:"message"`
+	IsEnabled       bool                    `json:"is_enabled"`
+	CreatedAt       string                  `json:"created_at"`
+	UpdatedAt       string                  `json:"updated_at"`
+}
+
+// intentMatch is the best-scoring intent for a message.
+type intentMatch struct {
+	Intent     *models.ChatbotIntent
+	Confidence float64
+	Method     models.IntentMethod
+}
+
+func chatbotIntentToResponse(intent models.ChatbotIntent) ChatbotIntentResponse {
+	examples := []string(intent.Examples)
+	if examples == nil {
+		examples = []string{}
+	}
+	return ChatbotIntentResponse{
+		ID:              intent.ID,
+		Name:            intent.Name,
+		Description:     intent.Description,
+		WhatsAppAccount: intent.WhatsAppAccount,
+		Examples:        examples,
+		TargetType:      intent.TargetType,
+		TargetID:        intent.TargetID,
+		Message:         intent.Message,
+		IsEnabled:       intent.IsEnabled,
+		CreatedAt:       intent.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       intent.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ListChatbotIntents returns the organization's intents
+func (a *App) ListChatbotIntents(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.ChatbotIntent{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		searchPattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var intents []models.ChatbotIntent
+	if err := pg.Apply(query.Order("name ASC")).Find(&intents).Error; err != nil {
+		a.Log.Error("Failed to list intents", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list intents", nil, "")
+	}
+
+	result := make([]ChatbotIntentResponse, len(intents))
+	for i, intent := range intents {
+		result[i] = chatbotIntentToResponse(intent)
+	}
+
+	return r.SendEnvelope(listEnvelope("intents", result, total, pg))
+}
+
+// GetChatbotIntent returns a single intent
+func (a *App) GetChatbotIntent(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "intent")
+	if err != nil {
+		return nil
+	}
+
+	intent, err := findByIDAndOrg[models.ChatbotIntent](a.DB, r, id, orgID, "Intent")
+	if err != nil {
+		return nil
+	}
+	return r.SendEnvelope(chatbotIntentToResponse(*intent))
+}
+
+// CreateChatbotIntent creates a new intent
+func (a *App) CreateChatbotIntent(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	var req ChatbotIntentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if err := a.validateIntentRequest(orgID, uuid.Nil, &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	intent := models.ChatbotIntent{
+		OrganizationID:  orgID,
+		WhatsAppAccount: req.WhatsAppAccount,
+		Name:            req.Name,
+		Description:     req.Description,
+		Examples:        models.StringArray(req.Examples),
+		TargetType:      req.TargetType,
+		TargetID:        req.TargetID,
+		Message:         req.Message,
+		IsEnabled:       req.IsEnabled == nil || *req.IsEnabled,
+		CreatedByID:     &userID,
+		UpdatedByID:     &userID,
+	}
+	if err := a.DB.Create(&intent).Error; err != nil {
+		a.Log.Error("Failed to create intent", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create intent", nil, "")
+	}
+
+	a.logAudit(orgID, userID, "chatbot_intent", intent.ID, models.AuditActionCreated, nil, chatbotIntentToResponse(intent))
+
+	return r.SendEnvelope(chatbotIntentToResponse(intent))
+}
+
+// UpdateChatbotIntent replaces an intent's definition
+func (a *App) UpdateChatbotIntent(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "intent")
+	if err != nil {
+		return nil
+	}
+
+	intent, err := findByIDAndOrg[models.ChatbotIntent](a.DB, r, id, orgID, "Intent")
+	if err != nil {
+		return nil
+	}
+	oldIntent := chatbotIntentToResponse(*intent)
+
+	var req ChatbotIntentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if err := a.validateIntentRequest(orgID, intent.ID, &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	examplesChanged := strings.Join(req.Examples, "\n") != strings.Join(intent.Examples, "\n") ||
+		req.Name != intent.Name || req.Description != intent.Description
+	intent.Name = req.Name
+	intent.Description = req.Description
+	intent.WhatsAppAccount = req.WhatsAppAccount
+	intent.Examples = models.StringArray(req.Examples)
+	intent.TargetType = req.TargetType
+	intent.TargetID = req.TargetID
+	intent.Message = req.Message
+	if req.IsEnabled != nil {
+		intent.IsEnabled = *req.IsEnabled
+	}
+	if examplesChanged {
+		// Recomputed lazily by the embedding classifier.
+		intent.Embedding = nil
+		intent.EmbeddingModel = ""
+	}
+	intent.UpdatedByID = &userID
+
+	if err := a.DB.Save(intent).Error; err != nil {
+		a.Log.Error("Failed to update intent", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update intent", nil, "")
+	}
+
+	a.logAudit(orgID, userID, "chatbot_intent", intent.ID, models.AuditActionUpdated, oldIntent, chatbotIntentToResponse(*intent))
+
+	return r.SendEnvelope(chatbotIntentToResponse(*intent))
+}
+
+// DeleteChatbotIntent deletes an intent
+func (a *App) DeleteChatbotIntent(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionDelete)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "intent")
+	if err != nil {
+		return nil
+	}
+
+	intent, err := findByIDAndOrg[models.ChatbotIntent](a.DB, r, id, orgID, "Intent")
+	if err != nil {
+		return nil
+	}
+	if err := a.DB.Delete(intent).Error; err != nil {
+		a.Log.Error("Failed to delete intent", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete intent", nil, "")
+	}
+
+	a.logAudit(orgID, userID, "chatbot_intent", intent.ID, models.AuditActionDeleted, chatbotIntentToResponse(*intent), nil)
+
+	return r.SendEnvelope(map[string]string{"message": "Intent deleted"})
+}
+
+// ClassifyChatbotIntent classifies a test message without routing or
+// logging it, so admins can check their examples.
+func (a *App) ClassifyChatbotIntent(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Text            string `json:"text"`
+		WhatsAppAccount string `json:"whatsapp_account"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "text is required", nil, "")
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, req.WhatsAppAccount)
+	if err != nil || !aiConfigured(settings.AI) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "AI is not configured", nil, "")
+	}
+
+	scope := aiUsageScope{OrgID: orgID, Account: req.WhatsAppAccount, Feature: models.AIFeatureIntent, UserID: &userID}
+	match, err := a.classifyIntent(settings, scope, a.loadChatbotIntents(orgID, req.WhatsAppAccount), req.Text)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Classification failed: "+err.Error(), nil, "")
+	}
+
+	result := map[string]any{
+		"method":    intentMethodOrDefault(settings.AI.IntentMethod),
+		"threshold": settings.AI.IntentThreshold,
+		"intent":    nil,
+	}
+	if match != nil && match.Intent != nil {
+		result["intent"] = chatbotIntentToResponse(*match.Intent)
+		result["confidence"] = match.Confidence
+		result["would_route"] = match.Confidence >= settings.AI.IntentThreshold
+	}
+	return r.SendEnvelope(result)
+}
+
+// ListIntentClassifications returns logged classifications for review.
+// Filters: intent_id, routed (true/false), reviewed (true/false).
+func (a *App) ListIntentClassifications(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	args := r.RequestCtx.QueryArgs()
+
+	query := a.DB.Model(&models.IntentClassification{}).Where("organization_id = ?", orgID)
+	if intentID := string(args.Peek("intent_id")); intentID != "" {
+		id, err := uuid.Parse(intentID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid intent_id", nil, "")
+		}
+		query = query.Where("intent_id = ?", id)
+	}
+	switch string(args.Peek("routed")) {
+	case "true":
+		query = query.Where("routed = ?", true)
+	case "false":
+		query = query.Where("routed = ?", false)
+	}
+	switch string(args.Peek("reviewed")) {
+	case "true":
+		query = query.Where("reviewed_at IS NOT NULL")
+	case "false":
+		query = query.Where("reviewed_at IS NULL")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var items []models.IntentClassification
+	if err := pg.Apply(query.Order("created_at DESC")).Find(&items).Error; err != nil {
+		a.Log.Error("Failed to list intent classifications", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list classifications", nil, "")
+	}
+
+	return r.SendEnvelope(listEnvelope("classifications", items, total, pg))
+}
+
+// ReviewIntentClassification records the correct intent for a logged
+// message. With add_as_example the message is appended to that intent's
+// examples, so corrections improve future classification.
+func (a *App) ReviewIntentClassification(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "classification")
+	if err != nil {
+		return nil
+	}
+
+	var item models.IntentClassification
+	if err := a.DB.Where("id = ? AND organization_id = ?", id, orgID).First(&item).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Classification not found", nil, "")
+	}
+
+	var req struct {
+		CorrectIntentID *uuid.UUID `json:"correct_intent_id"` // nil = no intent applies
+		AddAsExample    bool       `json:"add_as_example"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	var correct *models.ChatbotIntent
+	if req.CorrectIntentID != nil {
+		var intent models.ChatbotIntent
+		if err := a.DB.Where("id = ? AND organization_id = ?", *req.CorrectIntentID, orgID).First(&intent).Error; err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Intent not found", nil, "")
+		}
+		correct = &intent
+	} else if req.AddAsExample {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "correct_intent_id is required to add an example", nil, "")
+	}
+
+	now := time.Now()
+	item.CorrectIntentID = req.CorrectIntentID
+	item.ReviewedByID = &userID
+	item.ReviewedAt = &now
+	if err := a.DB.Save(&item).Error; err != nil {
+		a.Log.Error("Failed to review intent classification", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save review", nil, "")
+	}
+
+	if req.AddAsExample && correct != nil && !containsFold(correct.Examples, item.Message) {
+		correct.Examples = append(correct.Examples, item.Message)
+		if err := a.DB.Model(correct).Updates(map[string]any{
+			"examples":        correct.Examples,
+			"embedding":       nil,
+			"embedding_model": "",
+			"updated_by_id":   userID,
+		}).Error; err != nil {
+			a.Log.Error("Failed to add intent example", "error", err, "intent_id", correct.ID)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to add example", nil, "")
+		}
+	}
+
+	return r.SendEnvelope(item)
+}
+
+// validateIntentRequest normalizes req and checks names and targets.
+func (a *App) validateIntentRequest(orgID, intentID uuid.UUID, req *ChatbotIntentRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.WhatsAppAccount = strings.TrimSpace(req.WhatsAppAccount)
+	if req.Name == "" || len(req.Name) > 100 {
+		return fmt.Errorf("name is required (max 100 characters)")
+	}
+	if strings.EqualFold(req.Name, "none") {
+		return fmt.Errorf(`"none" is reserved`)
+	}
+
+	examples := make([]string, 0, len(req.Examples))
+	for _, ex := range req.Examples {
+		if ex = strings.TrimSpace(ex); ex != "" && !containsFold(examples, ex) {
+			examples = append(examples, ex)
+		}
+	}
+	req.Examples = examples
+	if len(req.Examples) == 0 && strings.TrimSpace(req.Description) == "" {
+		return fmt.Errorf("add a description or at least one example")
+	}
+
+	switch req.TargetType {
+	case models.IntentTargetFlow:
+		if req.TargetID == nil {
+			return fmt.Errorf("target_id is required for flow intents")
+		}
+		var count int64
+		a.DB.Model(&models.ChatbotFlow{}).Where("id = ? AND organization_id = ?", *req.TargetID, orgID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("flow not found")
+		}
+	case models.IntentTargetKeywordRule:
+		if req.TargetID == nil {
+			return fmt.Errorf("target_id is required for keyword_rule intents")
+		}
+		var count int64
+		a.DB.Model(&models.KeywordRule{}).Where("id = ? AND organization_id = ?", *req.TargetID, orgID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("keyword rule not found")
+		}
+	case models.IntentTargetTransfer:
+		if req.TargetID != nil {
+			var count int64
+			a.DB.Model(&models.Team{}).Where("id = ? AND organization_id = ?", *req.TargetID, orgID).Count(&count)
+			if count == 0 {
+				return fmt.Errorf("team not found")
+			}
+		}
+	default:
+		return fmt.Errorf("target_type must be flow, keyword_rule or transfer")
+	}
+
+	if req.WhatsAppAccount != "" {
+		if _, err := a.resolveWhatsAppAccount(orgID, req.WhatsAppAccount); err != nil {
+			return fmt.Errorf("WhatsApp account not found")
+		}
+	}
+
+	var count int64
+	a.DB.Model(&models.ChatbotIntent{}).
+		Where("organization_id = ? AND whats_app_account = ? AND LOWER(name) = LOWER(?) AND id <> ?",
+			orgID, req.WhatsAppAccount, req.Name, intentID).
+		Count(&count)
+	if count > 0 {
+		return fmt.Errorf("an intent named %q already exists", req.Name)
+	}
+	return nil
+}
+
+// loadChatbotIntents returns the enabled intents for an account plus the
+// org-level ones. Not cached: it only runs when intent routing is enabled
+// and the embedding column doesn't survive the JSON cache.
+func (a *App) loadChatbotIntents(orgID uuid.UUID, accountName string) []models.ChatbotIntent {
+	var intents []models.ChatbotIntent
+	if err := a.DB.Where("organization_id = ? AND (whats_app_account = ? OR whats_app_account = '') AND is_enabled = true",
+		orgID, accountName).
+		Order("whats_app_account DESC, name ASC").
+		Find(&intents).Error; err != nil {
+		a.Log.Error("Failed to load intents", "error", err, "org_id", orgID)
+		return nil
+	}
+	return intents
+}
+
+// routeByIntent classifies chatbotInput and, when the best intent clears
+// the confidence threshold, runs its target. Every classification is
+// logged. Returns true when the message was handled.
+func (a *App) routeByIntent(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, settings *models.ChatbotSettings, chatbotInput string) bool {
+	if !settings.AI.IntentRoutingEnabled || !aiConfigured(settings.AI) || strings.TrimSpace(chatbotInput) == "" {
+		return false
+	}
+	intents := a.loadChatbotIntents(account.OrganizationID, account.Name)
+	if len(intents) == 0 {
+		return false
+	}
+
+	match, err := a.classifyIntent(settings, sessionUsageScope(account.OrganizationID, session, models.AIFeatureIntent), intents, chatbotInput)
+	if err != nil {
+		a.Log.Error("Intent classification failed", "error", err, "org_id", account.OrganizationID)
+		return false
+	}
+
+	entry := models.IntentClassification{
+		OrganizationID:  account.OrganizationID,
+		WhatsAppAccount: account.Name,
+		SessionID:       &session.ID,
+		ContactID:       &contact.ID,
+		Message:         chatbotInput,
+		Method:          intentMethodOrDefault(settings.AI.IntentMethod),
+	}
+	if match != nil && match.Intent != nil {
+		entry.IntentID = &match.Intent.ID
+		entry.IntentName = match.Intent.Name
+		entry.Confidence = match.Confidence
+		if match.Confidence >= settings.AI.IntentThreshold {
+			a.Log.Info("Intent matched", "intent", match.Intent.Name, "confidence", match.Confidence, "contact", contact.PhoneNumber)
+			entry.Routed = a.runIntentTarget(account, contact, session, settings, match.Intent, chatbotInput)
+		}
+	}
+	if err := a.DB.Create(&entry).Error; err != nil {
+		a.Log.Error("Failed to log intent classification", "error", err)
+	}
+	return entry.Routed
+}
+
+// runIntentTarget routes the conversation to the intent's target. Returns
+// false when the target no longer exists, so keyword routing can run.
+func (a *App) runIntentTarget(account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, settings *models.ChatbotSettings, intent *models.ChatbotIntent, chatbotInput string) bool {
+	stepName := "intent:" + intent.Name
+
+	switch intent.TargetType {
+	case models.IntentTargetFlow:
+		if intent.TargetID == nil {
+			return false
+		}
+		flow, err := a.getChatbotFlowByIDCached(account.OrganizationID, *intent.TargetID)
+		if err != nil || flow == nil || flow.Graph == nil {
+			a.Log.Warn("Intent target flow not loadable", "intent", intent.Name, "flow", intent.TargetID, "error", err)
+			return false
+		}
+		a.startChatFlow(account, contact, session, flow, chatbotInput, "", nil)
+		return true
+
+	case models.IntentTargetKeywordRule:
+		if intent.TargetID == nil {
+			return false
+		}
+		var rule models.KeywordRule
+		if err := a.DB.Where("id = ? AND organization_id = ?", *intent.TargetID, account.OrganizationID).First(&rule).Error; err != nil {
+			a.Log.Warn("Intent target keyword rule not found", "intent", intent.Name, "rule", intent.TargetID)
+			return false
+		}
+		if !keywordRuleActive(&rule, time.Now()) || !a.keywordRuleConditionsMatch(&rule, contact, chatbotInput) {
+			return false
+		}
+		response := keywordRuleResponse(rule)
+		if response == nil {
+			return false
+		}
+		if response.ResponseType == models.ResponseTypeTransfer {
+			a.sendTransferMessage(account, contact, settings, response.Body)
+			a.createTransferFromKeyword(account, contact)
+			return true
+		}
+		a.sendKeywordResponse(account, contact, session, response, chatbotInput, stepName)
+		return true
+
+	case models.IntentTargetTransfer:
+		a.sendTransferMessage(account, contact, settings, intent.Message)
+		if intent.TargetID != nil {
+			a.createTransferToTeam(account, contact, *intent.TargetID, "Intent: "+intent.Name, models.TransferSourceIntent)
+		} else {
+			a.createTransferToQueue(account, contact, models.TransferSourceIntent)
+		}
+		return true
+	}
+	return false
+}
+
+// sendTransferMessage sends the hand-off text ahead of a transfer. It is
+// skipped outside business hours, where the transfer helpers send the
+// out-of-hours message instead.
+func (a *App) sendTransferMessage(account *models.WhatsAppAccount, contact *models.Contact, settings *models.ChatbotSettings, body string) {
+	if body == "" {
+		return
+	}
+	if settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 && !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
+		return
+	}
+	if err := a.sendAndSaveTextMessage(account, contact, body); err != nil {
+		a.Log.Error("Failed to send transfer message", "error", err, "contact", contact.PhoneNumber)
+	}
+}
+
+// classifyIntent returns the best intent for text, or nil when none fits.
+// The caller applies the confidence threshold.
+func (a *App) classifyIntent(settings *models.ChatbotSettings, scope aiUsageScope, intents []models.ChatbotIntent, text string) (*intentMatch, error) {
+	if len(intents) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), intentClassifyTimeout)
+	defer cancel()
+
+	if intentMethodOrDefault(settings.AI.IntentMethod) == models.IntentMethodEmbedding {
+		return a.classifyIntentByEmbedding(ctx, settings, scope, intents, text)
+	}
+	return a.classifyIntentByLLM(ctx, settings, scope, intents, text)
+}
+
+// classifyIntentByLLM asks the chat model to pick one intent and rate its
+// confidence.
+func (a *App) classifyIntentByLLM(ctx context.Context, settings *models.ChatbotSettings, scope aiUsageScope, intents []models.ChatbotIntent, text string) (*intentMatch, error) {
+	provider, err := a.newMeteredAIProvider(settings.AI, scope)
+	if err != nil {
+		return nil, err
+	}
+	redactor := a.newAIRedactor(settings.AI)
+	defer a.logRedactions(scope, redactor)
+
+	resp, err := provider.Complete(ctx, ai.Request{
+		Model:        settings.AI.Model,
+		SystemPrompt: buildIntentPrompt(intents),
+		Messages:     []ai.Message{{Role: ai.RoleUser, Content: redactor.Redact(text)}},
+		MaxTokens:    100,
+		Temperature:  0.1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name, confidence, err := parseIntentClassification(resp.Content)
+	if err != nil {
+		return nil, err
+	}
+	for i := range intents {
+		if strings.EqualFold(intents[i].Name, name) {
+			return &intentMatch{Intent: &intents[i], Confidence: confidence, Method: models.IntentMethodLLM}, nil
+		}
+	}
+	return nil, nil
+}
+
+// classifyIntentByEmbedding scores text against each intent's example
+// centroid. Centroids missing for the current embedding model are
+// computed and stored on the way.
+func (a *App) classifyIntentByEmbedding(ctx context.Context, settings *models.ChatbotSettings, scope aiUsageScope, intents []models.ChatbotIntent, text string) (*intentMatch, error) {
+	model := settings.AI.EmbeddingModel
+	if model == "" {
+		return nil, fmt.Errorf("embedding intent classification requires an embedding model")
+	}
+	embedder, err := a.newMeteredAIEmbedder(settings.AI, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range intents {
+		intent := &intents[i]
+		if intent.EmbeddingModel == model && len(intent.Embedding) > 0 {
+			continue
+		}
+		inputs := intentEmbeddingInputs(*intent)
+		resp, err := embedder.Embed(ctx, model, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("embed intent %q: %w", intent.Name, err)
+		}
+		intent.Embedding = centroid(resp.Vectors)
+		intent.EmbeddingModel = model
+		a.DB.Model(&models.ChatbotIntent{}).Where("id = ?", intent.ID).Updates(map[string]any{
+			"embedding":       intent.Embedding,
+			"embedding_model": model,
+		})
+	}
+
+	resp, err := embedder.Embed(ctx, model, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("embed message: %w", err)
+	}
+	if len(resp.Vectors) == 0 {
+		return nil, nil
+	}
+
+	var best *intentMatch
+	for i := range intents {
+		score := knowledge.Cosine(resp.Vectors[0], intents[i].Embedding)
+		if best == nil || score > best.Confidence {
+			best = &intentMatch{Intent: &intents[i], Confidence: score, Method: models.IntentMethodEmbedding}
+		}
+	}
+	if best != nil && best.Confidence <= 0 {
+		return nil, nil
+	}
+	return best, nil
+}
+
+// intentEmbeddingInputs is the text embedded for an intent: its examples,
+// or its name and description when it has none.
+func intentEmbeddingInputs(intent models.ChatbotIntent) []string {
+	if len(intent.Examples) > 0 {
+		return intent.Examples
+	}
+	return []string{strings.TrimSpace(intent.Name + ": " + intent.Description)}
+}
+
+// centroid returns the normalized mean of vectors.
+func centroid(vectors [][]float32) models.Vector {
+	if len(vectors) == 0 {
+		return nil
+	}
+	sum := make([]float64, len(vectors[0]))
+	for _, v := range vectors {
+		if len(v) != len(sum) {
+			continue
+		}
+		var norm float64
+		for _, f := range v {
+			norm += float64(f) * float64(f)
+		}
+		if norm == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for i, f := range v {
+			sum[i] += float64(f) / norm
+		}
+	}
+	var norm float64
+	for _, f := range sum {
+		norm += f * f
+	}
+	norm = math.Sqrt(norm)
+	out := make(models.Vector, len(sum))
+	if norm == 0 {
+		return out
+	}
+	for i, f := range sum {
+		out[i] = float32(f / norm)
+	}
+	return out
+}
+
+// buildIntentPrompt renders the classification instructions and intent list.
+func buildIntentPrompt(intents []models.ChatbotIntent) string {
+	var b strings.Builder
+	b.WriteString("You classify customer messages sent to a business on WhatsApp. ")
+	b.WriteString("Pick the single intent that best matches the message, or \"none\" if no intent fits. ")
+	b.WriteString(`Reply with JSON only, e.g. {"intent": "<name or none>", "confidence": 0.0-1.0}.`)
+	b.WriteString("\n\nIntents:")
+	for _, intent := range intents {
+		fmt.Fprintf(&b, "\n- %s", intent.Name)
+		if intent.Description != "" {
+			fmt.Fprintf(&b, ": %s", intent.Description)
+		}
+		if n := min(len(intent.Examples), maxIntentExamplesInPrompt); n > 0 {
+			quoted := make([]string, n)
+			for i := range n {
+				quoted[i] = fmt.Sprintf("%q", intent.Examples[i])
+			}
+			fmt.Fprintf(&b, "\n  Examples: %s", strings.Join(quoted, "; "))
+		}
+	}
+	return b.String()
+}
+
+// parseIntentClassification extracts {"intent", "confidence"} from a model
+// reply, tolerating code fences or prose around the JSON. An intent of
+// "none" yields an empty name.
+func parseIntentClassification(content string) (string, float64, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return "", 0, fmt.Errorf("no JSON in classification reply")
+	}
+	var out struct {
+		Intent     string  `json:"intent"`
+		Confidence float64 `json:"confidence"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &out); err != nil {
+		return "", 0, fmt.Errorf("invalid classification reply: %w", err)
+	}
+	name := strings.TrimSpace(out.Intent)
+	if strings.EqualFold(name, "none") {
+		name = ""
+	}
+	return name, math.Max(0, math.Min(1, out.Confidence)), nil
+}
+
+func intentMethodOrDefault(m models.IntentMethod) models.IntentMethod {
+	if m == models.IntentMethodEmbedding {
+		return m
+	}
+	return models.IntentMethodLLM
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/knowledge"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIntentClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantName string
+		wantConf float64
+		wantErr  bool
+	}{
+		{name: "plain json", content: `{"intent": "order_status", "confidence": 0.92}`, wantName: "order_status", wantConf: 0.92},
+		{name: "fenced", content: "```json\n{\"intent\": \"refund\", \"confidence\": 0.8}\n```", wantName: "refund", wantConf: 0.8},
+		{name: "none", content: `{"intent": "None", "confidence": 0.9}`, wantName: "", wantConf: 0.9},
+		{name: "clamped", content: `{"intent": "refund", "confidence": 3}`, wantName: "refund", wantConf: 1},
+		{name: "no json", content: "refund", wantErr: true},
+		{name: "bad json", content: `{"intent": }`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, conf, err := parseIntentClassification(tt.content)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.InDelta(t, tt.wantConf, conf, 1e-9)
+		})
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	c := centroid([][]float32{{1, 0}, {0, 2}})
+	require.Len(t, c, 2)
+	assert.InDelta(t, 1.0, knowledge.Cosine(c, c), 1e-6)
+	assert.InDelta(t, c[0], c[1], 1e-6, "examples are weighted equally regardless of magnitude")
+
+	assert.Nil(t, centroid(nil))
+}
+
+func TestBuildIntentPrompt_LimitsExamples(t *testing.T) {
+	intent := models.ChatbotIntent{
+		Name:        "refund",
+		Description: "Customer wants their money back",
+		Examples:    models.StringArray{"a", "b", "c", "d", "e", "f"},
+	}
+	prompt := buildIntentPrompt([]models.ChatbotIntent{intent})
+
+	assert.Contains(t, prompt, "- refund: Customer wants their money back")
+	assert.Contains(t, prompt, `"e"`)
+	assert.NotContains(t, prompt, `"f"`)
+}
@@ -0,0 +1,684 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/knowledge"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxKnowledgeDocumentSize caps uploads; extracted text is usually far smaller.
+	maxKnowledgeDocumentSize = 20 << 20 // 20MB
+	// knowledgeEmbedBatchSize is the number of chunks sent per embeddings call.
+	knowledgeEmbedBatchSize = 32
+	// maxKnowledgeChunks caps the chunks an organization can index.
+	// Similarity is computed in Go, so this bounds the vectors scored per
+	// query; documents that would go past it are rejected.
+	maxKnowledgeChunks = 20000
+	// knowledgeCacheMaxVectors bounds the vectors kept in memory across
+	// all knowledge bases, roughly 300MB with 1536-dimension embeddings.
+	knowledgeCacheMaxVectors = 50000
+	// knowledgeMinScore drops chunks that are only weakly related to the
+	// query, so off-topic questions don't pull in random excerpts.
+	knowledgeMinScore = 0.2
+	// knowledgeIngestTimeout bounds embedding a whole document.
+	knowledgeIngestTimeout = 10 * time.Minute
+	// knowledgeIngestStaleAfter is how long a document may stay processing
+	// before its ingest is assumed lost, e.g. to a restart, and it can be
+	// reindexed again.
+	knowledgeIngestStaleAfter = knowledgeIngestTimeout + 5*time.Minute
+	// knowledgeQueryTimeout bounds embedding a customer message at reply time.
+	knowledgeQueryTimeout = 15 * time.Second
+)
+
+// KnowledgeDocumentResponse represents a knowledge-base document for API responses
+type KnowledgeDocumentResponse struct {
+	ID              uuid.UUID                      `json:"id"`
+	Name            string                         `json:"name"`
+	FileName        string                         `json:"file_name"`
+	Format          string                         `json:"format"`
+	SizeBytes       int64                          `json:"size_bytes"`
+	WhatsAppAccount string                         `json:"whatsapp_account"`
+	Status          models.KnowledgeDocumentStatus `json:"status"`
+	Error           string                         `json:"error,omitempty"`
+	ChunkCount      int                            `json:"chunk_count"`
+	EmbeddingModel  string                         `json:"embedding_model"`
+	CreatedAt       string                         `json:"created_at"`
+	UpdatedAt       string                         `json:"updated_at"`
+}
+
+// KnowledgeSearchResult is a retrieved chunk returned by the search endpoint
+type KnowledgeSearchResult struct {
+	ChunkID      uuid.UUID `json:"chunk_id"`
+	DocumentID   uuid.UUID `json:"document_id"`
+	DocumentName string    `json:"document_name"`
+	ChunkIndex   int       `json:"chunk_index"`
+	Content      string    `json:"content"`
+	Score        float64   `json:"score"`
+}
+
+// knowledgeHit is a chunk selected for an AI prompt, with its similarity.
+type knowledgeHit struct {
+	Chunk models.KnowledgeChunk
+	Score float64
+}
+
+func knowledgeDocumentToResponse(doc models.KnowledgeDocument) KnowledgeDocumentResponse {
+	return KnowledgeDocumentResponse{
+		ID:              doc.ID,
+		Name:            doc.Name,
+		FileName:        doc.FileName,
+		Format:          doc.Format,
+		SizeBytes:       doc.SizeBytes,
+		WhatsAppAccount: doc.WhatsAppAccount,
+		Status:          doc.Status,
+		Error:           doc.Error,
+		ChunkCount:      doc.ChunkCount,
+		EmbeddingModel:  doc.EmbeddingModel,
+		CreatedAt:       doc.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       doc.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ListKnowledgeDocuments returns the organization's knowledge-base documents
+func (a *App) ListKnowledgeDocuments(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+	account := string(r.RequestCtx.QueryArgs().Peek("whatsapp_account"))
+
+	query := a.DB.Model(&models.KnowledgeDocument{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		searchPattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR file_name ILIKE ?", searchPattern, searchPattern)
+	}
+	if account != "" {
+		query = query.Where("whats_app_account = ?", account)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var docs []models.KnowledgeDocument
+	if err := pg.Apply(query.Order("created_at DESC")).Find(&docs).Error; err != nil {
+		a.Log.Error("Failed to list knowledge documents", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list knowledge documents", nil, "")
+	}
+
+	result := make([]KnowledgeDocumentResponse, len(docs))
+	for i, doc := range docs {
+		result[i] = knowledgeDocumentToResponse(doc)
+	}
+
+	// Uploads are rejected past maxKnowledgeChunks, so show how close the
+	// org is to that
+	var chunkCount int64
+	a.DB.Model(&models.KnowledgeChunk{}).Where("organization_id = ?", orgID).Count(&chunkCount)
+
+	resp := listEnvelope("documents", result, total, pg)
+	resp["chunk_count"] = chunkCount
+	resp["chunk_limit"] = maxKnowledgeChunks
+	return r.SendEnvelope(resp)
+}
+
+// GetKnowledgeDocument returns a single knowledge-base document
+func (a *App) GetKnowledgeDocument(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "document")
+	if err != nil {
+		return nil
+	}
+
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Document")
+	if err != nil {
+		return nil
+	}
+	return r.SendEnvelope(knowledgeDocumentToResponse(*doc))
+}
+
+// UploadKnowledgeDocument accepts a PDF, Markdown, HTML, CSV or text file,
+// extracts its text and queues chunking and embedding in the background.
+// Form fields: file (required), name, whatsapp_account (empty = all accounts).
+func (a *App) UploadKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	form, err := r.RequestCtx.MultipartForm()
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid multipart form", nil, "")
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No file provided", nil, "")
+	}
+	fileHeader := files[0]
+
+	format := knowledge.DetectFormat(fileHeader.Filename)
+	if format == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+			"Unsupported file type. Allowed: PDF, Markdown, HTML, CSV, TXT", nil, "")
+	}
+
+	formValue := func(key string) string {
+		if v := form.Value[key]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+	accountName := formValue("whatsapp_account")
+	if accountName != "" {
+		if _, err := a.resolveWhatsAppAccount(orgID, accountName); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
+		}
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, accountName)
+	if err != nil || settings.AI.EmbeddingModel == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+			"Configure an AI provider and embedding model before uploading documents", nil, "")
+	}
+	if _, err := a.newAIEmbedder(settings.AI); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Failed to open file", nil, "")
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxKnowledgeDocumentSize+1))
+	if err != nil {
+		a.Log.Error("Failed to read knowledge document", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
+	}
+	if len(data) > maxKnowledgeDocumentSize {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "File too large. Maximum size is 20MB", nil, "")
+	}
+
+	text, err := knowledge.Extract(format, data)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Failed to extract text: "+err.Error(), nil, "")
+	}
+
+	chunkCount := len(knowledge.Chunk(text, knowledge.DefaultChunkSize, knowledge.DefaultChunkOverlap))
+	var indexed int64
+	if err := a.DB.Model(&models.KnowledgeChunk{}).Where("organization_id = ?", orgID).Count(&indexed).Error; err != nil {
+		a.Log.Error("Failed to count knowledge chunks", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save document", nil, "")
+	}
+	if int(indexed)+chunkCount > maxKnowledgeChunks {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+			fmt.Sprintf("Knowledge base is full: this document needs %d chunks and %d of %d are used. Delete documents to make room.",
+				chunkCount, indexed, maxKnowledgeChunks), nil, "")
+	}
+
+	name := formValue("name")
+	if name == "" {
+		name = fileHeader.Filename
+	}
+
+	doc := models.KnowledgeDocument{
+		OrganizationID:  orgID,
+		WhatsAppAccount: accountName,
+		Name:            name,
+		FileName:        fileHeader.Filename,
+		Format:          format,
+		SizeBytes:       int64(len(data)),
+		Content:         text,
+		Status:          models.KnowledgeDocumentStatusProcessing,
+		EmbeddingModel:  settings.AI.EmbeddingModel,
+		CreatedByID:     &userID,
+	}
+	if err := a.DB.Create(&doc).Error; err != nil {
+		a.Log.Error("Failed to create knowledge document", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save document", nil, "")
+	}
+
+	a.logAudit(orgID, userID, "knowledge_document", doc.ID, models.AuditActionCreated, nil, knowledgeDocumentToResponse(doc))
+	a.startKnowledgeIngest(doc.ID)
+
+	return r.SendEnvelope(knowledgeDocumentToResponse(doc))
+}
+
+// ReindexKnowledgeDocument re-chunks and re-embeds a document with the
+// current embedding model, e.g. after switching providers.
+func (a *App) ReindexKnowledgeDocument(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "document")
+	if err != nil {
+		return nil
+	}
+
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Document")
+	if err != nil {
+		return nil
+	}
+
+	// Claim the document, unless an ingest that hasn't gone stale holds it
+	res := a.DB.Model(doc).
+		Where("status <> ? OR updated_at < ?", models.KnowledgeDocumentStatusProcessing, time.Now().Add(-knowledgeIngestStaleAfter)).
+		Updates(map[string]any{
+			"status": models.KnowledgeDocumentStatusProcessing,
+			"error":  "",
+		})
+	if res.Error != nil {
+		a.Log.Error("Failed to update knowledge document", "error", res.Error)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to reindex document", nil, "")
+	}
+	if res.RowsAffected == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Document is already being processed", nil, "")
+	}
+	a.startKnowledgeIngest(doc.ID)
+
+	doc.Status = models.KnowledgeDocumentStatusProcessing
+	doc.Error = ""
+	return r.SendEnvelope(knowledgeDocumentToResponse(*doc))
+}
+
+// DeleteKnowledgeDocument removes a document and its chunks
+func (a *App) DeleteKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionDelete)
+	if err != nil {
+		return nil
+	}
+	id, err := parsePathUUID(r, "id", "document")
+	if err != nil {
+		return nil
+	}
+
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Document")
+	if err != nil {
+		return nil
+	}
+
+	// Lock the document so a running ingest either finishes first or sees
+	// it deleted, and can't leave chunks behind
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", doc.ID).First(&models.KnowledgeDocument{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(doc).Error
+	}); err != nil {
+		a.Log.Error("Failed to delete knowledge document", "error", err, "document_id", doc.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete document", nil, "")
+	}
+	a.invalidateKnowledgeVectors(orgID)
+
+	a.logAudit(orgID, userID, "knowledge_document", doc.ID, models.AuditActionDeleted, knowledgeDocumentToResponse(*doc), nil)
+
+	return r.SendEnvelope(map[string]string{"message": "Document deleted"})
+}
+
+// SearchKnowledgeBase runs retrieval for a test query so admins can check
+// which chunks a customer question would pull into the prompt.
+func (a *App) SearchKnowledgeBase(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceChatbotAI, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Query           string `json:"query"`
+		WhatsAppAccount string `json:"whatsapp_account"`
+		Limit           int    `json:"limit"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "query is required", nil, "")
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, req.WhatsAppAccount)
+	if err != nil || settings.AI.EmbeddingModel == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No embedding model configured", nil, "")
+	}
+	if req.Limit > 0 {
+		settings.AI.KnowledgeTopK = min(req.Limit, 20)
+	}
+
+	hits, err := a.retrieveKnowledge(settings, aiUsageScope{
+		OrgID:   orgID,
+		Account: req.WhatsAppAccount,
+		Feature: models.AIFeatureKnowledge,
+		UserID:  &userID,
+	}, req.Query)
+	if err != nil {
+		a.Log.Error("Knowledge search failed", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Knowledge search failed: "+err.Error(), nil, "")
+	}
+
+	results := make([]KnowledgeSearchResult, len(hits))
+	for i, h := range hits {
+		results[i] = KnowledgeSearchResult{
+			ChunkID:    h.Chunk.ID,
+			DocumentID: h.Chunk.DocumentID,
+			ChunkIndex: h.Chunk.ChunkIndex,
+			Content:    h.Chunk.Content,
+			Score:      h.Score,
+		}
+		if h.Chunk.Document != nil {
+			results[i].DocumentName = h.Chunk.Document.Name
+		}
+	}
+	return r.SendEnvelope(map[string]any{"results": results})
+}
+
+// newAIEmbedder builds an ai.Embedder from the org's AI settings.
+func (a *App) newAIEmbedder(cfg models.AIConfig) (ai.Embedder, error) {
+	return ai.NewEmbedder(ai.Config{
+		Provider: string(cfg.Provider),
+		APIKey:   cfg.APIKey,
+		BaseURL:  cfg.BaseURL,
+		Headers:  aiHeadersFromJSONB(cfg.Headers),
+	}, a.aiHTTPClient())
+}
+
+// FailStaleKnowledgeIngests marks documents whose ingest was lost, e.g. to
+// a restart, as failed so they can be reindexed. Ingests still running on
+// other instances are left alone until they go stale.
+func (a *App) FailStaleKnowledgeIngests() {
+	res := a.DB.Model(&models.KnowledgeDocument{}).
+		Where("status = ? AND updated_at < ?", models.KnowledgeDocumentStatusProcessing, time.Now().Add(-knowledgeIngestStaleAfter)).
+		Updates(map[string]any{
+			"status": models.KnowledgeDocumentStatusFailed,
+			"error":  "Indexing was interrupted; reindex the document to retry",
+		})
+	if res.Error != nil {
+		a.Log.Error("Failed to fail stale knowledge ingests", "error", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		a.Log.Warn("Marked interrupted knowledge documents as failed", "count", res.RowsAffected)
+	}
+}
+
+// startKnowledgeIngest chunks and embeds a document in the background.
+func (a *App) startKnowledgeIngest(docID uuid.UUID) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.ingestKnowledgeDocument(docID); err != nil {
+			a.Log.Error("Knowledge document ingestion failed", "error", err, "document_id", docID)
+			a.DB.Model(&models.KnowledgeDocument{}).Where("id = ?", docID).Updates(map[string]any{
+				"status": models.KnowledgeDocumentStatusFailed,
+				"error":  err.Error(),
+			})
+		}
+	}()
+}
+
+// ingestKnowledgeDocument replaces the document's chunks with freshly
+// embedded ones and marks it ready.
+func (a *App) ingestKnowledgeDocument(docID uuid.UUID) error {
+	var doc models.KnowledgeDocument
+	if err := a.DB.Where("id = ?", docID).First(&doc).Error; err != nil {
+		return fmt.Errorf("load document: %w", err)
+	}
+
+	settings, err := a.getChatbotSettingsCached(doc.OrganizationID, doc.WhatsAppAccount)
+	if err != nil {
+		return fmt.Errorf("load chatbot settings: %w", err)
+	}
+	if settings.AI.EmbeddingModel == "" {
+		return fmt.Errorf("no embedding model configured")
+	}
+	embedder, err := a.newMeteredAIEmbedder(settings.AI, aiUsageScope{
+		OrgID:   doc.OrganizationID,
+		Account: doc.WhatsAppAccount,
+		Feature: models.AIFeatureKnowledge,
+	})
+	if err != nil {
+		return err
+	}
+
+	texts := knowledge.Chunk(doc.Content, knowledge.DefaultChunkSize, knowledge.DefaultChunkOverlap)
+	if len(texts) == 0 {
+		return fmt.Errorf("document has no text to index")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), knowledgeIngestTimeout)
+	defer cancel()
+
+	chunks := make([]models.KnowledgeChunk, 0, len(texts))
+	for start := 0; start < len(texts); start += knowledgeEmbedBatchSize {
+		end := min(start+knowledgeEmbedBatchSize, len(texts))
+		resp, err := embedder.Embed(ctx, settings.AI.EmbeddingModel, texts[start:end])
+		if err != nil {
+			return fmt.Errorf("embed chunks: %w", err)
+		}
+		for i, vec := range resp.Vectors {
+			chunks = append(chunks, models.KnowledgeChunk{
+				ID:              uuid.New(),
+				OrganizationID:  doc.OrganizationID,
+				DocumentID:      doc.ID,
+				WhatsAppAccount: doc.WhatsAppAccount,
+				ChunkIndex:      start + i,
+				Content:         texts[start+i],
+				Embedding:       vec,
+				EmbeddingModel:  settings.AI.EmbeddingModel,
+			})
+		}
+	}
+
+	tx := a.DB.Begin()
+	// The document may have been deleted while it was being embedded; the
+	// lock keeps a concurrent delete from running until we're done
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", doc.ID).First(&models.KnowledgeDocument{}).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			a.Log.Info("Knowledge document deleted during ingestion", "document_id", doc.ID)
+			return nil
+		}
+		return fmt.Errorf("lock document: %w", err)
+	}
+	// Serialize the org's ingests so concurrent ones can't both fit under
+	// the chunk cap
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", doc.OrganizationID).First(&models.Organization{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("lock organization: %w", err)
+	}
+	var indexed int64
+	if err := tx.Model(&models.KnowledgeChunk{}).
+		Where("organization_id = ? AND document_id <> ?", doc.OrganizationID, doc.ID).
+		Count(&indexed).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("count chunks: %w", err)
+	}
+	if int(indexed)+len(chunks) > maxKnowledgeChunks {
+		tx.Rollback()
+		return fmt.Errorf("knowledge base is full: this document needs %d chunks and %d of %d are used", len(chunks), indexed, maxKnowledgeChunks)
+	}
+	if err := tx.Where("document_id = ?", doc.ID).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("clear old chunks: %w", err)
+	}
+	if err := tx.CreateInBatches(chunks, 100).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store chunks: %w", err)
+	}
+	if err := tx.Model(&doc).Updates(map[string]any{
+		"status":          models.KnowledgeDocumentStatusReady,
+		"error":           "",
+		"chunk_count":     len(chunks),
+		"embedding_model": settings.AI.EmbeddingModel,
+	}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update document: %w", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	a.invalidateKnowledgeVectors(doc.OrganizationID)
+
+	a.Log.Info("Knowledge document indexed", "document_id", doc.ID, "chunks", len(chunks))
+	return nil
+}
+
+// retrieveKnowledge embeds query and returns the most similar chunks from
+// the account's documents and the org-level ones. Only chunks embedded
+// with the current model are compared, since vectors from different
+// models live in different spaces.
+func (a *App) retrieveKnowledge(settings *models.ChatbotSettings, scope aiUsageScope, query string) ([]knowledgeHit, error) {
+	if settings.AI.EmbeddingModel == "" || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	topK := settings.AI.KnowledgeTopK
+	if topK <= 0 {
+		return nil, nil
+	}
+
+	vectors, err := a.knowledgeVectors(settings.OrganizationID, settings.AI.EmbeddingModel, scope.Account)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, nil
+	}
+
+	embedder, err := a.newMeteredAIEmbedder(settings.AI, scope)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), knowledgeQueryTimeout)
+	defer cancel()
+	resp, err := embedder.Embed(ctx, settings.AI.EmbeddingModel, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(resp.Vectors) == 0 {
+		return nil, nil
+	}
+
+	matches := knowledge.TopK(resp.Vectors[0], vectors, topK, knowledgeMinScore)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	var chunks []models.KnowledgeChunk
+	if err := a.DB.Omit("embedding").Preload("Document").Where("id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("load matched chunks: %w", err)
+	}
+	byID := make(map[uuid.UUID]models.KnowledgeChunk, len(chunks))
+	for _, c := range chunks {
+		byID[c.ID] = c
+	}
+
+	hits := make([]knowledgeHit, 0, len(matches))
+	for _, m := range matches {
+		if c, ok := byID[m.ID]; ok {
+			hits = append(hits, knowledgeHit{Chunk: c, Score: m.Score})
+		}
+	}
+	return hits, nil
+}
+
+// knowledgeVectors returns the chunk vectors retrieval scores for an
+// account: its own documents' and the org-level ones', embedded with
+// model. They are served from memory while the account's documents are
+// unchanged; the stamp also catches writes made by other instances.
+func (a *App) knowledgeVectors(orgID uuid.UUID, model, account string) ([]knowledge.Candidate, error) {
+	var state struct {
+		Count   int64
+		Changed *time.Time
+	}
+	if err := a.DB.Unscoped().Model(&models.KnowledgeDocument{}).
+		Select("COUNT(*) AS count, MAX(COALESCE(deleted_at, updated_at)) AS changed").
+		Where("organization_id = ? AND (whats_app_account = ? OR whats_app_account = '')", orgID, account).
+		Scan(&state).Error; err != nil {
+		return nil, fmt.Errorf("load knowledge state: %w", err)
+	}
+	stamp := strconv.FormatInt(state.Count, 10)
+	if state.Changed != nil {
+		stamp += ":" + strconv.FormatInt(state.Changed.UnixNano(), 10)
+	}
+
+	cache := a.knowledgeVectorCache()
+	key := knowledgeCacheKey(orgID, model, account)
+	if vectors, ok := cache.Get(key, stamp); ok {
+		return vectors, nil
+	}
+
+	var chunks []models.KnowledgeChunk
+	if err := a.DB.Select("id", "embedding").
+		Where("organization_id = ? AND embedding_model = ? AND (whats_app_account = ? OR whats_app_account = '')",
+			orgID, model, account).
+		Limit(maxKnowledgeChunks).
+		Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("load chunks: %w", err)
+	}
+	vectors := make([]knowledge.Candidate, len(chunks))
+	for i, c := range chunks {
+		vectors[i] = knowledge.Candidate{ID: c.ID, Vector: c.Embedding}
+	}
+	cache.Put(key, stamp, vectors)
+	return vectors, nil
+}
+
+// knowledgeVectorCache returns the App's in-memory chunk vector cache.
+func (a *App) knowledgeVectorCache() *knowledge.VectorCache {
+	a.knowledgeCacheOnce.Do(func() {
+		a.knowledgeCache = knowledge.NewVectorCache(knowledgeCacheMaxVectors)
+	})
+	return a.knowledgeCache
+}
+
+// invalidateKnowledgeVectors drops the org's cached vectors after its
+// documents change.
+func (a *App) invalidateKnowledgeVectors(orgID uuid.UUID) {
+	a.knowledgeVectorCache().InvalidatePrefix(orgID.String() + ":")
+}
+
+func knowledgeCacheKey(orgID uuid.UUID, model, account string) string {
+	return orgID.String() + ":" + model + ":" + account
+}
+
+// formatKnowledgeContext renders retrieved chunks as a system-prompt section.
+func formatKnowledgeContext(hits []knowledgeHit) string {
+	if len(hits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Knowledge Base\n\nUse these excerpts to answer when they are relevant.")
+	for i, h := range hits {
+		source := "document"
+		if h.Chunk.Document != nil {
+			source = h.Chunk.Document.Name
+		}
+		fmt.Fprintf(&b, "\n\n### [%d] %s\n%s", i+1, source, h.Chunk.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,259 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/knowledge"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// knowledgeTestText splits into several chunks.
+var knowledgeTestText = strings.Repeat("Orders ship within two business days of payment. ", 60)
+
+// newKnowledgeTestApp returns an App whose org has an OpenAI-compatible
+// embedding model served by a stub, and counts the embeddings calls.
+func newKnowledgeTestApp(t *testing.T) (*handlers.App, *models.Organization, *models.User, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var body struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		data := make([]map[string]any, len(body.Input))
+		for i := range body.Input {
+			data[i] = map[string]any{"index": i, "embedding": []float32{1, 0, float32(i)}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(server.Close)
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("knowledge")), testutil.WithSuperAdmin())
+	require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		IsEnabled:      true,
+		AI: models.AIConfig{
+			Enabled:        true,
+			Provider:       models.AIProviderOpenAICompatible,
+			BaseURL:        server.URL,
+			Model:          "llama3",
+			EmbeddingModel: "test-embed",
+		},
+	}).Error)
+	return app, org, user, &calls
+}
+
+// newKnowledgeUploadRequest builds a multipart upload of text as fileName.
+func newKnowledgeUploadRequest(t *testing.T, fileName, text string) *fastglue.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", fileName)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(text))
+	require.NoError(t, err)
+	require.NoError(t, w.WriteField("name", "Shipping FAQ"))
+	require.NoError(t, w.Close())
+
+	req := testutil.NewRequest(t)
+	req.RequestCtx.Request.Header.SetMethod("POST")
+	req.RequestCtx.Request.Header.SetContentType(w.FormDataContentType())
+	req.RequestCtx.Request.SetBody(body.Bytes())
+	return req
+}
+
+// uploadKnowledgeDocument uploads knowledgeTestText and waits for it to be indexed.
+func uploadKnowledgeDocument(t *testing.T, app *handlers.App, org *models.Organization, user *models.User) handlers.KnowledgeDocumentResponse {
+	t.Helper()
+	req := newKnowledgeUploadRequest(t, "shipping.txt", knowledgeTestText)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.UploadKnowledgeDocument(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var resp struct {
+		Data handlers.KnowledgeDocumentResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	waitForKnowledgeStatus(t, app, resp.Data.ID, models.KnowledgeDocumentStatusReady)
+	return resp.Data
+}
+
+func waitForKnowledgeStatus(t *testing.T, app *handlers.App, id uuid.UUID, status models.KnowledgeDocumentStatus) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		var doc models.KnowledgeDocument
+		return app.DB.Where("id = ?", id).First(&doc).Error == nil && doc.Status == status
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func countKnowledgeChunks(t *testing.T, app *handlers.App, docID uuid.UUID) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, app.DB.Model(&models.KnowledgeChunk{}).Where("document_id = ?", docID).Count(&n).Error)
+	return n
+}
+
+func TestApp_KnowledgeBase_UploadIndexesDocument(t *testing.T) {
+	app, org, user, calls := newKnowledgeTestApp(t)
+	want := len(knowledge.Chunk(knowledgeTestText, knowledge.DefaultChunkSize, knowledge.DefaultChunkOverlap))
+	require.Greater(t, want, 1)
+
+	doc := uploadKnowledgeDocument(t, app, org, user)
+	assert.Equal(t, "Shipping FAQ", doc.Name)
+	assert.Equal(t, knowledge.FormatText, doc.Format)
+	assert.Equal(t, "test-embed", doc.EmbeddingModel)
+
+	var stored models.KnowledgeDocument
+	require.NoError(t, app.DB.Where("id = ?", doc.ID).First(&stored).Error)
+	assert.Equal(t, want, stored.ChunkCount)
+	assert.Equal(t, int64(want), countKnowledgeChunks(t, app, doc.ID))
+	assert.Equal(t, int32(1), calls.Load(), "chunks are embedded in one batch")
+}
+
+func TestApp_KnowledgeBase_UploadRejectsUnsupportedType(t *testing.T) {
+	app, org, user, _ := newKnowledgeTestApp(t)
+
+	req := newKnowledgeUploadRequest(t, "shipping.docx", knowledgeTestText)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.UploadKnowledgeDocument(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_KnowledgeBase_UploadRejectedPastChunkCap(t *testing.T) {
+	app, org, user, calls := newKnowledgeTestApp(t)
+
+	// Fill the knowledge base to one chunk below the cap
+	filler := models.KnowledgeDocument{
+		OrganizationID: org.ID,
+		Name:           "Filler",
+		Format:         knowledge.FormatText,
+		Status:         models.KnowledgeDocumentStatusReady,
+	}
+	require.NoError(t, app.DB.Create(&filler).Error)
+	require.NoError(t, app.DB.Exec(`INSERT INTO knowledge_chunks (id, organization_id, document_id, chunk_index, content, embedding_model, created_at)
+		SELECT gen_random_uuid(), ?, ?, g, 'filler', 'test-embed', NOW() FROM generate_series(1, ?) AS g`,
+		org.ID, filler.ID, 20000-1).Error)
+
+	req := newKnowledgeUploadRequest(t, "shipping.txt", knowledgeTestText)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.UploadKnowledgeDocument(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+	assert.Contains(t, string(testutil.GetResponseBody(req)), "Knowledge base is full")
+
+	var docs int64
+	require.NoError(t, app.DB.Model(&models.KnowledgeDocument{}).Where("organization_id = ?", org.ID).Count(&docs).Error)
+	assert.Equal(t, int64(1), docs, "the rejected document is not saved")
+	assert.Zero(t, calls.Load(), "nothing is embedded")
+}
+
+func TestApp_KnowledgeBase_Reindex(t *testing.T) {
+	app, org, user, calls := newKnowledgeTestApp(t)
+	doc := uploadKnowledgeDocument(t, app, org, user)
+	chunks := countKnowledgeChunks(t, app, doc.ID)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", doc.ID.String())
+	require.NoError(t, app.ReindexKnowledgeDocument(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	waitForKnowledgeStatus(t, app, doc.ID, models.KnowledgeDocumentStatusReady)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, chunks, countKnowledgeChunks(t, app, doc.ID), "old chunks are replaced, not added to")
+
+	// A document another ingest is working on can't be claimed
+	require.NoError(t, app.DB.Model(&models.KnowledgeDocument{}).Where("id = ?", doc.ID).
+		Update("status", models.KnowledgeDocumentStatusProcessing).Error)
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", doc.ID.String())
+	require.NoError(t, app.ReindexKnowledgeDocument(req))
+	assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+
+	// ...unless that ingest has gone stale
+	require.NoError(t, app.DB.Model(&models.KnowledgeDocument{}).Where("id = ?", doc.ID).
+		UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error)
+	req = testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", doc.ID.String())
+	require.NoError(t, app.ReindexKnowledgeDocument(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	waitForKnowledgeStatus(t, app, doc.ID, models.KnowledgeDocumentStatusReady)
+}
+
+func TestApp_KnowledgeBase_DeleteRemovesChunks(t *testing.T) {
+	app, org, user, _ := newKnowledgeTestApp(t)
+	doc := uploadKnowledgeDocument(t, app, org, user)
+	require.NotZero(t, countKnowledgeChunks(t, app, doc.ID))
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", doc.ID.String())
+	require.NoError(t, app.DeleteKnowledgeDocument(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	assert.Zero(t, countKnowledgeChunks(t, app, doc.ID))
+	err := app.DB.Where("id = ?", doc.ID).First(&models.KnowledgeDocument{}).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestApp_KnowledgeBase_DeleteWaitsForIngestHoldingLock(t *testing.T) {
+	app, org, user, _ := newKnowledgeTestApp(t)
+	doc := uploadKnowledgeDocument(t, app, org, user)
+
+	// Hold the document lock the way an ingest storing its chunks does
+	tx := app.DB.Begin()
+	require.NoError(t, tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", doc.ID).First(&models.KnowledgeDocument{}).Error)
+	require.NoError(t, tx.Where("document_id = ?", doc.ID).Delete(&models.KnowledgeChunk{}).Error)
+	require.NoError(t, tx.Create(&models.KnowledgeChunk{
+		ID:             uuid.New(),
+		OrganizationID: org.ID,
+		DocumentID:     doc.ID,
+		Content:        "stored by the ingest",
+		EmbeddingModel: "test-embed",
+	}).Error)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", doc.ID.String())
+	done := make(chan error, 1)
+	go func() { done <- app.DeleteKnowledgeDocument(req) }()
+
+	select {
+	case <-done:
+		tx.Rollback()
+		t.Fatal("delete ran while the ingest held the document lock")
+	case <-time.After(200 * time.Millisecond):
+	}
+	require.NoError(t, tx.Commit().Error)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("delete did not finish after the ingest committed")
+	}
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	assert.Zero(t, countKnowledgeChunks(t, app, doc.ID), "chunks stored by the ingest are deleted too")
+}
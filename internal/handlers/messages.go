@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/langutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/templateutil"
 	"github.com/shridarpatil/whatomate/internal/utils"
@@ -73,6 +74,9 @@ type OutgoingMessageRequest struct {
 
 	// Reply context
 	ReplyToMessage *models.Message
+
+	// Extra metadata stored on the saved message (e.g. knowledge-base citations)
+	Metadata models.JSONB
 }
 
 // MessageSendOptions configures optional behaviors for message sending
@@ -339,6 +343,15 @@ func (a *App) createOutgoingMessage(req OutgoingMessageRequest, opts MessageSend
 		}
 	}
 
+	if len(req.Metadata) > 0 {
+		if msg.Metadata == nil {
+			msg.Metadata = models.JSONB{}
+		}
+		for k, v := range req.Metadata {
+			msg.Metadata[k] = v
+		}
+	}
+
 	// Handle reply context
 	if req.ReplyToMessage != nil {
 		msg.IsReply = true
@@ -761,11 +774,6 @@ func (a *App) SendTemplateMessage(r *fastglue.Request) error {
 		}
 	}
 
-	// Check template is approved
-	if template.Status != "APPROVED" {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Template is not approved (status: %s)", template.Status), nil, "")
-	}
-
 	// Get contact or use phone number directly
 	var contact *models.Contact
 
@@ -800,6 +808,14 @@ func (a *App) SendTemplateMessage(r *fastglue.Request) error {
 		contact = &c
 	}
 
+	// Send the approved variant in the contact's language when one exists
+	template = *langutil.TemplateVariant(a.DB, &template, contact.Language)
+
+	// Check template is approved
+	if template.Status != "APPROVED" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Template is not approved (status: %s)", template.Status), nil, "")
+	}
+
 	// Determine which WhatsApp account to use (explicit > template > contact > default)
 	accountName := req.AccountName
 	if accountName == "" {
@@ -0,0 +1,684 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// Segment condition fields.
+const (
+	segmentFieldTags            = "tags"
+	segmentFieldMetadata        = "metadata"
+	segmentFieldLastInboundAt   = "last_inbound_at"
+	segmentFieldWhatsAppAccount = "whatsapp_account"
+	segmentFieldMarketingOptOut = "marketing_opt_out"
+	segmentFieldAssignedUser    = "assigned_user_id"
+	segmentFieldChatbotVariable = "chatbot_variable"
+)
+
+// segmentPreviewSampleSize is how many matching contacts a preview returns.
+const segmentPreviewSampleSize = 10
+
+// segmentKeyPattern limits metadata and chatbot variable keys to the
+// characters the rest of the app uses for variable names.
+var segmentKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
+// SegmentCondition is one rule over a contact field. Key names the
+// metadata entry or chatbot variable for those fields.
+type SegmentCondition struct {
+	Field    string `json:"field"`
+	Key      string `json:"key,omitempty"`
+	Operator string `json:"operator"`
+	Value    any    `json:"value,omitempty"`
+}
+
+// SegmentRules combines conditions with all (AND, the default) or any (OR).
+type SegmentRules struct {
+	Match      string             `json:"match"`
+	Conditions []SegmentCondition `json:"conditions"`
+}
+
+// SegmentRequest represents the request body for creating/updating a segment
+type SegmentRequest struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Rules       SegmentRules `json:"rules"`
+}
+
+// SegmentResponse represents a segment in API responses
+type SegmentResponse struct {
+	ID          uuid.UUID    `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Rules       SegmentRules `json:"rules"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// SegmentPreviewContact is a matching contact shown in a preview.
+type SegmentPreviewContact struct {
+	ID          uuid.UUID `json:"id"`
+	PhoneNumber string    `json:"phone_number"`
+	ProfileName string    `json:"profile_name"`
+}
+
+// SegmentPreviewResponse reports how many contacts a segment matches now.
+type SegmentPreviewResponse struct {
+	Count  int64                   `json:"count"`
+	Sample []SegmentPreviewContact `json:"sample"`
+}
+
+// ListSegments returns the organization's contact segments
+func (a *App) ListSegments(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.ContactSegment{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		query = query.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var segments []models.ContactSegment
+	if err := pg.Apply(query.Order("name ASC")).Find(&segments).Error; err != nil {
+		a.Log.Error("Failed to list segments", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list segments", nil, "")
+	}
+
+	result := make([]SegmentResponse, len(segments))
+	for i := range segments {
+		result[i] = segmentToResponse(&segments[i])
+	}
+
+	return r.SendEnvelope(listEnvelope("segments", result, total, pg))
+}
+
+// CreateSegment creates a contact segment
+func (a *App) CreateSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	var req SegmentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "name is required", nil, "")
+	}
+	if _, _, err := segmentWhere(req.Rules, time.Now()); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	segment := models.ContactSegment{
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Rules:          segmentRulesToJSONB(req.Rules),
+		CreatedByID:    userID,
+		UpdatedByID:    &userID,
+	}
+	if err := a.DB.Create(&segment).Error; err != nil {
+		a.Log.Error("Failed to create segment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create segment", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"contact_segment", segment.ID, models.AuditActionCreated, nil, &segment)
+
+	return r.SendEnvelope(segmentToResponse(&segment))
+}
+
+// GetSegment returns a single contact segment
+func (a *App) GetSegment(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.ContactSegment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	return r.SendEnvelope(segmentToResponse(segment))
+}
+
+// UpdateSegment updates a contact segment
+func (a *App) UpdateSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.ContactSegment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+	oldSegment := *segment
+
+	var req SegmentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		segment.Name = name
+	}
+	segment.Description = req.Description
+	if _, _, err := segmentWhere(req.Rules, time.Now()); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	segment.Rules = segmentRulesToJSONB(req.Rules)
+	segment.UpdatedByID = &userID
+
+	if err := a.DB.Save(segment).Error; err != nil {
+		a.Log.Error("Failed to update segment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update segment", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"contact_segment", segment.ID, models.AuditActionUpdated, &oldSegment, segment)
+
+	return r.SendEnvelope(segmentToResponse(segment))
+}
+
+// DeleteSegment deletes a contact segment that no pending campaign targets
+func (a *App) DeleteSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionDelete)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.ContactSegment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	var inUse int64
+	a.DB.Model(&models.BulkMessageCampaign{}).
+		Where("organization_id = ? AND segment_id = ? AND status IN ?", orgID, id,
+			[]models.CampaignStatus{models.CampaignStatusDraft, models.CampaignStatusScheduled}).
+		Count(&inUse)
+	if inUse > 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict,
+			fmt.Sprintf("Segment is used by %d campaign(s) that haven't started", inUse), nil, "")
+	}
+
+	if err := a.DB.Delete(segment).Error; err != nil {
+		a.Log.Error("Failed to delete segment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete segment", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"contact_segment", id, models.AuditActionDeleted, segment, nil)
+
+	return r.SendEnvelope(map[string]string{"message": "Segment deleted"})
+}
+
+// PreviewSegmentRules returns the size of an unsaved rule set, so the
+// editor can show the audience while rules are being built.
+func (a *App) PreviewSegmentRules(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Rules SegmentRules `json:"rules"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	return a.sendSegmentPreview(r, orgID, req.Rules)
+}
+
+// PreviewSegment returns the current size of a saved segment
+func (a *App) PreviewSegment(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.ContactSegment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	return a.sendSegmentPreview(r, orgID, segmentRulesFromJSONB(segment.Rules))
+}
+
+func (a *App) sendSegmentPreview(r *fastglue.Request, orgID uuid.UUID, rules SegmentRules) error {
+	query, err := a.segmentContactsQuery(orgID, rules)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		a.Log.Error("Failed to count segment contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to preview segment", nil, "")
+	}
+
+	sample := []SegmentPreviewContact{}
+	if err := query.Select("id", "phone_number", "profile_name").
+		Order("last_message_at DESC NULLS LAST").
+		Limit(segmentPreviewSampleSize).
+		Scan(&sample).Error; err != nil {
+		a.Log.Error("Failed to sample segment contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to preview segment", nil, "")
+	}
+
+	return r.SendEnvelope(SegmentPreviewResponse{Count: count, Sample: sample})
+}
+
+// segmentContactsQuery returns a query over the organization's contacts
+// that match rules.
+func (a *App) segmentContactsQuery(orgID uuid.UUID, rules SegmentRules) (*gorm.DB, error) {
+	where, args, err := segmentWhere(rules, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	query := a.DB.Model(&models.Contact{}).Where("organization_id = ?", orgID)
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+	return query, nil
+}
+
+// segmentWhere validates rules and compiles them into a parameterised SQL
+// condition over the contacts table. Fields and operators are whitelisted;
+// values only ever reach the query as arguments. An empty rule set
+// returns "" and matches every contact.
+func segmentWhere(rules SegmentRules, now time.Time) (string, []any, error) {
+	joiner := " AND "
+	switch rules.Match {
+	case "", "all":
+	case "any":
+		joiner = " OR "
+	default:
+		return "", nil, fmt.Errorf("match must be all or any")
+	}
+
+	clauses := make([]string, 0, len(rules.Conditions))
+	var args []any
+	for i, cond := range rules.Conditions {
+		clause, condArgs, err := segmentConditionSQL(cond, now)
+		if err != nil {
+			return "", nil, fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		clauses = append(clauses, "("+clause+")")
+		args = append(args, condArgs...)
+	}
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+	return "(" + strings.Join(clauses, joiner) + ")", args, nil
+}
+
+func segmentConditionSQL(cond SegmentCondition, now time.Time) (string, []any, error) {
+	switch cond.Field {
+	case segmentFieldTags:
+		switch cond.Operator {
+		case "has", "not_has":
+			tag, ok := segmentString(cond.Value)
+			if !ok {
+				return "", nil, fmt.Errorf("tags needs a tag name")
+			}
+			clause := "COALESCE(tags, '[]'::jsonb) @> ?::jsonb"
+			if cond.Operator == "not_has" {
+				clause = "NOT " + clause
+			}
+			return clause, []any{segmentTagJSON(tag)}, nil
+		case "has_any":
+			tags, ok := segmentStrings(cond.Value)
+			if !ok {
+				return "", nil, fmt.Errorf("tags needs a list of tag names")
+			}
+			parts := make([]string, len(tags))
+			args := make([]any, len(tags))
+			for i, tag := range tags {
+				parts[i] = "COALESCE(tags, '[]'::jsonb) @> ?::jsonb"
+				args[i] = segmentTagJSON(tag)
+			}
+			return strings.Join(parts, " OR "), args, nil
+		}
+
+	case segmentFieldMetadata, segmentFieldChatbotVariable:
+		if !segmentKeyPattern.MatchString(cond.Key) {
+			return "", nil, fmt.Errorf("%s needs a key of letters, digits, '_', '.' or '-'", cond.Field)
+		}
+		// Chatbot variables come from the contact's most recent session.
+		expr := "metadata->>?::text"
+		if cond.Field == segmentFieldChatbotVariable {
+			expr = "(SELECT cs.session_data->>?::text FROM chatbot_sessions cs" +
+				" WHERE cs.contact_id = contacts.id AND cs.deleted_at IS NULL" +
+				" ORDER BY cs.last_activity_at DESC LIMIT 1)"
+		}
+		switch cond.Operator {
+		case "exists":
+			return expr + " IS NOT NULL", []any{cond.Key}, nil
+		case "not_exists":
+			return expr + " IS NULL", []any{cond.Key}, nil
+		case "equals", "not_equals", "contains":
+			value, ok := segmentScalar(cond.Value)
+			if !ok {
+				return "", nil, fmt.Errorf("%s %s needs a value", cond.Field, cond.Operator)
+			}
+			switch cond.Operator {
+			case "equals":
+				return expr + " = ?", []any{cond.Key, value}, nil
+			case "not_equals":
+				return expr + " IS DISTINCT FROM ?", []any{cond.Key, value}, nil
+			default:
+				return expr + " ILIKE ?", []any{cond.Key, "%" + value + "%"}, nil
+			}
+		}
+
+	case segmentFieldLastInboundAt:
+		switch cond.Operator {
+		case "within_days", "not_within_days":
+			days, ok := cond.Value.(float64)
+			if !ok || days < 1 || days != float64(int(days)) {
+				return "", nil, fmt.Errorf("last_inbound_at %s needs a whole number of days", cond.Operator)
+			}
+			since := now.AddDate(0, 0, -int(days))
+			if cond.Operator == "within_days" {
+				return "last_inbound_at >= ?", []any{since}, nil
+			}
+			return "last_inbound_at IS NULL OR last_inbound_at < ?", []any{since}, nil
+		case "before", "after":
+			s, _ := cond.Value.(string)
+			t, err := parseSegmentTime(s)
+			if err != nil {
+				return "", nil, fmt.Errorf("last_inbound_at %s needs a date (YYYY-MM-DD or RFC 3339)", cond.Operator)
+			}
+			if cond.Operator == "before" {
+				return "last_inbound_at < ?", []any{t}, nil
+			}
+			return "last_inbound_at > ?", []any{t}, nil
+		case "is_empty":
+			return "last_inbound_at IS NULL", nil, nil
+		case "is_not_empty":
+			return "last_inbound_at IS NOT NULL", nil, nil
+		}
+
+	case segmentFieldWhatsAppAccount:
+		name, ok := segmentString(cond.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("whatsapp_account needs an account name")
+		}
+		switch cond.Operator {
+		case "equals":
+			return "whats_app_account = ?", []any{name}, nil
+		case "not_equals":
+			return "whats_app_account <> ?", []any{name}, nil
+		}
+
+	case segmentFieldMarketingOptOut:
+		if cond.Operator == "equals" {
+			optedOut, ok := cond.Value.(bool)
+			if !ok {
+				return "", nil, fmt.Errorf("marketing_opt_out needs true or false")
+			}
+			return "marketing_opt_out = ?", []any{optedOut}, nil
+		}
+
+	case segmentFieldAssignedUser:
+		switch cond.Operator {
+		case "equals", "not_equals":
+			s, _ := cond.Value.(string)
+			userID, err := uuid.Parse(s)
+			if err != nil {
+				return "", nil, fmt.Errorf("assigned_user_id needs a user id")
+			}
+			if cond.Operator == "equals" {
+				return "assigned_user_id = ?", []any{userID}, nil
+			}
+			return "assigned_user_id IS DISTINCT FROM ?", []any{userID}, nil
+		case "is_empty":
+			return "assigned_user_id IS NULL", nil, nil
+		case "is_not_empty":
+			return "assigned_user_id IS NOT NULL", nil, nil
+		}
+
+	default:
+		return "", nil, fmt.Errorf("unknown field %q", cond.Field)
+	}
+	return "", nil, fmt.Errorf("operator %q is not supported for %s", cond.Operator, cond.Field)
+}
+
+func segmentString(v any) (string, bool) {
+	s, ok := v.(string)
+	s = strings.TrimSpace(s)
+	return s, ok && s != ""
+}
+
+func segmentStrings(v any) ([]string, bool) {
+	items, ok := v.([]any)
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := segmentString(item)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// segmentScalar renders a string, number or boolean the way ->> returns it.
+func segmentScalar(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, val != ""
+	case float64, bool:
+		b, _ := json.Marshal(val)
+		return string(b), true
+	}
+	return "", false
+}
+
+func segmentTagJSON(tag string) string {
+	b, _ := json.Marshal([]string{tag})
+	return string(b)
+}
+
+func parseSegmentTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func segmentRulesToJSONB(rules SegmentRules) models.JSONB {
+	if rules.Match == "" {
+		rules.Match = "all"
+	}
+	if rules.Conditions == nil {
+		rules.Conditions = []SegmentCondition{}
+	}
+	b, _ := json.Marshal(rules)
+	var out models.JSONB
+	_ = json.Unmarshal(b, &out)
+	return out
+}
+
+func segmentRulesFromJSONB(raw models.JSONB) SegmentRules {
+	var rules SegmentRules
+	if b, err := json.Marshal(raw); err == nil {
+		_ = json.Unmarshal(b, &rules)
+	}
+	if rules.Conditions == nil {
+		rules.Conditions = []SegmentCondition{}
+	}
+	return rules
+}
+
+func segmentToResponse(segment *models.ContactSegment) SegmentResponse {
+	return SegmentResponse{
+		ID:          segment.ID,
+		Name:        segment.Name,
+		Description: segment.Description,
+		Rules:       segmentRulesFromJSONB(segment.Rules),
+		CreatedAt:   segment.CreatedAt,
+		UpdatedAt:   segment.UpdatedAt,
+	}
+}
+
+// segmentTemplateData is the data segment_params are rendered against.
+// contact.fields holds the org's custom contact fields, falling back to
+// each field's default when the contact has no value.
+func segmentTemplateData(contact *models.Contact, fields []models.ContactField) map[string]any {
+	tags := make([]any, len(contact.Tags))
+	copy(tags, contact.Tags)
+	metadata := map[string]any{}
+	for k, v := range contact.Metadata {
+		metadata[k] = v
+	}
+	customFields := make(map[string]any, len(fields))
+	for i := range fields {
+		f := &fields[i]
+		if v, ok := contact.Metadata[f.Key]; ok {
+			customFields[f.Key] = v
+		} else if v, err := contactutil.CoerceFieldValue(f, f.DefaultValue); err == nil && v != nil {
+			customFields[f.Key] = v
+		}
+	}
+	return map[string]any{
+		"contact": map[string]any{
+			"name":             contact.ProfileName,
+			"phone_number":     contact.PhoneNumber,
+			"whatsapp_account": contact.WhatsAppAccount,
+			"language":         contact.Language,
+			"tags":             tags,
+			"metadata":         metadata,
+			"fields":           customFields,
+		},
+	}
+}
+
+// segmentRecipientParams renders a campaign's segment_params for contact.
+func segmentRecipientParams(params models.JSONB, contact *models.Contact, fields []models.ContactField) models.JSONB {
+	out := models.JSONB{}
+	if len(params) == 0 {
+		return out
+	}
+	data := segmentTemplateData(contact, fields)
+	for name, v := range params {
+		s, ok := v.(string)
+		if !ok {
+			out[name] = v
+			continue
+		}
+		out[name] = processVariables(s, data)
+	}
+	return out
+}
+
+// addSegmentRecipients resolves campaign's segment and adds a pending
+// recipient for each matching contact that isn't already on the
+// campaign. Returns the number added.
+func (a *App) addSegmentRecipients(campaign *models.BulkMessageCampaign, segment *models.ContactSegment) (int, error) {
+	query, err := a.segmentContactsQuery(campaign.OrganizationID, segmentRulesFromJSONB(segment.Rules))
+	if err != nil {
+		return 0, err
+	}
+
+	var existing []string
+	if err := a.DB.Model(&models.BulkMessageRecipient{}).
+		Where("campaign_id = ?", campaign.ID).
+		Pluck("phone_number", &existing).Error; err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, phone := range existing {
+		seen[phone] = true
+	}
+
+	fields, err := contactutil.LoadFields(a.DB, campaign.OrganizationID)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	var contacts []models.Contact
+	err = query.FindInBatches(&contacts, 500, func(tx *gorm.DB, _ int) error {
+		recipients := make([]models.BulkMessageRecipient, 0, len(contacts))
+		for i := range contacts {
+			c := &contacts[i]
+			if seen[c.PhoneNumber] {
+				continue
+			}
+			seen[c.PhoneNumber] = true
+			recipients = append(recipients, models.BulkMessageRecipient{
+				CampaignID:     campaign.ID,
+				PhoneNumber:    c.PhoneNumber,
+				RecipientName:  c.ProfileName,
+				TemplateParams: segmentRecipientParams(campaign.SegmentParams, c, fields),
+				HeaderParams:   models.JSONB{},
+				Status:         models.MessageStatusPending,
+			})
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+		if err := a.DB.Create(&recipients).Error; err != nil {
+			return err
+		}
+		added += len(recipients)
+		return nil
+	}).Error
+	if err != nil {
+		return added, err
+	}
+
+	var total int64
+	a.DB.Model(&models.BulkMessageRecipient{}).Where("campaign_id = ?", campaign.ID).Count(&total)
+	a.DB.Model(campaign).Update("total_recipients", total)
+	return added, nil
+}
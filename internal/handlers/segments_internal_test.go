@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentWhere_CompilesConditions(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	where, args, err := segmentWhere(SegmentRules{
+		Conditions: []SegmentCondition{
+			{Field: "tags", Operator: "has", Value: "vip"},
+			{Field: "metadata", Key: "city", Operator: "equals", Value: "Pune"},
+			{Field: "last_inbound_at", Operator: "within_days", Value: float64(30)},
+			{Field: "marketing_opt_out", Operator: "equals", Value: false},
+		},
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "((COALESCE(tags, '[]'::jsonb) @> ?::jsonb) AND (metadata->>?::text = ?)"+
+		" AND (last_inbound_at >= ?) AND (marketing_opt_out = ?))", where)
+	assert.Equal(t, []any{`["vip"]`, "city", "Pune", now.AddDate(0, 0, -30), false}, args)
+
+	where, args, err = segmentWhere(SegmentRules{
+		Match: "any",
+		Conditions: []SegmentCondition{
+			{Field: "chatbot_variable", Key: "plan", Operator: "exists"},
+			{Field: "assigned_user_id", Operator: "is_empty"},
+		},
+	}, now)
+	require.NoError(t, err)
+	assert.Contains(t, where, ") OR (assigned_user_id IS NULL)")
+	assert.Contains(t, where, "FROM chatbot_sessions cs WHERE cs.contact_id = contacts.id")
+	assert.Equal(t, []any{"plan"}, args)
+
+	where, args, err = segmentWhere(SegmentRules{}, now)
+	require.NoError(t, err)
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+}
+
+func TestSegmentWhere_RejectsInvalidConditions(t *testing.T) {
+	for name, cond := range map[string]SegmentCondition{
+		"unknown field":        {Field: "password", Operator: "equals", Value: "x"},
+		"unknown operator":     {Field: "tags", Operator: "matches", Value: "x"},
+		"tag without value":    {Field: "tags", Operator: "has"},
+		"metadata without key": {Field: "metadata", Operator: "exists"},
+		"injected key":         {Field: "metadata", Key: "a' OR 1=1 --", Operator: "exists"},
+		"fractional days":      {Field: "last_inbound_at", Operator: "within_days", Value: 1.5},
+		"bad date":             {Field: "last_inbound_at", Operator: "before", Value: "yesterday"},
+		"bad user id":          {Field: "assigned_user_id", Operator: "equals", Value: "nobody"},
+		"opt-out not bool":     {Field: "marketing_opt_out", Operator: "equals", Value: "yes"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := segmentWhere(SegmentRules{Conditions: []SegmentCondition{cond}}, time.Now())
+			assert.Error(t, err)
+		})
+	}
+
+	_, _, err := segmentWhere(SegmentRules{Match: "some"}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestSegmentRecipientParams(t *testing.T) {
+	contact := &models.Contact{
+		BaseModel:   models.BaseModel{ID: uuid.New()},
+		PhoneNumber: "+15550001111",
+		ProfileName: "Asha",
+		Metadata:    models.JSONB{"city": "Pune"},
+	}
+	params := segmentRecipientParams(models.JSONB{
+		"1":    "{{contact.name}}",
+		"city": "Hello from {{ contact.metadata.city }}",
+		"code": "SAVE10",
+	}, contact, nil)
+	assert.Equal(t, models.JSONB{"1": "Asha", "city": "Hello from Pune", "code": "SAVE10"}, params)
+}
+
+func TestSegmentRecipientParams_ContactFields(t *testing.T) {
+	contact := &models.Contact{
+		PhoneNumber: "+15550001111",
+		Metadata:    models.JSONB{"points": float64(120)},
+	}
+	fields := []models.ContactField{
+		{Key: "points", Type: models.ContactFieldNumber},
+		{Key: "tier", Type: models.ContactFieldEnum, Options: models.StringArray{"silver", "gold"}, DefaultValue: "silver"},
+		{Key: "city", Type: models.ContactFieldText},
+	}
+	params := segmentRecipientParams(models.JSONB{
+		"1": "{{contact.fields.points}} points",
+		"2": "{{contact.fields.tier}}",
+		"3": "{{contact.fields.city}}",
+	}, contact, fields)
+	assert.Equal(t, models.JSONB{"1": "120 points", "2": "silver", "3": ""}, params)
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_Segments_CreateAndPreview(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("segments")), testutil.WithSuperAdmin())
+
+	vip := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15550000001"))
+	require.NoError(t, app.DB.Model(vip).Updates(map[string]any{
+		"tags":     models.JSONBArray{"vip"},
+		"metadata": models.JSONB{"city": "Pune"},
+	}).Error)
+	testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15550000002"))
+
+	rules := map[string]any{
+		"match": "all",
+		"conditions": []map[string]any{
+			{"field": "tags", "operator": "has", "value": "vip"},
+			{"field": "metadata", "key": "city", "operator": "equals", "value": "Pune"},
+		},
+	}
+
+	req := testutil.NewJSONRequest(t, map[string]any{"name": "Pune VIPs", "rules": rules})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSegment(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var created struct {
+		Data handlers.SegmentResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &created))
+	assert.Len(t, created.Data.Rules.Conditions, 2)
+
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", created.Data.ID.String())
+	require.NoError(t, app.PreviewSegment(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var preview struct {
+		Data handlers.SegmentPreviewResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &preview))
+	assert.Equal(t, int64(1), preview.Data.Count)
+	require.Len(t, preview.Data.Sample, 1)
+	assert.Equal(t, vip.ID, preview.Data.Sample[0].ID)
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"name":  "Broken",
+		"rules": map[string]any{"conditions": []map[string]any{{"field": "password", "operator": "equals"}}},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSegment(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_StartCampaign_ResolvesSegment(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("segment-campaign")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("segment-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	member := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15550000011"))
+	require.NoError(t, app.DB.Model(member).Update("tags", models.JSONBArray{"beta"}).Error)
+	testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15550000012"))
+
+	segment := &models.ContactSegment{
+		OrganizationID: org.ID,
+		Name:           "Beta",
+		Rules: models.JSONB{"match": "all", "conditions": []any{
+			map[string]any{"field": "tags", "operator": "has", "value": "beta"},
+		}},
+		CreatedByID: user.ID,
+	}
+	require.NoError(t, app.DB.Create(segment).Error)
+
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+	require.NoError(t, app.DB.Model(campaign).Updates(map[string]any{
+		"segment_id":     segment.ID,
+		"segment_params": models.JSONB{"1": "{{contact.name}}"},
+	}).Error)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+	require.NoError(t, app.StartCampaign(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	require.Len(t, mockQueue.Jobs, 1)
+	assert.Equal(t, member.PhoneNumber, mockQueue.Jobs[0].PhoneNumber)
+	assert.Equal(t, member.ProfileName, mockQueue.Jobs[0].TemplateParams["1"])
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, 1, updated.TotalRecipients)
+}
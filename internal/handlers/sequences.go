@@ -0,0 +1,729 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sequence limits.
+const (
+	maxSequenceSteps        = 20
+	maxSequenceDelayMinutes = 365 * 24 * 60
+)
+
+// SequenceStepRequest is one step of a sequence. DelayMinutes counts from
+// enrollment and can't be less than the previous step's.
+type SequenceStepRequest struct {
+	DelayMinutes   int            `json:"delay_minutes"`
+	TemplateID     string         `json:"template_id"`
+	TemplateParams map[string]any `json:"template_params"`
+}
+
+// SequenceRequest represents the request body for creating/updating a
+// sequence. On update, nil Steps leaves the steps unchanged.
+type SequenceRequest struct {
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	WhatsAppAccount  string                 `json:"whatsapp_account"`
+	TriggerType      models.SequenceTrigger `json:"trigger_type"`
+	TriggerValue     string                 `json:"trigger_value"`
+	ExitOnReply      bool                   `json:"exit_on_reply"`
+	ExitOnOptOut     *bool                  `json:"exit_on_opt_out"` // Defaults to true
+	ExitOnTagRemoved string                 `json:"exit_on_tag_removed"`
+	IsActive         bool                   `json:"is_active"`
+	Steps            []SequenceStepRequest  `json:"steps"`
+}
+
+// SequenceStepResponse represents a sequence step with its send stats
+type SequenceStepResponse struct {
+	ID             uuid.UUID    `json:"id"`
+	Position       int          `json:"position"`
+	DelayMinutes   int          `json:"delay_minutes"`
+	TemplateID     uuid.UUID    `json:"template_id"`
+	TemplateName   string       `json:"template_name,omitempty"`
+	TemplateParams models.JSONB `json:"template_params"`
+	CampaignID     uuid.UUID    `json:"campaign_id"`
+	SentCount      int          `json:"sent_count"`
+	DeliveredCount int          `json:"delivered_count"`
+	ReadCount      int          `json:"read_count"`
+	FailedCount    int          `json:"failed_count"`
+}
+
+// SequenceResponse represents a sequence in API responses
+type SequenceResponse struct {
+	ID                uuid.UUID              `json:"id"`
+	Name              string                 `json:"name"`
+	Description       string                 `json:"description"`
+	WhatsAppAccount   string                 `json:"whatsapp_account"`
+	TriggerType       models.SequenceTrigger `json:"trigger_type"`
+	TriggerValue      string                 `json:"trigger_value,omitempty"`
+	ExitOnReply       bool                   `json:"exit_on_reply"`
+	ExitOnOptOut      bool                   `json:"exit_on_opt_out"`
+	ExitOnTagRemoved  string                 `json:"exit_on_tag_removed,omitempty"`
+	IsActive          bool                   `json:"is_active"`
+	Steps             []SequenceStepResponse `json:"steps"`
+	ActiveEnrollments int64                  `json:"active_enrollments"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// EnrollContactRequest enrolls a contact by ID or phone number
+type EnrollContactRequest struct {
+	ContactID   string `json:"contact_id"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// SequenceEnrollmentResponse represents an enrollment in API responses
+type SequenceEnrollmentResponse struct {
+	ID          uuid.UUID               `json:"id"`
+	ContactID   uuid.UUID               `json:"contact_id"`
+	PhoneNumber string                  `json:"phone_number,omitempty"`
+	ContactName string                  `json:"contact_name,omitempty"`
+	Trigger     models.SequenceTrigger  `json:"trigger"`
+	Status      models.EnrollmentStatus `json:"status"`
+	NextStep    int                     `json:"next_step"`
+	NextRunAt   *time.Time              `json:"next_run_at,omitempty"`
+	ExitReason  string                  `json:"exit_reason,omitempty"`
+	EnrolledAt  time.Time               `json:"enrolled_at"`
+	FinishedAt  *time.Time              `json:"finished_at,omitempty"`
+}
+
+// ListSequences returns the organization's campaign sequences
+func (a *App) ListSequences(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.CampaignSequence{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		query = query.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var sequences []models.CampaignSequence
+	if err := pg.Apply(preloadSequenceSteps(query).Order("created_at DESC")).Find(&sequences).Error; err != nil {
+		a.Log.Error("Failed to list sequences", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list sequences", nil, "")
+	}
+
+	result := make([]SequenceResponse, len(sequences))
+	for i := range sequences {
+		result[i] = a.sequenceToResponse(&sequences[i])
+	}
+
+	return r.SendEnvelope(listEnvelope("sequences", result, total, pg))
+}
+
+// CreateSequence creates a campaign sequence
+func (a *App) CreateSequence(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	var req SequenceRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	sequence := models.CampaignSequence{
+		OrganizationID: orgID,
+		ExitOnOptOut:   true,
+		CreatedByID:    userID,
+		UpdatedByID:    &userID,
+	}
+	if err := a.applySequenceRequest(r, orgID, &sequence, &req); err != nil {
+		return nil
+	}
+	if len(req.Steps) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "A sequence needs at least one step", nil, "")
+	}
+	steps, err := a.sequenceSteps(r, orgID, req.Steps)
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sequence).Error; err != nil {
+			return err
+		}
+		return createSequenceSteps(tx, &sequence, steps)
+	}); err != nil {
+		a.Log.Error("Failed to create sequence", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create sequence", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"campaign_sequence", sequence.ID, models.AuditActionCreated, nil, &sequence)
+
+	return a.sendSequence(r, sequence.ID)
+}
+
+// GetSequence returns a single campaign sequence
+func (a *App) GetSequence(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "sequence")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.CampaignSequence](a.DB, r, id, orgID, "Sequence"); err != nil {
+		return nil
+	}
+
+	return a.sendSequence(r, id)
+}
+
+// UpdateSequence updates a campaign sequence. Contacts already enrolled
+// carry on from the same step number when the steps are replaced.
+func (a *App) UpdateSequence(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "sequence")
+	if err != nil {
+		return nil
+	}
+
+	sequence, err := findByIDAndOrg[models.CampaignSequence](a.DB, r, id, orgID, "Sequence")
+	if err != nil {
+		return nil
+	}
+	oldSequence := *sequence
+
+	var req SequenceRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if err := a.applySequenceRequest(r, orgID, sequence, &req); err != nil {
+		return nil
+	}
+	sequence.UpdatedByID = &userID
+
+	var steps []models.CampaignSequenceStep
+	if req.Steps != nil {
+		if len(req.Steps) == 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "A sequence needs at least one step", nil, "")
+		}
+		if steps, err = a.sequenceSteps(r, orgID, req.Steps); err != nil {
+			return nil
+		}
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(sequence).Error; err != nil {
+			return err
+		}
+		if req.Steps == nil {
+			// Step campaigns follow the sequence's account
+			return tx.Model(&models.BulkMessageCampaign{}).
+				Where("sequence_id = ?", sequence.ID).
+				Update("whats_app_account", sequence.WhatsAppAccount).Error
+		}
+		if err := closeSequenceSteps(tx, sequence.ID); err != nil {
+			return err
+		}
+		return createSequenceSteps(tx, sequence, steps)
+	}); err != nil {
+		a.Log.Error("Failed to update sequence", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update sequence", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"campaign_sequence", sequence.ID, models.AuditActionUpdated, &oldSequence, sequence)
+
+	return a.sendSequence(r, id)
+}
+
+// DeleteSequence deletes a sequence, ending its active enrollments
+func (a *App) DeleteSequence(r *fastglue.Request) error {
+	orgID, userID, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionDelete)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "sequence")
+	if err != nil {
+		return nil
+	}
+
+	sequence, err := findByIDAndOrg[models.CampaignSequence](a.DB, r, id, orgID, "Sequence")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.SequenceEnrollment{}).
+			Where("sequence_id = ? AND status = ?", id, models.EnrollmentStatusActive).
+			Updates(map[string]any{
+				"status":      models.EnrollmentStatusExited,
+				"exit_reason": models.SequenceExitDeleted,
+				"next_run_at": nil,
+				"finished_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		if err := closeSequenceSteps(tx, id); err != nil {
+			return err
+		}
+		return tx.Delete(sequence).Error
+	}); err != nil {
+		a.Log.Error("Failed to delete sequence", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete sequence", nil, "")
+	}
+
+	a.logAudit(orgID, userID,
+		"campaign_sequence", id, models.AuditActionDeleted, sequence, nil)
+
+	return r.SendEnvelope(map[string]string{"message": "Sequence deleted"})
+}
+
+// EnrollSequenceContact enrolls a contact in a sequence through the API,
+// whatever the sequence's trigger.
+func (a *App) EnrollSequenceContact(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "sequence")
+	if err != nil {
+		return nil
+	}
+
+	var req EnrollContactRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	var sequence models.CampaignSequence
+	if err := preloadSequenceSteps(a.DB).Where("id = ? AND organization_id = ?", id, orgID).First(&sequence).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Sequence not found", nil, "")
+	}
+
+	var contact *models.Contact
+	switch {
+	case req.ContactID != "":
+		contactID, err := uuid.Parse(req.ContactID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid contact ID", nil, "")
+		}
+		if contact, err = findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact"); err != nil {
+			return nil
+		}
+	case req.PhoneNumber != "":
+		if contact, err = contactutil.FindContact(a.DB, orgID, req.PhoneNumber); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Contact not found", nil, "")
+		}
+	default:
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "contact_id or phone_number is required", nil, "")
+	}
+
+	enrollment, err := a.enrollInSequence(&sequence, contact.ID, models.SequenceTriggerAPI)
+	if errors.Is(err, errAlreadyEnrolled) {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact is already in this sequence", nil, "")
+	}
+	if err != nil {
+		a.Log.Error("Failed to enroll contact", "error", err, "sequence_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to enroll contact", nil, "")
+	}
+	enrollment.Contact = contact
+
+	return r.SendEnvelope(enrollmentToResponse(enrollment))
+}
+
+// ListSequenceEnrollments returns a sequence's enrollments, newest first
+func (a *App) ListSequenceEnrollments(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionRead)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "sequence")
+	if err != nil {
+		return nil
+	}
+	if _, err := findByIDAndOrg[models.CampaignSequence](a.DB, r, id, orgID, "Sequence"); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	query := a.DB.Model(&models.SequenceEnrollment{}).Where("sequence_id = ?", id)
+	if status := string(r.RequestCtx.QueryArgs().Peek("status")); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var enrollments []models.SequenceEnrollment
+	if err := pg.Apply(query.Preload("Contact").Order("created_at DESC")).Find(&enrollments).Error; err != nil {
+		a.Log.Error("Failed to list enrollments", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list enrollments", nil, "")
+	}
+
+	result := make([]SequenceEnrollmentResponse, len(enrollments))
+	for i := range enrollments {
+		result[i] = enrollmentToResponse(&enrollments[i])
+	}
+
+	return r.SendEnvelope(listEnvelope("enrollments", result, total, pg))
+}
+
+// ExitSequenceEnrollment takes a contact out of a sequence
+func (a *App) ExitSequenceEnrollment(r *fastglue.Request) error {
+	orgID, _, err := a.requireAuth(r, models.ResourceCampaigns, models.ActionWrite)
+	if err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "sequence")
+	if err != nil {
+		return nil
+	}
+	enrollmentID, err := parsePathUUID(r, "enrollmentId", "enrollment")
+	if err != nil {
+		return nil
+	}
+
+	var enrollment models.SequenceEnrollment
+	if err := a.DB.Where("id = ? AND sequence_id = ? AND organization_id = ?", enrollmentID, id, orgID).
+		First(&enrollment).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Enrollment not found", nil, "")
+	}
+	if enrollment.Status != models.EnrollmentStatusActive {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Enrollment has already finished", nil, "")
+	}
+
+	if err := exitEnrollment(a.DB, &enrollment, models.SequenceExitManual); err != nil {
+		a.Log.Error("Failed to exit enrollment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to exit enrollment", nil, "")
+	}
+
+	return r.SendEnvelope(enrollmentToResponse(&enrollment))
+}
+
+// applySequenceRequest validates req and copies it onto sequence, sending
+// a 400 when it's invalid.
+func (a *App) applySequenceRequest(r *fastglue.Request, orgID uuid.UUID, sequence *models.CampaignSequence, req *SequenceRequest) error {
+	fail := func(msg string) error {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+		return errEnvelopeSent
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fail("name is required")
+	}
+	if _, err := a.resolveWhatsAppAccount(orgID, req.WhatsAppAccount); err != nil {
+		return fail("WhatsApp account not found")
+	}
+
+	req.TriggerValue = strings.TrimSpace(req.TriggerValue)
+	switch req.TriggerType {
+	case models.SequenceTriggerContactCreated, models.SequenceTriggerAPI:
+		req.TriggerValue = ""
+	case models.SequenceTriggerTagAdded:
+		if req.TriggerValue == "" {
+			return fail("trigger_value must name the tag")
+		}
+	case models.SequenceTriggerFlowCompleted:
+		flowID, err := uuid.Parse(req.TriggerValue)
+		if err != nil {
+			return fail("trigger_value must be a chatbot flow ID")
+		}
+		var count int64
+		a.DB.Model(&models.ChatbotFlow{}).Where("id = ? AND organization_id = ?", flowID, orgID).Count(&count)
+		if count == 0 {
+			return fail("Chatbot flow not found")
+		}
+	default:
+		return fail("invalid trigger_type. Valid types: contact_created, tag_added, flow_completed, api")
+	}
+
+	sequence.Name = req.Name
+	sequence.Description = req.Description
+	sequence.WhatsAppAccount = req.WhatsAppAccount
+	sequence.TriggerType = req.TriggerType
+	sequence.TriggerValue = req.TriggerValue
+	sequence.ExitOnReply = req.ExitOnReply
+	if req.ExitOnOptOut != nil {
+		sequence.ExitOnOptOut = *req.ExitOnOptOut
+	}
+	sequence.ExitOnTagRemoved = strings.TrimSpace(req.ExitOnTagRemoved)
+	sequence.IsActive = req.IsActive
+	return nil
+}
+
+// sequenceSteps validates step requests, sending a 400 or 404 when they
+// are invalid.
+func (a *App) sequenceSteps(r *fastglue.Request, orgID uuid.UUID, reqs []SequenceStepRequest) ([]models.CampaignSequenceStep, error) {
+	if len(reqs) > maxSequenceSteps {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("A sequence can have at most %d steps", maxSequenceSteps), nil, "")
+		return nil, errEnvelopeSent
+	}
+
+	steps := make([]models.CampaignSequenceStep, len(reqs))
+	for i, req := range reqs {
+		if req.DelayMinutes < 0 || req.DelayMinutes > maxSequenceDelayMinutes {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+				fmt.Sprintf("Step %d: delay_minutes must be between 0 and %d", i+1, maxSequenceDelayMinutes), nil, "")
+			return nil, errEnvelopeSent
+		}
+		if i > 0 && req.DelayMinutes < reqs[i-1].DelayMinutes {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest,
+				fmt.Sprintf("Step %d: delay_minutes can't be less than the previous step's", i+1), nil, "")
+			return nil, errEnvelopeSent
+		}
+		templateID, err := uuid.Parse(req.TemplateID)
+		if err != nil {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Step %d: invalid template ID", i+1), nil, "")
+			return nil, errEnvelopeSent
+		}
+		template, err := findByIDAndOrg[models.Template](a.DB, r, templateID, orgID, "Template")
+		if err != nil {
+			return nil, err
+		}
+		steps[i] = models.CampaignSequenceStep{
+			Position:       i,
+			DelayMinutes:   req.DelayMinutes,
+			TemplateID:     templateID,
+			TemplateParams: models.JSONB(req.TemplateParams),
+			Template:       template,
+		}
+		if steps[i].TemplateParams == nil {
+			steps[i].TemplateParams = models.JSONB{}
+		}
+	}
+	return steps, nil
+}
+
+// createSequenceSteps saves steps, each with the campaign that carries its
+// sends.
+func createSequenceSteps(tx *gorm.DB, sequence *models.CampaignSequence, steps []models.CampaignSequenceStep) error {
+	now := time.Now()
+	for i := range steps {
+		campaign := models.BulkMessageCampaign{
+			OrganizationID:  sequence.OrganizationID,
+			WhatsAppAccount: sequence.WhatsAppAccount,
+			Name:            fmt.Sprintf("%s: step %d", sequence.Name, i+1),
+			TemplateID:      steps[i].TemplateID,
+			Status:          models.CampaignStatusProcessing,
+			StartedAt:       &now,
+			CreatedBy:       sequence.CreatedByID,
+			SequenceID:      &sequence.ID,
+		}
+		if err := tx.Create(&campaign).Error; err != nil {
+			return err
+		}
+		steps[i].SequenceID = sequence.ID
+		steps[i].CampaignID = campaign.ID
+		template := steps[i].Template
+		steps[i].Template = nil
+		err := tx.Create(&steps[i]).Error
+		steps[i].Template = template
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeSequenceSteps removes a sequence's steps. Their campaigns are kept
+// as a record of what was sent. A campaign that still has pending
+// recipients, e.g. waiting for a send window or an enqueue retry, stays
+// processing until the worker drains it.
+func closeSequenceSteps(tx *gorm.DB, sequenceID uuid.UUID) error {
+	if err := tx.Model(&models.BulkMessageCampaign{}).
+		Where("sequence_id = ? AND status = ?", sequenceID, models.CampaignStatusProcessing).
+		Where(`NOT EXISTS (SELECT 1 FROM bulk_message_recipients r
+			WHERE r.campaign_id = bulk_message_campaigns.id AND r.status = ? AND r.deleted_at IS NULL)`, models.MessageStatusPending).
+		Updates(map[string]any{
+			"status":       models.CampaignStatusCompleted,
+			"completed_at": time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+	return tx.Where("sequence_id = ?", sequenceID).Delete(&models.CampaignSequenceStep{}).Error
+}
+
+// preloadSequenceSteps preloads steps in order with their templates and
+// campaigns.
+func preloadSequenceSteps(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Steps.Template").
+		Preload("Steps.Campaign")
+}
+
+func (a *App) sendSequence(r *fastglue.Request, id uuid.UUID) error {
+	var sequence models.CampaignSequence
+	if err := preloadSequenceSteps(a.DB).Where("id = ?", id).First(&sequence).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Sequence not found", nil, "")
+	}
+	return r.SendEnvelope(a.sequenceToResponse(&sequence))
+}
+
+func (a *App) sequenceToResponse(sequence *models.CampaignSequence) SequenceResponse {
+	resp := SequenceResponse{
+		ID:               sequence.ID,
+		Name:             sequence.Name,
+		Description:      sequence.Description,
+		WhatsAppAccount:  sequence.WhatsAppAccount,
+		TriggerType:      sequence.TriggerType,
+		TriggerValue:     sequence.TriggerValue,
+		ExitOnReply:      sequence.ExitOnReply,
+		ExitOnOptOut:     sequence.ExitOnOptOut,
+		ExitOnTagRemoved: sequence.ExitOnTagRemoved,
+		IsActive:         sequence.IsActive,
+		Steps:            make([]SequenceStepResponse, len(sequence.Steps)),
+		CreatedAt:        sequence.CreatedAt,
+		UpdatedAt:        sequence.UpdatedAt,
+	}
+	for i, step := range sequence.Steps {
+		resp.Steps[i] = SequenceStepResponse{
+			ID:             step.ID,
+			Position:       step.Position,
+			DelayMinutes:   step.DelayMinutes,
+			TemplateID:     step.TemplateID,
+			TemplateParams: step.TemplateParams,
+			CampaignID:     step.CampaignID,
+		}
+		if step.Template != nil {
+			resp.Steps[i].TemplateName = step.Template.Name
+		}
+		if c := step.Campaign; c != nil {
+			resp.Steps[i].SentCount = c.SentCount
+			resp.Steps[i].DeliveredCount = c.DeliveredCount
+			resp.Steps[i].ReadCount = c.ReadCount
+			resp.Steps[i].FailedCount = c.FailedCount
+		}
+	}
+	a.DB.Model(&models.SequenceEnrollment{}).
+		Where("sequence_id = ? AND status = ?", sequence.ID, models.EnrollmentStatusActive).
+		Count(&resp.ActiveEnrollments)
+	return resp
+}
+
+func enrollmentToResponse(e *models.SequenceEnrollment) SequenceEnrollmentResponse {
+	resp := SequenceEnrollmentResponse{
+		ID:         e.ID,
+		ContactID:  e.ContactID,
+		Trigger:    e.Trigger,
+		Status:     e.Status,
+		NextStep:   e.NextStep,
+		NextRunAt:  e.NextRunAt,
+		ExitReason: e.ExitReason,
+		EnrolledAt: e.CreatedAt,
+		FinishedAt: e.FinishedAt,
+	}
+	if e.Contact != nil {
+		resp.PhoneNumber = e.Contact.PhoneNumber
+		resp.ContactName = e.Contact.ProfileName
+	}
+	return resp
+}
+
+// errAlreadyEnrolled is returned when a contact is already working
+// through a sequence.
+var errAlreadyEnrolled = errors.New("contact is already enrolled")
+
+// enrollInSequence starts contactID on sequence's first step. Steps must
+// be preloaded.
+func (a *App) enrollInSequence(sequence *models.CampaignSequence, contactID uuid.UUID, trigger models.SequenceTrigger) (*models.SequenceEnrollment, error) {
+	if len(sequence.Steps) == 0 {
+		return nil, errors.New("sequence has no steps")
+	}
+
+	now := time.Now()
+	nextRunAt := now.Add(time.Duration(sequence.Steps[0].DelayMinutes) * time.Minute)
+	enrollment := models.SequenceEnrollment{
+		BaseModel:      models.BaseModel{CreatedAt: now},
+		OrganizationID: sequence.OrganizationID,
+		SequenceID:     sequence.ID,
+		ContactID:      contactID,
+		Trigger:        trigger,
+		Status:         models.EnrollmentStatusActive,
+		NextRunAt:      &nextRunAt,
+	}
+	// The partial unique index on active enrollments decides concurrent
+	// enrollments of the same contact; the loser inserts nothing.
+	result := a.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&enrollment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errAlreadyEnrolled
+	}
+	return &enrollment, nil
+}
+
+// triggerSequences enrolls a contact in the organization's active
+// sequences started by trigger. value is the tag or flow ID for triggers
+// that have one. Failures are logged: the event that fired the trigger
+// has already happened.
+func (a *App) triggerSequences(orgID, contactID uuid.UUID, trigger models.SequenceTrigger, value string) {
+	var sequences []models.CampaignSequence
+	if err := preloadSequenceSteps(a.DB).
+		Where("organization_id = ? AND is_active = ? AND trigger_type = ? AND trigger_value = ?", orgID, true, trigger, value).
+		Find(&sequences).Error; err != nil {
+		a.Log.Error("Failed to load sequences for trigger", "error", err, "trigger", trigger)
+		return
+	}
+	for i := range sequences {
+		if _, err := a.enrollInSequence(&sequences[i], contactID, trigger); err != nil && !errors.Is(err, errAlreadyEnrolled) {
+			a.Log.Error("Failed to enroll contact in sequence", "error", err, "sequence_id", sequences[i].ID, "contact_id", contactID)
+		}
+	}
+}
+
+// triggerTagSequences enrolls a contact in tag_added sequences for each
+// tag in newTags that wasn't in oldTags.
+func (a *App) triggerTagSequences(orgID, contactID uuid.UUID, oldTags, newTags models.JSONBArray) {
+	for _, t := range newTags {
+		tag, ok := t.(string)
+		if ok && !slices.Contains(oldTags, any(tag)) {
+			a.triggerSequences(orgID, contactID, models.SequenceTriggerTagAdded, tag)
+		}
+	}
+}
+
+// exitEnrollment ends an active enrollment early.
+func exitEnrollment(db *gorm.DB, e *models.SequenceEnrollment, reason string) error {
+	now := time.Now()
+	e.Status = models.EnrollmentStatusExited
+	e.ExitReason = reason
+	e.NextRunAt = nil
+	e.FinishedAt = &now
+	return db.Model(e).Updates(map[string]any{
+		"status":      e.Status,
+		"exit_reason": reason,
+		"next_run_at": nil,
+		"finished_at": now,
+	}).Error
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequenceExitReason(t *testing.T) {
+	sequence := &models.CampaignSequence{ExitOnOptOut: true, ExitOnTagRemoved: "trial"}
+	contact := &models.Contact{Tags: models.JSONBArray{"trial"}}
+
+	assert.Empty(t, sequenceExitReason(sequence, contact, false))
+	assert.Equal(t, models.SequenceExitReplied, sequenceExitReason(sequence, contact, true))
+
+	contact.MarketingOptOut = true
+	assert.Equal(t, models.SequenceExitOptedOut, sequenceExitReason(sequence, contact, true))
+	sequence.ExitOnOptOut = false
+	assert.Empty(t, sequenceExitReason(sequence, contact, false))
+
+	contact.Tags = models.JSONBArray{"customer"}
+	assert.Equal(t, models.SequenceExitTagRemoved, sequenceExitReason(sequence, contact, false))
+}
@@ -0,0 +1,308 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_Sequences_TagTriggerSendsStepsUntilReply(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("sequences")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("sequence-account"))
+	welcome := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	followUp := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Trial onboarding",
+		"whatsapp_account": account.Name,
+		"trigger_type":     "tag_added",
+		"trigger_value":    "trial",
+		"exit_on_reply":    true,
+		"is_active":        true,
+		"steps": []map[string]any{
+			{"delay_minutes": 0, "template_id": welcome.ID.String(), "template_params": map[string]any{"1": "{{contact.name}}"}},
+			{"delay_minutes": 60 * 24, "template_id": followUp.ID.String()},
+		},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSequence(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var created struct {
+		Data handlers.SequenceResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &created))
+	require.Len(t, created.Data.Steps, 2)
+	assert.True(t, created.Data.ExitOnOptOut, "defaults to true")
+
+	// Tagging the contact enrolls it
+	contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15551230001"))
+	require.NoError(t, app.DB.Model(contact).Update("profile_name", "Asha").Error)
+	req = testutil.NewJSONRequest(t, map[string]any{"tags": []string{"trial"}})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+	require.NoError(t, app.UpdateContactTags(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var enrollment models.SequenceEnrollment
+	require.NoError(t, app.DB.Where("sequence_id = ? AND contact_id = ?", created.Data.ID, contact.ID).First(&enrollment).Error)
+	assert.Equal(t, models.SequenceTriggerTagAdded, enrollment.Trigger)
+
+	// Enrolling again while active is refused
+	req = testutil.NewJSONRequest(t, map[string]any{"contact_id": contact.ID.String()})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", created.Data.ID.String())
+	require.NoError(t, app.EnrollSequenceContact(req))
+	assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	job := mockQueue.GetJobs()[0]
+	assert.Equal(t, created.Data.Steps[0].CampaignID, job.CampaignID)
+	assert.Equal(t, "Asha", job.TemplateParams["1"])
+
+	require.NoError(t, app.DB.First(&enrollment, enrollment.ID).Error)
+	assert.Equal(t, 1, enrollment.NextStep)
+	require.NotNil(t, enrollment.NextRunAt)
+	assert.WithinDuration(t, enrollment.CreatedAt.Add(24*time.Hour), *enrollment.NextRunAt, time.Second)
+
+	// A reply before the second step takes the contact out
+	require.NoError(t, app.DB.Create(&models.Message{
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "Thanks!",
+	}).Error)
+	require.NoError(t, app.DB.Model(&enrollment).Update("next_run_at", time.Now().Add(-time.Minute)).Error)
+
+	require.Eventually(t, func() bool {
+		app.DB.First(&enrollment, enrollment.ID)
+		return enrollment.Status == models.EnrollmentStatusExited
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, models.SequenceExitReplied, enrollment.ExitReason)
+	assert.Equal(t, 1, mockQueue.JobCount())
+
+	// Step campaigns stay out of the campaign list
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.ListCampaigns(req))
+	var list struct {
+		Data struct {
+			Campaigns []handlers.CampaignResponse `json:"campaigns"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &list))
+	assert.Empty(t, list.Data.Campaigns)
+}
+
+func TestApp_Sequences_RejectsDecreasingDelays(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("sequences-delay")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("sequence-delay-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Backwards",
+		"whatsapp_account": account.Name,
+		"trigger_type":     "api",
+		"steps": []map[string]any{
+			{"delay_minutes": 60, "template_id": template.ID.String()},
+			{"delay_minutes": 30, "template_id": template.ID.String()},
+		},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSequence(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_Sequences_RetriesStepWhenEnqueueFails(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	var mu sync.Mutex
+	var calls [][]*queue.RecipientJob
+	mockQueue.EnqueuesFunc = func(_ context.Context, jobs []*queue.RecipientJob) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, jobs)
+		if len(calls) == 1 {
+			return errors.New("redis unavailable")
+		}
+		return nil
+	}
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("sequences-retry")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("sequence-retry-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Retry",
+		"whatsapp_account": account.Name,
+		"trigger_type":     "api",
+		"is_active":        true,
+		"steps":            []map[string]any{{"delay_minutes": 0, "template_id": template.ID.String()}},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSequence(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var created struct {
+		Data handlers.SequenceResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &created))
+
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	req = testutil.NewJSONRequest(t, map[string]any{"contact_id": contact.ID.String()})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", created.Data.ID.String())
+	require.NoError(t, app.EnrollSequenceContact(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls) >= 2
+	}, 5*time.Second, 20*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, calls[0], 1)
+	require.Len(t, calls[1], 1)
+	assert.Equal(t, calls[0][0].RecipientID, calls[1][0].RecipientID, "the failed step is queued again")
+}
+
+func TestApp_Sequences_ConcurrentEnrollmentsEnrollOnce(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("sequences-race")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("sequence-race-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Race",
+		"whatsapp_account": account.Name,
+		"trigger_type":     "api",
+		"is_active":        true,
+		"steps":            []map[string]any{{"delay_minutes": 60, "template_id": template.ID.String()}},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSequence(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var created struct {
+		Data handlers.SequenceResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &created))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	const attempts = 8
+	statuses := make([]int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := testutil.NewJSONRequest(t, map[string]any{"contact_id": contact.ID.String()})
+			testutil.SetAuthContext(req, org.ID, user.ID)
+			testutil.SetPathParam(req, "id", created.Data.ID.String())
+			assert.NoError(t, app.EnrollSequenceContact(req))
+			statuses[i] = testutil.GetResponseStatusCode(req)
+		}(i)
+	}
+	wg.Wait()
+
+	var ok, conflict int
+	for _, status := range statuses {
+		switch status {
+		case fasthttp.StatusOK:
+			ok++
+		case fasthttp.StatusConflict:
+			conflict++
+		}
+	}
+	assert.Equal(t, 1, ok)
+	assert.Equal(t, attempts-1, conflict, "losing enrollments are reported as already enrolled")
+
+	var active int64
+	require.NoError(t, app.DB.Model(&models.SequenceEnrollment{}).
+		Where("sequence_id = ? AND contact_id = ? AND status = ?", created.Data.ID, contact.ID, models.EnrollmentStatusActive).
+		Count(&active).Error)
+	assert.Equal(t, int64(1), active)
+}
+
+func TestApp_Sequences_EditKeepsDeferredStepRecipients(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("sequences-edit")), testutil.WithSuperAdmin())
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("sequence-edit-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Edited",
+		"whatsapp_account": account.Name,
+		"trigger_type":     "api",
+		"is_active":        true,
+		"steps":            []map[string]any{{"delay_minutes": 0, "template_id": template.ID.String()}},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.CreateSequence(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+	var created struct {
+		Data handlers.SequenceResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &created))
+	stepCampaignID := created.Data.Steps[0].CampaignID
+
+	// A step send waiting for its send window
+	recipient := createTestRecipient(t, app, stepCampaignID, "+15550001111", models.MessageStatusPending)
+	require.NoError(t, app.DB.Model(recipient).Update("send_after", time.Now().Add(-time.Minute)).Error)
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"steps": []map[string]any{{"delay_minutes": 5, "template_id": template.ID.String()}},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", created.Data.ID.String())
+	require.NoError(t, app.UpdateSequence(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var campaign models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&campaign, stepCampaignID).Error)
+	assert.Equal(t, models.CampaignStatusProcessing, campaign.Status, "the old step campaign stays open until drained")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handlers.NewCampaignScheduleProcessor(app, 10*time.Millisecond).Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return mockQueue.JobCount() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, recipient.ID, mockQueue.GetJobs()[0].RecipientID)
+}
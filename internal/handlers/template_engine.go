@@ -154,8 +154,8 @@ func processConditionals(template string, data map[string]any) string {
 // processVariables replaces {{variable}} and {{object.path}} with values
 func processVariables(template string, data map[string]any) string {
 	return variablePattern.ReplaceAllStringFunc(template, func(match string) string {
-		// Remove {{ and }}
-		path := match[2 : len(match)-2]
+		// Remove {{ and }} and any padding inside them
+		path := strings.TrimSpace(match[2 : len(match)-2])
 
 		value := getNestedValue(data, path)
 		return formatValue(value)
@@ -262,6 +262,12 @@ func TestProcessVariables_NestedPath(t *testing.T) {
 	assert.Equal(t, "Hello Alice", processVariables("Hello {{user.profile.name}}", data))
 }
 
+func TestProcessVariables_PaddedPlaceholder(t *testing.T) {
+	t.Parallel()
+	data := map[string]any{"user": map[string]any{"name": "Alice"}}
+	assert.Equal(t, "Hello Alice", processVariables("Hello {{ user.name }}", data))
+}
+
 func TestProcessVariables_ArrayIndex(t *testing.T) {
 	t.Parallel()
 	data := map[string]any{
@@ -761,6 +761,7 @@ func (a *App) processContactSync(phoneNumberID, contactPhone, contactName, actio
 				ContactName:     contact.ProfileName,
 				WhatsAppAccount: account.Name,
 			})
+			a.triggerSequences(account.OrganizationID, contact.ID, models.SequenceTriggerContactCreated, "")
 		}
 	case "remove":
 		// Try to find the contact first using the FindContact helper
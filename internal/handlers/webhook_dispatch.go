@@ -55,6 +55,16 @@ type TransferEventData struct {
 	AgentID         *string               `json:"agent_id,omitempty"`
 	AgentName       *string               `json:"agent_name,omitempty"`
 	WhatsAppAccount string                `json:"whatsapp_account"`
+	Summary         string                `json:"summary,omitempty"` // AI summary, when enabled
+}
+
+// AIBudgetAlertData represents data for AI budget alerts.
+type AIBudgetAlertData struct {
+	Level       string  `json:"level"`  // "soft" or "hard"
+	Period      string  `json:"period"` // YYYY-MM
+	UsedTokens  int64   `json:"used_tokens"`
+	LimitTokens int64   `json:"limit_tokens"`
+	UsedPercent float64 `json:"used_percent"`
 }
 
 // maxConcurrentWebhooks bounds immediate delivery work for a single dispatch.
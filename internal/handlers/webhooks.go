@@ -122,6 +122,7 @@ var AvailableWebhookEvents = []map[string]string{
 	{"value": string(models.WebhookEventTransferCreated), "label": "Transfer Created", "description": "When a transfer to human agent is requested"},
 	{"value": string(models.WebhookEventTransferAssigned), "label": "Transfer Assigned", "description": "When a transfer is assigned to an agent"},
 	{"value": string(models.WebhookEventTransferResumed), "label": "Transfer Resumed", "description": "When chatbot is resumed (transfer closed)"},
+	{"value": string(models.WebhookEventAIBudgetAlert), "label": "AI Budget Alert", "description": "When AI usage crosses the monthly soft or hard limit"},
 }
 
 // ListWebhooks returns all webhooks for the organization
@@ -124,6 +124,7 @@ var widgetDataSources = map[string][]string{
 	"campaigns": {"status", "message_status"},
 	"transfers": {"status", "source"},
 	"sessions":  {"status"},
+	"ai_usage":  {"provider", "model", "feature", "operation", "success", "whats_app_account"},
 }
 
 // Available metrics
@@ -820,6 +821,10 @@ func (a *App) executeWidgetQuery(orgID uuid.UUID, widget models.Widget, fromStr,
 	case "sessions":
 		currentValue = a.querySessions(orgID, widget.Metric, filters, periodStart, periodEnd)
 		previousValue = a.querySessions(orgID, widget.Metric, filters, previousPeriodStart, previousPeriodEnd)
+
+	case "ai_usage":
+		currentValue = a.queryAIUsage(orgID, widget.Metric, widget.Field, filters, periodStart, periodEnd)
+		previousValue = a.queryAIUsage(orgID, widget.Metric, widget.Field, filters, previousPeriodStart, previousPeriodEnd)
 	}
 
 	response.Value = currentValue
@@ -939,6 +944,38 @@ func (a *App) querySessions(orgID uuid.UUID, _ string, filters []FilterInput, st
 	return float64(count)
 }
 
+// queryAIUsage counts AI calls, or sums/averages a token or latency
+// column (total_tokens when no field is given).
+func (a *App) queryAIUsage(orgID uuid.UUID, metric, field string, filters []FilterInput, start, end time.Time) float64 {
+	query := a.DB.Model(&models.AIUsageLog{}).Where("organization_id = ? AND created_at >= ? AND created_at <= ?", orgID, start, end)
+
+	for _, f := range filters {
+		query = applyFilter("ai_usage", query, f)
+	}
+
+	var result float64
+	switch metric {
+	case "count":
+		var count int64
+		query.Count(&count)
+		result = float64(count)
+	case "sum", "avg":
+		if field == "" {
+			field = "total_tokens"
+		}
+		if allowedAggregateFields["ai_usage"][field] {
+			var val float64
+			if metric == "sum" {
+				query.Select("COALESCE(SUM(" + field + "), 0)").Scan(&val)
+			} else {
+				query.Select("COALESCE(AVG(" + field + "), 0)").Scan(&val)
+			}
+			result = val
+		}
+	}
+	return result
+}
+
 func (a *App) getChartData(orgID uuid.UUID, widget models.Widget, filters []FilterInput, start, end time.Time) []ChartPoint {
 	chartData := make([]ChartPoint, 0)
 
@@ -1020,6 +1057,18 @@ var allowedFilterFields = map[string]map[string]bool{
 		"status":  true,
 		"flow_id": true,
 	},
+	"ai_usage": {
+		"provider":          true,
+		"model":             true,
+		"feature":           true,
+		"operation":         true,
+		"success":           true,
+		"whats_app_account": true,
+		"session_id":        true,
+		"flow_id":           true,
+		"contact_id":        true,
+		"user_id":           true,
+	},
 }
 
 // allowedAggregateFields enumerates the columns each data source is
@@ -1030,6 +1079,12 @@ var allowedAggregateFields = map[string]map[string]bool{
 	// Messages have no obvious numeric column to aggregate on today;
 	// leaving empty until a use case appears.
 	"messages": {},
+	"ai_usage": {
+		"prompt_tokens":     true,
+		"completion_tokens": true,
+		"total_tokens":      true,
+		"latency_ms":        true,
+	},
 }
 
 func resolveDataSourceTable(dataSource string) (tableName, dateField string, ok bool) {
@@ -1044,6 +1099,8 @@ func resolveDataSourceTable(dataSource string) (tableName, dateField string, ok
 		return "agent_transfers", "transferred_at", true
 	case "sessions":
 		return "chatbot_sessions", "created_at", true
+	case "ai_usage":
+		return "ai_usage_logs", "created_at", true
 	default:
 		return "", "", false
 	}
@@ -1084,6 +1141,7 @@ func (a *App) getGroupedData(orgID uuid.UUID, widget models.Widget, filters []Fi
 		"message_type": true, "assigned_user_id": true, "channel": true,
 		"is_active": true, "priority": true, "category": true,
 		"type": true, "action_type": true, "provider": true,
+		"model": true, "feature": true, "operation": true,
 	}
 	if !allowedGroupByFields[widget.GroupByField] {
 		a.Log.Error("Invalid GroupByField", "field", widget.GroupByField)
@@ -1385,6 +1443,14 @@ var tableQuerySQL = map[string]struct{ base, orderBy string }{
 			WHERE s.organization_id = ? AND s.created_at >= ? AND s.created_at <= ?`,
 		orderBy: " ORDER BY s.created_at DESC LIMIT 10",
 	},
+	"ai_usage": {
+		base: `SELECT id, provider || ' / ' || model as label,
+			feature || ' · ' || total_tokens || ' tokens' as sub_label,
+			CASE WHEN success THEN 'success' ELSE 'failed' END as status, '' as direction, created_at
+			FROM ai_usage_logs
+			WHERE organization_id = ? AND created_at >= ? AND created_at <= ?`,
+		orderBy: " ORDER BY created_at DESC LIMIT 10",
+	},
 }
 
 // getTableRows returns the last 10 rows for a table widget based on the data source.
@@ -0,0 +1,98 @@
+package knowledge
+
+import (
+	"strings"
+	"sync"
+)
+
+// VectorCache keeps the candidate vectors of recently queried knowledge
+// bases in memory so retrieval doesn't reload them on every message.
+// Entries carry a stamp that describes the state of the knowledge base
+// they were loaded from; a lookup with a different stamp misses, which
+// lets other instances' writes invalidate the cache. The least recently
+// used entries are evicted once more than maxVectors are held.
+type VectorCache struct {
+	mu         sync.Mutex
+	maxVectors int
+	vectors    int
+	clock      uint64
+	entries    map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	stamp      string
+	candidates []Candidate
+	used       uint64
+}
+
+// NewVectorCache returns a cache holding at most maxVectors vectors.
+func NewVectorCache(maxVectors int) *VectorCache {
+	return &VectorCache{maxVectors: maxVectors, entries: map[string]*cacheEntry{}}
+}
+
+// Get returns the candidates cached under key if they were stored with
+// stamp.
+func (c *VectorCache) Get(key, stamp string) ([]Candidate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.stamp != stamp {
+		return nil, false
+	}
+	c.clock++
+	e.used = c.clock
+	return e.candidates, true
+}
+
+// Put caches candidates under key, replacing any older entry. Sets larger
+// than the whole cache are not kept.
+func (c *VectorCache) Put(key, stamp string, candidates []Candidate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	if len(candidates) > c.maxVectors {
+		return
+	}
+	for c.vectors+len(candidates) > c.maxVectors {
+		c.evictOldest()
+	}
+	c.clock++
+	c.entries[key] = &cacheEntry{stamp: stamp, candidates: candidates, used: c.clock}
+	c.vectors += len(candidates)
+}
+
+// InvalidatePrefix drops every entry whose key starts with prefix.
+func (c *VectorCache) InvalidatePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(key)
+		}
+	}
+}
+
+// Len returns the number of cached vectors.
+func (c *VectorCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.vectors
+}
+
+func (c *VectorCache) remove(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.vectors -= len(e.candidates)
+		delete(c.entries, key)
+	}
+}
+
+func (c *VectorCache) evictOldest() {
+	var oldest string
+	var used uint64
+	for key, e := range c.entries {
+		if oldest == "" || e.used < used {
+			oldest, used = key, e.used
+		}
+	}
+	c.remove(oldest)
+}
@@ -0,0 +1,146 @@
+package knowledge
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default chunking parameters, in runes. ~1000 characters keeps a chunk
+// well inside every embedding model's input limit while still holding a
+// complete FAQ answer.
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+var sentenceEndRe = regexp.MustCompile(`([.!?])\s+`)
+
+// Chunk splits text into pieces of at most size runes. Paragraph
+// boundaries are preferred, then sentence boundaries, then word
+// boundaries. Each chunk after the first starts with up to overlap runes
+// from the end of the previous one so facts spanning a boundary are still
+// retrievable.
+func Chunk(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	// Leave room for the overlap tail and its separator so a chunk never
+	// exceeds size.
+	unitMax := size - overlap - 2
+	if unitMax <= 0 {
+		unitMax = size
+	}
+
+	var units []string
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		units = append(units, splitToSize(para, unitMax)...)
+	}
+
+	var chunks []string
+	cur := ""
+	for _, u := range units {
+		if cur != "" && utf8.RuneCountInString(cur)+2+utf8.RuneCountInString(u) > size {
+			chunks = append(chunks, cur)
+			cur = overlapTail(cur, overlap)
+		}
+		if cur != "" {
+			cur += "\n\n"
+		}
+		cur += u
+	}
+	if cur != "" {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
+
+// splitToSize breaks a paragraph that exceeds limit runes into sentences,
+// falling back to word-wrapping for very long sentences.
+func splitToSize(para string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(para) <= limit {
+		return []string{para}
+	}
+
+	sentences := sentenceEndRe.ReplaceAllString(para, "$1\x00")
+	var out []string
+	var cur strings.Builder
+	for _, s := range strings.Split(sentences, "\x00") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		for _, piece := range wrapWords(s, limit) {
+			if cur.Len() > 0 && utf8.RuneCountInString(cur.String())+1+utf8.RuneCountInString(piece) > limit {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+			if cur.Len() > 0 {
+				cur.WriteByte(' ')
+			}
+			cur.WriteString(piece)
+		}
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+// wrapWords splits s on spaces into pieces of at most limit runes. A single
+// word longer than limit is hard-cut.
+func wrapWords(s string, limit int) []string {
+	if utf8.RuneCountInString(s) <= limit {
+		return []string{s}
+	}
+	var out []string
+	var cur []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		for len(w) > limit {
+			if len(cur) > 0 {
+				out = append(out, string(cur))
+				cur = nil
+			}
+			out = append(out, string(w[:limit]))
+			w = w[limit:]
+		}
+		if len(cur) > 0 && len(cur)+1+len(w) > limit {
+			out = append(out, string(cur))
+			cur = nil
+		}
+		if len(cur) > 0 {
+			cur = append(cur, ' ')
+		}
+		cur = append(cur, w...)
+	}
+	if len(cur) > 0 {
+		out = append(out, string(cur))
+	}
+	return out
+}
+
+// overlapTail returns roughly the last n runes of s, starting at a word
+// boundary.
+func overlapTail(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	tail := string(r[len(r)-n:])
+	if idx := strings.IndexAny(tail, " \n"); idx >= 0 && idx < len(tail)-1 {
+		tail = tail[idx+1:]
+	}
+	return strings.TrimSpace(tail)
+}
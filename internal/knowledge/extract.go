@@ -0,0 +1,186 @@
+// Package knowledge turns uploaded documents into retrievable text chunks
+// for the AI knowledge base: format-specific text extraction, chunking with
+// overlap, and cosine-similarity ranking of embedding vectors. Embedding
+// itself is done by the caller through the configured ai.Embedder, and
+// storage lives in the models package, so everything here is pure and
+// unit-testable.
+package knowledge
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Supported document formats.
+const (
+	FormatPDF      = "pdf"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatCSV      = "csv"
+	FormatText     = "text"
+)
+
+// DetectFormat maps a file name to one of the Format* constants, or ""
+// when the extension is not supported.
+func DetectFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return FormatPDF
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm":
+		return FormatHTML
+	case ".csv":
+		return FormatCSV
+	case ".txt":
+		return FormatText
+	default:
+		return ""
+	}
+}
+
+// Extract returns the plain text of a document in the given format.
+func Extract(format string, data []byte) (string, error) {
+	var (
+		text string
+		err  error
+	)
+	switch format {
+	case FormatPDF:
+		text, err = extractPDF(data)
+	case FormatMarkdown, FormatText:
+		text = string(data)
+	case FormatHTML:
+		text, err = extractHTML(data)
+	case FormatCSV:
+		text, err = extractCSV(data)
+	default:
+		return "", fmt.Errorf("unsupported document format: %q", format)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = normalizeWhitespace(text)
+	if text == "" {
+		return "", fmt.Errorf("document contains no extractable text")
+	}
+	return text, nil
+}
+
+var (
+	spaceRunRe     = regexp.MustCompile(`[ \t\f\v\r]+`)
+	blankLineRunRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// normalizeWhitespace collapses runs of spaces and keeps at most one blank
+// line between paragraphs, which is what the chunker splits on.
+func normalizeWhitespace(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = spaceRunRe.ReplaceAllString(s, " ")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	s = strings.Join(lines, "\n")
+	s = blankLineRunRe.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// htmlBlockTags end a paragraph when closed, so headings, list items and
+// table rows don't run together.
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "table": true, "ul": true, "ol": true,
+	"blockquote": true, "pre": true, "hr": true, "dt": true, "dd": true,
+}
+
+// htmlSkipTags contain no user-visible text.
+var htmlSkipTags = map[string]bool{
+	"script": true, "style": true, "head": true, "noscript": true, "template": true,
+}
+
+func extractHTML(data []byte) (string, error) {
+	z := html.NewTokenizer(bytes.NewReader(data))
+	var b strings.Builder
+	skipDepth := 0
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return b.String(), nil
+			}
+			return "", fmt.Errorf("failed to parse HTML: %w", z.Err())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if htmlSkipTags[tag] {
+				skipDepth++
+			}
+			if htmlBlockTags[tag] {
+				b.WriteString("\n\n")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if htmlSkipTags[tag] && skipDepth > 0 {
+				skipDepth--
+			}
+			if htmlBlockTags[tag] {
+				b.WriteString("\n\n")
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.Write(z.Text())
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// extractCSV renders each row as "header: value" pairs, one row per
+// paragraph, so a FAQ spreadsheet chunks along row boundaries.
+func extractCSV(data []byte) (string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return "", nil
+	}
+
+	headers := records[0]
+	var b strings.Builder
+	for _, row := range records[1:] {
+		var parts []string
+		for i, v := range row {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if i < len(headers) && strings.TrimSpace(headers[i]) != "" {
+				parts = append(parts, strings.TrimSpace(headers[i])+": "+v)
+			} else {
+				parts = append(parts, v)
+			}
+		}
+		if len(parts) > 0 {
+			b.WriteString(strings.Join(parts, "\n"))
+			b.WriteString("\n\n")
+		}
+	}
+	return b.String(), nil
+}
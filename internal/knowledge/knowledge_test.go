@@ -0,0 +1,200 @@
+package knowledge_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/knowledge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, knowledge.FormatPDF, knowledge.DetectFormat("Guide.PDF"))
+	assert.Equal(t, knowledge.FormatMarkdown, knowledge.DetectFormat("faq.md"))
+	assert.Equal(t, knowledge.FormatHTML, knowledge.DetectFormat("page.htm"))
+	assert.Equal(t, knowledge.FormatCSV, knowledge.DetectFormat("rows.csv"))
+	assert.Equal(t, "", knowledge.DetectFormat("archive.zip"))
+}
+
+func TestExtract_HTML(t *testing.T) {
+	doc := `<html><head><title>x</title><style>p{color:red}</style></head>
+<body><h1>Returns</h1><p>Items can be returned within <b>30 days</b>.</p>
+<script>alert(1)</script><ul><li>Keep the receipt</li></ul></body></html>`
+
+	text, err := knowledge.Extract(knowledge.FormatHTML, []byte(doc))
+	require.NoError(t, err)
+	assert.Contains(t, text, "Returns")
+	assert.Contains(t, text, "Items can be returned within 30 days")
+	assert.Contains(t, text, "Keep the receipt")
+	assert.NotContains(t, text, "alert")
+	assert.NotContains(t, text, "color:red")
+}
+
+func TestExtract_CSV(t *testing.T) {
+	doc := "question,answer\nWhat are your hours?,9 to 5\nDo you ship abroad?,Yes\n"
+
+	text, err := knowledge.Extract(knowledge.FormatCSV, []byte(doc))
+	require.NoError(t, err)
+	assert.Contains(t, text, "question: What are your hours?\nanswer: 9 to 5")
+	paras := strings.Split(text, "\n\n")
+	assert.Len(t, paras, 2, "one paragraph per row")
+}
+
+func TestExtract_Empty(t *testing.T) {
+	_, err := knowledge.Extract(knowledge.FormatMarkdown, []byte("   \n\n "))
+	assert.Error(t, err)
+
+	_, err = knowledge.Extract("docx", []byte("x"))
+	assert.Error(t, err)
+}
+
+// buildPDF assembles a minimal single-page PDF whose content stream is
+// Flate-compressed.
+func buildPDF(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	b.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	b.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&b, "4 0 obj << /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	b.Write(compressed.Bytes())
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestExtract_PDF(t *testing.T) {
+	content := `BT /F1 12 Tf 72 720 Td (Shipping Policy) Tj 0 -14 Td
+[(Orders ship in ) -50 (2 days) -300 (worldwide.)] TJ
+T* (Escaped \(parens\) work) Tj ET`
+
+	text, err := knowledge.Extract(knowledge.FormatPDF, buildPDF(t, content))
+	require.NoError(t, err)
+	assert.Contains(t, text, "Shipping Policy")
+	assert.Contains(t, text, "Orders ship in 2 days worldwide.")
+	assert.Contains(t, text, "Escaped (parens) work")
+}
+
+func TestExtract_PDFRejectsNonPDF(t *testing.T) {
+	_, err := knowledge.Extract(knowledge.FormatPDF, []byte("hello"))
+	assert.Error(t, err)
+}
+
+func TestChunk_RespectsSizeAndOverlap(t *testing.T) {
+	var paras []string
+	for i := 0; i < 30; i++ {
+		paras = append(paras, fmt.Sprintf("Paragraph %d talks about topic %d in a few words.", i, i))
+	}
+	text := strings.Join(paras, "\n\n")
+
+	chunks := knowledge.Chunk(text, 200, 40)
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(c), 200)
+	}
+	// The second chunk begins with text carried over from the first.
+	tail := chunks[0][len(chunks[0])-10:]
+	assert.Contains(t, chunks[1], tail)
+}
+
+func TestChunk_SplitsLongParagraph(t *testing.T) {
+	long := strings.Repeat("This is one sentence. ", 100)
+	chunks := knowledge.Chunk(long, 300, 0)
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(c), 300)
+		assert.True(t, strings.HasSuffix(c, "."), "splits on sentence boundaries")
+	}
+}
+
+func TestChunk_Short(t *testing.T) {
+	assert.Equal(t, []string{"hello"}, knowledge.Chunk("hello", 0, 0))
+	assert.Empty(t, knowledge.Chunk("", 100, 10))
+}
+
+func TestCosine(t *testing.T) {
+	assert.InDelta(t, 1.0, knowledge.Cosine([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, knowledge.Cosine([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, knowledge.Cosine([]float32{1}, []float32{1, 2}))
+	assert.Equal(t, 0.0, knowledge.Cosine([]float32{0, 0}, []float32{1, 2}))
+}
+
+func TestTopK(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	candidates := []knowledge.Candidate{
+		{ID: a, Vector: []float32{1, 0}},
+		{ID: b, Vector: []float32{0.9, 0.1}},
+		{ID: c, Vector: []float32{0, 1}},
+	}
+
+	matches := knowledge.TopK([]float32{1, 0}, candidates, 2, 0)
+	require.Len(t, matches, 2)
+	assert.Equal(t, a, matches[0].ID)
+	assert.Equal(t, b, matches[1].ID)
+
+	matches = knowledge.TopK([]float32{1, 0}, candidates, 5, 0.999)
+	require.Len(t, matches, 1)
+	assert.Equal(t, a, matches[0].ID)
+}
+
+func TestVectorCache_StampAndInvalidate(t *testing.T) {
+	cache := knowledge.NewVectorCache(10)
+	candidates := []knowledge.Candidate{{ID: uuid.New(), Vector: []float32{1, 0}}}
+
+	cache.Put("org1:model:", "v1", candidates)
+	got, ok := cache.Get("org1:model:", "v1")
+	require.True(t, ok)
+	assert.Equal(t, candidates, got)
+
+	_, ok = cache.Get("org1:model:", "v2")
+	assert.False(t, ok, "a changed knowledge base misses")
+
+	cache.Put("org2:model:", "v1", candidates)
+	cache.InvalidatePrefix("org1:")
+	_, ok = cache.Get("org1:model:", "v1")
+	assert.False(t, ok)
+	_, ok = cache.Get("org2:model:", "v1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestVectorCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := knowledge.NewVectorCache(4)
+	two := func() []knowledge.Candidate {
+		return []knowledge.Candidate{{ID: uuid.New()}, {ID: uuid.New()}}
+	}
+
+	cache.Put("a", "v", two())
+	cache.Put("b", "v", two())
+	_, _ = cache.Get("a", "v")
+	cache.Put("c", "v", two())
+
+	_, ok := cache.Get("b", "v")
+	assert.False(t, ok, "b was used least recently")
+	_, ok = cache.Get("a", "v")
+	assert.True(t, ok)
+	_, ok = cache.Get("c", "v")
+	assert.True(t, ok)
+	assert.Equal(t, 4, cache.Len())
+
+	cache.Put("full", "v", make([]knowledge.Candidate, 4))
+	_, ok = cache.Get("a", "v")
+	assert.False(t, ok, "a full-size entry evicts the rest")
+	assert.Equal(t, 4, cache.Len())
+
+	cache.Put("too-big", "v", make([]knowledge.Candidate, 5))
+	_, ok = cache.Get("too-big", "v")
+	assert.False(t, ok)
+}
@@ -0,0 +1,364 @@
+package knowledge
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFStreamBytes caps the decompressed size of a single content stream.
+const maxPDFStreamBytes = 16 * 1024 * 1024
+
+// extractPDF is a best-effort text extractor for text-based PDFs. It
+// decodes FlateDecode (or unfiltered) content streams and collects the
+// strings shown by the text operators (Tj, TJ, ', "). Fonts with custom
+// CID encodings, scanned pages and encrypted files yield no text; the
+// caller reports that as an extraction error.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", fmt.Errorf("not a PDF file")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", fmt.Errorf("encrypted PDFs are not supported")
+	}
+
+	var out strings.Builder
+	rest := data
+	for {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		// Skip "endstream" matches.
+		if start >= 3 && bytes.Equal(rest[start-3:start], []byte("end")) {
+			rest = rest[start+len("stream"):]
+			continue
+		}
+
+		dictStart := bytes.LastIndex(rest[:start], []byte("<<"))
+		dict := []byte{}
+		if dictStart >= 0 {
+			dict = rest[dictStart:start]
+		}
+
+		bodyStart := start + len("stream")
+		if bodyStart < len(rest) && rest[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart < len(rest) && rest[bodyStart] == '\n' {
+			bodyStart++
+		}
+		end := bytes.Index(rest[bodyStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		body := rest[bodyStart : bodyStart+end]
+		rest = rest[bodyStart+end+len("endstream"):]
+
+		content, ok := decodePDFStream(dict, body)
+		if !ok {
+			continue
+		}
+		if text := pdfContentText(content); strings.TrimSpace(text) != "" {
+			out.WriteString(text)
+			out.WriteString("\n\n")
+		}
+	}
+
+	return out.String(), nil
+}
+
+// decodePDFStream returns the decoded stream body, or false when the
+// stream uses a filter we don't handle or is not a page content stream.
+func decodePDFStream(dict, body []byte) ([]byte, bool) {
+	// Images, fonts and embedded files never carry page text.
+	for _, skip := range []string{"/Image", "/FontFile", "/Length1", "/XRef", "/ObjStm", "/EmbeddedFile"} {
+		if bytes.Contains(dict, []byte(skip)) {
+			return nil, false
+		}
+	}
+
+	switch {
+	case bytes.Contains(dict, []byte("/FlateDecode")):
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, false
+		}
+		defer func() { _ = zr.Close() }()
+		decoded, err := io.ReadAll(io.LimitReader(zr, maxPDFStreamBytes))
+		if err != nil && len(decoded) == 0 {
+			return nil, false
+		}
+		return decoded, true
+	case bytes.Contains(dict, []byte("/Filter")):
+		return nil, false
+	default:
+		return body, true
+	}
+}
+
+// pdfContentText interprets the text-showing operators of a content stream.
+func pdfContentText(content []byte) string {
+	var (
+		out      strings.Builder
+		operands []any
+		inText   bool
+	)
+
+	newline := func() {
+		s := out.String()
+		if len(s) > 0 && !strings.HasSuffix(s, "\n") {
+			out.WriteByte('\n')
+		}
+	}
+
+	i := 0
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := readPDFLiteral(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			// Inline dictionary (e.g. marked-content properties) — skip.
+			end := bytes.Index(content[i:], []byte(">>"))
+			if end < 0 {
+				return out.String()
+			}
+			i += end + 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return out.String()
+			}
+			operands = append(operands, decodePDFHex(content[i+1:i+end]))
+			i += end + 1
+		case c == '[':
+			arr, n := readPDFArray(content[i:])
+			operands = append(operands, arr)
+			i += n
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelim(content[i]) {
+				i++
+			}
+		default:
+			j := i
+			for j < len(content) && !isPDFSpace(content[j]) && !isPDFDelim(content[j]) {
+				j++
+			}
+			if j == i {
+				i++
+				continue
+			}
+			tok := string(content[i:j])
+			i = j
+
+			if f, err := strconv.ParseFloat(tok, 64); err == nil {
+				operands = append(operands, f)
+				continue
+			}
+
+			switch tok {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				newline()
+			case "Tj", "'", "\"":
+				if tok != "Tj" {
+					newline()
+				}
+				if inText && len(operands) > 0 {
+					if s, ok := operands[len(operands)-1].(string); ok {
+						out.WriteString(s)
+					}
+				}
+			case "TJ":
+				if inText && len(operands) > 0 {
+					if arr, ok := operands[len(operands)-1].([]any); ok {
+						for _, el := range arr {
+							switch v := el.(type) {
+							case string:
+								out.WriteString(v)
+							case float64:
+								// Large negative kerning is a word gap.
+								if v < -200 {
+									out.WriteByte(' ')
+								}
+							}
+						}
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 2 {
+					if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+						newline()
+					} else {
+						out.WriteByte(' ')
+					}
+				}
+			case "T*", "Tm":
+				newline()
+			}
+			operands = operands[:0]
+		}
+	}
+
+	return out.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// readPDFLiteral parses a (...) string starting at b[0] and returns the
+// decoded text and the number of bytes consumed.
+func readPDFLiteral(b []byte) (string, int) {
+	var out []byte
+	depth := 0
+	i := 0
+	for i < len(b) {
+		c := b[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePDFText(out), i + 1
+			}
+			out = append(out, c)
+		case '\\':
+			i++
+			if i >= len(b) {
+				break
+			}
+			switch e := b[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation.
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					k := 0
+					for k < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7' {
+						n = n*8 + int(b[i]-'0')
+						i++
+						k++
+					}
+					i--
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+		i++
+	}
+	return decodePDFText(out), len(b)
+}
+
+// readPDFArray parses a [...] operand holding strings and numbers.
+func readPDFArray(b []byte) ([]any, int) {
+	var arr []any
+	i := 1
+	for i < len(b) {
+		c := b[i]
+		switch {
+		case c == ']':
+			return arr, i + 1
+		case isPDFSpace(c):
+			i++
+		case c == '(':
+			s, n := readPDFLiteral(b[i:])
+			arr = append(arr, s)
+			i += n
+		case c == '<':
+			end := bytes.IndexByte(b[i:], '>')
+			if end < 0 {
+				return arr, len(b)
+			}
+			arr = append(arr, decodePDFHex(b[i+1:i+end]))
+			i += end + 1
+		default:
+			j := i
+			for j < len(b) && !isPDFSpace(b[j]) && !isPDFDelim(b[j]) {
+				j++
+			}
+			if j == i {
+				i++
+				continue
+			}
+			if f, err := strconv.ParseFloat(string(b[i:j]), 64); err == nil {
+				arr = append(arr, f)
+			}
+			i = j
+		}
+	}
+	return arr, len(b)
+}
+
+func decodePDFHex(b []byte) string {
+	clean := make([]byte, 0, len(b))
+	for _, c := range b {
+		if !isPDFSpace(c) {
+			clean = append(clean, c)
+		}
+	}
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+	raw, err := hex.DecodeString(string(clean))
+	if err != nil {
+		return ""
+	}
+	return decodePDFText(raw)
+}
+
+// decodePDFText handles UTF-16BE strings (with BOM) and otherwise treats
+// bytes as Latin-1, which covers PDFDocEncoding/WinAnsi for ASCII text.
+func decodePDFText(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		u := make([]uint16, 0, (len(b)-2)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, 0, len(b))
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\t' {
+			continue
+		}
+		r = append(r, rune(c))
+	}
+	return string(r)
+}
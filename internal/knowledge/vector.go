@@ -0,0 +1,59 @@
+package knowledge
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Candidate is a stored chunk embedding considered during retrieval.
+type Candidate struct {
+	ID     uuid.UUID
+	Vector []float32
+}
+
+// Match is a ranked retrieval result.
+type Match struct {
+	ID    uuid.UUID
+	Score float64
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 when the vectors
+// differ in length or either is all zeros.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// TopK ranks candidates by cosine similarity to query and returns the best
+// k with a score of at least minScore, highest first.
+func TopK(query []float32, candidates []Candidate, k int, minScore float64) []Match {
+	if k <= 0 {
+		return nil
+	}
+	matches := make([]Match, 0, len(candidates))
+	for _, c := range candidates {
+		score := Cosine(query, c.Vector)
+		if score >= minScore && score > 0 {
+			matches = append(matches, Match{ID: c.ID, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
@@ -0,0 +1,205 @@
+// Package langutil handles the language codes used for contacts, chatbot
+// flow translations and message templates. Codes follow Meta's template
+// convention: a lowercase base language with an optional uppercase region
+// ("es", "en_US", "pt_BR").
+package langutil
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// Normalize converts a language code into Meta's form ("en-us" -> "en_US").
+// Returns "" when the code isn't a plausible language tag.
+func Normalize(code string) string {
+	code = strings.TrimSpace(strings.ReplaceAll(code, "-", "_"))
+	if code == "" {
+		return ""
+	}
+	base, region, hasRegion := strings.Cut(code, "_")
+	if !isLetters(base, 2, 3) {
+		return ""
+	}
+	base = strings.ToLower(base)
+	if !hasRegion {
+		return base
+	}
+	if !isLetters(region, 2, 4) {
+		return ""
+	}
+	return base + "_" + strings.ToUpper(region)
+}
+
+// Base returns the language without its region ("es_MX" -> "es").
+func Base(code string) string {
+	base, _, _ := strings.Cut(Normalize(code), "_")
+	return base
+}
+
+// Candidates lists the codes to try for lang, most specific first:
+// "es_MX" yields ["es_MX", "es"].
+func Candidates(lang string) []string {
+	lang = Normalize(lang)
+	if lang == "" {
+		return nil
+	}
+	if base := Base(lang); base != lang {
+		return []string{lang, base}
+	}
+	return []string{lang}
+}
+
+// Match returns the index of the entry in available that best fits lang:
+// an exact match first, then the same base language ("es" matches
+// "es_MX" and the other way round). Returns -1 when nothing fits.
+func Match(lang string, available []string) int {
+	lang = Normalize(lang)
+	if lang == "" {
+		return -1
+	}
+	for i, code := range available {
+		if Normalize(code) == lang {
+			return i
+		}
+	}
+	// Prefer a region-less variant over another region of the same base.
+	base := Base(lang)
+	fallback := -1
+	for i, code := range available {
+		if Base(code) != base {
+			continue
+		}
+		if Normalize(code) == base {
+			return i
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// TemplateVariant returns the approved template with the same name and
+// WhatsApp account as t in the language that best fits lang. t itself is
+// returned when lang is empty or no approved variant matches.
+func TemplateVariant(db *gorm.DB, t *models.Template, lang string) *models.Template {
+	if t == nil || Normalize(lang) == "" || Normalize(t.Language) == Normalize(lang) {
+		return t
+	}
+	var variants []models.Template
+	if err := db.Where("organization_id = ? AND whats_app_account = ? AND name = ? AND status = ?",
+		t.OrganizationID, t.WhatsAppAccount, t.Name, "APPROVED").
+		Order("created_at").Find(&variants).Error; err != nil || len(variants) == 0 {
+		return t
+	}
+	languages := make([]string, len(variants))
+	for i, v := range variants {
+		languages[i] = v.Language
+	}
+	if i := Match(lang, languages); i >= 0 {
+		return &variants[i]
+	}
+	return t
+}
+
+// Supported languages for automatic detection.
+const (
+	English = "en"
+	Hindi   = "hi"
+	Spanish = "es"
+)
+
+// Common words that give a language away in short chat messages.
+// Romanized Hindi ("Hinglish") is common on WhatsApp, so it counts
+// toward Hindi as well.
+var detectWords = map[string][]string{
+	English: {
+		"hello", "hey", "thanks", "thank", "you", "please", "want", "need",
+		"what", "where", "when", "how", "is", "are", "the", "my", "order",
+		"help", "can", "would", "good", "morning", "yes", "hi",
+	},
+	Spanish: {
+		"hola", "gracias", "por", "favor", "quiero", "necesito", "qué", "que",
+		"dónde", "donde", "cuándo", "cuando", "cómo", "como", "es", "está",
+		"mi", "pedido", "ayuda", "buenos", "buenas", "días", "dias", "tengo",
+		"sí", "el", "la", "los", "las", "una", "con", "para",
+	},
+	Hindi: {
+		"namaste", "namaskar", "kya", "hai", "hain", "mujhe", "mera", "meri",
+		"chahiye", "nahi", "nahin", "kaise", "kab", "kahan", "aap", "haan",
+		"dhanyavad", "shukriya", "karna", "kar", "ho", "hoga", "bhai", "ji",
+	},
+}
+
+var detectIndex = func() map[string][]string {
+	index := map[string][]string{}
+	for lang, words := range detectWords {
+		for _, w := range words {
+			index[w] = append(index[w], lang)
+		}
+	}
+	return index
+}()
+
+// Detect guesses the language of a chat message. Devanagari script is
+// Hindi; Latin text is scored against common English, Spanish and
+// romanized Hindi words. Returns "" when the text gives no clear signal,
+// so callers can wait for a longer message instead of guessing.
+func Detect(text string) string {
+	var devanagari, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if devanagari > 0 && devanagari >= latin {
+		return Hindi
+	}
+	if latin == 0 {
+		return ""
+	}
+
+	lower := strings.ToLower(text)
+	scores := map[string]int{}
+	if strings.ContainsAny(lower, "ñ¿¡") {
+		scores[Spanish] += 2
+	}
+	words := strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, w := range words {
+		for _, lang := range detectIndex[w] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for _, lang := range []string{English, Spanish, Hindi} {
+		switch s := scores[lang]; {
+		case s > bestScore:
+			best, bestScore, tied = lang, s, false
+		case s == bestScore && s > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
+
+func isLetters(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) || r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package langutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "en_US", Normalize("en-us"))
+	assert.Equal(t, "es", Normalize(" ES "))
+	assert.Equal(t, "pt_BR", Normalize("pt_br"))
+	assert.Equal(t, "", Normalize("e"))
+	assert.Equal(t, "", Normalize("en_1"))
+	assert.Equal(t, "", Normalize(""))
+}
+
+func TestCandidates(t *testing.T) {
+	assert.Equal(t, []string{"es_MX", "es"}, Candidates("es-MX"))
+	assert.Equal(t, []string{"hi"}, Candidates("hi"))
+	assert.Nil(t, Candidates(""))
+}
+
+func TestMatch(t *testing.T) {
+	available := []string{"en_US", "es_MX", "es", "hi"}
+	assert.Equal(t, 3, Match("hi", available))
+	assert.Equal(t, 1, Match("es_MX", available), "exact region wins")
+	assert.Equal(t, 2, Match("es_AR", available), "bare base beats another region")
+	assert.Equal(t, 0, Match("en", available), "base matches a regional variant")
+	assert.Equal(t, -1, Match("fr", available))
+	assert.Equal(t, -1, Match("", available))
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"मुझे मेरा ऑर्डर चाहिए", Hindi},
+		{"Hola, necesito ayuda con mi pedido", Spanish},
+		{"¿Dónde está?", Spanish},
+		{"Hello, where is my order?", English},
+		{"mujhe order ka status chahiye", Hindi},
+		{"12345", ""},
+		{"ok", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Detect(tt.text), tt.text)
+	}
+}
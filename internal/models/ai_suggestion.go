@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SuggestionStatus tracks an AI reply-suggestion request.
+type SuggestionStatus string
+
+const (
+	SuggestionStatusGenerating SuggestionStatus = "generating"
+	SuggestionStatusReady      SuggestionStatus = "ready"
+	SuggestionStatusFailed     SuggestionStatus = "failed"
+)
+
+// AIReplySuggestion is one batch of AI-suggested replies shown to an agent
+// in the chat composer. UsedIndex records which suggestion (if any) the
+// agent sent, for adoption analytics.
+type AIReplySuggestion struct {
+	BaseModel
+	OrganizationID uuid.UUID        `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID      uuid.UUID        `gorm:"type:uuid;index;not null" json:"contact_id"`
+	UserID         uuid.UUID        `gorm:"type:uuid;not null" json:"user_id"`
+	Status         SuggestionStatus `gorm:"size:20;not null" json:"status"`
+	Suggestions    StringArray      `gorm:"type:jsonb;default:'[]'" json:"suggestions"`
+	Error          string           `gorm:"type:text" json:"error,omitempty"`
+	UsedIndex      *int             `json:"used_index,omitempty"`
+	Edited         bool             `gorm:"default:false" json:"edited"` // Agent changed the text before sending
+	UsedAt         *time.Time       `json:"used_at,omitempty"`
+
+	// Relations
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (AIReplySuggestion) TableName() string {
+	return "ai_reply_suggestions"
+}
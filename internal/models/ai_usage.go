@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AIFeature identifies what triggered an AI call, for usage reporting.
+type AIFeature string
+
+const (
+	AIFeatureReply      AIFeature = "reply"      // Chatbot answer (fallback or ai_response node)
+	AIFeatureIntent     AIFeature = "intent"     // Intent classification
+	AIFeatureSummary    AIFeature = "summary"    // Transfer summary note
+	AIFeatureSuggestion AIFeature = "suggestion" // Agent reply suggestions
+	AIFeatureKnowledge  AIFeature = "knowledge"  // Knowledge-base indexing and retrieval
+)
+
+// AIOperation is the kind of provider call.
+type AIOperation string
+
+const (
+	AIOperationCompletion AIOperation = "completion"
+	AIOperationEmbedding  AIOperation = "embedding"
+)
+
+// AIUsageLog records a single call to an AI provider. Calls rejected by the
+// budget never reach the provider and are logged with zero tokens.
+type AIUsageLog struct {
+	ID               uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID   uuid.UUID   `gorm:"type:uuid;not null" json:"organization_id"`
+	WhatsAppAccount  string      `gorm:"size:100" json:"whatsapp_account"`
+	Provider         string      `gorm:"size:50;not null" json:"provider"`
+	Model            string      `gorm:"size:100" json:"model"`
+	Feature          AIFeature   `gorm:"size:20;not null" json:"feature"`
+	Operation        AIOperation `gorm:"size:20;not null" json:"operation"`
+	PromptTokens     int         `gorm:"default:0" json:"prompt_tokens"`
+	CompletionTokens int         `gorm:"default:0" json:"completion_tokens"`
+	TotalTokens      int         `gorm:"default:0" json:"total_tokens"`
+	LatencyMs        int64       `gorm:"default:0" json:"latency_ms"`
+	Success          bool        `gorm:"default:true" json:"success"`
+	Error            string      `gorm:"type:text" json:"error,omitempty"`
+	SessionID        *uuid.UUID  `gorm:"type:uuid" json:"session_id,omitempty"`
+	FlowID           *uuid.UUID  `gorm:"type:uuid" json:"flow_id,omitempty"`
+	ContactID        *uuid.UUID  `gorm:"type:uuid" json:"contact_id,omitempty"`
+	UserID           *uuid.UUID  `gorm:"type:uuid" json:"user_id,omitempty"`
+	CreatedAt        time.Time   `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AIUsageLog) TableName() string {
+	return "ai_usage_logs"
+}
+
+// AIBudget is an organization's monthly AI token allowance. Crossing
+// SoftLimitPercent raises an alert; with HardLimitEnabled, calls are refused
+// once the limit is reached and chatbot replies use FallbackMessage.
+// The cost rates only feed spend estimates in analytics.
+type AIBudget struct {
+	BaseModel
+	OrganizationID       uuid.UUID  `gorm:"type:uuid;uniqueIndex;not null" json:"organization_id"`
+	MonthlyTokenLimit    int64      `gorm:"default:0" json:"monthly_token_limit"` // 0 = unlimited
+	SoftLimitPercent     int        `gorm:"default:80" json:"soft_limit_percent"`
+	HardLimitEnabled     bool       `gorm:"default:false" json:"hard_limit_enabled"`
+	FallbackMessage      string     `gorm:"type:text" json:"fallback_message"`
+	InputCostPerMillion  float64    `gorm:"default:0" json:"input_cost_per_million"`  // USD per 1M prompt tokens
+	OutputCostPerMillion float64    `gorm:"default:0" json:"output_cost_per_million"` // USD per 1M completion tokens
+	SoftAlertPeriod      string     `gorm:"size:7" json:"-"`                          // YYYY-MM of the last soft-limit alert
+	HardAlertPeriod      string     `gorm:"size:7" json:"-"`                          // YYYY-MM of the last hard-limit alert
+	UpdatedByID          *uuid.UUID `gorm:"type:uuid" json:"updated_by_id,omitempty"`
+}
+
+func (AIBudget) TableName() string {
+	return "ai_budgets"
+}
+
+// AIRedactionLog records how much personal data was masked in one AI
+// request. Only counts per detector are stored, never the values.
+type AIRedactionLog struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID  uuid.UUID  `gorm:"type:uuid;not null" json:"organization_id"`
+	WhatsAppAccount string     `gorm:"size:100" json:"whatsapp_account"`
+	Feature         AIFeature  `gorm:"size:20;not null" json:"feature"`
+	Counts          JSONB      `gorm:"type:jsonb;default:'{}'" json:"counts"` // detector -> distinct values masked
+	Total           int        `gorm:"default:0" json:"total"`
+	SessionID       *uuid.UUID `gorm:"type:uuid" json:"session_id,omitempty"`
+	ContactID       *uuid.UUID `gorm:"type:uuid" json:"contact_id,omitempty"`
+	UserID          *uuid.UUID `gorm:"type:uuid" json:"user_id,omitempty"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AIRedactionLog) TableName() string {
+	return "ai_redaction_logs"
+}
@@ -29,12 +29,56 @@ type BulkMessageCampaign struct {
 	CreatedBy            uuid.UUID      `gorm:"type:uuid;not null" json:"created_by"`
 	UpdatedByID          *uuid.UUID     `gorm:"type:uuid" json:"updated_by_id,omitempty"`
 
+	// Segment audience. When set, recipients are resolved from the segment
+	// when the campaign starts; SegmentParams maps template params to
+	// contact fields, e.g. {"1": "{{contact.name}}"}.
+	SegmentID     *uuid.UUID `gorm:"type:uuid;index" json:"segment_id,omitempty"`
+	SegmentParams JSONB      `gorm:"type:jsonb;default:'{}'" json:"segment_params"`
+
+	// A/B test. With two or more Variants, starting the campaign sends each
+	// variant to a random share of ABTestPercent of the audience. When
+	// ABTestEndsAt passes, the variant that did best on ABTestMetric is sent
+	// to the remaining recipients. ABTestEndsAt stays unset while part of
+	// the test slice waits for the send window, and is pushed out when a
+	// deferred part of the slice is sent late.
+	ABTestPercent       int          `gorm:"default:0" json:"ab_test_percent"`
+	ABTestMetric        ABTestMetric `gorm:"size:20" json:"ab_test_metric"`
+	ABTestWindowMinutes int          `gorm:"default:0" json:"ab_test_window_minutes"`
+	ABTestStatus        ABTestStatus `gorm:"size:20;index" json:"ab_test_status"`
+	ABTestEndsAt        *time.Time   `json:"ab_test_ends_at,omitempty"`
+	WinnerVariantID     *uuid.UUID   `gorm:"type:uuid" json:"winner_variant_id,omitempty"`
+
+	// Recurring campaigns. A campaign with a Recurrence (a cron expression,
+	// evaluated in RecurrenceTimezone) isn't sent itself: at each NextRunAt
+	// it starts a copy against its segment, linked by ParentCampaignID.
+	Recurrence         string     `gorm:"size:100" json:"recurrence,omitempty"`
+	RecurrenceTimezone string     `gorm:"size:64" json:"recurrence_timezone,omitempty"`
+	NextRunAt          *time.Time `gorm:"index" json:"next_run_at,omitempty"`
+	ParentCampaignID   *uuid.UUID `gorm:"type:uuid;index" json:"parent_campaign_id,omitempty"`
+
+	// SequenceID is set on the campaigns that carry a sequence step's sends.
+	// They are managed by the sequence and left out of campaign lists.
+	SequenceID *uuid.UUID `gorm:"type:uuid;index" json:"sequence_id,omitempty"`
+
+	// Send window. When SendWindowStart and SendWindowEnd are set ("HH:MM"),
+	// each recipient is only sent to between those times in their own
+	// timezone, taken from their phone number's calling code or from the
+	// contact field TimezoneField. DefaultTimezone covers recipients whose
+	// timezone can't be told.
+	SendWindowStart string         `gorm:"size:5" json:"send_window_start,omitempty"`
+	SendWindowEnd   string         `gorm:"size:5" json:"send_window_end,omitempty"`
+	TimezoneSource  TimezoneSource `gorm:"size:20" json:"timezone_source,omitempty"`
+	TimezoneField   string         `gorm:"size:50" json:"timezone_field,omitempty"`
+	DefaultTimezone string         `gorm:"size:64" json:"default_timezone,omitempty"`
+
 	// Relations
 	Organization *Organization          `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 	Template     *Template              `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+	Segment      *ContactSegment        `gorm:"foreignKey:SegmentID" json:"segment,omitempty"`
 	Creator      *User                  `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
 	UpdatedBy    *User                  `gorm:"foreignKey:UpdatedByID" json:"updated_by,omitempty"`
 	Recipients   []BulkMessageRecipient `gorm:"foreignKey:CampaignID" json:"recipients,omitempty"`
+	Variants     []CampaignVariant      `gorm:"foreignKey:CampaignID" json:"variants,omitempty"`
 }
 
 func (BulkMessageCampaign) TableName() string {
@@ -60,6 +104,13 @@ type BulkMessageRecipient struct {
 	SentAt            *time.Time    `json:"sent_at,omitempty"`
 	DeliveredAt       *time.Time    `json:"delivered_at,omitempty"`
 	ReadAt            *time.Time    `json:"read_at,omitempty"`
+	// VariantID is the A/B test variant sent to this recipient. Nil until
+	// the recipient is picked for the test slice or the winner rollout.
+	VariantID *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"`
+	// Timezone is the recipient's timezone for the campaign's send window.
+	// SendAfter is set while the recipient waits for the window to open.
+	Timezone  string     `gorm:"size:64" json:"timezone,omitempty"`
+	SendAfter *time.Time `gorm:"index" json:"send_after,omitempty"`
 
 	// Relations
 	Campaign *BulkMessageCampaign `gorm:"foreignKey:CampaignID" json:"campaign,omitempty"`
@@ -70,6 +121,42 @@ func (BulkMessageRecipient) TableName() string {
 	return "bulk_message_recipients"
 }
 
+// CampaignVariant is one template an A/B tested campaign tries out.
+type CampaignVariant struct {
+	BaseModel
+	CampaignID uuid.UUID `gorm:"type:uuid;index;not null" json:"campaign_id"`
+	Name       string    `gorm:"size:50;not null" json:"name"` // "A", "B", ...
+	TemplateID uuid.UUID `gorm:"type:uuid;not null" json:"template_id"`
+	Position   int       `gorm:"default:0" json:"position"`
+
+	// Relations
+	Template *Template `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+}
+
+func (CampaignVariant) TableName() string {
+	return "campaign_variants"
+}
+
+// ContactSegment is a saved audience defined by rules over contact fields.
+// Rules are evaluated in SQL each time the segment is used, so membership
+// follows the contacts as they change.
+type ContactSegment struct {
+	BaseModel
+	OrganizationID uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name           string     `gorm:"size:255;not null" json:"name"`
+	Description    string     `gorm:"type:text" json:"description"`
+	Rules          JSONB      `gorm:"type:jsonb;default:'{}'" json:"rules"` // {match: all|any, conditions: [{field, key, operator, value}]}
+	CreatedByID    uuid.UUID  `gorm:"type:uuid" json:"created_by_id"`
+	UpdatedByID    *uuid.UUID `gorm:"type:uuid" json:"updated_by_id,omitempty"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (ContactSegment) TableName() string {
+	return "contact_segments"
+}
+
 // NotificationRule defines automated notification rules
 type NotificationRule struct {
 	BaseModel
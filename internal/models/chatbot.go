@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/crypto"
 )
 
 // BusinessHoursConfig holds business hours settings
@@ -45,14 +46,71 @@ type ClientInactivityConfig struct {
 // AIConfig holds AI provider settings
 type AIConfig struct {
 	Enabled        bool       `gorm:"column:ai_enabled;default:false" json:"ai_enabled"`
-	Provider       AIProvider `gorm:"column:ai_provider;size:20" json:"ai_provider"` // openai, anthropic, google
-	APIKey         string     `gorm:"column:ai_api_key;type:text" json:"-"`          // encrypted
+	Provider       AIProvider `gorm:"column:ai_provider;size:20" json:"ai_provider"`      // openai, anthropic, google, openai_compatible
+	APIKey         string     `gorm:"column:ai_api_key;type:text" json:"-"`               // encrypted
+	BaseURL        string     `gorm:"column:ai_base_url;type:text" json:"ai_base_url"`    // Endpoint root; required for openai_compatible
+	Headers        JSONB      `gorm:"column:ai_headers;type:jsonb;default:'{}'" json:"-"` // Extra request headers (may carry secrets); values encrypted
 	Model          string     `gorm:"column:ai_model;size:100" json:"ai_model"`
 	MaxTokens      int        `gorm:"column:ai_max_tokens;default:500" json:"ai_max_tokens"`
 	Temperature    float64    `gorm:"column:ai_temperature;type:decimal(3,2);default:0.7" json:"ai_temperature"`
 	SystemPrompt   string     `gorm:"column:ai_system_prompt;type:text" json:"ai_system_prompt"`
 	IncludeHistory bool       `gorm:"column:ai_include_history;default:true" json:"ai_include_history"`
 	HistoryLimit   int        `gorm:"column:ai_history_limit;default:4" json:"ai_history_limit"`
+	// Knowledge base retrieval. Disabled while EmbeddingModel is empty.
+	EmbeddingModel string `gorm:"column:ai_embedding_model;size:100" json:"ai_embedding_model"`
+	KnowledgeTopK  int    `gorm:"column:ai_knowledge_top_k;default:4" json:"ai_knowledge_top_k"`
+	// Tool calling: how many model↔tool round trips a single reply may take.
+	MaxToolIterations int `gorm:"column:ai_max_tool_iterations;default:3" json:"ai_max_tool_iterations"`
+	// Intent routing: classify messages against ChatbotIntents before
+	// keyword rules. The embedding method requires EmbeddingModel.
+	IntentRoutingEnabled bool         `gorm:"column:ai_intent_routing_enabled;default:false" json:"ai_intent_routing_enabled"`
+	IntentMethod         IntentMethod `gorm:"column:ai_intent_method;size:20;default:'llm'" json:"ai_intent_method"`
+	IntentThreshold      float64      `gorm:"column:ai_intent_threshold;default:0.7" json:"ai_intent_threshold"`
+	// Summaries: pinned AI note on transfer and resolution.
+	SummariesEnabled bool `gorm:"column:ai_summaries_enabled;default:false" json:"ai_summaries_enabled"`
+	// Reply suggestions for agents in the chat composer.
+	ReplySuggestionsEnabled bool `gorm:"column:ai_reply_suggestions_enabled;default:false" json:"ai_reply_suggestions_enabled"`
+	// PII redaction of conversation data sent to the provider. Types are
+	// built-in detectors (redact.Detectors); patterns are [{name, pattern}].
+	RedactionEnabled  bool        `gorm:"column:ai_redaction_enabled;default:false" json:"ai_redaction_enabled"`
+	RedactionTypes    StringArray `gorm:"column:ai_redaction_types;type:jsonb;default:'[\"card\",\"iban\",\"email\",\"phone\"]'" json:"ai_redaction_types"`
+	RedactionPatterns JSONBArray  `gorm:"column:ai_redaction_patterns;type:jsonb;default:'[]'" json:"ai_redaction_patterns"`
+}
+
+// EncryptSecrets encrypts the API key and the custom header values. Values
+// that are already encrypted are left as they are.
+func (c *AIConfig) EncryptSecrets(encryptionKey string) error {
+	if err := crypto.EncryptFields(encryptionKey, &c.APIKey); err != nil {
+		return err
+	}
+	for k, v := range c.Headers {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if err := crypto.EncryptFields(encryptionKey, &s); err != nil {
+			return err
+		}
+		c.Headers[k] = s
+	}
+	return nil
+}
+
+// DecryptSecrets decrypts the API key and the custom header values.
+func (c *AIConfig) DecryptSecrets(encryptionKey string) {
+	crypto.DecryptFields(encryptionKey, &c.APIKey)
+	if len(c.Headers) == 0 {
+		return
+	}
+	headers := make(JSONB, len(c.Headers))
+	for k, v := range c.Headers {
+		if s, ok := v.(string); ok {
+			crypto.DecryptFields(encryptionKey, &s)
+			v = s
+		}
+		headers[k] = v
+	}
+	c.Headers = headers
 }
 
 // PanelFieldConfig defines a field to display in the contact info panel
@@ -104,6 +162,10 @@ type ChatbotSettings struct {
 	// Session settings
 	SessionTimeoutMins int        `gorm:"default:30" json:"session_timeout_minutes"`
 	ExcludedNumbers    JSONBArray `gorm:"type:jsonb;default:'[]'" json:"excluded_numbers"`
+	// InterruptRules are checked before any other routing, including the
+	// active flow: [{id, keywords, match_type, intent_id, action, flow_id, team_id, message}].
+	// A flow's InterruptRules override these by id while the session is in it.
+	InterruptRules JSONBArray `gorm:"type:jsonb;default:'[]'" json:"interrupt_rules"`
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -124,9 +186,9 @@ type KeywordRule struct {
 	Keywords        StringArray  `gorm:"type:jsonb;not null" json:"keywords"`
 	MatchType       MatchType    `gorm:"size:20;default:'contains'" json:"match_type"` // exact, contains, starts_with, regex
 	CaseSensitive   bool         `gorm:"default:false" json:"case_sensitive"`
-	ResponseType    ResponseType `gorm:"size:20;not null" json:"response_type"` // text, template, media, flow, script
+	ResponseType    ResponseType `gorm:"size:20;not null" json:"response_type"` // text, template, media, flow, chatbot_flow, script, transfer
 	ResponseContent JSONB        `gorm:"type:jsonb;not null" json:"response_content"`
-	Conditions      string       `gorm:"type:text" json:"conditions"`
+	Conditions      string       `gorm:"type:text" json:"conditions"` // expr over contact fields, tags and message
 	ActiveFrom      *time.Time   `json:"active_from,omitempty"`
 	ActiveUntil     *time.Time   `json:"active_until,omitempty"`
 	CreatedByID     *uuid.UUID   `gorm:"type:uuid" json:"created_by_id,omitempty"`
@@ -160,6 +222,7 @@ type ChatbotFlow struct {
 	CompletionConfig   JSONB        `gorm:"type:jsonb" json:"completion_config"`
 	TimeoutMessage     string       `gorm:"type:text" json:"timeout_message"`
 	CancelKeywords     StringArray  `gorm:"type:jsonb" json:"cancel_keywords"`
+	InterruptRules     JSONBArray   `gorm:"type:jsonb;default:'[]'" json:"interrupt_rules"`
 	PanelConfig        JSONB        `gorm:"type:jsonb;default:'{}'" json:"panel_config"` // Contact info panel configuration
 	Graph              JSONB        `gorm:"type:jsonb" json:"graph"`                     // v2 flow graph: {version, nodes, edges, entry_node}
 	CreatedByID        *uuid.UUID   `gorm:"type:uuid" json:"created_by_id,omitempty"`
@@ -223,6 +286,10 @@ type ChatbotSession struct {
 	StartedAt       time.Time     `gorm:"autoCreateTime" json:"started_at"`
 	LastActivityAt  time.Time     `json:"last_activity_at"`
 	CompletedAt     *time.Time    `json:"completed_at,omitempty"`
+	// AwaitingReplyUntil is set on a session started by API outside the
+	// customer service window: until then it waits for the contact's reply
+	// to its opening template, regardless of the session timeout.
+	AwaitingReplyUntil *time.Time `json:"awaiting_reply_until,omitempty"`
 
 	// Relations
 	Organization *Organization           `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -235,6 +302,28 @@ func (ChatbotSession) TableName() string {
 	return "chatbot_sessions"
 }
 
+// ChatbotWakeup schedules the resume of a chatbot session parked at a wait
+// or wait_for_reply node. Rows are claimed by the wake-up processor, so a
+// wait survives restarts.
+type ChatbotWakeup struct {
+	BaseModel
+	OrganizationID      uuid.UUID    `gorm:"type:uuid;index;not null" json:"organization_id"`
+	SessionID           uuid.UUID    `gorm:"type:uuid;index;not null" json:"session_id"`
+	FlowID              uuid.UUID    `gorm:"type:uuid;not null" json:"flow_id"`
+	NodeID              string       `gorm:"size:100;not null" json:"node_id"`
+	WakeAt              time.Time    `gorm:"not null" json:"wake_at"`
+	Status              WakeupStatus `gorm:"size:20;default:'pending'" json:"status"` // pending, in_progress, done, cancelled
+	ProcessingStartedAt *time.Time   `json:"processing_started_at,omitempty"`
+	LastError           string       `gorm:"type:text" json:"last_error,omitempty"`
+
+	// Relations
+	Session *ChatbotSession `gorm:"foreignKey:SessionID" json:"session,omitempty"`
+}
+
+func (ChatbotWakeup) TableName() string {
+	return "chatbot_wakeups"
+}
+
 // ChatbotSessionMessage stores message history within a session
 type ChatbotSessionMessage struct {
 	BaseModel
@@ -243,6 +332,12 @@ type ChatbotSessionMessage struct {
 	Message   string    `gorm:"type:text" json:"message"`
 	StepName  string    `gorm:"size:100" json:"step_name"`
 
+	// Set on AI tool-call rows: Message holds the tool result and
+	// ToolArguments the arguments the model passed.
+	ToolName      string `gorm:"size:100;default:''" json:"tool_name,omitempty"`
+	ToolCallID    string `gorm:"size:100;default:''" json:"tool_call_id,omitempty"`
+	ToolArguments JSONB  `gorm:"type:jsonb" json:"tool_arguments,omitempty"`
+
 	// Relations
 	Session *ChatbotSession `gorm:"foreignKey:SessionID" json:"session,omitempty"`
 }
@@ -276,6 +371,31 @@ func (AIContext) TableName() string {
 	return "ai_contexts"
 }
 
+// AITool is an org-defined function the AI may call mid-conversation. The
+// model sees Name, Description and Parameters; ApiConfig is executed
+// server-side with the same url/method/headers/body templating as the
+// api_call flow node, with the model's arguments available as variables.
+type AITool struct {
+	BaseModel
+	OrganizationID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount string     `gorm:"size:100;index" json:"whatsapp_account"` // References WhatsAppAccount.Name (empty for org-level)
+	Name            string     `gorm:"size:64;not null" json:"name"`           // Function name shown to the model
+	Description     string     `gorm:"type:text" json:"description"`
+	Parameters      JSONB      `gorm:"type:jsonb;default:'{}'" json:"parameters"` // JSON Schema for the arguments
+	ApiConfig       JSONB      `gorm:"type:jsonb;default:'{}'" json:"api_config"` // url, method, headers, body
+	TimeoutSeconds  int        `gorm:"default:10" json:"timeout_seconds"`
+	IsEnabled       bool       `gorm:"default:true" json:"is_enabled"`
+	CreatedByID     *uuid.UUID `gorm:"type:uuid" json:"created_by_id,omitempty"`
+	UpdatedByID     *uuid.UUID `gorm:"type:uuid" json:"updated_by_id,omitempty"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (AITool) TableName() string {
+	return "ai_tools"
+}
+
 // SLATracking holds SLA-related tracking fields for agent transfers
 type SLATracking struct {
 	ResponseDeadline   *time.Time `gorm:"column:sla_response_deadline;index" json:"sla_response_deadline,omitempty"`     // When pickup is due
@@ -298,7 +418,7 @@ type AgentTransfer struct {
 	WhatsAppAccount     string         `gorm:"size:100;index;not null" json:"whatsapp_account"` // References WhatsAppAccount.Name
 	PhoneNumber         string         `gorm:"size:50;not null" json:"phone_number"`
 	Status              TransferStatus `gorm:"size:20;default:'active'" json:"status"` // active, resumed
-	Source              TransferSource `gorm:"size:20;default:'manual'" json:"source"` // manual, flow, keyword, chatbot_disabled
+	Source              TransferSource `gorm:"size:20;default:'manual'" json:"source"` // manual, flow, keyword, chatbot_disabled, intent
 	AgentID             *uuid.UUID     `gorm:"type:uuid" json:"agent_id,omitempty"`
 	TeamID              *uuid.UUID     `gorm:"type:uuid;index" json:"team_id,omitempty"`          // Team queue (null = general queue)
 	TransferredByUserID *uuid.UUID     `gorm:"type:uuid" json:"transferred_by_user_id,omitempty"` // User who initiated the transfer (null for system)
@@ -58,9 +58,10 @@ const (
 type AIProvider string
 
 const (
-	AIProviderOpenAI    AIProvider = "openai"
-	AIProviderAnthropic AIProvider = "anthropic"
-	AIProviderGoogle    AIProvider = "google"
+	AIProviderOpenAI           AIProvider = "openai"
+	AIProviderAnthropic        AIProvider = "anthropic"
+	AIProviderGoogle           AIProvider = "google"
+	AIProviderOpenAICompatible AIProvider = "openai_compatible" // Ollama, vLLM, LocalAI, etc. at a custom base URL
 )
 
 // MatchType represents keyword matching strategies
@@ -77,12 +78,23 @@ const (
 type ResponseType string
 
 const (
-	ResponseTypeText     ResponseType = "text"
-	ResponseTypeTemplate ResponseType = "template"
-	ResponseTypeMedia    ResponseType = "media"
-	ResponseTypeFlow     ResponseType = "flow"
-	ResponseTypeScript   ResponseType = "script"
-	ResponseTypeTransfer ResponseType = "transfer"
+	ResponseTypeText        ResponseType = "text"
+	ResponseTypeTemplate    ResponseType = "template"
+	ResponseTypeMedia       ResponseType = "media"
+	ResponseTypeFlow        ResponseType = "flow" // WhatsApp Flow
+	ResponseTypeScript      ResponseType = "script"
+	ResponseTypeTransfer    ResponseType = "transfer"
+	ResponseTypeChatbotFlow ResponseType = "chatbot_flow"
+)
+
+// WakeupStatus represents the state of a scheduled chatbot session wake-up
+type WakeupStatus string
+
+const (
+	WakeupStatusPending    WakeupStatus = "pending"
+	WakeupStatusInProgress WakeupStatus = "in_progress"
+	WakeupStatusDone       WakeupStatus = "done"
+	WakeupStatusCancelled  WakeupStatus = "cancelled"
 )
 
 // FlowStepType represents chatbot flow step message types
@@ -125,6 +137,27 @@ const (
 	TransferSourceFlow            TransferSource = "flow"
 	TransferSourceKeyword         TransferSource = "keyword"
 	TransferSourceChatbotDisabled TransferSource = "chatbot_disabled"
+	TransferSourceIntent          TransferSource = "intent"
+	TransferSourceTakeover        TransferSource = "takeover"
+)
+
+// LanguageSource records how a contact's language was set. Detection
+// never overrides a language set by a flow or an agent.
+type LanguageSource string
+
+const (
+	LanguageSourceDetected LanguageSource = "detected"
+	LanguageSourceFlow     LanguageSource = "flow"
+	LanguageSourceManual   LanguageSource = "manual"
+)
+
+// NoteSource identifies who wrote a conversation note
+type NoteSource string
+
+const (
+	NoteSourceAgent     NoteSource = "agent"
+	NoteSourceAISummary NoteSource = "ai_summary"
+	NoteSourceChatbot   NoteSource = "chatbot"
 )
 
 // CampaignStatus represents bulk message campaign states
@@ -141,6 +174,62 @@ const (
 	CampaignStatusFailed     CampaignStatus = "failed"
 )
 
+// ABTestStatus tracks a campaign's A/B test
+type ABTestStatus string
+
+const (
+	ABTestStatusNone    ABTestStatus = ""        // Not an A/B test, or not started
+	ABTestStatusTesting ABTestStatus = "testing" // Test slice sent; waiting for the window to end
+	ABTestStatusDecided ABTestStatus = "decided" // Winner picked and sent to the rest of the audience
+)
+
+// ABTestMetric is how A/B test variants are compared
+type ABTestMetric string
+
+const (
+	ABTestMetricReadRate  ABTestMetric = "read_rate"  // Read receipts / sent
+	ABTestMetricReplyRate ABTestMetric = "reply_rate" // Contacts who sent any message / sent
+	ABTestMetricClickRate ABTestMetric = "click_rate" // Contacts who tapped a button / sent
+)
+
+// TimezoneSource is where a campaign reads each recipient's timezone from
+// for its send window
+type TimezoneSource string
+
+const (
+	TimezoneSourcePhone        TimezoneSource = "phone"         // Country calling code of the phone number
+	TimezoneSourceContactField TimezoneSource = "contact_field" // IANA name in a contact field
+)
+
+// SequenceTrigger is the event that enrolls a contact in a campaign sequence
+type SequenceTrigger string
+
+const (
+	SequenceTriggerContactCreated SequenceTrigger = "contact_created"
+	SequenceTriggerTagAdded       SequenceTrigger = "tag_added"      // TriggerValue is the tag name
+	SequenceTriggerFlowCompleted  SequenceTrigger = "flow_completed" // TriggerValue is the chatbot flow ID
+	SequenceTriggerAPI            SequenceTrigger = "api"            // Only enrolled through the API
+)
+
+// EnrollmentStatus tracks a contact's progress through a sequence
+type EnrollmentStatus string
+
+const (
+	EnrollmentStatusActive    EnrollmentStatus = "active"
+	EnrollmentStatusCompleted EnrollmentStatus = "completed" // Every step was sent
+	EnrollmentStatusExited    EnrollmentStatus = "exited"    // Left early; see ExitReason
+)
+
+// Sequence exit reasons
+const (
+	SequenceExitReplied    = "replied"
+	SequenceExitOptedOut   = "opted_out"
+	SequenceExitTagRemoved = "tag_removed"
+	SequenceExitManual     = "manual"
+	SequenceExitDeleted    = "sequence_deleted"
+	SequenceExitNoContact  = "contact_deleted"
+)
+
 // TemplateStatus represents WhatsApp template approval states
 type TemplateStatus string
 
@@ -213,6 +302,7 @@ const (
 	WebhookEventTransferCreated  WebhookEvent = "transfer.created"
 	WebhookEventTransferResumed  WebhookEvent = "transfer.resumed"
 	WebhookEventTransferAssigned WebhookEvent = "transfer.assigned"
+	WebhookEventAIBudgetAlert    WebhookEvent = "ai.budget_alert"
 )
 
 // ActionType represents custom action types
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ContactFieldType is the value type of a custom contact field
+type ContactFieldType string
+
+const (
+	ContactFieldText    ContactFieldType = "text"
+	ContactFieldNumber  ContactFieldType = "number"
+	ContactFieldDate    ContactFieldType = "date"
+	ContactFieldEnum    ContactFieldType = "enum"
+	ContactFieldBoolean ContactFieldType = "boolean"
+	ContactFieldPhone   ContactFieldType = "phone"
+	ContactFieldEmail   ContactFieldType = "email"
+)
+
+// ContactField is an organization-defined contact attribute. Values live in
+// Contact.Metadata under Key; the definition types and validates them
+// wherever metadata is written (API, import, chatbot flows).
+type ContactField struct {
+	BaseModel
+	OrganizationID uuid.UUID        `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Key            string           `gorm:"size:50;not null" json:"key"` // Metadata key; immutable once created
+	Label          string           `gorm:"size:100;not null" json:"label"`
+	Type           ContactFieldType `gorm:"size:20;not null" json:"type"`
+	Options        StringArray      `gorm:"type:jsonb;default:'[]'" json:"options"` // Allowed values for enum fields
+	DefaultValue   string           `gorm:"type:text" json:"default_value"`         // Applied when a contact is created without the field
+	IsRequired     bool             `gorm:"default:false" json:"is_required"`       // Enforced on API create/update and import
+	IsUnique       bool             `gorm:"default:false" json:"is_unique"`         // No two contacts in the org share a value
+	ShowInPanel    bool             `gorm:"default:false" json:"show_in_panel"`     // Shown in the chat contact info panel
+	Position       int              `gorm:"default:0" json:"position"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (ContactField) TableName() string {
+	return "contact_fields"
+}
+
+// IsValidContactFieldType checks if t is a supported field type
+func IsValidContactFieldType(t ContactFieldType) bool {
+	switch t {
+	case ContactFieldText, ContactFieldNumber, ContactFieldDate, ContactFieldEnum,
+		ContactFieldBoolean, ContactFieldPhone, ContactFieldEmail:
+		return true
+	}
+	return false
+}
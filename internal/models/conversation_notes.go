@@ -7,10 +7,13 @@ import (
 // ConversationNote represents a private internal note on a contact, visible only to agents.
 type ConversationNote struct {
 	BaseModel
-	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
-	ContactID      uuid.UUID `gorm:"type:uuid;index;not null" json:"contact_id"`
-	CreatedByID    uuid.UUID `gorm:"type:uuid;not null" json:"created_by_id"`
-	Content        string    `gorm:"type:text;not null" json:"content"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"contact_id"`
+	CreatedByID    *uuid.UUID `gorm:"type:uuid" json:"created_by_id"` // nil for system notes
+	Content        string     `gorm:"type:text;not null" json:"content"`
+	Source         NoteSource `gorm:"size:20;default:'agent'" json:"source"`
+	IsPinned       bool       `gorm:"default:false" json:"is_pinned"`
+	TransferID     *uuid.UUID `gorm:"type:uuid;index" json:"transfer_id,omitempty"` // Set on AI summaries
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
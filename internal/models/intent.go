@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IntentTargetType is what a matched intent routes the conversation to.
+type IntentTargetType string
+
+const (
+	IntentTargetFlow        IntentTargetType = "flow"         // TargetID = ChatbotFlow.ID
+	IntentTargetKeywordRule IntentTargetType = "keyword_rule" // TargetID = KeywordRule.ID
+	IntentTargetTransfer    IntentTargetType = "transfer"     // TargetID = Team.ID, nil for the general queue
+)
+
+// IntentMethod selects how incoming messages are classified.
+type IntentMethod string
+
+const (
+	IntentMethodLLM       IntentMethod = "llm"       // Ask the chat model to pick an intent
+	IntentMethodEmbedding IntentMethod = "embedding" // Cosine similarity against example embeddings
+)
+
+// ChatbotIntent is an admin-defined intent with example utterances. When
+// intent routing is enabled, incoming messages that aren't part of an
+// active flow are classified against the enabled intents before keyword
+// rules are tried.
+type ChatbotIntent struct {
+	BaseModel
+	OrganizationID  uuid.UUID        `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount string           `gorm:"size:100;index" json:"whatsapp_account"` // References WhatsAppAccount.Name (empty for org-level)
+	Name            string           `gorm:"size:100;not null" json:"name"`
+	Description     string           `gorm:"type:text" json:"description"`
+	Examples        StringArray      `gorm:"type:jsonb;default:'[]'" json:"examples"`
+	TargetType      IntentTargetType `gorm:"size:20;not null" json:"target_type"`
+	TargetID        *uuid.UUID       `gorm:"type:uuid" json:"target_id,omitempty"`
+	Message         string           `gorm:"type:text" json:"message"` // Optional text sent before a transfer
+	IsEnabled       bool             `gorm:"default:true" json:"is_enabled"`
+	// Embedding is the normalized mean of the example embeddings, computed
+	// with EmbeddingModel. Cleared when the examples change.
+	Embedding      Vector     `gorm:"type:bytea" json:"-"`
+	EmbeddingModel string     `gorm:"size:100" json:"-"`
+	CreatedByID    *uuid.UUID `gorm:"type:uuid" json:"created_by_id,omitempty"`
+	UpdatedByID    *uuid.UUID `gorm:"type:uuid" json:"updated_by_id,omitempty"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (ChatbotIntent) TableName() string {
+	return "chatbot_intents"
+}
+
+// IntentClassification logs one classification of an incoming message so
+// admins can review misroutes and feed corrected messages back as examples.
+type IntentClassification struct {
+	ID              uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID  uuid.UUID    `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount string       `gorm:"size:100" json:"whatsapp_account"`
+	SessionID       *uuid.UUID   `gorm:"type:uuid" json:"session_id,omitempty"`
+	ContactID       *uuid.UUID   `gorm:"type:uuid" json:"contact_id,omitempty"`
+	Message         string       `gorm:"type:text;not null" json:"message"`
+	Method          IntentMethod `gorm:"size:20;not null" json:"method"`
+	IntentID        *uuid.UUID   `gorm:"type:uuid;index" json:"intent_id,omitempty"` // Best match, even when below threshold
+	IntentName      string       `gorm:"size:100" json:"intent_name"`
+	Confidence      float64      `json:"confidence"`
+	Routed          bool         `gorm:"default:false" json:"routed"` // Confidence met the threshold and the target ran
+	CorrectIntentID *uuid.UUID   `gorm:"type:uuid" json:"correct_intent_id,omitempty"`
+	ReviewedByID    *uuid.UUID   `gorm:"type:uuid" json:"reviewed_by_id,omitempty"`
+	ReviewedAt      *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time    `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+func (IntentClassification) TableName() string {
+	return "intent_classifications"
+}
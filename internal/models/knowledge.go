@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KnowledgeDocumentStatus tracks a knowledge-base document through ingestion.
+type KnowledgeDocumentStatus string
+
+const (
+	KnowledgeDocumentStatusProcessing KnowledgeDocumentStatus = "processing"
+	KnowledgeDocumentStatusReady      KnowledgeDocumentStatus = "ready"
+	KnowledgeDocumentStatusFailed     KnowledgeDocumentStatus = "failed"
+)
+
+// KnowledgeDocument is an uploaded document whose text is chunked and
+// embedded for retrieval-augmented AI responses. The extracted text is kept
+// so the document can be re-embedded when the embedding model changes.
+type KnowledgeDocument struct {
+	BaseModel
+	OrganizationID  uuid.UUID               `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount string                  `gorm:"size:100;index" json:"whatsapp_account"` // References WhatsAppAccount.Name (empty for org-level)
+	Name            string                  `gorm:"size:255;not null" json:"name"`
+	FileName        string                  `gorm:"size:255" json:"file_name"`
+	Format          string                  `gorm:"size:20;not null" json:"format"` // pdf, markdown, html, csv, text
+	SizeBytes       int64                   `json:"size_bytes"`
+	Content         string                  `gorm:"type:text" json:"-"`
+	Status          KnowledgeDocumentStatus `gorm:"size:20;not null;default:'processing'" json:"status"`
+	Error           string                  `gorm:"type:text" json:"error,omitempty"`
+	ChunkCount      int                     `gorm:"default:0" json:"chunk_count"`
+	EmbeddingModel  string                  `gorm:"size:100" json:"embedding_model"`
+	CreatedByID     *uuid.UUID              `gorm:"type:uuid" json:"created_by_id,omitempty"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	CreatedBy    *User         `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+}
+
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// KnowledgeChunk is one embedded slice of a KnowledgeDocument. Chunks are
+// replaced wholesale on re-index, so they are hard-deleted and carry no
+// BaseModel soft-delete column.
+type KnowledgeChunk struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID  uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	DocumentID      uuid.UUID `gorm:"type:uuid;index;not null" json:"document_id"`
+	WhatsAppAccount string    `gorm:"size:100;index" json:"whatsapp_account"`
+	ChunkIndex      int       `gorm:"not null" json:"chunk_index"`
+	Content         string    `gorm:"type:text;not null" json:"content"`
+	Embedding       Vector    `gorm:"type:bytea" json:"-"`
+	EmbeddingModel  string    `gorm:"size:100;index" json:"embedding_model"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// Relations
+	Document *KnowledgeDocument `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
+}
+
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
+
+// Vector is an embedding stored as little-endian float32s in a bytea
+// column. Similarity is computed in Go, so no database extension is needed.
+type Vector []float32
+
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf, nil
+}
+
+func (v *Vector) Scan(value any) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+	buf, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	if len(buf)%4 != 0 {
+		return errors.New("invalid vector length")
+	}
+	out := make(Vector, len(buf)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	*v = out
+	return nil
+}
@@ -394,6 +394,11 @@ type Contact struct {
 	// Business-Scoped User ID (from Meta BSUID rollout)
 	BSUID string `gorm:"size:150;index" json:"bsuid,omitempty"`
 
+	// Preferred language (Meta code such as "es" or "en_US") used to pick
+	// flow translations and template variants.
+	Language       string         `gorm:"size:10" json:"language,omitempty"`
+	LanguageSource LanguageSource `gorm:"size:20" json:"language_source,omitempty"`
+
 	// Chatbot SLA tracking
 	ChatbotLastMessageAt *time.Time `json:"chatbot_last_message_at,omitempty"` // When chatbot last sent a message
 	ChatbotReminderSent  bool       `gorm:"default:false" json:"chatbot_reminder_sent"`
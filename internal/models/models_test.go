@@ -318,3 +318,44 @@ func TestJSONBArray_Scan(t *testing.T) {
 		})
 	}
 }
+
+func TestVector_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	v := models.Vector{0.25, -1.5, 3}
+	raw, err := v.Value()
+	require.NoError(t, err)
+
+	var got models.Vector
+	require.NoError(t, got.Scan(raw))
+	assert.Equal(t, v, got)
+
+	nilVal, err := models.Vector(nil).Value()
+	require.NoError(t, err)
+	assert.Nil(t, nilVal)
+
+	assert.Error(t, got.Scan([]byte{1, 2, 3}), "length must be a multiple of 4")
+	assert.Error(t, got.Scan("not bytes"))
+}
+
+func TestAIConfig_SecretsRoundTrip(t *testing.T) {
+	key := "this-is-a-32-character-test-key-XX"
+	cfg := models.AIConfig{
+		APIKey:  "sk-secret",
+		Headers: models.JSONB{"X-Api-Key": "header-secret", "X-Retries": float64(3)},
+	}
+
+	require.NoError(t, cfg.EncryptSecrets(key))
+	assert.NotEqual(t, "sk-secret", cfg.APIKey)
+	assert.NotEqual(t, "header-secret", cfg.Headers["X-Api-Key"])
+	assert.Equal(t, float64(3), cfg.Headers["X-Retries"])
+
+	// Encrypting again leaves encrypted values alone
+	stored := cfg.Headers["X-Api-Key"]
+	require.NoError(t, cfg.EncryptSecrets(key))
+	assert.Equal(t, stored, cfg.Headers["X-Api-Key"])
+
+	cfg.DecryptSecrets(key)
+	assert.Equal(t, "sk-secret", cfg.APIKey)
+	assert.Equal(t, "header-secret", cfg.Headers["X-Api-Key"])
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CampaignSequence is a drip campaign: a series of template messages sent
+// at offsets from when a contact is enrolled. Contacts are enrolled by
+// TriggerType and leave early on the exit conditions, which are checked
+// before each step is sent.
+type CampaignSequence struct {
+	BaseModel
+	OrganizationID   uuid.UUID       `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount  string          `gorm:"size:100;not null" json:"whatsapp_account"` // References WhatsAppAccount.Name
+	Name             string          `gorm:"size:255;not null" json:"name"`
+	Description      string          `gorm:"type:text" json:"description"`
+	TriggerType      SequenceTrigger `gorm:"size:30;index;not null" json:"trigger_type"`
+	TriggerValue     string          `gorm:"size:255" json:"trigger_value"` // Tag name or flow ID, per TriggerType
+	ExitOnReply      bool            `gorm:"default:false" json:"exit_on_reply"`
+	ExitOnOptOut     bool            `gorm:"not null" json:"exit_on_opt_out"`    // The API defaults this to true
+	ExitOnTagRemoved string          `gorm:"size:50" json:"exit_on_tag_removed"` // Exit once the contact no longer has this tag
+	IsActive         bool            `gorm:"default:false" json:"is_active"`
+	CreatedByID      uuid.UUID       `gorm:"type:uuid" json:"created_by_id"`
+	UpdatedByID      *uuid.UUID      `gorm:"type:uuid" json:"updated_by_id,omitempty"`
+
+	// Relations
+	Steps []CampaignSequenceStep `gorm:"foreignKey:SequenceID" json:"steps,omitempty"`
+}
+
+func (CampaignSequence) TableName() string {
+	return "campaign_sequences"
+}
+
+// CampaignSequenceStep is one message in a sequence. Its sends are
+// recipients of CampaignID, so they go through the campaign queue and
+// worker and get the same delivery stats.
+type CampaignSequenceStep struct {
+	BaseModel
+	SequenceID     uuid.UUID `gorm:"type:uuid;index;not null" json:"sequence_id"`
+	Position       int       `gorm:"default:0" json:"position"`
+	DelayMinutes   int       `gorm:"default:0" json:"delay_minutes"` // After enrollment
+	TemplateID     uuid.UUID `gorm:"type:uuid;not null" json:"template_id"`
+	TemplateParams JSONB     `gorm:"type:jsonb;default:'{}'" json:"template_params"` // Like a campaign's segment_params
+	CampaignID     uuid.UUID `gorm:"type:uuid;not null" json:"campaign_id"`
+
+	// Relations
+	Template *Template            `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+	Campaign *BulkMessageCampaign `gorm:"foreignKey:CampaignID" json:"campaign,omitempty"`
+}
+
+func (CampaignSequenceStep) TableName() string {
+	return "campaign_sequence_steps"
+}
+
+// SequenceEnrollment is a contact's way through a sequence. NextStep is the
+// position of the next step to send, due at NextRunAt.
+type SequenceEnrollment struct {
+	BaseModel
+	OrganizationID uuid.UUID        `gorm:"type:uuid;index;not null" json:"organization_id"`
+	SequenceID     uuid.UUID        `gorm:"type:uuid;index;not null" json:"sequence_id"`
+	ContactID      uuid.UUID        `gorm:"type:uuid;index;not null" json:"contact_id"`
+	Trigger        SequenceTrigger  `gorm:"size:30" json:"trigger"`
+	Status         EnrollmentStatus `gorm:"size:20;not null;default:'active'" json:"status"`
+	NextStep       int              `gorm:"default:0" json:"next_step"`
+	NextRunAt      *time.Time       `json:"next_run_at,omitempty"`
+	FirstSentAt    *time.Time       `json:"first_sent_at,omitempty"` // Replies count from here
+	ExitReason     string           `gorm:"size:50" json:"exit_reason,omitempty"`
+	FinishedAt     *time.Time       `json:"finished_at,omitempty"`
+
+	// Relations
+	Contact *Contact `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+}
+
+func (SequenceEnrollment) TableName() string {
+	return "sequence_enrollments"
+}
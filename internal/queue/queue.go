@@ -28,7 +28,10 @@ type RecipientJob struct {
 	// these headers to one variable). Sent separately from TemplateParams to
 	// avoid positional-key collisions between header and body.
 	HeaderParams models.JSONB `json:"header_params"`
-	EnqueuedAt   time.Time    `json:"enqueued_at"`
+	// VariantID selects an A/B test variant's template instead of the
+	// campaign's own.
+	VariantID  *uuid.UUID `json:"variant_id,omitempty"`
+	EnqueuedAt time.Time  `json:"enqueued_at"`
 }
 
 // Queue defines the interface for job queue operations
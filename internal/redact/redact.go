@@ -0,0 +1,261 @@
+// Package redact masks personal data in text before it is sent to an
+// external AI provider. Each detected value is replaced by a stable
+// placeholder such as [EMAIL_1]; the Redactor keeps the mapping so the
+// model's reply can be re-hydrated with the original values. Only counts
+// per detector are exposed for auditing, never the values themselves.
+package redact
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Built-in detectors.
+const (
+	DetectorCard  = "card"
+	DetectorIBAN  = "iban"
+	DetectorEmail = "email"
+	DetectorPhone = "phone"
+)
+
+// Detectors lists the built-in detectors in the order they run. Cards and
+// IBANs go first so their digits aren't claimed by the phone detector.
+var Detectors = []string{DetectorCard, DetectorIBAN, DetectorEmail, DetectorPhone}
+
+// Pattern is an org-defined detector. Name becomes the placeholder label.
+type Pattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// Config selects the detectors for a Redactor.
+type Config struct {
+	Detectors []string
+	Patterns  []Pattern
+}
+
+var (
+	cardRe  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	ibanRe  = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?:[A-Z0-9]{11,30}|(?: [A-Z0-9]{4}){2,7}(?: [A-Z0-9]{1,4})?)\b`)
+	emailRe = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phoneRe = regexp.MustCompile(`\+?\(?\d[\d ().-]{5,}\d`)
+	dateRe  = regexp.MustCompile(`^\d{1,4}[/.-]\d{1,2}[/.-]\d{1,4}$`)
+	nameRe  = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+type detector struct {
+	label string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+// Redactor replaces personal data with placeholders and restores it
+// later. A nil *Redactor is valid and leaves text unchanged, so callers
+// don't need to branch on whether redaction is enabled. Not safe for
+// concurrent use.
+type Redactor struct {
+	detectors []detector
+	tokens    map[string]string // value -> placeholder
+	values    map[string]string // placeholder -> value
+	counts    map[string]int    // detector name -> distinct values
+	next      map[string]int    // label -> last placeholder number
+}
+
+// New builds a Redactor. Unknown detector names and invalid custom
+// patterns are errors; use ValidatePattern when accepting patterns.
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{
+		tokens: map[string]string{},
+		values: map[string]string{},
+		counts: map[string]int{},
+		next:   map[string]int{},
+	}
+	enabled := map[string]bool{}
+	for _, d := range cfg.Detectors {
+		if !IsDetector(d) {
+			return nil, fmt.Errorf("unknown detector %q", d)
+		}
+		enabled[d] = true
+	}
+	for _, d := range Detectors {
+		if !enabled[d] {
+			continue
+		}
+		switch d {
+		case DetectorCard:
+			r.detectors = append(r.detectors, detector{label: d, re: cardRe, valid: luhnValid})
+		case DetectorIBAN:
+			r.detectors = append(r.detectors, detector{label: d, re: ibanRe, valid: ibanValid})
+		case DetectorEmail:
+			r.detectors = append(r.detectors, detector{label: d, re: emailRe})
+		case DetectorPhone:
+			r.detectors = append(r.detectors, detector{label: d, re: phoneRe, valid: phoneValid})
+		}
+	}
+	for _, p := range cfg.Patterns {
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		r.detectors = append(r.detectors, detector{label: p.Name, re: re})
+	}
+	return r, nil
+}
+
+// IsDetector reports whether name is a built-in detector.
+func IsDetector(name string) bool {
+	for _, d := range Detectors {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePattern checks a custom pattern before it is stored.
+func ValidatePattern(p Pattern) error {
+	_, err := compilePattern(p)
+	return err
+}
+
+func compilePattern(p Pattern) (*regexp.Regexp, error) {
+	if strings.TrimSpace(p.Name) == "" {
+		return nil, fmt.Errorf("pattern name is required")
+	}
+	if p.Pattern == "" {
+		return nil, fmt.Errorf("pattern %q is empty", p.Name)
+	}
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", p.Name, err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("pattern %q matches empty text", p.Name)
+	}
+	return re, nil
+}
+
+// Redact replaces every detected value with its placeholder. The same
+// value always gets the same placeholder within a Redactor.
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	for _, d := range r.detectors {
+		text = d.re.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			return r.placeholder(d.label, match)
+		})
+	}
+	return text
+}
+
+// Restore puts the original values back in place of placeholders.
+func (r *Redactor) Restore(text string) string {
+	if r == nil || len(r.values) == 0 || text == "" {
+		return text
+	}
+	pairs := make([]string, 0, len(r.values)*2)
+	for token, value := range r.values {
+		pairs = append(pairs, token, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Counts returns how many distinct values each detector replaced.
+func (r *Redactor) Counts() map[string]int {
+	if r == nil {
+		return nil
+	}
+	counts := make(map[string]int, len(r.counts))
+	for k, v := range r.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (r *Redactor) placeholder(label, value string) string {
+	if token, ok := r.tokens[value]; ok {
+		return token
+	}
+	name := nameRe.ReplaceAllString(strings.ToUpper(label), "_")
+	r.next[name]++
+	token := fmt.Sprintf("[%s_%d]", strings.Trim(name, "_"), r.next[name])
+	r.tokens[value] = token
+	r.values[token] = value
+	r.counts[label]++
+	return token
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if unicode.IsDigit(c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid reports whether s holds a 13-19 digit number passing the Luhn
+// checksum used by payment cards.
+func luhnValid(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanValid checks the ISO 13616 mod-97 checksum.
+func ibanValid(s string) bool {
+	iban := strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var b strings.Builder
+	for _, c := range rearranged {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			fmt.Fprintf(&b, "%d", c-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// phoneValid accepts 8-15 digit numbers (E.164 allows up to 15) that
+// don't look like dates.
+func phoneValid(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 8 || len(digits) > 15 {
+		return false
+	}
+	return !dateRe.MatchString(strings.TrimSpace(s))
+}
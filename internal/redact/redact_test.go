@@ -0,0 +1,66 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact_BuiltInDetectors(t *testing.T) {
+	r, err := redact.New(redact.Config{Detectors: redact.Detectors})
+	require.NoError(t, err)
+
+	text := "Card 4111 1111 1111 1111, IBAN GB82 WEST 1234 5698 7654 32, mail asha@example.com, call +44 20 7946 0958"
+	redacted := r.Redact(text)
+
+	assert.Equal(t, "Card [CARD_1], IBAN [IBAN_1], mail [EMAIL_1], call [PHONE_1]", redacted)
+	assert.Equal(t, map[string]int{"card": 1, "iban": 1, "email": 1, "phone": 1}, r.Counts())
+	assert.Equal(t, text, r.Restore(redacted))
+}
+
+func TestRedact_ChecksumsAndFalsePositives(t *testing.T) {
+	r, err := redact.New(redact.Config{Detectors: []string{redact.DetectorCard, redact.DetectorIBAN}})
+	require.NoError(t, err)
+	assert.Equal(t, "ref 4111 1111 1111 1112", r.Redact("ref 4111 1111 1111 1112"), "fails Luhn")
+	assert.Equal(t, "GB00 WEST 1234 5698 7654 32", r.Redact("GB00 WEST 1234 5698 7654 32"), "fails mod-97")
+	assert.Empty(t, r.Counts())
+
+	phones, err := redact.New(redact.Config{Detectors: []string{redact.DetectorPhone}})
+	require.NoError(t, err)
+	assert.Equal(t, "on 2026-10-18, order 12345", phones.Redact("on 2026-10-18, order 12345"))
+	assert.Empty(t, phones.Counts())
+}
+
+func TestRedact_StablePlaceholders(t *testing.T) {
+	r, err := redact.New(redact.Config{Detectors: []string{redact.DetectorEmail}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "[EMAIL_1] and [EMAIL_2]", r.Redact("a@x.io and b@x.io"))
+	assert.Equal(t, "again [EMAIL_1]", r.Redact("again a@x.io"), "same value, same placeholder across calls")
+	assert.Equal(t, map[string]int{"email": 2}, r.Counts())
+}
+
+func TestRedact_CustomPatterns(t *testing.T) {
+	r, err := redact.New(redact.Config{Patterns: []redact.Pattern{{Name: "Aadhaar id", Pattern: `\b\d{4} \d{4} \d{4}\b`}}})
+	require.NoError(t, err)
+
+	redacted := r.Redact("id 1234 5678 9012")
+	assert.Equal(t, "id [AADHAAR_ID_1]", redacted)
+	assert.Equal(t, map[string]int{"Aadhaar id": 1}, r.Counts())
+	assert.Equal(t, "Your id 1234 5678 9012 is verified", r.Restore("Your id [AADHAAR_ID_1] is verified"))
+
+	assert.Error(t, redact.ValidatePattern(redact.Pattern{Name: "bad", Pattern: "("}))
+	assert.Error(t, redact.ValidatePattern(redact.Pattern{Name: "empty", Pattern: "x*"}))
+	assert.Error(t, redact.ValidatePattern(redact.Pattern{Pattern: `\d+`}))
+	_, err = redact.New(redact.Config{Detectors: []string{"ssn"}})
+	assert.Error(t, err)
+}
+
+func TestRedact_NilRedactor(t *testing.T) {
+	var r *redact.Redactor
+	assert.Equal(t, "a@x.io", r.Redact("a@x.io"))
+	assert.Equal(t, "[EMAIL_1]", r.Restore("[EMAIL_1]"))
+	assert.Nil(t, r.Counts())
+}
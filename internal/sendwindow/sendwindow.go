@@ -0,0 +1,109 @@
+// Package sendwindow works out when campaign messages may be sent to a
+// recipient: within a daily window of local time in the recipient's own
+// timezone.
+package sendwindow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/utils"
+)
+
+// Window is a daily range of local time, in minutes after midnight. A
+// window whose end is before its start runs past midnight.
+type Window struct {
+	start, end int
+}
+
+// Parse parses a window from "HH:MM" start and end times.
+func Parse(start, end string) (*Window, error) {
+	s, err := parseClock(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start %q: %w", start, err)
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end %q: %w", end, err)
+	}
+	if s == e {
+		return nil, fmt.Errorf("start and end can't be the same")
+	}
+	return &Window{start: s, end: e}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t's local time, in t's location, is inside the
+// window.
+func (w *Window) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// Next returns t if it's inside the window, or else when the window next
+// opens, in t's location.
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	y, mo, d := t.Date()
+	open := time.Date(y, mo, d, w.start/60, w.start%60, 0, 0, t.Location())
+	if !open.After(t) {
+		open = time.Date(y, mo, d+1, w.start/60, w.start%60, 0, 0, t.Location())
+	}
+	return open
+}
+
+// ForCampaign returns a campaign's send window, or nil if it has none.
+func ForCampaign(c *models.BulkMessageCampaign) (*Window, error) {
+	if c.SendWindowStart == "" && c.SendWindowEnd == "" {
+		return nil, nil
+	}
+	return Parse(c.SendWindowStart, c.SendWindowEnd)
+}
+
+// RecipientTimezone is a recipient's timezone name for campaign c. metadata
+// is the recipient's contact metadata, or nil when there is no contact.
+// Falls back to the campaign's DefaultTimezone, then UTC.
+func RecipientTimezone(c *models.BulkMessageCampaign, phone string, metadata models.JSONB) string {
+	var tz string
+	switch c.TimezoneSource {
+	case models.TimezoneSourceContactField:
+		if s, ok := metadata[c.TimezoneField].(string); ok {
+			if _, err := time.LoadLocation(s); err == nil && s != "" {
+				tz = s
+			}
+		}
+	default:
+		tz = utils.TimezoneForPhone(phone)
+	}
+	if tz == "" {
+		tz = c.DefaultTimezone
+	}
+	if tz == "" {
+		tz = "UTC"
+	}
+	return tz
+}
+
+// Location loads a timezone by name, falling back to UTC when it isn't
+// known.
+func Location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
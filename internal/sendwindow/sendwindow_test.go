@@ -0,0 +1,58 @@
+package sendwindow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow_Next(t *testing.T) {
+	w, err := Parse("09:00", "20:00")
+	require.NoError(t, err)
+
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 18, h, m, 0, 0, time.UTC) }
+	assert.Equal(t, at(10, 30), w.Next(at(10, 30)), "inside")
+	assert.Equal(t, at(9, 0), w.Next(at(3, 0)), "before it opens")
+	assert.Equal(t, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), w.Next(at(20, 0)), "after it closes")
+
+	overnight, err := Parse("22:00", "06:00")
+	require.NoError(t, err)
+	assert.True(t, overnight.Contains(at(23, 0)))
+	assert.True(t, overnight.Contains(at(5, 59)))
+	assert.Equal(t, at(22, 0), overnight.Next(at(12, 0)))
+}
+
+func TestWindow_NextInLocation(t *testing.T) {
+	w, err := Parse("09:00", "20:00")
+	require.NoError(t, err)
+	ist := time.FixedZone("IST", 5*3600+1800)
+
+	// 02:00 UTC is 07:30 in India: wait until 09:00 there
+	next := w.Next(time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC).In(ist))
+	assert.Equal(t, time.Date(2026, 10, 18, 3, 30, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for _, tt := range [][2]string{{"9", "20:00"}, {"09:00", "24:00"}, {"09:00", "09:00"}, {"", "20:00"}} {
+		_, err := Parse(tt[0], tt[1])
+		assert.Error(t, err, tt)
+	}
+}
+
+func TestRecipientTimezone(t *testing.T) {
+	campaign := &models.BulkMessageCampaign{DefaultTimezone: "Europe/Paris"}
+	assert.Equal(t, "Asia/Kolkata", RecipientTimezone(campaign, "919876543210", nil))
+	assert.Equal(t, "Asia/Dubai", RecipientTimezone(campaign, "+971 50 123 4567", nil))
+	assert.Equal(t, "Europe/Paris", RecipientTimezone(campaign, "999123", nil), "unknown code")
+
+	campaign.TimezoneSource = models.TimezoneSourceContactField
+	campaign.TimezoneField = "tz"
+	assert.Equal(t, "America/Chicago", RecipientTimezone(campaign, "919876543210", models.JSONB{"tz": "America/Chicago"}))
+	assert.Equal(t, "Europe/Paris", RecipientTimezone(campaign, "919876543210", models.JSONB{"tz": "Nowhere/Else"}))
+
+	campaign.DefaultTimezone = ""
+	assert.Equal(t, "UTC", RecipientTimezone(campaign, "919876543210", nil))
+}
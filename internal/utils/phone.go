@@ -34,3 +34,70 @@ func MaskIfPhoneNumber(s string) string {
 	}
 	return s
 }
+
+// callingCodeTimezones maps country calling codes to the timezone most of
+// the country lives in. Countries spanning several zones (the US and
+// Canada, Russia, Brazil, Australia, Mexico, Indonesia) get one
+// representative zone, so this is an estimate for those.
+var callingCodeTimezones = map[string]string{
+	"1": "America/New_York", "7": "Europe/Moscow",
+	"20": "Africa/Cairo", "27": "Africa/Johannesburg", "30": "Europe/Athens",
+	"31": "Europe/Amsterdam", "32": "Europe/Brussels", "33": "Europe/Paris",
+	"34": "Europe/Madrid", "36": "Europe/Budapest", "39": "Europe/Rome",
+	"40": "Europe/Bucharest", "41": "Europe/Zurich", "43": "Europe/Vienna",
+	"44": "Europe/London", "45": "Europe/Copenhagen", "46": "Europe/Stockholm",
+	"47": "Europe/Oslo", "48": "Europe/Warsaw", "49": "Europe/Berlin",
+	"51": "America/Lima", "52": "America/Mexico_City", "53": "America/Havana",
+	"54": "America/Argentina/Buenos_Aires", "55": "America/Sao_Paulo",
+	"56": "America/Santiago", "57": "America/Bogota", "58": "America/Caracas",
+	"60": "Asia/Kuala_Lumpur", "61": "Australia/Sydney", "62": "Asia/Jakarta",
+	"63": "Asia/Manila", "64": "Pacific/Auckland", "65": "Asia/Singapore",
+	"66": "Asia/Bangkok", "81": "Asia/Tokyo", "82": "Asia/Seoul",
+	"84": "Asia/Ho_Chi_Minh", "86": "Asia/Shanghai", "90": "Europe/Istanbul",
+	"91": "Asia/Kolkata", "92": "Asia/Karachi", "93": "Asia/Kabul",
+	"94": "Asia/Colombo", "95": "Asia/Yangon", "98": "Asia/Tehran",
+	"211": "Africa/Juba", "212": "Africa/Casablanca", "213": "Africa/Algiers",
+	"216": "Africa/Tunis", "218": "Africa/Tripoli", "220": "Africa/Banjul",
+	"221": "Africa/Dakar", "225": "Africa/Abidjan", "233": "Africa/Accra",
+	"234": "Africa/Lagos", "237": "Africa/Douala", "243": "Africa/Kinshasa",
+	"244": "Africa/Luanda", "249": "Africa/Khartoum", "250": "Africa/Kigali",
+	"251": "Africa/Addis_Ababa", "254": "Africa/Nairobi", "255": "Africa/Dar_es_Salaam",
+	"256": "Africa/Kampala", "260": "Africa/Lusaka", "263": "Africa/Harare",
+	"351": "Europe/Lisbon", "352": "Europe/Luxembourg", "353": "Europe/Dublin",
+	"358": "Europe/Helsinki", "359": "Europe/Sofia", "370": "Europe/Vilnius",
+	"371": "Europe/Riga", "372": "Europe/Tallinn", "380": "Europe/Kyiv",
+	"381": "Europe/Belgrade", "385": "Europe/Zagreb", "420": "Europe/Prague",
+	"421": "Europe/Bratislava", "593": "America/Guayaquil", "595": "America/Asuncion",
+	"598": "America/Montevideo", "852": "Asia/Hong_Kong", "855": "Asia/Phnom_Penh",
+	"880": "Asia/Dhaka", "886": "Asia/Taipei", "960": "Indian/Maldives",
+	"961": "Asia/Beirut", "962": "Asia/Amman", "964": "Asia/Baghdad",
+	"965": "Asia/Kuwait", "966": "Asia/Riyadh", "967": "Asia/Aden",
+	"968": "Asia/Muscat", "970": "Asia/Gaza", "971": "Asia/Dubai",
+	"972": "Asia/Jerusalem", "973": "Asia/Bahrain", "974": "Asia/Qatar",
+	"975": "Asia/Thimphu", "977": "Asia/Kathmandu", "992": "Asia/Dushanbe",
+	"994": "Asia/Baku", "995": "Asia/Tbilisi", "996": "Asia/Bishkek",
+	"998": "Asia/Tashkent",
+}
+
+// TimezoneForPhone returns the IANA timezone for an international phone
+// number's country calling code, or "" when the code isn't known.
+func TimezoneForPhone(phone string) string {
+	digits := make([]byte, 0, len(phone))
+	for i := 0; i < len(phone); i++ {
+		if phone[i] >= '0' && phone[i] <= '9' {
+			digits = append(digits, phone[i])
+		}
+	}
+	s := string(digits)
+	if len(s) > 2 && s[:2] == "00" {
+		s = s[2:]
+	}
+	for n := 3; n >= 1; n-- {
+		if len(s) > n {
+			if tz, ok := callingCodeTimezones[s[:n]]; ok {
+				return tz
+			}
+		}
+	}
+	return ""
+}
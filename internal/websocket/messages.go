@@ -29,6 +29,9 @@ const (
 	// Campaign types
 	TypeCampaignStatsUpdate = "campaign_stats_update"
 
+	// Chatbot session types
+	TypeChatbotSessionUpdate = "chatbot_session_update"
+
 	// Permission types
 	TypePermissionsUpdated = "permissions_updated"
 
@@ -37,6 +40,12 @@ const (
 	TypeConversationNoteUpdated = "conversation_note_updated"
 	TypeConversationNoteDeleted = "conversation_note_deleted"
 
+	// AI reply suggestion types
+	TypeAIReplySuggestions = "ai_reply_suggestions"
+
+	// AI budget types
+	TypeAIBudgetAlert = "ai_budget_alert"
+
 	// Call types
 	TypeCallIncoming = "call_incoming"
 	TypeCallAnswered = "call_answered"
@@ -10,8 +10,10 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/shridarpatil/whatomate/internal/config"
 	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/langutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/internal/sendwindow"
 	"github.com/shridarpatil/whatomate/internal/templateutil"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 	"github.com/zerodha/logf"
@@ -99,8 +101,27 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 		return nil // Don't retry
 	}
 
+	// Outside the recipient's send window, e.g. after waiting in the queue,
+	// defer them until it opens again
+	if w.deferOutsideSendWindow(&campaign, job, contact) {
+		return nil
+	}
+
+	// A/B tested campaigns send the recipient's variant template
+	baseTemplate := campaign.Template
+	if job.VariantID != nil {
+		var variant models.CampaignVariant
+		if err := w.DB.Where("id = ? AND campaign_id = ?", *job.VariantID, job.CampaignID).Preload("Template").First(&variant).Error; err != nil || variant.Template == nil {
+			w.Log.Error("Failed to load campaign variant", "error", err, "variant_id", *job.VariantID)
+			w.updateRecipientStatus(job.RecipientID, models.MessageStatusFailed, "", "Campaign variant template not found")
+			w.incrementCampaignCount(job.CampaignID, "failed_count")
+			return nil
+		}
+		baseTemplate = variant.Template
+	}
+
 	// Check marketing opt-out
-	if contact.MarketingOptOut && campaign.Template != nil && strings.EqualFold(campaign.Template.Category, "MARKETING") {
+	if contact.MarketingOptOut && baseTemplate != nil && strings.EqualFold(baseTemplate.Category, "MARKETING") {
 		w.Log.Info("Skipping marketing message for opted-out contact", "contact_id", contact.ID, "phone", job.PhoneNumber)
 		w.updateRecipientStatus(job.RecipientID, models.MessageStatusFailed, "", "Contact opted out of marketing messages")
 		w.incrementCampaignCount(job.CampaignID, "failed_count")
@@ -115,8 +136,9 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 		HeaderParams:   job.HeaderParams,
 	}
 
-	// Send template message
-	waMessageID, err := w.sendTemplateMessage(ctx, &account, campaign.Template, recipient, campaign.HeaderMediaID, campaign.HeaderMediaFilename)
+	// Send template message, in the contact's language when an approved variant exists
+	template := langutil.TemplateVariant(w.DB, baseTemplate, contact.Language)
+	waMessageID, err := w.sendTemplateMessage(ctx, &account, template, recipient, campaign.HeaderMediaID, campaign.HeaderMediaFilename)
 
 	// Create Message record
 	message := models.Message{
@@ -132,9 +154,12 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 			"recipient_name": job.RecipientName,
 		},
 	}
-	if campaign.Template != nil {
-		message.TemplateName = campaign.Template.Name
-		content := templateutil.ReplaceWithJSONBParams(campaign.Template.BodyContent, campaign.Template.BodyContent, job.TemplateParams)
+	if job.VariantID != nil {
+		message.Metadata["variant_id"] = job.VariantID.String()
+	}
+	if template != nil {
+		message.TemplateName = template.Name
+		content := templateutil.ReplaceWithJSONBParams(template.BodyContent, template.BodyContent, job.TemplateParams)
 		message.Content = content
 		// Store campaign header media so it renders in the chat bubble
 		if campaign.HeaderMediaLocalPath != "" {
@@ -167,6 +192,35 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 	return nil
 }
 
+// deferOutsideSendWindow reports whether the campaign's send window is
+// closed for the recipient, in which case it is given a SendAfter for the
+// schedule processor to queue it again.
+func (w *Worker) deferOutsideSendWindow(campaign *models.BulkMessageCampaign, job *queue.RecipientJob, contact *models.Contact) bool {
+	window, err := sendwindow.ForCampaign(campaign)
+	if err != nil || window == nil {
+		return false
+	}
+
+	var recipient models.BulkMessageRecipient
+	if err := w.DB.Select("id", "timezone").Where("id = ?", job.RecipientID).First(&recipient).Error; err != nil {
+		return false
+	}
+	tz := recipient.Timezone
+	if tz == "" {
+		tz = sendwindow.RecipientTimezone(campaign, job.PhoneNumber, contact.Metadata)
+	}
+
+	now := time.Now()
+	next := window.Next(now.In(sendwindow.Location(tz)))
+	if !next.After(now) {
+		return false
+	}
+	w.DB.Model(&models.BulkMessageRecipient{}).Where("id = ?", job.RecipientID).
+		Updates(map[string]any{"timezone": tz, "send_after": next})
+	w.Log.Info("Outside send window, deferring recipient", "campaign_id", job.CampaignID, "recipient_id", job.RecipientID, "send_after", next)
+	return true
+}
+
 // updateRecipientStatus updates the recipient's status in the database
 func (w *Worker) updateRecipientStatus(recipientID uuid.UUID, status models.MessageStatus, waMessageID, errorMsg string) {
 	updates := map[string]any{
@@ -222,10 +276,15 @@ func (w *Worker) checkCampaignCompletion(ctx context.Context, campaignID, organi
 			return
 		}
 
-		// Only complete if currently processing
+		// Only complete if currently processing. Sequence step campaigns
+		// stay open while their step exists, as contacts are enrolled.
 		if campaign.Status != models.CampaignStatusProcessing {
 			return
 		}
+		if campaign.SequenceID != nil && w.hasSequenceStep(campaignID) {
+			w.publishCampaignStats(ctx, campaignID, organizationID)
+			return
+		}
 
 		now := time.Now()
 		w.DB.Model(&campaign).Updates(map[string]any{
@@ -251,6 +310,16 @@ func (w *Worker) checkCampaignCompletion(ctx context.Context, campaignID, organi
 	}
 }
 
+// hasSequenceStep reports whether a sequence step still sends through the
+// campaign. Steps are removed when their sequence is edited or deleted.
+func (w *Worker) hasSequenceStep(campaignID uuid.UUID) bool {
+	var count int64
+	if err := w.DB.Model(&models.CampaignSequenceStep{}).Where("campaign_id = ?", campaignID).Count(&count).Error; err != nil {
+		return true
+	}
+	return count > 0
+}
+
 // sendTemplateMessage sends a template message via WhatsApp Cloud API
 func (w *Worker) sendTemplateMessage(ctx context.Context, account *models.WhatsAppAccount, template *models.Template, recipient *models.BulkMessageRecipient, campaignHeaderMediaID, campaignHeaderMediaFilename string) (string, error) {
 	waAccount := account.ToWAAccount()
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/config"
@@ -205,6 +206,35 @@ func TestWorker_HandleRecipientJob_CampaignCancelled(t *testing.T) {
 	assert.Equal(t, models.MessageStatusPending, updatedRecipient.Status)
 }
 
+func TestWorker_HandleRecipientJob_OutsideSendWindow(t *testing.T) {
+	w := testWorker(t)
+	org, _, _, campaign, recipient := createTestCampaignData(t, w)
+
+	// A window that opens two hours from now, UTC
+	now := time.Now().UTC()
+	require.NoError(t, w.DB.Model(campaign).Updates(map[string]any{
+		"send_window_start": now.Add(2 * time.Hour).Format("15:04"),
+		"send_window_end":   now.Add(3 * time.Hour).Format("15:04"),
+	}).Error)
+	require.NoError(t, w.DB.Model(recipient).Update("timezone", "UTC").Error)
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		RecipientName:  recipient.RecipientName,
+	}
+	require.NoError(t, w.HandleRecipientJob(context.Background(), job))
+
+	// Not sent: it waits for the window
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusPending, updated.Status)
+	require.NotNil(t, updated.SendAfter)
+	assert.WithinDuration(t, now.Add(2*time.Hour), *updated.SendAfter, time.Minute)
+}
+
 func TestWorker_HandleRecipientJob_AccountNotFound(t *testing.T) {
 	w := testWorker(t)
 	org, _, _, campaign, recipient := createTestCampaignData(t, w)
@@ -451,6 +481,40 @@ func TestWorker_checkCampaignCompletion_NotProcessingStatus(t *testing.T) {
 	assert.Equal(t, models.CampaignStatusPaused, updated.Status)
 }
 
+func TestWorker_checkCampaignCompletion_SequenceCampaignStaysOpen(t *testing.T) {
+	w := testWorker(t)
+
+	org, user, template, campaign := createMinimalCampaignData(t, w, models.CampaignStatusProcessing)
+	sequence := &models.CampaignSequence{
+		OrganizationID:  org.ID,
+		WhatsAppAccount: campaign.WhatsAppAccount,
+		Name:            "Drip",
+		TriggerType:     models.SequenceTriggerAPI,
+		CreatedByID:     user.ID,
+	}
+	require.NoError(t, w.DB.Create(sequence).Error)
+	step := &models.CampaignSequenceStep{SequenceID: sequence.ID, TemplateID: template.ID, CampaignID: campaign.ID}
+	require.NoError(t, w.DB.Create(step).Error)
+	require.NoError(t, w.DB.Model(campaign).Update("sequence_id", sequence.ID).Error)
+
+	// No pending recipients, but the sequence may add more later
+	w.checkCampaignCompletion(context.Background(), campaign.ID, org.ID)
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, w.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusProcessing, updated.Status)
+	assert.Nil(t, updated.CompletedAt)
+
+	// Once the sequence is edited the step is gone and the campaign is
+	// completed when drained
+	require.NoError(t, w.DB.Delete(step).Error)
+	w.checkCampaignCompletion(context.Background(), campaign.ID, org.ID)
+
+	require.NoError(t, w.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusCompleted, updated.Status)
+	assert.NotNil(t, updated.CompletedAt)
+}
+
 func TestWorker_sendTemplateMessage_BuildsComponents(t *testing.T) {
 	w := testWorker(t)
 
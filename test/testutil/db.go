@@ -97,6 +97,7 @@ func runMigrations(db *gorm.DB) error {
 		&models.WhatsAppAccount{},
 		&models.Contact{},
 		&models.Tag{},
+		&models.ContactField{},
 		&models.Message{},
 		&models.Template{},
 		&models.WhatsAppFlow{},
@@ -107,11 +108,26 @@ func runMigrations(db *gorm.DB) error {
 		&models.ChatbotFlowStep{},
 		&models.ChatbotSession{},
 		&models.ChatbotSessionMessage{},
+		&models.ChatbotWakeup{},
 		&models.AIContext{},
+		&models.AITool{},
+		&models.ChatbotIntent{},
+		&models.IntentClassification{},
+		&models.AIReplySuggestion{},
+		&models.AIUsageLog{},
+		&models.AIBudget{},
+		&models.AIRedactionLog{},
 		&models.AgentTransfer{},
+		&models.KnowledgeDocument{},
+		&models.KnowledgeChunk{},
 		// Bulk message models
 		&models.BulkMessageCampaign{},
 		&models.BulkMessageRecipient{},
+		&models.CampaignVariant{},
+		&models.ContactSegment{},
+		&models.CampaignSequence{},
+		&models.CampaignSequenceStep{},
+		&models.SequenceEnrollment{},
 		&models.NotificationRule{},
 		// Catalog models
 		&models.Catalog{},
@@ -145,9 +161,15 @@ func cleanupTables(db *gorm.DB) {
 		"canned_responses",
 		// Bulk message tables
 		"bulk_message_recipients",
+		"campaign_variants",
+		"sequence_enrollments",
+		"campaign_sequence_steps",
+		"campaign_sequences",
 		"bulk_message_campaigns",
+		"contact_segments",
 		"notification_rules",
 		// Chatbot tables
+		"chatbot_wakeups",
 		"chatbot_session_messages",
 		"chatbot_sessions",
 		"chatbot_flow_steps",
@@ -155,10 +177,20 @@ func cleanupTables(db *gorm.DB) {
 		"keyword_rules",
 		"chatbot_settings",
 		"ai_contexts",
+		"ai_tools",
+		"chatbot_intents",
+		"intent_classifications",
+		"ai_reply_suggestions",
+		"ai_usage_logs",
+		"ai_budgets",
+		"ai_redaction_logs",
 		"agent_transfers",
+		"knowledge_chunks",
+		"knowledge_documents",
 		// WhatsApp tables
 		"messages",
 		"tags",
+		"contact_fields",
 		"contacts",
 		"templates",
 		"whatsapp_flows",
@@ -194,8 +226,14 @@ func TruncateTables(db *gorm.DB) {
 		"catalogs",
 		"canned_responses",
 		"bulk_message_recipients",
+		"campaign_variants",
+		"sequence_enrollments",
+		"campaign_sequence_steps",
+		"campaign_sequences",
 		"bulk_message_campaigns",
+		"contact_segments",
 		"notification_rules",
+		"chatbot_wakeups",
 		"chatbot_session_messages",
 		"chatbot_sessions",
 		"chatbot_flow_steps",
@@ -203,9 +241,19 @@ func TruncateTables(db *gorm.DB) {
 		"keyword_rules",
 		"chatbot_settings",
 		"ai_contexts",
+		"ai_tools",
+		"chatbot_intents",
+		"intent_classifications",
+		"ai_reply_suggestions",
+		"ai_usage_logs",
+		"ai_budgets",
+		"ai_redaction_logs",
 		"agent_transfers",
+		"knowledge_chunks",
+		"knowledge_documents",
 		"messages",
 		"tags",
+		"contact_fields",
 		"contacts",
 		"templates",
 		"whatsapp_flows",